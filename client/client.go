@@ -27,17 +27,21 @@ import (
 type Client struct {
 	*chshare.Logger
 
-	config         *Config
-	sshConfig      *ssh.ClientConfig
-	sshConn        ssh.Conn
-	running        bool
-	runningc       chan error
-	connStats      chshare.ConnStats
-	cmdExec        CmdExecutor
-	curCmdPID      *int
-	curCmdPIDMutex sync.Mutex
-	systemInfo     SystemInfo
-	runCmdMutex    sync.Mutex
+	config      *Config
+	sshConfig   *ssh.ClientConfig
+	sshConn     ssh.Conn
+	running     bool
+	runningc    chan error
+	connStats   chshare.ConnStats
+	cmdExec     CmdExecutor
+	systemInfo  SystemInfo
+	runningCmds runningCmds
+
+	runCmdMutex      sync.Mutex // guards runningCmdsCount and queuedJobs
+	runningCmdsCount int
+	queuedJobs       []*queuedJob
+	// cmdCtx is the context the client is started with, queued commands are started with it
+	cmdCtx context.Context
 }
 
 //NewClient creates a new client instance
@@ -84,6 +88,7 @@ func (c *Client) verifyServer(hostname string, remote net.Addr, key ssh.PublicKe
 
 //Start client and does not block
 func (c *Client) Start(ctx context.Context) error {
+	c.cmdCtx = ctx
 	via := ""
 	if c.config.Client.proxyURL != nil {
 		via = " via " + c.config.Client.proxyURL.String()
@@ -245,6 +250,8 @@ func (c *Client) handleSSHRequests(ctx context.Context, reqs <-chan *ssh.Request
 			resp, err = checkPort(r.Payload)
 		case comm.RequestTypeRunCmd:
 			resp, err = c.HandleRunCmdRequest(ctx, r.Payload)
+		case comm.RequestTypeCancelCmd:
+			resp, err = c.HandleCancelCmdRequest(r.Payload)
 		default:
 			c.Debugf("Unknown request: %q", r.Type)
 			continue
@@ -316,6 +323,10 @@ func (c *Client) connectStreams(chans <-chan ssh.NewChannel) {
 		}
 		go ssh.DiscardRequests(reqs)
 		l := c.Logger.Fork("conn#%d", c.connStats.New())
+		if ch.ChannelType() == chshare.UDPChannelType {
+			go chshare.HandleUDPStream(l, &c.connStats, stream, remote)
+			continue
+		}
 		go chshare.HandleTCPStream(l, &c.connStats, stream, remote)
 	}
 }
@@ -349,18 +360,6 @@ func (c *Client) localIPAddresses() ([]string, []string, error) {
 	return ipv4, ipv6, nil
 }
 
-func (c *Client) getCurCmdPID() *int {
-	c.curCmdPIDMutex.Lock()
-	defer c.curCmdPIDMutex.Unlock()
-	return c.curCmdPID
-}
-
-func (c *Client) setCurCmdPID(pid *int) {
-	c.curCmdPIDMutex.Lock()
-	defer c.curCmdPIDMutex.Unlock()
-	c.curCmdPID = pid
-}
-
 func (c *Client) connectionRequest(ctx context.Context) *chshare.ConnectionRequest {
 	ctx, cancel := context.WithTimeout(ctx, time.Second*5)
 	defer cancel()
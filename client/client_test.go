@@ -39,7 +39,8 @@ func TestCustomHeaders(t *testing.T) {
 			HeadersRaw:       []string{"Foo: Bar"},
 		},
 		RemoteCommands: CommandsConfig{
-			Order: allowDenyOrder,
+			Order:         allowDenyOrder,
+			MaxConcurrent: 1,
 		},
 	}
 	err := config.ParseAndValidate()
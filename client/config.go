@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"path"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"time"
@@ -56,9 +58,17 @@ var (
 type CommandsConfig struct {
 	Enabled       bool      `mapstructure:"enabled"`
 	SendBackLimit int       `mapstructure:"send_back_limit"`
+	MaxConcurrent int       `mapstructure:"max_concurrent"`
 	Allow         []string  `mapstructure:"allow"`
 	Deny          []string  `mapstructure:"deny"`
 	Order         [2]string `mapstructure:"order"`
+	AllowedUsers  []string  `mapstructure:"allowed_users"`
+	// AllowScripts enables execution of scripts. The allow and deny filters can't check the content of scripts.
+	AllowScripts bool `mapstructure:"allow_scripts"`
+	// AllowedCwd are directories, including their subdirectories, commands are allowed to run in.
+	AllowedCwd []string `mapstructure:"allowed_cwd"`
+	// DenyEnv are patterns of names of environment variables commands are not allowed to set, e.g. "LD_*".
+	DenyEnv []string `mapstructure:"deny_env"`
 
 	allowRegexp []*regexp.Regexp
 	denyRegexp  []*regexp.Regexp
@@ -175,6 +185,10 @@ func (c *Config) parseRemoteCommands() error {
 		return fmt.Errorf("send back limit can not be negative: %d", c.RemoteCommands.SendBackLimit)
 	}
 
+	if c.RemoteCommands.MaxConcurrent < 1 {
+		return fmt.Errorf("max concurrent commands should be at least 1: %d", c.RemoteCommands.MaxConcurrent)
+	}
+
 	allow, err := parseRegexpList(c.RemoteCommands.Allow)
 	if err != nil {
 		return fmt.Errorf("allow regexp: %v", err)
@@ -191,6 +205,18 @@ func (c *Config) parseRemoteCommands() error {
 		return fmt.Errorf("invalid order: %v", c.RemoteCommands.Order)
 	}
 
+	for _, dir := range c.RemoteCommands.AllowedCwd {
+		if !filepath.IsAbs(dir) {
+			return fmt.Errorf("allowed cwd should be an absolute path: %q", dir)
+		}
+	}
+
+	for _, pattern := range c.RemoteCommands.DenyEnv {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid deny env pattern %q: %v", pattern, err)
+		}
+	}
+
 	return nil
 }
 
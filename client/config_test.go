@@ -20,6 +20,7 @@ var defaultValidMinConfig = Config{
 	RemoteCommands: CommandsConfig{
 		Enabled:       true,
 		SendBackLimit: 2048,
+		MaxConcurrent: 1,
 		Order:         allowDenyOrder,
 		allowRegexp:   []*regexp.Regexp{regexp.MustCompile(".*")},
 	},
@@ -230,6 +231,7 @@ func TestConfigParseAndValidateRemotes(t *testing.T) {
 				&chshare.Remote{
 					RemoteHost: "0.0.0.0",
 					RemotePort: "8000",
+					Protocol:   chshare.ProtocolTCP,
 				},
 			},
 		}, {
@@ -239,12 +241,51 @@ func TestConfigParseAndValidateRemotes(t *testing.T) {
 				&chshare.Remote{
 					RemoteHost: "0.0.0.0",
 					RemotePort: "8000",
+					Protocol:   chshare.ProtocolTCP,
 				},
 				&chshare.Remote{
 					RemoteHost: "0.0.0.0",
 					RemotePort: "3000",
+					Protocol:   chshare.ProtocolTCP,
 				},
 			},
+		}, {
+			Name:    "udp",
+			Remotes: []string{"5353:192.168.1.1:53/udp"},
+			ExpectedRemotes: []*chshare.Remote{
+				&chshare.Remote{
+					LocalHost:  "0.0.0.0",
+					LocalPort:  "5353",
+					RemoteHost: "192.168.1.1",
+					RemotePort: "53",
+					Protocol:   chshare.ProtocolUDP,
+				},
+			},
+		}, {
+			Name:    "ipv6",
+			Remotes: []string{"[::]:2222:[fd00::1]:22", "[::1]:80"},
+			ExpectedRemotes: []*chshare.Remote{
+				&chshare.Remote{
+					LocalHost:  "::",
+					LocalPort:  "2222",
+					RemoteHost: "fd00::1",
+					RemotePort: "22",
+					Protocol:   chshare.ProtocolTCP,
+				},
+				&chshare.Remote{
+					RemoteHost: "::1",
+					RemotePort: "80",
+					Protocol:   chshare.ProtocolTCP,
+				},
+			},
+		}, {
+			Name:          "invalid ipv6 host",
+			Remotes:       []string{"[fd00::1:22"},
+			ExpectedError: `failed to decode remote "[fd00::1:22": Missing ']' in IPv6 host`,
+		}, {
+			Name:          "invalid protocol",
+			Remotes:       []string{"8000/sctp"},
+			ExpectedError: `failed to decode remote "8000/sctp": Invalid protocol "sctp", expected one of: tcp, udp`,
 		}, {
 			Name:          "invalid",
 			Remotes:       []string{"abc"},
@@ -342,6 +383,44 @@ func TestConfigParseAndValidateSendBackLimit(t *testing.T) {
 	}
 }
 
+func TestConfigParseAndValidateMaxConcurrent(t *testing.T) {
+	testCases := []struct {
+		name            string
+		maxConcurrent   int
+		wantErrContains string
+	}{
+		{
+			name:            "valid",
+			maxConcurrent:   3,
+			wantErrContains: "",
+		},
+		{
+			name:            "invalid zero",
+			maxConcurrent:   0,
+			wantErrContains: "max concurrent commands should be at least 1",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			config := defaultValidMinConfig
+			config.RemoteCommands.MaxConcurrent = tc.maxConcurrent
+
+			// when
+			gotErr := config.ParseAndValidate()
+
+			// then
+			if tc.wantErrContains != "" {
+				require.Error(t, gotErr)
+				assert.Contains(t, gotErr.Error(), tc.wantErrContains)
+			} else {
+				require.NoError(t, gotErr)
+			}
+		})
+	}
+}
+
 func TestConfigParseAndValidateAllowRegexp(t *testing.T) {
 	testCases := []struct {
 		name            string
@@ -487,3 +566,48 @@ func TestConfigParseAndValidateAllowDenyOrder(t *testing.T) {
 		})
 	}
 }
+
+func TestConfigParseAndValidateCwdAndEnv(t *testing.T) {
+	testCases := []struct {
+		name            string
+		allowedCwd      []string
+		denyEnv         []string
+		wantErrContains string
+	}{
+		{
+			name:       "valid",
+			allowedCwd: []string{"/var/www"},
+			denyEnv:    []string{"PATH", "LD_*"},
+		},
+		{
+			name:            "relative cwd",
+			allowedCwd:      []string{"www"},
+			wantErrContains: `allowed cwd should be an absolute path: "www"`,
+		},
+		{
+			name:            "invalid env pattern",
+			denyEnv:         []string{"LD_["},
+			wantErrContains: `invalid deny env pattern "LD_["`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			config := defaultValidMinConfig
+			config.RemoteCommands.AllowedCwd = tc.allowedCwd
+			config.RemoteCommands.DenyEnv = tc.denyEnv
+
+			// when
+			gotErr := config.ParseAndValidate()
+
+			// then
+			if tc.wantErrContains != "" {
+				require.Error(t, gotErr)
+				assert.Contains(t, gotErr.Error(), tc.wantErrContains)
+			} else {
+				require.NoError(t, gotErr)
+			}
+		})
+	}
+}
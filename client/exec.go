@@ -6,9 +6,15 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
 	"os/exec"
+	"path"
+	"path/filepath"
 	"regexp"
 	"runtime"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/cloudradar-monitoring/rport/share/comm"
@@ -17,8 +23,16 @@ import (
 
 type CmdExecutor interface {
 	New(ctx context.Context, shell, cmd string) *exec.Cmd
+	// NewScript returns a command that executes a script file by a given interpreter with given args.
+	NewScript(ctx context.Context, interpreter string, args []string) *exec.Cmd
 	Start(cmd *exec.Cmd) error
 	Wait(cmd *exec.Cmd) error
+	// ExitCode returns the exit code of a given finished command or -1 if it was terminated by a signal.
+	ExitCode(cmd *exec.Cmd) int
+	// Terminate asks a given started command together with all its child processes to stop gracefully.
+	Terminate(cmd *exec.Cmd) error
+	// Kill kills a given started command together with all its child processes.
+	Kill(cmd *exec.Cmd) error
 }
 
 type CmdExecutorImpl struct {
@@ -36,6 +50,13 @@ func (e *CmdExecutorImpl) Wait(cmd *exec.Cmd) error {
 	return cmd.Wait()
 }
 
+func (e *CmdExecutorImpl) ExitCode(cmd *exec.Cmd) int {
+	if cmd.ProcessState == nil {
+		return -1
+	}
+	return cmd.ProcessState.ExitCode()
+}
+
 func (e *CmdExecutorImpl) newCmd(ctx context.Context, shell, command string) *exec.Cmd {
 	var args []string
 	args = append(args, shellOptions[shell]...)
@@ -74,104 +95,333 @@ func (c *Client) HandleRunCmdRequest(ctx context.Context, reqPayload []byte) (*c
 		return nil, fmt.Errorf("failed to decode requested job: %s", err)
 	}
 
-	// do not accept a new request when the previous is not finished yet, except multi-client job. In this case wait
-	// NOTE: HandleRunCmdRequest is run sequentially, that's why no need to lock a block with read/write curPID
-	curPID := c.getCurCmdPID()
-	if curPID != nil {
-		if job.MultiJobID == nil {
-			return nil, fmt.Errorf("a previous command execution with PID %d is still running", *curPID)
+	if job.Script != "" {
+		// the content of scripts can't be checked by the filters
+		if !c.config.RemoteCommands.AllowScripts {
+			return nil, errors.New("scripts execution is disabled")
+		}
+
+		job.Interpreter, err = getInterpreter(job.Interpreter, runtime.GOOS)
+		if err != nil {
+			return nil, err
+		}
+
+		// for scripts the filters are applied to the interpreter
+		if !c.isAllowed(job.Interpreter) {
+			return nil, fmt.Errorf("script interpreter is not allowed: %v", job.Interpreter)
+		}
+	} else {
+		job.Shell, err = getShell(job.Shell, runtime.GOOS)
+		if err != nil {
+			return nil, err
+		}
+
+		if !c.isAllowed(job.Command) {
+			return nil, fmt.Errorf("command is not allowed: %v", job.Command)
 		}
-		c.Debugf("Waiting for a previous command with PID %d to finish", *curPID)
 	}
 
-	// TODO: temporary solution, refactor with using worker pool
+	if job.RunAsUser != "" && !c.isUserAllowed(job.RunAsUser) {
+		return nil, fmt.Errorf("running commands as user %q is not allowed", job.RunAsUser)
+	}
+
+	if job.Cwd != "" && !c.isCwdAllowed(job.Cwd) {
+		return nil, fmt.Errorf("running commands in directory %q is not allowed", job.Cwd)
+	}
+
+	if name := c.deniedEnv(job.Env); name != "" {
+		return nil, fmt.Errorf("setting environment variable %q is not allowed", name)
+	}
+
 	c.runCmdMutex.Lock()
+	defer c.runCmdMutex.Unlock()
+
+	// queue the job if all workers are busy, it's started as soon as one of running commands is finished
+	if c.runningCmdsCount >= c.config.RemoteCommands.MaxConcurrent {
+		c.queuedJobs = append(c.queuedJobs, &queuedJob{job: &job})
+		c.Debugf("max number of concurrent commands (%d) is reached, command[jid=%q] is queued", c.config.RemoteCommands.MaxConcurrent, job.JID)
+		return &comm.RunCmdResponse{Queued: true}, nil
+	}
 
-	job.Shell, err = getShell(job.Shell, runtime.GOOS)
+	res, err := c.startCmd(ctx, &job, false)
 	if err != nil {
-		c.runCmdMutex.Unlock()
 		return nil, err
 	}
+	c.runningCmdsCount++
 
-	if !c.isAllowed(job.Command) {
-		c.runCmdMutex.Unlock()
-		return nil, fmt.Errorf("command is not allowed: %v", job.Command)
-	}
+	return res, nil
+}
 
-	cmd := c.cmdExec.New(ctx, job.Shell, job.Command)
-	stdOut := CapacityBuffer{capacity: c.config.RemoteCommands.SendBackLimit}
-	stdErr := CapacityBuffer{capacity: c.config.RemoteCommands.SendBackLimit}
-	cmd.Stdout = &stdOut
-	cmd.Stderr = &stdErr
+// startCmd starts a command of a given job and observes its execution in background.
+// If notifyStarted is true the server is notified about the started job before its result can be sent.
+// NOTE: runCmdMutex should be locked.
+func (c *Client) startCmd(ctx context.Context, job *models.Job, notifyStarted bool) (*comm.RunCmdResponse, error) {
+	cmd, scriptPath, err := c.newJobCmd(ctx, job)
+	if err != nil {
+		return nil, err
+	}
+	output := newOutputStream(c.config.RemoteCommands.SendBackLimit, func(stdOut, stdErr string) {
+		c.sendCmdOutput(job, stdOut, stdErr)
+	})
+	cmd.Stdout = output.StdOut()
+	cmd.Stderr = output.StdErr()
 
 	startedAt := now()
 	err = c.cmdExec.Start(cmd)
 	if err != nil {
-		c.runCmdMutex.Unlock()
+		c.removeScript(scriptPath)
 		return nil, fmt.Errorf("failed to start a command: %s", err)
 	}
 
-	// set running PID
-	c.setCurCmdPID(&cmd.Process.Pid)
+	c.runningCmds.Add(job.JID, cmd)
+	output.Start()
 
 	res := &comm.RunCmdResponse{
 		Pid:       cmd.Process.Pid,
 		StartedAt: startedAt,
 	}
 
-	// observe the cmd execution in background
+	if notifyStarted {
+		job.Status = models.JobStatusRunning
+		job.PID = &res.Pid
+		job.StartedAt = startedAt
+		c.sendJob(comm.RequestTypeCmdStarted, job)
+	}
+
+	go c.observeCmd(job, cmd, output, res, scriptPath)
+
+	return res, nil
+}
+
+// newJobCmd returns a command to execute a given job. If it's a script job the script is written to a file,
+// its path is returned as well.
+func (c *Client) newJobCmd(ctx context.Context, job *models.Job) (cmd *exec.Cmd, scriptPath string, err error) {
+	if job.Script != "" {
+		scriptPath, err = writeScript(job)
+		if err != nil {
+			return nil, "", err
+		}
+		cmd = c.cmdExec.NewScript(ctx, job.Interpreter, scriptArgs(job.Interpreter, scriptPath))
+	} else {
+		cmd = c.cmdExec.New(ctx, job.Shell, job.Command)
+	}
+
+	cmd.Dir = job.Cwd
+	if len(job.Env) > 0 {
+		cmd.Env = append(os.Environ(), envList(job.Env)...)
+	}
+	if job.RunAsUser != "" {
+		if err := setCmdUser(cmd, job.RunAsUser); err != nil {
+			c.removeScript(scriptPath)
+			return nil, "", err
+		}
+	}
+
+	return cmd, scriptPath, nil
+}
+
+// removeScript removes a script file of a finished job if there is any.
+func (c *Client) removeScript(scriptPath string) {
+	if scriptPath == "" {
+		return
+	}
+	if err := os.Remove(scriptPath); err != nil {
+		c.Errorf("failed to remove script file %q: %s", scriptPath, err)
+	}
+}
+
+func (c *Client) observeCmd(job *models.Job, cmd *exec.Cmd, output *outputStream, res *comm.RunCmdResponse, scriptPath string) {
+	c.Debugf("started to observe cmd [jid=%q,pid=%d]", job.JID, res.Pid)
+
+	// after timeout stop observing but leave the cmd running
+	done := make(chan error, 1)
+	var cancelled bool
 	go func() {
-		c.Debugf("started to observe cmd [jid=%q,pid=%d]", job.JID, res.Pid)
-
-		// after timeout stop observing but leave the cmd running
-		done := make(chan error)
-		go func() { done <- c.cmdExec.Wait(cmd) }()
-
-		var status string
-		select {
-		case err := <-done:
-			if err != nil {
-				status = models.JobStatusFailed
-				c.Errorf("failed to run command[jid=%q,pid=%d]:\ncmd:\n%s\nerr: %s", job.JID, res.Pid, job.Command, err)
-			} else {
-				status = models.JobStatusSuccessful
-			}
-		case <-time.After(time.Duration(job.TimeoutSec) * time.Second):
+		err := c.cmdExec.Wait(cmd)
+		// the script file is needed until the cmd is finished even if observing is stopped
+		c.removeScript(scriptPath)
+		// keep the cmd until it's finished to be able to cancel it even after observing is stopped
+		cancelled = c.runningCmds.Remove(job.JID)
+		// the cmd is finished - release the worker
+		c.finishCmd()
+		done <- err
+	}()
+
+	var status string
+	select {
+	case err := <-done:
+		exitCode := c.cmdExec.ExitCode(cmd)
+		if exitCode >= 0 {
+			job.ExitCode = &exitCode
+		}
+		_, isExitErr := err.(*exec.ExitError)
+		if cancelled {
+			status = models.JobStatusCancelled
+			c.Infof("command[jid=%q,pid=%d] was cancelled", job.JID, res.Pid)
+		} else if err != nil && !isExitErr {
+			status = models.JobStatusFailed
+			c.Errorf("failed to run command[jid=%q,pid=%d]:\ncmd:\n%s\nerr: %s", job.JID, res.Pid, job.Command, err)
+		} else if !job.IsSuccessExitCode(exitCode) {
+			status = models.JobStatusFailed
+			c.Errorf("command[jid=%q,pid=%d] finished with exit code %d:\ncmd:\n%s", job.JID, res.Pid, exitCode, job.Command)
+		} else {
+			status = models.JobStatusSuccessful
+		}
+	case <-time.After(time.Duration(job.TimeoutSec) * time.Second):
+		if job.TimeoutPolicy == models.JobTimeoutPolicyKill {
+			status = models.JobStatusTimeout
+			c.Infof("timeout (%d seconds) reached, stopping command[jid=%q,pid=%d]:\n%s", job.TimeoutSec, job.JID, res.Pid, job.Command)
+			c.stopCmd(job, cmd, done)
+		} else {
 			status = models.JobStatusUnknown
 			c.Debugf("timeout (%d seconds) reached, stop observing command[jid=%q,pid=%d]:\n%s", job.TimeoutSec, job.JID, res.Pid, job.Command)
 		}
+	}
 
-		// observing stopped - unset PID
-		c.setCurCmdPID(nil)
-		c.runCmdMutex.Unlock()
+	// send the remaining output before the result
+	output.Stop()
 
-		// fill all unset fields
-		now := now()
-		job.FinishedAt = &now
-		job.Status = status
-		job.PID = &res.Pid
-		job.StartedAt = startedAt
-		job.Result = &models.JobResult{
-			StdOut: stdOut.String(),
-			StdErr: stdErr.String(),
-		}
+	// fill all unset fields
+	now := now()
+	job.FinishedAt = &now
+	job.Status = status
+	job.PID = &res.Pid
+	job.StartedAt = res.StartedAt
+	job.Result = output.Result()
+
+	// send the filled job to the server
+	c.sendJob(comm.RequestTypeCmdResult, job)
+
+	c.Debugf("finished to observe cmd [jid=%q,pid=%d]", job.JID, res.Pid)
+}
+
+// stopCmd terminates a given command and kills it if it's still running after the grace period of a given job.
+// done is a channel that receives a value when the command is finished.
+func (c *Client) stopCmd(job *models.Job, cmd *exec.Cmd, done <-chan error) {
+	grace := time.Duration(job.TimeoutGraceSec) * time.Second
+
+	if err := c.cmdExec.Terminate(cmd); err != nil {
+		c.Errorf("failed to terminate command[jid=%q,pid=%d]: %s", job.JID, cmd.Process.Pid, err)
+	}
+	select {
+	case <-done:
+		return
+	case <-time.After(grace):
+	}
 
-		// send the filled job to the server
-		jobBytes, err := json.Marshal(job)
+	c.Infof("grace period (%d seconds) is over, killing command[jid=%q,pid=%d]", job.TimeoutGraceSec, job.JID, cmd.Process.Pid)
+	if err := c.cmdExec.Kill(cmd); err != nil {
+		c.Errorf("failed to kill command[jid=%q,pid=%d]: %s", job.JID, cmd.Process.Pid, err)
+		return
+	}
+	// wait for the remaining output, but not forever: a child process that left the process group can hold it open
+	select {
+	case <-done:
+	case <-time.After(grace):
+	}
+}
+
+// finishCmd releases a worker of a finished command and starts queued commands.
+func (c *Client) finishCmd() {
+	c.runCmdMutex.Lock()
+	defer c.runCmdMutex.Unlock()
+
+	c.runningCmdsCount--
+	for c.runningCmdsCount < c.config.RemoteCommands.MaxConcurrent && len(c.queuedJobs) > 0 {
+		next := c.queuedJobs[0]
+		c.queuedJobs = c.queuedJobs[1:]
+
+		// the run_cmd request of a queued job is already handled, so it's started with the context of the client
+		_, err := c.startCmd(c.cmdCtx, next.job, true)
 		if err != nil {
-			c.Errorf("failed to send command result for [jid=%q,pid=%d]: failed to encode job result: %s", job.JID, res.Pid, err)
-			return
+			c.Errorf("failed to start queued command[jid=%q]: %s", next.job.JID, err)
+			now := now()
+			next.job.Status = models.JobStatusFailed
+			next.job.FinishedAt = &now
+			next.job.Error = err.Error()
+			c.sendJob(comm.RequestTypeCmdResult, next.job)
+			continue
 		}
-		c.Debugf("sending job to server: %v", job)
-		_, _, err = c.sshConn.SendRequest(comm.RequestTypeCmdResult, false, jobBytes)
-		if err != nil {
-			c.Errorf("failed to send command result to server[jid=%q,pid=%d]: %s", job.JID, res.Pid, err)
+		c.runningCmdsCount++
+	}
+}
+
+// cancelQueued removes a queued job with a given ID. Returns false if it's not queued.
+func (c *Client) cancelQueued(jid string) bool {
+	c.runCmdMutex.Lock()
+	defer c.runCmdMutex.Unlock()
+
+	for i, cur := range c.queuedJobs {
+		if cur.job.JID != jid {
+			continue
 		}
+		c.queuedJobs = append(c.queuedJobs[:i], c.queuedJobs[i+1:]...)
 
-		c.Debugf("finished to observe cmd [jid=%q,pid=%d]", job.JID, res.Pid)
-	}()
+		now := now()
+		cur.job.Status = models.JobStatusCancelled
+		cur.job.FinishedAt = &now
+		c.sendJob(comm.RequestTypeCmdResult, cur.job)
+		return true
+	}
+	return false
+}
 
-	return res, nil
+// sendJob sends a given job to the server with a given request type.
+func (c *Client) sendJob(reqType string, job *models.Job) {
+	jobBytes, err := json.Marshal(job)
+	if err != nil {
+		c.Errorf("failed to send %s for [jid=%q]: failed to encode job: %s", reqType, job.JID, err)
+		return
+	}
+	c.Debugf("sending job to server: %v", job)
+	_, _, err = c.sshConn.SendRequest(reqType, false, jobBytes)
+	if err != nil {
+		c.Errorf("failed to send %s to server[jid=%q]: %s", reqType, job.JID, err)
+	}
+}
+
+// HandleCancelCmdRequest kills a running command of a given job together with its child processes.
+func (c *Client) HandleCancelCmdRequest(reqPayload []byte) (*comm.CancelCmdResponse, error) {
+	req := comm.CancelCmdRequest{}
+	err := json.Unmarshal(reqPayload, &req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode cancel request: %s", err)
+	}
+
+	if c.cancelQueued(req.JID) {
+		c.Debugf("cancelled queued command[jid=%q]", req.JID)
+		return &comm.CancelCmdResponse{}, nil
+	}
+
+	pid, err := c.runningCmds.Cancel(req.JID, c.cmdExec.Kill)
+	if err != nil {
+		return nil, err
+	}
+	c.Debugf("cancelled command[jid=%q,pid=%d]", req.JID, pid)
+
+	return &comm.CancelCmdResponse{
+		Pid: pid,
+	}, nil
+}
+
+// sendCmdOutput sends a chunk of output of a running job to the server.
+func (c *Client) sendCmdOutput(job *models.Job, stdOut, stdErr string) {
+	chunk := models.JobOutput{
+		JID:        job.JID,
+		MultiJobID: job.MultiJobID,
+		ClientID:   job.ClientID,
+		StdOut:     stdOut,
+		StdErr:     stdErr,
+	}
+	chunkBytes, err := json.Marshal(chunk)
+	if err != nil {
+		c.Errorf("failed to encode command output[jid=%q]: %s", job.JID, err)
+		return
+	}
+	_, _, err = c.sshConn.SendRequest(comm.RequestTypeCmdOutput, false, chunkBytes)
+	if err != nil {
+		c.Errorf("failed to send command output to server[jid=%q]: %s", job.JID, err)
+	}
 }
 
 // var is used to override in tests
@@ -211,6 +461,74 @@ func (c *Client) isAllowed(cmd string) bool {
 	return false
 }
 
+// isUserAllowed returns true if commands are allowed to run as a given user.
+func (c *Client) isUserAllowed(username string) bool {
+	for _, cur := range c.config.RemoteCommands.AllowedUsers {
+		if cur == username {
+			return true
+		}
+	}
+	return false
+}
+
+// isCwdAllowed returns true if commands are allowed to run in a given working directory. It should be one of allowed
+// directories or their subdirectory. Symlinks are resolved, so they can't be used to leave an allowed directory.
+func (c *Client) isCwdAllowed(cwd string) bool {
+	if !filepath.IsAbs(cwd) {
+		return false
+	}
+	resolved := resolvePath(cwd)
+	for _, dir := range c.config.RemoteCommands.AllowedCwd {
+		rel, err := filepath.Rel(resolvePath(dir), resolved)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolvePath returns a given path with evaluated symlinks. If it can't be evaluated, e.g. the path doesn't exist,
+// the path is returned cleaned.
+func resolvePath(p string) string {
+	if resolved, err := filepath.EvalSymlinks(p); err == nil {
+		return resolved
+	}
+	return filepath.Clean(p)
+}
+
+// deniedEnv returns the first name of given environment variables, in sorted order, that commands are not allowed to set.
+// Returns an empty string if all are allowed. Names are matched case-insensitively as on Windows they're case-insensitive.
+func (c *Client) deniedEnv(env map[string]string) string {
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		// such names would set other variables
+		if name == "" || strings.ContainsAny(name, "=\x00") {
+			return name
+		}
+		for _, pattern := range c.config.RemoteCommands.DenyEnv {
+			if matched, _ := path.Match(strings.ToUpper(pattern), strings.ToUpper(name)); matched {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// envList returns given environment variables in "key=value" form sorted by key.
+func envList(env map[string]string) []string {
+	res := make([]string, 0, len(env))
+	for k, v := range env {
+		res = append(res, k+"="+v)
+	}
+	sort.Strings(res)
+	return res
+}
+
 // matchRegexp returns true if a given command matches at least one of given regular expressions.
 func matchRegexp(cmd string, regexpList []*regexp.Regexp) bool {
 	for _, regexp := range regexpList {
@@ -241,3 +559,52 @@ func (b *CapacityBuffer) Write(p []byte) (n int, err error) {
 
 	return b.Buffer.Write(p)
 }
+
+type queuedJob struct {
+	job *models.Job
+}
+
+type runningCmd struct {
+	cmd       *exec.Cmd
+	cancelled bool
+}
+
+// runningCmds is thread safe. It keeps started commands by job ID until they are finished.
+// A zero value is ready to use.
+type runningCmds struct {
+	mu   sync.Mutex
+	cmds map[string]*runningCmd
+}
+
+func (r *runningCmds) Add(jid string, cmd *exec.Cmd) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.cmds == nil {
+		r.cmds = make(map[string]*runningCmd)
+	}
+	r.cmds[jid] = &runningCmd{cmd: cmd}
+}
+
+// Remove removes a finished command and returns whether it was cancelled.
+func (r *runningCmds) Remove(jid string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cur := r.cmds[jid]
+	delete(r.cmds, jid)
+	return cur != nil && cur.cancelled
+}
+
+// Cancel kills a running command of a given job and returns its PID.
+func (r *runningCmds) Cancel(jid string, kill func(cmd *exec.Cmd) error) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cur := r.cmds[jid]
+	if cur == nil {
+		return 0, fmt.Errorf("no running command found for job %q", jid)
+	}
+	if err := kill(cur.cmd); err != nil {
+		return 0, fmt.Errorf("failed to kill command with PID %d: %s", cur.cmd.Process.Pid, err)
+	}
+	cur.cancelled = true
+	return cur.cmd.Process.Pid, nil
+}
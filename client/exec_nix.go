@@ -4,9 +4,91 @@ package chclient
 
 import (
 	"context"
+	"fmt"
+	"os"
 	"os/exec"
+	"os/user"
+	"strconv"
+	"syscall"
 )
 
 func (e *CmdExecutorImpl) New(ctx context.Context, shell, command string) *exec.Cmd {
-	return e.newCmd(ctx, shell, command)
+	cmd := e.newCmd(ctx, shell, command)
+	// run in a separate process group to be able to kill the cmd together with its child processes
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	return cmd
+}
+
+func (e *CmdExecutorImpl) NewScript(ctx context.Context, interpreter string, args []string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, interpreter, args...)
+	// run in a separate process group to be able to kill the cmd together with its child processes
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	return cmd
+}
+
+func (e *CmdExecutorImpl) Terminate(cmd *exec.Cmd) error {
+	// negative PID terminates the whole process group
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGTERM)
+}
+
+func (e *CmdExecutorImpl) Kill(cmd *exec.Cmd) error {
+	// negative PID kills the whole process group
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
+
+// setCmdUser makes a given command run with the user and group IDs of a given local user.
+func setCmdUser(cmd *exec.Cmd, username string) error {
+	cred, err := userCredential(username)
+	if err != nil {
+		return err
+	}
+
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Credential = cred
+	return nil
+}
+
+// chownToUser changes the owner of a given file to a given local user.
+func chownToUser(path, username string) error {
+	cred, err := userCredential(username)
+	if err != nil {
+		return err
+	}
+	return os.Chown(path, int(cred.Uid), int(cred.Gid))
+}
+
+// userCredential returns the user and group IDs of a given local user.
+func userCredential(username string) (*syscall.Credential, error) {
+	u, err := user.Lookup(username)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find user %q: %s", username, err)
+	}
+	uid, err := strconv.ParseUint(u.Uid, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid uid of user %q: %s", username, err)
+	}
+	gid, err := strconv.ParseUint(u.Gid, 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid gid of user %q: %s", username, err)
+	}
+	groupIDs, err := u.GroupIds()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get groups of user %q: %s", username, err)
+	}
+	groups := make([]uint32, 0, len(groupIDs))
+	for _, cur := range groupIDs {
+		g, err := strconv.ParseUint(cur, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid group id of user %q: %s", username, err)
+		}
+		groups = append(groups, uint32(g))
+	}
+
+	return &syscall.Credential{
+		Uid:    uint32(uid),
+		Gid:    uint32(gid),
+		Groups: groups,
+	}, nil
 }
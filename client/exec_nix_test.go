@@ -0,0 +1,36 @@
+//+build !windows
+
+package chclient
+
+import (
+	"os/exec"
+	"os/user"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetCmdUser(t *testing.T) {
+	// given
+	curUser, err := user.Current()
+	require.NoError(t, err)
+	wantUID, err := strconv.ParseUint(curUser.Uid, 10, 32)
+	require.NoError(t, err)
+	wantGID, err := strconv.ParseUint(curUser.Gid, 10, 32)
+	require.NoError(t, err)
+	cmd := exec.Command("/bin/sh", "-c", "id")
+
+	// when
+	gotErr := setCmdUser(cmd, curUser.Username)
+	gotUnknownErr := setCmdUser(exec.Command("/bin/sh", "-c", "id"), "unknown-test-user")
+
+	// then
+	require.NoError(t, gotErr)
+	require.NotNil(t, cmd.SysProcAttr)
+	require.NotNil(t, cmd.SysProcAttr.Credential)
+	assert.Equal(t, uint32(wantUID), cmd.SysProcAttr.Credential.Uid)
+	assert.Equal(t, uint32(wantGID), cmd.SysProcAttr.Credential.Gid)
+	assert.Error(t, gotUnknownErr)
+}
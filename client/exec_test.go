@@ -2,7 +2,9 @@ package chclient
 
 import (
 	"context"
-	"fmt"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
 	"log"
 	"os"
 	"os/exec"
@@ -17,6 +19,7 @@ import (
 
 	chshare "github.com/cloudradar-monitoring/rport/share"
 	"github.com/cloudradar-monitoring/rport/share/comm"
+	"github.com/cloudradar-monitoring/rport/share/models"
 	"github.com/cloudradar-monitoring/rport/share/test"
 )
 
@@ -25,8 +28,12 @@ type CmdExecutorMock struct {
 	ReturnPID      int
 	ReturnStartErr error
 	ReturnWaitErr  error
+	ReturnExitCode int
+	ReturnKillErr  error
 	ReturnStdOut   []string
 	ReturnStdErr   []string
+	// StopChannel receives a name of a called method when a command is terminated or killed, if set.
+	StopChannel chan string
 
 	wg sync.WaitGroup
 }
@@ -43,6 +50,10 @@ func (e *CmdExecutorMock) New(ctx context.Context, shell, command string) *exec.
 	return cmd
 }
 
+func (e *CmdExecutorMock) NewScript(ctx context.Context, interpreter string, args []string) *exec.Cmd {
+	return exec.CommandContext(ctx, interpreter, args...)
+}
+
 func (e *CmdExecutorMock) Start(cmd *exec.Cmd) error {
 	if e.ReturnStartErr != nil {
 		return e.ReturnStartErr
@@ -95,6 +106,24 @@ func (e *CmdExecutorMock) Wait(cmd *exec.Cmd) error {
 	return nil
 }
 
+func (e *CmdExecutorMock) ExitCode(cmd *exec.Cmd) int {
+	return e.ReturnExitCode
+}
+
+func (e *CmdExecutorMock) Terminate(cmd *exec.Cmd) error {
+	if e.StopChannel != nil {
+		e.StopChannel <- "terminate"
+	}
+	return nil
+}
+
+func (e *CmdExecutorMock) Kill(cmd *exec.Cmd) error {
+	if e.StopChannel != nil {
+		e.StopChannel <- "kill"
+	}
+	return e.ReturnKillErr
+}
+
 // nowMock is used to override time now.
 var nowMockF = func() time.Time {
 	n, _ := time.Parse(time.RFC3339, "2020-08-19T12:00:00+03:00")
@@ -210,6 +239,7 @@ func TestHandleRunCmdRequestPositiveCase(t *testing.T) {
 	// mimic real behavior and wait until background task sends the request
 	done := make(chan bool)
 	connMock.DoneChannel = done
+	connMock.IgnoredRequests = []string{comm.RequestTypeCmdOutput}
 	configCopy := defaultValidMinConfig
 	c := Client{
 		cmdExec: execMock,
@@ -226,12 +256,25 @@ func TestHandleRunCmdRequestPositiveCase(t *testing.T) {
 	"client_id": "d81e6b93e75aef59a7701b90555f43808458b34e30370c3b808c1816a32252b3",
 	"command": "/bin/date;foo;whoami",
 	"shell": "test-shell",
+	"interpreter": "",
+	"script": "",
+	"script_hash": "",
+	"script_id": "",
+	"script_version": 0,
+	"cwd": "",
+	"env": null,
+	"run_as_user": "",
 	"pid": 123,
 	"started_at": "2020-08-19T12:00:00+03:00",
 	"created_by": "admin",
 	"timeout_sec": 60,
+	"timeout_policy": "",
+	"timeout_grace_sec": 0,
+	"success_exit_codes": null,
 	"multi_job_id":null,
+	"expires_at":null,
 	"error":"",
+	"exit_code": 0,
 `
 	wantJSONPart2 := `
 	   "result": {
@@ -328,7 +371,7 @@ func TestHandleRunCmdRequestPositiveCase(t *testing.T) {
 	}
 }
 
-func TestHandleRunCmdRequestHasRunningCmd(t *testing.T) {
+func TestHandleRunCmdRequestQueued(t *testing.T) {
 	now = nowMockF
 	assert := assert.New(t)
 
@@ -344,6 +387,7 @@ func TestHandleRunCmdRequestHasRunningCmd(t *testing.T) {
 	// mimic real behavior to have the 1st command still running when the 2nd request comes
 	doneSendResp := make(chan bool)
 	connMock.DoneChannel = doneSendResp
+	connMock.IgnoredRequests = []string{comm.RequestTypeCmdOutput}
 	doneCmd := make(chan bool)
 	execMock.DoneChannel = doneCmd
 
@@ -353,32 +397,414 @@ func TestHandleRunCmdRequestHasRunningCmd(t *testing.T) {
 		sshConn: connMock,
 		Logger:  testLog,
 		config:  &configCopy,
+		cmdCtx:  context.Background(),
 	}
+	job2JSON := strings.Replace(jobToRunJSON, "5f02b216-3f8a-42be-b66c-f4c1d0ea3809", "job-2", 1)
 
 	// when
-	// run two cmds to get an error for the 2nd
+	// run two cmds to get the 2nd queued
 	res1, err1 := c.HandleRunCmdRequest(context.Background(), []byte(jobToRunJSON))
-	res2, err2 := c.HandleRunCmdRequest(context.Background(), []byte(jobToRunJSON))
+	res2, err2 := c.HandleRunCmdRequest(context.Background(), []byte(job2JSON))
 
 	// then
-	// check that running new commands is blocked
-	curPID := c.getCurCmdPID()
-	require.NotNil(t, curPID)
-	assert.Equal(wantPID, *curPID)
-	// finish the cmd execution
+	require.NoError(t, err1)
+	assert.Equal(&comm.RunCmdResponse{Pid: wantPID, StartedAt: nowMock}, res1)
+	require.NoError(t, err2)
+	assert.Equal(&comm.RunCmdResponse{Queued: true}, res2)
+
+	// finish the 1st cmd execution, the 2nd cmd is started
+	<-doneCmd
+	<-doneSendResp
+	<-doneSendResp
+	// finish the 2nd cmd execution
 	<-doneCmd
-	// finish to send the response to server
 	<-doneSendResp
-	// check that running new commands is not blocked anymore
-	curPID = c.getCurCmdPID()
-	assert.Nil(curPID)
 
-	// check the result
-	require.NoError(t, err1)
-	assert.Equal(&comm.RunCmdResponse{Pid: wantPID, StartedAt: nowMock}, res1)
-	assert.Error(err2)
-	assert.Equal(fmt.Errorf("a previous command execution with PID %d is still running", wantPID), err2)
-	assert.Nil(res2)
+	gotJobs := make(map[string]models.Job)
+	for _, req := range connMock.InputSendRequests() {
+		job := models.Job{}
+		require.NoError(t, json.Unmarshal(req.Payload, &job))
+		gotJobs[req.Name+" "+job.JID] = job
+	}
+	require.Len(t, gotJobs, 3)
+	assert.Equal(models.JobStatusSuccessful, gotJobs[comm.RequestTypeCmdResult+" 5f02b216-3f8a-42be-b66c-f4c1d0ea3809"].Status)
+	gotStarted := gotJobs[comm.RequestTypeCmdStarted+" job-2"]
+	assert.Equal(models.JobStatusRunning, gotStarted.Status)
+	assert.Equal(&wantPID, gotStarted.PID)
+	assert.Equal(nowMock, gotStarted.StartedAt)
+	assert.Equal(models.JobStatusSuccessful, gotJobs[comm.RequestTypeCmdResult+" job-2"].Status)
+	assert.Equal(comm.RequestTypeCmdResult, connMock.InputSendRequests()[2].Name)
+	assert.Equal(0, c.runningCmdsCount)
+	assert.Empty(c.queuedJobs)
+}
+
+func TestHandleCancelCmdRequest(t *testing.T) {
+	now = nowMockF
+
+	// given
+	wantPID := 123
+	execMock := NewCmdExecutorMock()
+	execMock.ReturnPID = wantPID
+	doneCmd := make(chan bool)
+	execMock.DoneChannel = doneCmd
+
+	connMock := test.NewConnMock()
+	doneSendResp := make(chan bool)
+	connMock.DoneChannel = doneSendResp
+	connMock.IgnoredRequests = []string{comm.RequestTypeCmdOutput}
+
+	configCopy := defaultValidMinConfig
+	c := Client{
+		cmdExec: execMock,
+		sshConn: connMock,
+		Logger:  testLog,
+		config:  &configCopy,
+	}
+	_, err := c.HandleRunCmdRequest(context.Background(), []byte(jobToRunJSON))
+	require.NoError(t, err)
+
+	// when
+	_, gotUnknownErr := c.HandleCancelCmdRequest([]byte(`{"JID":"unknown-jid"}`))
+	execMock.ReturnKillErr = errors.New("kill fake error")
+	_, gotKillErr := c.HandleCancelCmdRequest([]byte(`{"JID":"5f02b216-3f8a-42be-b66c-f4c1d0ea3809"}`))
+	execMock.ReturnKillErr = nil
+	gotRes, gotErr := c.HandleCancelCmdRequest([]byte(`{"JID":"5f02b216-3f8a-42be-b66c-f4c1d0ea3809"}`))
+	// finish the killed cmd
+	<-doneCmd
+	<-doneSendResp
+
+	// then
+	assert.EqualError(t, gotUnknownErr, `no running command found for job "unknown-jid"`)
+	assert.EqualError(t, gotKillErr, "failed to kill command with PID 123: kill fake error")
+	require.NoError(t, gotErr)
+	assert.Equal(t, &comm.CancelCmdResponse{Pid: wantPID}, gotRes)
+
+	inputRequestName, _, inputPayload := connMock.InputSendRequest()
+	assert.Equal(t, comm.RequestTypeCmdResult, inputRequestName)
+	gotJob := models.Job{}
+	require.NoError(t, json.Unmarshal(inputPayload, &gotJob))
+	assert.Equal(t, models.JobStatusCancelled, gotJob.Status)
+
+	// verify the finished cmd can't be cancelled
+	_, gotErr = c.HandleCancelCmdRequest([]byte(`{"JID":"5f02b216-3f8a-42be-b66c-f4c1d0ea3809"}`))
+	assert.Error(t, gotErr)
+}
+
+func TestHandleRunCmdRequestKillOnTimeout(t *testing.T) {
+	now = nowMockF
+	jobJSON := strings.Replace(jobToRunJSON, `"timeout_sec": 60`, `"timeout_sec": 1, "timeout_policy": "kill", "timeout_grace_sec": 1`, 1)
+
+	testCases := []struct {
+		name string
+
+		stopsOnTerminate bool
+
+		wantStops []string
+	}{
+		{
+			name:             "stops on terminate",
+			stopsOnTerminate: true,
+			wantStops:        []string{"terminate"},
+		},
+		{
+			name:             "killed after grace period",
+			stopsOnTerminate: false,
+			wantStops:        []string{"terminate", "kill"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			execMock := NewCmdExecutorMock()
+			execMock.ReturnPID = 123
+			execMock.ReturnStdOut = []string{"partial output"}
+			doneCmd := make(chan bool)
+			execMock.DoneChannel = doneCmd
+			stop := make(chan string)
+			execMock.StopChannel = stop
+
+			connMock := test.NewConnMock()
+			doneSendResp := make(chan bool)
+			connMock.DoneChannel = doneSendResp
+			connMock.IgnoredRequests = []string{comm.RequestTypeCmdOutput}
+
+			configCopy := defaultValidMinConfig
+			c := Client{
+				cmdExec: execMock,
+				sshConn: connMock,
+				Logger:  testLog,
+				config:  &configCopy,
+			}
+
+			// when
+			_, err := c.HandleRunCmdRequest(context.Background(), []byte(jobJSON))
+			require.NoError(t, err)
+
+			var gotStops []string
+			gotStops = append(gotStops, <-stop)
+			if !tc.stopsOnTerminate {
+				gotStops = append(gotStops, <-stop)
+			}
+			// finish the stopped cmd
+			<-doneCmd
+			<-doneSendResp
+
+			// then
+			assert.Equal(t, tc.wantStops, gotStops)
+			inputRequestName, _, inputPayload := connMock.InputSendRequest()
+			assert.Equal(t, comm.RequestTypeCmdResult, inputRequestName)
+			gotJob := models.Job{}
+			require.NoError(t, json.Unmarshal(inputPayload, &gotJob))
+			assert.Equal(t, models.JobStatusTimeout, gotJob.Status)
+			require.NotNil(t, gotJob.Result)
+			assert.Equal(t, "partial output", gotJob.Result.StdOut)
+			assert.Equal(t, 0, c.runningCmdsCount)
+		})
+	}
+}
+
+func TestHandleRunCmdRequestStopObservingOnTimeout(t *testing.T) {
+	now = nowMockF
+	jobJSON := strings.Replace(jobToRunJSON, `"timeout_sec": 60`, `"timeout_sec": 1`, 1)
+
+	// given
+	execMock := NewCmdExecutorMock()
+	execMock.ReturnPID = 123
+	doneCmd := make(chan bool)
+	execMock.DoneChannel = doneCmd
+
+	connMock := test.NewConnMock()
+	doneSendResp := make(chan bool)
+	connMock.DoneChannel = doneSendResp
+	connMock.IgnoredRequests = []string{comm.RequestTypeCmdOutput}
+
+	configCopy := defaultValidMinConfig
+	c := Client{
+		cmdExec: execMock,
+		sshConn: connMock,
+		Logger:  testLog,
+		config:  &configCopy,
+	}
+	runningCmdsCount := func() int {
+		c.runCmdMutex.Lock()
+		defer c.runCmdMutex.Unlock()
+		return c.runningCmdsCount
+	}
+
+	// when
+	_, err := c.HandleRunCmdRequest(context.Background(), []byte(jobJSON))
+	require.NoError(t, err)
+	<-doneSendResp
+
+	// then
+	inputRequestName, _, inputPayload := connMock.InputSendRequest()
+	assert.Equal(t, comm.RequestTypeCmdResult, inputRequestName)
+	gotJob := models.Job{}
+	require.NoError(t, json.Unmarshal(inputPayload, &gotJob))
+	assert.Equal(t, models.JobStatusUnknown, gotJob.Status)
+	// the worker is busy until the cmd is finished
+	assert.Equal(t, 1, runningCmdsCount())
+
+	// finish the cmd
+	<-doneCmd
+	require.Eventually(t, func() bool {
+		return runningCmdsCount() == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestHandleRunCmdRequestExitCode(t *testing.T) {
+	now = nowMockF
+
+	testCases := []struct {
+		name string
+
+		exitCode         int
+		successExitCodes string
+
+		wantStatus string
+	}{
+		{
+			name:       "zero exit code",
+			exitCode:   0,
+			wantStatus: models.JobStatusSuccessful,
+		},
+		{
+			name:       "non-zero exit code",
+			exitCode:   1,
+			wantStatus: models.JobStatusFailed,
+		},
+		{
+			name:             "non-zero success exit code",
+			exitCode:         1,
+			successExitCodes: "[0, 1]",
+			wantStatus:       models.JobStatusSuccessful,
+		},
+		{
+			name:             "zero exit code is not in success exit codes",
+			exitCode:         0,
+			successExitCodes: "[1]",
+			wantStatus:       models.JobStatusFailed,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			execMock := NewCmdExecutorMock()
+			execMock.ReturnPID = 123
+			execMock.ReturnExitCode = tc.exitCode
+
+			connMock := test.NewConnMock()
+			doneSendResp := make(chan bool)
+			connMock.DoneChannel = doneSendResp
+			connMock.IgnoredRequests = []string{comm.RequestTypeCmdOutput}
+
+			configCopy := defaultValidMinConfig
+			c := Client{
+				cmdExec: execMock,
+				sshConn: connMock,
+				Logger:  testLog,
+				config:  &configCopy,
+			}
+			jobJSON := jobToRunJSON
+			if tc.successExitCodes != "" {
+				jobJSON = strings.Replace(jobToRunJSON, `"timeout_sec": 60`, `"timeout_sec": 60, "success_exit_codes": `+tc.successExitCodes, 1)
+			}
+
+			// when
+			_, err := c.HandleRunCmdRequest(context.Background(), []byte(jobJSON))
+			require.NoError(t, err)
+			<-doneSendResp
+
+			// then
+			_, _, inputPayload := connMock.InputSendRequest()
+			gotJob := models.Job{}
+			require.NoError(t, json.Unmarshal(inputPayload, &gotJob))
+			assert.Equal(t, tc.wantStatus, gotJob.Status)
+			assert.Equal(t, &tc.exitCode, gotJob.ExitCode)
+		})
+	}
+}
+
+func TestHandleRunCmdRequestCwdEnvUser(t *testing.T) {
+	// given
+	execMock := NewCmdExecutorMock()
+	execMock.ReturnPID = 123
+	doneCmd := make(chan bool)
+	execMock.DoneChannel = doneCmd
+
+	connMock := test.NewConnMock()
+	doneSendResp := make(chan bool)
+	connMock.DoneChannel = doneSendResp
+	connMock.IgnoredRequests = []string{comm.RequestTypeCmdOutput}
+
+	configCopy := defaultValidMinConfig
+	configCopy.RemoteCommands.AllowedUsers = []string{"allowed-user"}
+	configCopy.RemoteCommands.AllowedCwd = []string{"/tmp"}
+	configCopy.RemoteCommands.DenyEnv = []string{"PATH", "*_ENV", "LD_*"}
+	c := Client{
+		cmdExec: execMock,
+		sshConn: connMock,
+		Logger:  testLog,
+		config:  &configCopy,
+	}
+	jobJSON := strings.Replace(jobToRunJSON, `"timeout_sec": 60`, `"timeout_sec": 60, "cwd": "/tmp", "env": {"VAR2": "value2", "VAR1": "value1"}`, 1)
+	deniedUserJobJSON := strings.Replace(jobToRunJSON, `"timeout_sec": 60`, `"timeout_sec": 60, "run_as_user": "denied-user"`, 1)
+
+	// when
+	_, gotDeniedErr := c.HandleRunCmdRequest(context.Background(), []byte(deniedUserJobJSON))
+	for _, tc := range []struct {
+		params  string
+		wantErr string
+	}{
+		{params: `"cwd": "/etc"`, wantErr: `running commands in directory "/etc" is not allowed`},
+		{params: `"cwd": "/tmp/../etc"`, wantErr: `running commands in directory "/tmp/../etc" is not allowed`},
+		{params: `"cwd": "tmp"`, wantErr: `running commands in directory "tmp" is not allowed`},
+		{params: `"env": {"LD_PRELOAD": "/tmp/lib.so"}`, wantErr: `setting environment variable "LD_PRELOAD" is not allowed`},
+		{params: `"env": {"LANG": "C", "Path": "/tmp"}`, wantErr: `setting environment variable "Path" is not allowed`},
+		{params: `"env": {"BASH_ENV": "/tmp/env"}`, wantErr: `setting environment variable "BASH_ENV" is not allowed`},
+		{params: `"env": {"PATH=/tmp:": ""}`, wantErr: `setting environment variable "PATH=/tmp:" is not allowed`},
+	} {
+		deniedJobJSON := strings.Replace(jobToRunJSON, `"timeout_sec": 60`, `"timeout_sec": 60, `+tc.params, 1)
+		_, err := c.HandleRunCmdRequest(context.Background(), []byte(deniedJobJSON))
+		assert.EqualError(t, err, tc.wantErr)
+	}
+	_, gotErr := c.HandleRunCmdRequest(context.Background(), []byte(jobJSON))
+	require.NoError(t, gotErr)
+	gotCmd := c.runningCmds.cmds["5f02b216-3f8a-42be-b66c-f4c1d0ea3809"].cmd
+	// finish the cmd
+	<-doneCmd
+	<-doneSendResp
+
+	// then
+	assert.EqualError(t, gotDeniedErr, `running commands as user "denied-user" is not allowed`)
+	assert.Equal(t, "/tmp", gotCmd.Dir)
+	assert.Equal(t, append(os.Environ(), "VAR1=value1", "VAR2=value2"), gotCmd.Env)
+}
+
+func TestHandleRunCmdRequestScript(t *testing.T) {
+	// given
+	getInterpreter = func(interpreter, os string) (string, error) {
+		return "/usr/bin/" + interpreter, nil
+	}
+	defer func() {
+		getInterpreter = defaultGetInterpreter
+	}()
+	execMock := NewCmdExecutorMock()
+	execMock.ReturnPID = 123
+	doneCmd := make(chan bool)
+	execMock.DoneChannel = doneCmd
+
+	connMock := test.NewConnMock()
+	doneSendResp := make(chan bool)
+	connMock.DoneChannel = doneSendResp
+	connMock.IgnoredRequests = []string{comm.RequestTypeCmdOutput}
+
+	configCopy := defaultValidMinConfig
+	configCopy.RemoteCommands.denyRegexp = []*regexp.Regexp{regexp.MustCompile("^/usr/bin/python$")}
+	c := Client{
+		cmdExec: execMock,
+		sshConn: connMock,
+		Logger:  testLog,
+		config:  &configCopy,
+	}
+	jobJSON := strings.Replace(jobToRunJSON, `"command": "/bin/date;foo;whoami"`, `"script": "echo 1\necho 2\n", "interpreter": "bash"`, 1)
+	deniedJobJSON := strings.Replace(jobToRunJSON, `"command": "/bin/date;foo;whoami"`, `"script": "print(1)", "interpreter": "python"`, 1)
+
+	// when
+	_, gotDisabledErr := c.HandleRunCmdRequest(context.Background(), []byte(jobJSON))
+	configCopy.RemoteCommands.AllowScripts = true
+	_, gotDeniedErr := c.HandleRunCmdRequest(context.Background(), []byte(deniedJobJSON))
+	_, gotErr := c.HandleRunCmdRequest(context.Background(), []byte(jobJSON))
+	require.NoError(t, gotErr)
+	gotCmd := c.runningCmds.cmds["5f02b216-3f8a-42be-b66c-f4c1d0ea3809"].cmd
+	require.Len(t, gotCmd.Args, 2)
+	gotScriptPath := gotCmd.Args[1]
+	gotScript, gotReadErr := ioutil.ReadFile(gotScriptPath)
+	gotFileInfo, gotStatErr := os.Stat(gotScriptPath)
+	// finish the cmd
+	<-doneCmd
+	<-doneSendResp
+
+	// then
+	assert.EqualError(t, gotDisabledErr, "scripts execution is disabled")
+	assert.EqualError(t, gotDeniedErr, "script interpreter is not allowed: /usr/bin/python")
+	assert.Equal(t, "/usr/bin/bash", gotCmd.Path)
+	require.NoError(t, gotReadErr)
+	assert.Equal(t, "echo 1\necho 2\n", string(gotScript))
+	require.NoError(t, gotStatErr)
+	assert.Equal(t, os.FileMode(0600), gotFileInfo.Mode().Perm())
+	assert.True(t, strings.HasSuffix(gotScriptPath, ".sh"))
+	_, gotStatErr = os.Stat(gotScriptPath)
+	assert.True(t, os.IsNotExist(gotStatErr), "script file should be removed")
+
+	_, _, inputPayload := connMock.InputSendRequest()
+	gotJob := models.Job{}
+	require.NoError(t, json.Unmarshal(inputPayload, &gotJob))
+	assert.Equal(t, models.JobStatusSuccessful, gotJob.Status)
+	assert.Equal(t, "/usr/bin/bash", gotJob.Interpreter)
+	assert.Equal(t, "", gotJob.Shell)
 }
 
 func TestRemoteCommandsDisabled(t *testing.T) {
@@ -4,7 +4,9 @@ package chclient
 
 import (
 	"context"
+	"errors"
 	"os/exec"
+	"strconv"
 	"strings"
 	"syscall"
 )
@@ -20,3 +22,27 @@ func (e *CmdExecutorImpl) New(ctx context.Context, shell, command string) *exec.
 
 	return e.newCmd(ctx, shell, command)
 }
+
+func (e *CmdExecutorImpl) NewScript(ctx context.Context, interpreter string, args []string) *exec.Cmd {
+	return exec.CommandContext(ctx, interpreter, args...)
+}
+
+func (e *CmdExecutorImpl) Terminate(cmd *exec.Cmd) error {
+	// taskkill without /F asks the cmd together with its child processes to close
+	return exec.Command("taskkill", "/T", "/PID", strconv.Itoa(cmd.Process.Pid)).Run()
+}
+
+func (e *CmdExecutorImpl) Kill(cmd *exec.Cmd) error {
+	// taskkill is used to kill the cmd together with its child processes
+	return exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid)).Run()
+}
+
+var errRunAsUserNotSupported = errors.New("running commands as another user is not supported on windows")
+
+func setCmdUser(cmd *exec.Cmd, username string) error {
+	return errRunAsUserNotSupported
+}
+
+func chownToUser(path, username string) error {
+	return errRunAsUserNotSupported
+}
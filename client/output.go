@@ -0,0 +1,105 @@
+package chclient
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cloudradar-monitoring/rport/share/models"
+)
+
+// var is used to override in tests
+var outputSendInterval = time.Second
+
+// outputStream collects command output limited by a given capacity and periodically sends new chunks of it
+// until it's stopped.
+type outputStream struct {
+	mu      sync.Mutex
+	stdOut  CapacityBuffer
+	stdErr  CapacityBuffer
+	sentOut int
+	sentErr int
+
+	send    func(stdOut, stdErr string)
+	stopCh  chan struct{}
+	stopped chan struct{}
+}
+
+func newOutputStream(capacity int, send func(stdOut, stdErr string)) *outputStream {
+	return &outputStream{
+		stdOut:  CapacityBuffer{capacity: capacity},
+		stdErr:  CapacityBuffer{capacity: capacity},
+		send:    send,
+		stopCh:  make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+}
+
+// StdOut returns a writer to collect stdout.
+func (s *outputStream) StdOut() *outputWriter {
+	return &outputWriter{stream: s, buf: &s.stdOut}
+}
+
+// StdErr returns a writer to collect stderr.
+func (s *outputStream) StdErr() *outputWriter {
+	return &outputWriter{stream: s, buf: &s.stdErr}
+}
+
+// Start starts sending output chunks in background.
+func (s *outputStream) Start() {
+	go func() {
+		defer close(s.stopped)
+		ticker := time.NewTicker(outputSendInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.flush()
+			case <-s.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops sending chunks in background and sends the remaining output. Output written after Stop is still
+// collected but not sent.
+func (s *outputStream) Stop() {
+	close(s.stopCh)
+	<-s.stopped
+	s.flush()
+}
+
+// Result returns all collected output.
+func (s *outputStream) Result() *models.JobResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return &models.JobResult{
+		StdOut: s.stdOut.String(),
+		StdErr: s.stdErr.String(),
+	}
+}
+
+func (s *outputStream) flush() {
+	s.mu.Lock()
+	stdOut := s.stdOut.String()[s.sentOut:]
+	stdErr := s.stdErr.String()[s.sentErr:]
+	s.sentOut += len(stdOut)
+	s.sentErr += len(stdErr)
+	s.mu.Unlock()
+
+	if stdOut == "" && stdErr == "" {
+		return
+	}
+	s.send(stdOut, stdErr)
+}
+
+type outputWriter struct {
+	stream *outputStream
+	buf    *CapacityBuffer
+}
+
+func (w *outputWriter) Write(p []byte) (int, error) {
+	w.stream.mu.Lock()
+	defer w.stream.mu.Unlock()
+	return w.buf.Write(p)
+}
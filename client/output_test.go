@@ -0,0 +1,46 @@
+package chclient
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudradar-monitoring/rport/share/models"
+)
+
+func TestOutputStream(t *testing.T) {
+	outputSendInterval = time.Millisecond
+	defer func() { outputSendInterval = time.Second }()
+
+	var mu sync.Mutex
+	var gotStdOut, gotStdErr []string
+	s := newOutputStream(10, func(stdOut, stdErr string) {
+		mu.Lock()
+		defer mu.Unlock()
+		gotStdOut = append(gotStdOut, stdOut)
+		gotStdErr = append(gotStdErr, stdErr)
+	})
+	sentChunks := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(gotStdOut)
+	}
+
+	s.Start()
+	_, err := s.StdOut().Write([]byte("out1 "))
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return sentChunks() == 1 }, time.Second, time.Millisecond)
+
+	_, err = s.StdErr().Write([]byte("err1"))
+	require.NoError(t, err)
+	_, err = s.StdOut().Write([]byte("out2 out3"))
+	require.NoError(t, err)
+	s.Stop()
+
+	assert.Equal(t, []string{"out1 ", "out2 "}, gotStdOut)
+	assert.Equal(t, []string{"", "err1"}, gotStdErr)
+	assert.Equal(t, &models.JobResult{StdOut: "out1 out2 ", StdErr: "err1"}, s.Result())
+}
@@ -0,0 +1,83 @@
+package chclient
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudradar-monitoring/rport/share/models"
+)
+
+// scriptExtensions are file extensions of scripts by interpreter name. Some interpreters can't run a script without it.
+var scriptExtensions = map[string]string{
+	"sh":         ".sh",
+	"bash":       ".sh",
+	"python":     ".py",
+	"python3":    ".py",
+	"cmd":        ".bat",
+	"powershell": ".ps1",
+	"pwsh":       ".ps1",
+}
+
+// var is used to override in tests
+var getInterpreter = defaultGetInterpreter
+
+func defaultGetInterpreter(interpreter, os string) (string, error) {
+	if interpreter == "" {
+		if os == "windows" {
+			interpreter = cmdShell
+		} else {
+			interpreter = unixShell
+		}
+	}
+
+	path, err := exec.LookPath(interpreter)
+	if err != nil {
+		return "", fmt.Errorf("script interpreter not found: %s", err)
+	}
+	// a full path is needed to apply allow and deny filters
+	return filepath.Abs(path)
+}
+
+// interpreterName returns a lower case base name of a given interpreter without an extension.
+func interpreterName(interpreter string) string {
+	base := filepath.Base(interpreter)
+	return strings.ToLower(strings.TrimSuffix(base, filepath.Ext(base)))
+}
+
+// scriptArgs returns arguments to execute a given script file by a given interpreter.
+func scriptArgs(interpreter, scriptPath string) []string {
+	switch interpreterName(interpreter) {
+	case cmdShell:
+		return []string{"/c", scriptPath}
+	case powerShell, "pwsh":
+		return []string{"-NoProfile", "-NonInteractive", "-ExecutionPolicy", "Bypass", "-File", scriptPath}
+	}
+	return []string{scriptPath}
+}
+
+// writeScript writes a script of a given job to a new temp file that is accessible only by the user who runs the job.
+// Returns a path to the file. The caller should remove it when the script is finished.
+func writeScript(job *models.Job) (string, error) {
+	f, err := ioutil.TempFile("", "rport-script-*"+scriptExtensions[interpreterName(job.Interpreter)])
+	if err != nil {
+		return "", fmt.Errorf("failed to create a script file: %s", err)
+	}
+
+	_, err = f.WriteString(job.Script)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && job.RunAsUser != "" {
+		err = chownToUser(f.Name(), job.RunAsUser)
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return "", fmt.Errorf("failed to write a script file: %s", err)
+	}
+
+	return f.Name(), nil
+}
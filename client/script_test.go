@@ -0,0 +1,49 @@
+package chclient
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScriptArgs(t *testing.T) {
+	testCases := []struct {
+		name        string
+		interpreter string
+		wantArgs    []string
+	}{
+		{
+			name:        "unix shell",
+			interpreter: "/bin/bash",
+			wantArgs:    []string{"/tmp/script.sh"},
+		},
+		{
+			name:        "cmd",
+			interpreter: "cmd.exe",
+			wantArgs:    []string{"/c", "/tmp/script.sh"},
+		},
+		{
+			name:        "powershell",
+			interpreter: "PowerShell.exe",
+			wantArgs:    []string{"-NoProfile", "-NonInteractive", "-ExecutionPolicy", "Bypass", "-File", "/tmp/script.sh"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// when
+			gotArgs := scriptArgs(tc.interpreter, "/tmp/script.sh")
+
+			// then
+			assert.Equal(t, tc.wantArgs, gotArgs)
+		})
+	}
+}
+
+func TestGetInterpreterNotFound(t *testing.T) {
+	// when
+	_, gotErr := defaultGetInterpreter("unknown-test-interpreter", "linux")
+
+	// then
+	assert.Error(t, gotErr)
+}
@@ -29,7 +29,7 @@ var clientHelp = `
   which does reverse port forwarding, sharing <remote-host>:<remote-port>
   from the client to the server's <local-interface>:<local-port>.
   If local part is omitted, a randomly chosen server port will be assigned.
-  Only IPv4 addresses are supported.
+  IPv6 hosts must be enclosed in square brackets, e.g. [::]:3000:[fd00::1]:22.
   If not set, client connects without active tunnel(s) waiting for tunnels to be initialized by the server.
 
   Examples:
@@ -53,6 +53,10 @@ var clientHelp = `
     from port 3000 to port 80 of google.com
     originating the connection from the client
 
+    ./rport <SERVER>:<PORT> "[::]:3000:[fd00::1]:22"
+    server will listen on port 3000 of all IPv4 and IPv6 interfaces forwarding all packets
+    to port 22 of fd00::1 originating the connection from the client
+
     ./rport "[2a01:4f9:c010:b278::1]:9999" 3389
     using IPv6 server address. Forwards randomly-assigned free port of the server
     to port 3389 of the client
@@ -125,6 +129,10 @@ var clientHelp = `
     Applies to the stdout and stderr separately. If exceeded the specified number of bytes are sent.
     Defaults: 2048
 
+    --remote-commands-max-concurrent, Maximum number of commands executed in parallel.
+    Further commands are queued until one of the running commands is finished.
+    Defaults: 1
+
     --config, -c, An optional arg to define a path to a config file. If it is set then
     configuration will be loaded from the file. Note: command arguments and env variables will override them.
     Config file should be in TOML format. You can find an example "rport.example.conf" in the release archive.
@@ -176,6 +184,7 @@ func init() {
 	pFlags.Bool("allow-root", false, "")
 	pFlags.Bool("remote-commands-enabled", false, "")
 	pFlags.Int("remote-commands-send-back-limit", 0, "")
+	pFlags.Int("remote-commands-max-concurrent", 0, "")
 
 	cfgPath = pFlags.StringP("config", "c", "", "")
 	svcCommand = pFlags.String("service", "", "")
@@ -198,6 +207,8 @@ func init() {
 	viperCfg.SetDefault("remote-commands.deny", []string{`(\||<|>|;|,|\n|&)`})
 	viperCfg.SetDefault("remote-commands.order", []string{"allow", "deny"})
 	viperCfg.SetDefault("remote-commands.send_back_limit", 2048)
+	viperCfg.SetDefault("remote-commands.max_concurrent", 1)
+	viperCfg.SetDefault("remote-commands.deny_env", []string{"PATH", "ENV", "*_ENV", "LD_*", "DYLD_*"})
 	viperCfg.SetDefault("remote-commands.enabled", true)
 }
 
@@ -223,6 +234,7 @@ func bindPFlags() {
 
 	_ = viperCfg.BindPFlag("remote-commands.enabled", pFlags.Lookup("remote-commands-enabled"))
 	_ = viperCfg.BindPFlag("remote-commands.send_back_limit", pFlags.Lookup("remote-commands-send-back-limit"))
+	_ = viperCfg.BindPFlag("remote-commands.max_concurrent", pFlags.Lookup("remote-commands-max-concurrent"))
 }
 
 func main() {
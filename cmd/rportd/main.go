@@ -20,6 +20,8 @@ const (
 	DefaultKeepLostClients        = time.Hour
 	DefaultCacheClientsInterval   = 1 * time.Second
 	DefaultCleanClientsInterval   = 3 * time.Second
+	DefaultCleanJobsInterval      = time.Hour
+	DefaultVacuumJobsInterval     = 24 * time.Hour
 	DefaultMaxRequestBytes        = 2 * 1024 // 2 KB
 	DefaultCheckPortTimeout       = 2 * time.Second
 	DefaultExcludedPorts          = "1-1024"
@@ -164,6 +166,18 @@ var serverHelp = `
     arg to define an interval to clean up internal storage from obsolete disconnected clients.
     By default, '3s' is used. It can contain "h"(hours), "m"(minutes), "s"(seconds).
 
+    --keep-jobs, An optional arg to define a duration to keep finished commands (jobs) in the database.
+    By default, jobs are kept forever. It can contain "h"(hours), "m"(minutes), "s"(seconds).
+
+    --max-jobs-per-client, An optional arg to define a max number of finished commands (jobs) to keep per client.
+    Older jobs are deleted. By default, there is no limit.
+
+    --cleanup-jobs-interval, An optional arg to define an interval to delete jobs that exceed
+    --keep-jobs or --max-jobs-per-client. By default, '1h' is used.
+
+    --vacuum-jobs-interval, An optional arg to define an interval to vacuum the jobs database to reclaim
+    the space of deleted jobs. By default, '24h' is used. To disable it set it to '0'.
+
     --check-port-timeout, An optional arg to define a timeout to check whether a remote destination of a requested
     new tunnel is available, i.e. whether a given remote port is open on a client machine. By default, "2s" is used.
 
@@ -176,6 +190,12 @@ var serverHelp = `
     --max-request-bytes, An optional arg to define a limit for data that can be sent by rport clients and API requests.
     By default is set to 2048(2Kb).
 
+    --tunnel-proxy-cert-file, An optional arg to specify certificate file to serve tunnels in http proxy mode with https.
+    Tunnels in http proxy mode can be created only if both cert and key file are set.
+
+    --tunnel-proxy-key-file, An optional arg to specify private key file to serve tunnels in http proxy mode with https.
+    Tunnels in http proxy mode can be created only if both cert and key file are set.
+
     --allow-root, An optional arg to allow running rportd as root. There is no technical requirement to run the rport
     server under the root user. Running it as root is an unnecessary security risk.
 
@@ -249,6 +269,10 @@ func init() {
 	pFlags.Duration("keep-lost-clients", 0, "")
 	pFlags.Duration("save-clients-interval", 0, "")
 	pFlags.Duration("cleanup-clients-interval", 0, "")
+	pFlags.Duration("keep-jobs", 0, "")
+	pFlags.Int("max-jobs-per-client", 0, "")
+	pFlags.Duration("cleanup-jobs-interval", 0, "")
+	pFlags.Duration("vacuum-jobs-interval", 0, "")
 	pFlags.Int64("max-request-bytes", 0, "")
 	pFlags.Duration("check-port-timeout", 0, "")
 	pFlags.Bool("auth-write", false, "")
@@ -256,6 +280,8 @@ func init() {
 	pFlags.Bool("equate-clientauthid-clientid", false, "")
 	pFlags.Int("run-remote-cmd-timeout-sec", 0, "")
 	pFlags.Bool("allow-root", false, "")
+	pFlags.String("tunnel-proxy-cert-file", "", "")
+	pFlags.String("tunnel-proxy-key-file", "", "")
 
 	cfgPath = pFlags.StringP("config", "c", "", "")
 	svcCommand = pFlags.String("service", "", "")
@@ -279,6 +305,8 @@ func init() {
 	viperCfg.SetDefault("server.keep_lost_clients", DefaultKeepLostClients)
 	viperCfg.SetDefault("server.save_clients_interval", DefaultCacheClientsInterval)
 	viperCfg.SetDefault("server.cleanup_clients_interval", DefaultCleanClientsInterval)
+	viperCfg.SetDefault("server.cleanup_jobs_interval", DefaultCleanJobsInterval)
+	viperCfg.SetDefault("server.vacuum_jobs_interval", DefaultVacuumJobsInterval)
 	viperCfg.SetDefault("server.max_request_bytes", DefaultMaxRequestBytes)
 	viperCfg.SetDefault("server.check_port_timeout", DefaultCheckPortTimeout)
 	viperCfg.SetDefault("server.auth_write", true)
@@ -305,10 +333,16 @@ func bindPFlags() {
 	_ = viperCfg.BindPFlag("server.keep_lost_clients", pFlags.Lookup("keep-lost-clients"))
 	_ = viperCfg.BindPFlag("server.save_clients_interval", pFlags.Lookup("save-clients-interval"))
 	_ = viperCfg.BindPFlag("server.cleanup_clients_interval", pFlags.Lookup("cleanup-clients-interval"))
+	_ = viperCfg.BindPFlag("server.keep_jobs", pFlags.Lookup("keep-jobs"))
+	_ = viperCfg.BindPFlag("server.max_jobs_per_client", pFlags.Lookup("max-jobs-per-client"))
+	_ = viperCfg.BindPFlag("server.cleanup_jobs_interval", pFlags.Lookup("cleanup-jobs-interval"))
+	_ = viperCfg.BindPFlag("server.vacuum_jobs_interval", pFlags.Lookup("vacuum-jobs-interval"))
 	_ = viperCfg.BindPFlag("server.max_request_bytes", pFlags.Lookup("max-request-bytes"))
 	_ = viperCfg.BindPFlag("server.check_port_timeout", pFlags.Lookup("check-port-timeout"))
 	_ = viperCfg.BindPFlag("server.run_remote_cmd_timeout_sec", pFlags.Lookup("run-remote-cmd-timeout-sec"))
 	_ = viperCfg.BindPFlag("server.allow_root", pFlags.Lookup("allow-root"))
+	_ = viperCfg.BindPFlag("server.tunnel_proxy_cert_file", pFlags.Lookup("tunnel-proxy-cert-file"))
+	_ = viperCfg.BindPFlag("server.tunnel_proxy_key_file", pFlags.Lookup("tunnel-proxy-key-file"))
 
 	_ = viperCfg.BindPFlag("logging.log_file", pFlags.Lookup("log-file"))
 	_ = viperCfg.BindPFlag("logging.log_level", pFlags.Lookup("log-level"))
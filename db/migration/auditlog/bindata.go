@@ -0,0 +1,267 @@
+// Code generated for package auditlog by go-bindata DO NOT EDIT. (@generated)
+// sources:
+// 001_init.down.sql
+// 001_init.up.sql
+package auditlog
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+func bindataRead(data []byte, name string) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewBuffer(data))
+	if err != nil {
+		return nil, fmt.Errorf("Read %q: %v", name, err)
+	}
+
+	var buf bytes.Buffer
+	_, err = io.Copy(&buf, gz)
+	clErr := gz.Close()
+
+	if err != nil {
+		return nil, fmt.Errorf("Read %q: %v", name, err)
+	}
+	if clErr != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+type asset struct {
+	bytes []byte
+	info  os.FileInfo
+}
+
+type bindataFileInfo struct {
+	name    string
+	size    int64
+	mode    os.FileMode
+	modTime time.Time
+}
+
+// Name return file name
+func (fi bindataFileInfo) Name() string {
+	return fi.name
+}
+
+// Size return file size
+func (fi bindataFileInfo) Size() int64 {
+	return fi.size
+}
+
+// Mode return file mode
+func (fi bindataFileInfo) Mode() os.FileMode {
+	return fi.mode
+}
+
+// Mode return file modify time
+func (fi bindataFileInfo) ModTime() time.Time {
+	return fi.modTime
+}
+
+// IsDir return file whether a directory
+func (fi bindataFileInfo) IsDir() bool {
+	return fi.mode&os.ModeDir != 0
+}
+
+// Sys return file is sys mode
+func (fi bindataFileInfo) Sys() interface{} {
+	return nil
+}
+
+var __001_initDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x72\x09\xf2\x0f\x50\xf0\xf4\x73\x71\x8d\x50\xc8\x4c\xa9\x88\x4f\x2c\x4d\xc9\x2c\xc9\xc9\x4f\x8f\x4f\xce\xc9\x4c\xcd\x2b\x89\xcf\x4c\xb1\xe6\xe2\xc2\xa5\xa6\xb4\x38\xb5\x28\x2f\x31\x37\x15\x8f\x92\x92\xcc\xdc\xd4\xe2\x92\xc4\xdc\x02\x98\x9a\x10\x47\x27\x1f\x57\x85\xc4\xd2\x94\xcc\x92\x9c\xfc\x74\x6b\x2e\xc0\x00\xa8\x13\x66\xfd\x80\x00\x00\x00")
+
+func _001_initDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__001_initDownSql,
+		"001_init.down.sql",
+	)
+}
+
+func _001_initDownSql() (*asset, error) {
+	bytes, err := _001_initDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "001_init.down.sql", size: 128, mode: os.FileMode(420), modTime: time.Unix(1792321271, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __001_initUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x94\xd0\xc1\x4a\xc3\x40\x10\xc6\xf1\x7b\x9e\xe2\x3b\x2a\xf4\x0d\x3c\xc5\x74\x90\xc5\x64\x23\xeb\x14\xda\x53\x58\xb2\x5b\x19\xc8\x26\x4b\xb2\x05\x1f\x5f\xa8\x26\x54\x9b\x0a\x3d\xff\x86\x0f\xe6\x5f\x18\xca\x99\xc0\xf9\x73\x49\xb0\x27\x27\xa9\x1b\x3e\xf0\x90\x01\x80\x38\x28\xcd\xf4\x42\x06\x6f\x46\x55\xb9\x39\xe0\x95\x0e\xc8\x77\x5c\x2b\x5d\x18\xaa\x48\xf3\xe6\x7c\x99\x24\xf8\x29\xd9\x10\xb1\xcd\x99\x58\x55\x04\x5d\x33\xf4\xae\x2c\xbf\x0f\x4e\x93\x1f\x7b\x1b\x3c\x98\xf6\xfc\xc7\x46\x1f\x86\xe4\x1b\x89\x6b\x68\x63\xec\xa4\xb5\x49\x86\x7e\x95\xdb\x9b\x72\x3c\xfa\x36\x79\xd7\x88\x5b\xe3\xb6\x13\xdf\xa7\x1b\x18\xed\x68\xc3\xf4\x5b\xb2\xc7\xa7\x2c\xfb\xa9\xa5\xf4\x96\xf6\x10\xf7\xd9\xcc\xc5\x9a\xa5\xc0\x79\xa0\xd6\x17\x2d\x2f\xe2\xd0\x7b\xf1\xff\xcc\xdc\xe9\x7a\x65\x96\x0d\xee\xd9\x5b\xde\xbc\x1e\x5c\x68\x65\xf1\x6b\x00\x74\x2b\x34\x37\x16\x02\x00\x00")
+
+func _001_initUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__001_initUpSql,
+		"001_init.up.sql",
+	)
+}
+
+func _001_initUpSql() (*asset, error) {
+	bytes, err := _001_initUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "001_init.up.sql", size: 534, mode: os.FileMode(420), modTime: time.Unix(1792321271, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+// Asset loads and returns the asset for the given name.
+// It returns an error if the asset could not be found or
+// could not be loaded.
+func Asset(name string) ([]byte, error) {
+	cannonicalName := strings.Replace(name, "\\", "/", -1)
+	if f, ok := _bindata[cannonicalName]; ok {
+		a, err := f()
+		if err != nil {
+			return nil, fmt.Errorf("Asset %s can't read by error: %v", name, err)
+		}
+		return a.bytes, nil
+	}
+	return nil, fmt.Errorf("Asset %s not found", name)
+}
+
+// MustAsset is like Asset but panics when Asset would return an error.
+// It simplifies safe initialization of global variables.
+func MustAsset(name string) []byte {
+	a, err := Asset(name)
+	if err != nil {
+		panic("asset: Asset(" + name + "): " + err.Error())
+	}
+
+	return a
+}
+
+// AssetInfo loads and returns the asset info for the given name.
+// It returns an error if the asset could not be found or
+// could not be loaded.
+func AssetInfo(name string) (os.FileInfo, error) {
+	cannonicalName := strings.Replace(name, "\\", "/", -1)
+	if f, ok := _bindata[cannonicalName]; ok {
+		a, err := f()
+		if err != nil {
+			return nil, fmt.Errorf("AssetInfo %s can't read by error: %v", name, err)
+		}
+		return a.info, nil
+	}
+	return nil, fmt.Errorf("AssetInfo %s not found", name)
+}
+
+// AssetNames returns the names of the assets.
+func AssetNames() []string {
+	names := make([]string, 0, len(_bindata))
+	for name := range _bindata {
+		names = append(names, name)
+	}
+	return names
+}
+
+// _bindata is a table, holding each asset generator, mapped to its name.
+var _bindata = map[string]func() (*asset, error){
+	"001_init.down.sql": _001_initDownSql,
+	"001_init.up.sql":   _001_initUpSql,
+}
+
+// AssetDir returns the file names below a certain
+// directory embedded in the file by go-bindata.
+// For example if you run go-bindata on data/... and data contains the
+// following hierarchy:
+//     data/
+//       foo.txt
+//       img/
+//         a.png
+//         b.png
+// then AssetDir("data") would return []string{"foo.txt", "img"}
+// AssetDir("data/img") would return []string{"a.png", "b.png"}
+// AssetDir("foo.txt") and AssetDir("notexist") would return an error
+// AssetDir("") will return []string{"data"}.
+func AssetDir(name string) ([]string, error) {
+	node := _bintree
+	if len(name) != 0 {
+		cannonicalName := strings.Replace(name, "\\", "/", -1)
+		pathList := strings.Split(cannonicalName, "/")
+		for _, p := range pathList {
+			node = node.Children[p]
+			if node == nil {
+				return nil, fmt.Errorf("Asset %s not found", name)
+			}
+		}
+	}
+	if node.Func != nil {
+		return nil, fmt.Errorf("Asset %s not found", name)
+	}
+	rv := make([]string, 0, len(node.Children))
+	for childName := range node.Children {
+		rv = append(rv, childName)
+	}
+	return rv, nil
+}
+
+type bintree struct {
+	Func     func() (*asset, error)
+	Children map[string]*bintree
+}
+
+var _bintree = &bintree{nil, map[string]*bintree{
+	"001_init.down.sql": &bintree{_001_initDownSql, map[string]*bintree{}},
+	"001_init.up.sql":   &bintree{_001_initUpSql, map[string]*bintree{}},
+}}
+
+// RestoreAsset restores an asset under the given directory
+func RestoreAsset(dir, name string) error {
+	data, err := Asset(name)
+	if err != nil {
+		return err
+	}
+	info, err := AssetInfo(name)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(_filePath(dir, filepath.Dir(name)), os.FileMode(0755))
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(_filePath(dir, name), data, info.Mode())
+	if err != nil {
+		return err
+	}
+	err = os.Chtimes(_filePath(dir, name), info.ModTime(), info.ModTime())
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// RestoreAssets restores an asset under the given directory recursively
+func RestoreAssets(dir, name string) error {
+	children, err := AssetDir(name)
+	// File
+	if err != nil {
+		return RestoreAsset(dir, name)
+	}
+	// Dir
+	for _, child := range children {
+		err = RestoreAssets(dir, filepath.Join(name, child))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func _filePath(dir, name string) string {
+	cannonicalName := strings.Replace(name, "\\", "/", -1)
+	return filepath.Join(append([]string{dir}, strings.Split(cannonicalName, "/")...)...)
+}
@@ -0,0 +1,7 @@
+DROP INDEX idx_auditlog_client_id;
+
+DROP INDEX idx_auditlog_username;
+
+DROP INDEX idx_auditlog_timestamp;
+
+DROP TABLE auditlog;
@@ -0,0 +1,20 @@
+CREATE TABLE auditlog (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    timestamp DATETIME NOT NULL,
+    username TEXT NOT NULL,
+    remote_ip TEXT NOT NULL,
+    application TEXT NOT NULL,
+    action TEXT NOT NULL,
+    affected_id TEXT NOT NULL,
+    client_id TEXT NOT NULL,
+    params TEXT NOT NULL
+);
+
+CREATE INDEX idx_auditlog_timestamp
+    ON auditlog (timestamp DESC);
+
+CREATE INDEX idx_auditlog_username
+    ON auditlog (username, timestamp DESC);
+
+CREATE INDEX idx_auditlog_client_id
+    ON auditlog (client_id, timestamp DESC);
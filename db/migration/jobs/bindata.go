@@ -2,6 +2,12 @@
 // sources:
 // 001_init.down.sql
 // 001_init.up.sql
+// 002_add_schedule_id.down.sql
+// 002_add_schedule_id.up.sql
+// 003_add_expires_at.down.sql
+// 003_add_expires_at.up.sql
+// 004_add_command.down.sql
+// 004_add_command.up.sql
 package jobs
 
 import (
@@ -78,7 +84,7 @@ func (fi bindataFileInfo) Sys() interface{} {
 	return nil
 }
 
-var __001_initDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x72\x09\xf2\x0f\x50\xf0\xf4\x73\x71\x8d\x50\xc8\x4c\xa9\x88\xcf\xca\x4f\x2a\x8e\x4f\xce\xc9\x4c\xcd\x2b\x89\xcf\x4c\x89\x2f\xc9\xcc\x4d\xb5\xe6\xe2\xc2\xa6\x28\xb7\x34\xa7\x24\x33\x3e\x33\x05\x26\x1d\xe2\xe8\xe4\xe3\xaa\x00\x92\x42\x15\x81\xa8\x83\x88\x03\x02\x00\x00\xff\xff\x32\x12\x92\x70\x6d\x00\x00\x00")
+var __001_initDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x6d\x00\x92\xff\x44\x52\x4f\x50\x20\x49\x4e\x44\x45\x58\x20\x69\x64\x78\x5f\x6a\x6f\x62\x73\x5f\x63\x6c\x69\x65\x6e\x74\x5f\x69\x64\x5f\x74\x69\x6d\x65\x3b\x0a\x0a\x44\x52\x4f\x50\x20\x49\x4e\x44\x45\x58\x20\x69\x64\x78\x5f\x6a\x6f\x62\x73\x5f\x6d\x75\x6c\x74\x69\x5f\x69\x64\x3b\x0a\x0a\x44\x52\x4f\x50\x20\x54\x41\x42\x4c\x45\x20\x6a\x6f\x62\x73\x3b\x0a\x0a\x44\x52\x4f\x50\x20\x54\x41\x42\x4c\x45\x20\x6d\x75\x6c\x74\x69\x5f\x6a\x6f\x62\x73\x3b\x0a\x03\x00\x32\x12\x92\x70\x6d\x00\x00\x00")
 
 func _001_initDownSqlBytes() ([]byte, error) {
 	return bindataRead(
@@ -93,12 +99,12 @@ func _001_initDownSql() (*asset, error) {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "001_init.down.sql", size: 109, mode: os.FileMode(420), modTime: time.Unix(1612369584, 0)}
+	info := bindataFileInfo{name: "001_init.down.sql", size: 109, mode: os.FileMode(420), modTime: time.Unix(1615901061, 0)}
 	a := &asset{bytes: bytes, info: info}
 	return a, nil
 }
 
-var __001_initUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x94\x91\xcd\x6e\xab\x30\x10\x85\xf7\x3c\xc5\x2c\x41\xca\x1b\x64\xc5\x85\xe1\xd6\x2a\x31\x95\x99\x28\x64\x65\x91\xd8\x55\x07\x91\x54\x8a\x1d\xa9\x7d\xfb\xaa\xa6\x41\x71\xd5\xf4\x67\xfd\x1d\xcf\x7c\x73\x5c\x28\xcc\x09\x81\xf2\x7f\x35\x82\xa8\x40\x36\x04\xd8\x89\x96\x5a\x38\x9c\x47\xcf\x7a\x78\xde\x39\x48\x13\x00\x80\x81\x0d\x10\x76\x04\x0f\x4a\xac\x72\xb5\x85\x7b\xdc\x86\x07\x72\x5d\xd7\x8b\x10\x71\xbe\x3f\x79\x6b\x74\xef\xa1\xcc\x09\x49\xac\xf0\x53\x62\x7f\xb2\xfd\x7b\x62\xf7\x3a\xcd\x8a\xa9\xb1\xbe\xe7\xd1\xc5\x28\xc9\x60\x23\xe8\xae\x59\x13\xa8\x66\x23\xca\x65\x92\x44\xda\x7f\x56\xf4\x67\xf7\xd5\xf2\x9f\xe5\x1f\xf9\xc8\xee\x29\x8e\xfc\xe6\xac\xfd\xc8\xf6\xe8\xf5\x45\x2e\x86\x73\xcf\x17\xfe\x4d\x15\x13\xaa\x1a\x85\xe2\xbf\x0c\xc7\xa5\xd7\xcf\x33\x50\x58\xa1\x42\x59\xe0\xf5\xff\xa5\x03\x9b\xec\x76\x8b\x42\x96\xd8\x01\x9b\x97\x10\xd6\xb3\xac\xf6\x7c\xb0\x61\x61\x23\x3f\x4a\x9e\xd9\x22\xee\x02\xdb\x22\xbb\x39\x70\x12\x61\x13\x8f\x8a\xbc\x97\xc9\x5b\x00\x00\x00\xff\xff\x97\x9b\x70\x8a\x89\x02\x00\x00")
+var __001_initUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x94\x91\xd1\x6e\xb2\x40\x10\x85\xef\x79\x8a\x73\x09\x89\x6f\xe0\x15\x3f\x0c\x7f\x37\xc5\xa5\x59\xc6\x88\x57\x04\x5d\x9a\x0e\x41\x9b\xc8\x9a\xb4\x6f\xdf\x08\x29\x71\x4d\x6d\xec\xf5\x77\x76\xe6\xdb\x33\x89\xa1\x98\x09\x1c\xff\xcb\x09\x2a\x83\x2e\x18\x54\xa9\x92\x4b\x1c\xce\xbd\x93\xba\x7b\xdf\x0d\x08\x03\x00\xe8\xc4\x82\xa9\x62\xbc\x18\xb5\x8a\xcd\x16\xcf\xb4\x1d\x1f\xe8\x75\x9e\x2f\xc6\xc8\xe0\x9a\x93\x6b\x6d\xdd\x38\xa4\x31\x13\xab\x15\xdd\x24\xf6\xa7\xb6\xb9\x24\x76\x9f\xd3\x2c\x9f\xda\xd6\x35\xd2\x0f\x3e\x0a\x22\x6c\x14\x3f\x15\x6b\x86\x29\x36\x2a\x5d\x06\x81\xa7\xfd\x67\x45\x77\xbe\xd9\xf0\xa8\xfc\xab\x1c\x65\x78\xf3\x23\x8f\x7c\x6b\xdf\x4b\x7b\x74\xb5\xd8\x9f\xe0\xdc\xf3\x37\xff\xa5\x8a\x09\x65\x85\x21\xf5\x5f\x8f\xfd\x87\xd7\xcf\x23\x18\xca\xc8\x90\x4e\xe8\xfa\x7e\x61\x27\x36\xba\xdf\xa2\xd2\x29\x55\x10\xfb\x31\x1e\xbb\x9e\x65\x6b\x27\x87\x76\x5c\x58\x68\x5c\x10\xc2\x99\x2d\xfc\x2e\xa8\x4c\xa2\xbb\x03\x27\x11\xb1\xfe\x28\xcf\x7b\x19\x7c\x0d\x00\x97\x9b\x70\x8a\x89\x02\x00\x00")
 
 func _001_initUpSqlBytes() ([]byte, error) {
 	return bindataRead(
@@ -113,7 +119,127 @@ func _001_initUpSql() (*asset, error) {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "001_init.up.sql", size: 649, mode: os.FileMode(420), modTime: time.Unix(1612369584, 0)}
+	info := bindataFileInfo{name: "001_init.up.sql", size: 649, mode: os.FileMode(420), modTime: time.Unix(1615901061, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __002_add_schedule_idDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x8c\x90\xc1\x6a\xf3\x30\x10\x84\xef\xfb\x14\x73\xfc\x03\x7e\x03\x9f\xf4\xc7\x5b\x2a\x2a\x4b\x41\xd9\x90\xe4\x24\x9c\x48\x50\x19\x87\x96\x58\x81\xf6\xed\x4b\x5d\x0a\x76\xda\x43\xcf\xf3\xed\xce\xc7\x34\xde\x6d\xa0\x6d\xc3\x07\xe4\xf8\x16\x2e\xb7\xa1\xe4\xd0\xbf\x9c\xc6\x30\x9e\x9f\x53\xbc\x0d\x29\xe4\x58\x13\xad\x3d\x2b\x61\x88\xfa\x6f\x18\x33\xaa\x5c\x5e\xf1\x8f\x00\xa0\xcf\x11\xc2\x07\xc1\xc6\xeb\x56\xf9\x23\x9e\xf8\x08\xeb\x04\x76\x67\x4c\x35\x21\x63\xe9\xae\x25\xc5\xd0\x15\x34\x4a\x58\x74\xcb\x77\xc4\xf9\x9a\xba\x4f\xe2\xf4\xfe\xf5\x6b\x99\xc6\x54\xba\x3c\x8c\xcb\x88\x56\xd8\x6b\x79\x74\x3b\x81\x77\x7b\xdd\xd4\x44\xda\x6e\xd9\x0b\xb4\x15\xf7\xc3\xb5\xcf\xb1\x9a\x89\x54\xb3\xca\xea\xbb\x60\x35\xb9\x6c\xd9\xf0\x5a\xf0\x97\x03\x3c\x78\xd7\xce\xaa\x6a\xa2\x69\xd8\xfb\xb9\x6a\x22\x65\x84\xfd\xef\x3b\x7a\xb6\xaa\x65\x2c\xa4\x6b\xfa\x18\x00\x7c\x0a\x5f\xf5\xa2\x01\x00\x00")
+
+func _002_add_schedule_idDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__002_add_schedule_idDownSql,
+		"002_add_schedule_id.down.sql",
+	)
+}
+
+func _002_add_schedule_idDownSql() (*asset, error) {
+	bytes, err := _002_add_schedule_idDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "002_add_schedule_id.down.sql", size: 418, mode: os.FileMode(420), modTime: time.Unix(1792324608, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __002_add_schedule_idUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x4c\x8c\xbd\x0a\xc2\x30\x10\xc7\xf7\x3c\xc5\x7f\x54\xf0\x0d\x3a\xc5\xe4\x06\x21\xa6\x50\x4f\xe8\x76\x54\x2f\x60\xa4\x22\x98\x2b\xf8\xf8\x8e\x66\xfe\x7d\xf8\xc4\x34\x81\xfd\x31\x11\x5e\xdb\x6a\x55\x9e\xef\x5b\x83\x8f\x11\x61\x4c\xd7\x73\x46\xbb\x3f\x8a\x6e\x6b\x91\xaa\x60\x9a\x79\x70\x2e\x4c\xe4\x99\x70\xca\x91\x66\x54\xfd\xca\xbf\x94\x4e\x77\x00\x30\xe6\xfe\xbb\xeb\xf0\x01\xcd\x96\x8f\x15\x95\xc5\x10\xe9\x12\xf6\x83\xfb\x0d\x00\x7f\x6b\x4d\x23\x8f\x00\x00\x00")
+
+func _002_add_schedule_idUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__002_add_schedule_idUpSql,
+		"002_add_schedule_id.up.sql",
+	)
+}
+
+func _002_add_schedule_idUpSql() (*asset, error) {
+	bytes, err := _002_add_schedule_idUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "002_add_schedule_id.up.sql", size: 143, mode: os.FileMode(420), modTime: time.Unix(1792324608, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __003_add_expires_atDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xac\x92\xcf\x6e\xb3\x30\x10\xc4\xef\x7e\x8a\x3d\x82\xe4\x37\xe0\xc4\x07\x9b\xaf\x56\xc1\x8e\xcc\x46\x49\x4e\x88\xc4\x54\x35\xca\x9f\x2a\x38\x52\xfb\xf6\x55\x4d\x52\x9c\x94\xe6\xd4\xab\x77\x76\x3c\xbf\xd5\xe4\x5a\xcd\x41\xc8\x1c\x57\x60\xcd\x7b\xdd\x1d\x37\x7d\xbd\xdd\xd9\xf6\xe0\x6a\x6b\xea\xde\x35\xee\xdc\x27\xec\xb1\xca\xd9\x7d\x3b\xad\xd9\x9f\x77\xce\xd6\xd6\x24\x8c\x65\x1a\x53\x42\xa0\xf4\x5f\x81\xe0\xbf\x71\xfb\x37\x88\x18\x00\x40\x67\x0d\x10\xae\x08\xe6\x5a\x94\xa9\x5e\xc3\x33\xae\x41\x2a\x02\xb9\x28\x0a\xee\x25\x43\x92\x41\xf5\x63\x72\x72\xad\xa9\x1b\x07\x79\x4a\x48\xa2\xc4\x3b\xc5\x8b\x3d\xd8\xfe\xf5\x56\x32\xec\x6e\x4f\x6d\xf3\xb5\xbb\xf9\x98\x72\xfe\x26\x9c\x1a\x0e\x68\xdd\x71\x73\x9d\x0f\xcf\xa6\x75\x8d\xdd\x4d\x06\x9d\x29\x8d\xe2\xbf\xf4\x70\x51\xb8\x1e\x83\xc6\x19\x6a\x94\x19\x56\xa3\x6f\x1f\x75\xd6\xc4\x2c\x86\xa5\xa0\x27\xb5\x20\xd0\x6a\x29\xf2\x84\x31\x21\x2b\xd4\x04\x42\x92\x0a\x0e\xd9\x59\xc3\x2f\x57\xe2\xc1\x4d\x78\x48\xcf\x03\x60\x3e\xe2\xf1\x1b\x18\x7e\x65\x88\x7d\xea\x0a\x0b\xcc\x08\xfe\xde\x1e\x66\x5a\x95\x1e\x20\x61\x43\x79\xc6\x6e\x24\x8c\xa5\x05\xa1\xbe\xaf\x8b\x46\x99\x96\x08\x17\xf0\xb1\x55\x0f\xab\xe9\x31\x94\xf4\x2b\x10\x05\xb1\x82\xe8\x90\x63\x95\xc5\xbf\x1a\x5e\x7b\x7c\x6b\x15\x62\xc5\x09\xfb\x1c\x00\x7c\x85\xf2\x5b\x4b\x03\x00\x00")
+
+func _003_add_expires_atDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__003_add_expires_atDownSql,
+		"003_add_expires_at.down.sql",
+	)
+}
+
+func _003_add_expires_atDownSql() (*asset, error) {
+	bytes, err := _003_add_expires_atDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "003_add_expires_at.down.sql", size: 843, mode: os.FileMode(420), modTime: time.Unix(1792325108, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __003_add_expires_atUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x7a\x00\x85\xff\x41\x4c\x54\x45\x52\x20\x54\x41\x42\x4c\x45\x20\x6a\x6f\x62\x73\x20\x41\x44\x44\x20\x43\x4f\x4c\x55\x4d\x4e\x20\x65\x78\x70\x69\x72\x65\x73\x5f\x61\x74\x20\x44\x41\x54\x45\x54\x49\x4d\x45\x3b\x0a\x0a\x43\x52\x45\x41\x54\x45\x20\x49\x4e\x44\x45\x58\x20\x69\x64\x78\x5f\x6a\x6f\x62\x73\x5f\x63\x6c\x69\x65\x6e\x74\x5f\x69\x64\x5f\x73\x74\x61\x74\x75\x73\x0a\x20\x20\x20\x20\x4f\x4e\x20\x6a\x6f\x62\x73\x20\x28\x63\x6c\x69\x65\x6e\x74\x5f\x69\x64\x2c\x20\x73\x74\x61\x74\x75\x73\x29\x3b\x0a\x03\x00\x87\x4d\x54\xb9\x7a\x00\x00\x00")
+
+func _003_add_expires_atUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__003_add_expires_atUpSql,
+		"003_add_expires_at.up.sql",
+	)
+}
+
+func _003_add_expires_atUpSql() (*asset, error) {
+	bytes, err := _003_add_expires_atUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "003_add_expires_at.up.sql", size: 122, mode: os.FileMode(420), modTime: time.Unix(1792325108, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __004_add_commandDownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xb4\x54\xcb\x6e\xa3\x30\x14\xdd\xfb\x2b\xee\x12\x24\xff\x01\x2b\x06\x6e\x66\xd0\x10\x53\x19\x57\x6d\x57\x28\x8d\x3d\xaa\xa3\xa4\x53\x81\x23\x75\xfe\x7e\x54\x9b\x87\x71\xdc\x34\xb3\x98\x2d\xf7\xdc\xc7\x79\xe0\x92\x37\x77\x50\xb1\x12\x1f\x41\xcb\xf7\xee\xf0\xfb\x79\xe8\xf6\x47\xad\x5e\x4d\xa7\x65\x37\x98\x5d\x6f\x94\xec\x76\x26\x23\x01\xf2\x74\x3e\x1a\xed\xf0\x57\x50\x97\xf3\xcc\x79\xf8\x0a\x65\xf4\x49\xc5\x31\x6e\xa9\x96\x57\xaf\xd9\xbf\x28\x79\x3e\x2a\x0b\x23\x05\xc7\x5c\x20\x88\xfc\x5b\x8d\x60\xaf\x31\xa7\x37\x48\x08\x00\xc0\x41\x4b\x10\xf8\x28\xe0\x8e\x57\xdb\x9c\x3f\xc1\x4f\x7c\x02\xd6\x08\x60\xf7\x75\x4d\x2d\xc4\x1d\xec\x50\x17\x95\x91\x34\x94\xb9\x40\x51\x6d\x31\x40\xfc\xd2\xaf\x7a\x78\x59\x43\x5c\xef\xbe\x57\xbb\x8f\xde\xe7\x3f\xb1\xc9\xb3\x10\xb1\xe2\x4c\x74\xaa\xbb\xcf\x52\x99\x9d\x3e\x0e\xb1\x0e\xf5\xfe\xa6\x7b\x35\x5c\x5e\xb1\x69\x38\x56\xdf\x99\x65\x9d\xf8\x73\x53\xe0\xb8\x41\x8e\xac\xc0\x76\x59\x38\x24\x07\x2d\x53\x92\xc2\x43\x25\x7e\x34\xf7\x02\x78\xf3\x50\x95\x19\x21\x15\x6b\x91\x0b\xa8\x98\x68\x3c\x85\x0f\x5a\xd2\x51\x3e\xea\x89\x45\x7d\x59\xa8\xa7\x04\x5d\x78\xd3\x15\x4b\x3a\x91\xa3\x1e\x95\xd4\xb2\x6e\xb1\xc6\x42\xc0\xff\x5d\x05\x1b\xde\x6c\x2d\xb1\x8c\xb8\xd8\x2d\x61\xca\x08\xc9\x6b\x81\x3c\xcc\x17\x47\x96\x6f\x11\x46\x41\xc2\x18\xce\x2b\xff\x3d\x8c\xbd\x59\xe7\x29\x40\x2c\x1c\x63\x49\xb8\x12\x12\xef\xa7\xb1\x9d\x5f\xd8\x1c\x32\x98\x1c\xe8\x4d\x44\xec\x59\x53\x6f\x49\xd4\xbf\x9b\xbb\x9d\x25\xcb\x11\x6b\x63\x56\xdf\x7d\x7b\x96\x42\x60\xd2\xaa\x63\xb4\x2a\x78\x79\xe6\xc4\xd8\xd7\xc9\x5e\xdf\x30\xeb\x2e\x24\x5e\x9a\xbc\xc4\x41\x89\x6d\x91\x7e\x3a\x70\x7a\xca\xd6\xa3\xe6\x4b\x3e\x34\xba\xe1\x18\x97\xfa\x4f\xcf\x71\xe5\xe8\xa0\xf8\x8b\x39\x4d\x5a\xaa\x90\x78\x65\xdf\x26\x28\xb1\x2d\xd2\x8c\xfc\x1d\x00\xb5\x57\x2c\x9a\x42\x06\x00\x00")
+
+func _004_add_commandDownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__004_add_commandDownSql,
+		"004_add_command.down.sql",
+	)
+}
+
+func _004_add_commandDownSql() (*asset, error) {
+	bytes, err := _004_add_commandDownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "004_add_command.down.sql", size: 1602, mode: os.FileMode(420), modTime: time.Unix(1792325686, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var __004_add_commandUpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\xe4\x92\x51\x6f\x9b\x30\x14\x85\xdf\xf9\x15\x47\xbc\x90\x68\xa1\x1a\xaf\xad\x56\x89\x05\x4f\xdb\xc4\x60\x0a\x46\xeb\x1b\x72\xf0\x65\x78\x32\x38\xb2\x5d\xb5\xfb\xf7\x13\x25\xa5\x99\x16\x35\xda\x73\x1f\x91\x8e\xbf\xfb\x71\xee\x4d\x73\xce\x76\xe0\xe9\xc7\x9c\xe1\x97\xd9\x3b\xa4\x59\x86\x6d\x99\xd7\xdf\x0a\xb4\x66\x18\xc4\x28\xc1\xd9\x1d\x47\x51\x72\x14\x75\x9e\x23\x63\x9f\xd2\x3a\xe7\x88\xa2\x9b\xe0\xf4\xf5\x70\xaf\xbd\x6a\xfe\x9f\x11\xc4\x31\x5a\x73\xf8\x0d\xdf\xd3\x12\x37\x1d\xe8\x51\x39\xaf\xc6\x9f\xb3\x56\x67\xcd\x00\x49\x5e\x28\xed\x36\x78\xe8\xc9\x12\x94\x8f\x1c\x9c\x37\x96\x24\x84\x83\xc0\xd7\xaa\x2c\xe0\xbc\x9d\x5e\x75\x46\x6b\xf3\x40\x12\xfb\x99\x1c\xba\x9e\xb4\x0e\xd1\x29\xd2\xf2\x6a\x1a\x5a\x1d\xa8\x55\x42\xa3\xed\x85\x15\xad\x27\xeb\x60\xba\x49\x45\x91\x7c\x16\x71\xb0\x34\x08\x35\xce\x68\x72\xad\x38\x90\xbc\x0a\xea\xef\x59\xca\x8f\x85\x55\x8c\x2f\xda\x1f\xe0\xee\xf7\xce\xdb\xd5\x62\xaa\xc6\xbf\x3e\xa3\xf0\x18\x0d\xaf\xc3\x68\x8d\x77\x48\x92\x33\x99\xcd\x51\xf6\x3a\x5a\x23\xbe\x80\x88\x91\x24\xeb\x00\x00\x7e\x7c\x66\x3b\x76\x21\x7d\x8b\xf7\x48\x8b\xec\xf5\x91\xb7\xaf\x43\x6e\x9e\x7f\xff\x64\xe3\x6f\xb0\x84\x60\xbb\x63\x53\x0b\x5f\x8a\x8c\xdd\x41\xc9\xc7\xa7\xdb\x6f\x5a\xad\x68\xf4\x8d\x92\x8d\xf3\xc2\x7a\x92\x8d\xf0\x4f\x66\x65\x31\xdf\xcb\x6a\x49\x6c\xf0\x12\x41\xc6\xaa\xed\x59\xea\x4b\xcb\x67\x88\x27\x2b\x58\xfd\x0b\xfb\x33\x00\xdf\x68\x59\x65\xdd\x03\x00\x00")
+
+func _004_add_commandUpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__004_add_commandUpSql,
+		"004_add_command.up.sql",
+	)
+}
+
+func _004_add_commandUpSql() (*asset, error) {
+	bytes, err := _004_add_commandUpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "004_add_command.up.sql", size: 989, mode: os.FileMode(420), modTime: time.Unix(1792325831, 0)}
 	a := &asset{bytes: bytes, info: info}
 	return a, nil
 }
@@ -170,8 +296,14 @@ func AssetNames() []string {
 
 // _bindata is a table, holding each asset generator, mapped to its name.
 var _bindata = map[string]func() (*asset, error){
-	"001_init.down.sql": _001_initDownSql,
-	"001_init.up.sql":   _001_initUpSql,
+	"001_init.down.sql":            _001_initDownSql,
+	"001_init.up.sql":              _001_initUpSql,
+	"002_add_schedule_id.down.sql": _002_add_schedule_idDownSql,
+	"002_add_schedule_id.up.sql":   _002_add_schedule_idUpSql,
+	"003_add_expires_at.down.sql":  _003_add_expires_atDownSql,
+	"003_add_expires_at.up.sql":    _003_add_expires_atUpSql,
+	"004_add_command.down.sql":     _004_add_commandDownSql,
+	"004_add_command.up.sql":       _004_add_commandUpSql,
 }
 
 // AssetDir returns the file names below a certain
@@ -215,8 +347,14 @@ type bintree struct {
 }
 
 var _bintree = &bintree{nil, map[string]*bintree{
-	"001_init.down.sql": &bintree{_001_initDownSql, map[string]*bintree{}},
-	"001_init.up.sql":   &bintree{_001_initUpSql, map[string]*bintree{}},
+	"001_init.down.sql":            &bintree{_001_initDownSql, map[string]*bintree{}},
+	"001_init.up.sql":              &bintree{_001_initUpSql, map[string]*bintree{}},
+	"002_add_schedule_id.down.sql": &bintree{_002_add_schedule_idDownSql, map[string]*bintree{}},
+	"002_add_schedule_id.up.sql":   &bintree{_002_add_schedule_idUpSql, map[string]*bintree{}},
+	"003_add_expires_at.down.sql":  &bintree{_003_add_expires_atDownSql, map[string]*bintree{}},
+	"003_add_expires_at.up.sql":    &bintree{_003_add_expires_atUpSql, map[string]*bintree{}},
+	"004_add_command.down.sql":     &bintree{_004_add_commandDownSql, map[string]*bintree{}},
+	"004_add_command.up.sql":       &bintree{_004_add_commandUpSql, map[string]*bintree{}},
 }}
 
 // RestoreAsset restores an asset under the given directory
@@ -0,0 +1,4 @@
+ALTER TABLE multi_jobs ADD COLUMN schedule_id TEXT;
+
+CREATE INDEX idx_multi_jobs_schedule_id
+    ON multi_jobs (schedule_id, started_at DESC);
@@ -0,0 +1,28 @@
+DROP INDEX idx_jobs_client_id_status;
+DROP INDEX idx_jobs_client_id_time;
+DROP INDEX idx_jobs_multi_id;
+
+CREATE TABLE jobs_tmp (
+    jid TEXT PRIMARY KEY NOT NULL,
+    status TEXT NOT NULL,
+    started_at DATETIME NOT NULL,
+    finished_at DATETIME,
+    created_by TEXT NOT NULL,
+    client_id TEXT NOT NULL,
+    multi_job_id TEXT,
+    details TEXT NOT NULL,
+    FOREIGN KEY (multi_job_id) REFERENCES multi_jobs(jid)
+) WITHOUT ROWID;
+
+INSERT INTO jobs_tmp (jid, status, started_at, finished_at, created_by, client_id, multi_job_id, details)
+    SELECT jid, status, started_at, finished_at, created_by, client_id, multi_job_id, details FROM jobs;
+
+DROP TABLE jobs;
+
+ALTER TABLE jobs_tmp RENAME TO jobs;
+
+CREATE INDEX idx_jobs_client_id_time
+    ON jobs (client_id, finished_at DESC);
+
+CREATE INDEX idx_jobs_multi_id
+    ON jobs (multi_job_id);
@@ -0,0 +1,4 @@
+ALTER TABLE jobs ADD COLUMN expires_at DATETIME;
+
+CREATE INDEX idx_jobs_client_id_status
+    ON jobs (client_id, status);
@@ -0,0 +1,53 @@
+DROP INDEX idx_jobs_client_id_started_at;
+DROP INDEX idx_multi_jobs_started_at;
+DROP INDEX idx_jobs_client_id_status;
+DROP INDEX idx_jobs_client_id_time;
+DROP INDEX idx_jobs_multi_id;
+DROP INDEX idx_multi_jobs_schedule_id;
+
+CREATE TABLE jobs_tmp (
+    jid TEXT PRIMARY KEY NOT NULL,
+    status TEXT NOT NULL,
+    started_at DATETIME NOT NULL,
+    finished_at DATETIME,
+    created_by TEXT NOT NULL,
+    client_id TEXT NOT NULL,
+    multi_job_id TEXT,
+    details TEXT NOT NULL,
+    expires_at DATETIME,
+    FOREIGN KEY (multi_job_id) REFERENCES multi_jobs(jid)
+) WITHOUT ROWID;
+
+INSERT INTO jobs_tmp (jid, status, started_at, finished_at, created_by, client_id, multi_job_id, details, expires_at)
+    SELECT jid, status, started_at, finished_at, created_by, client_id, multi_job_id, details, expires_at FROM jobs;
+
+DROP TABLE jobs;
+
+ALTER TABLE jobs_tmp RENAME TO jobs;
+
+CREATE TABLE multi_jobs_tmp (
+    jid TEXT PRIMARY KEY NOT NULL,
+    started_at DATETIME NOT NULL,
+    created_by TEXT NOT NULL,
+    details TEXT NOT NULL,
+    schedule_id TEXT
+) WITHOUT ROWID;
+
+INSERT INTO multi_jobs_tmp (jid, started_at, created_by, details, schedule_id)
+    SELECT jid, started_at, created_by, details, schedule_id FROM multi_jobs;
+
+DROP TABLE multi_jobs;
+
+ALTER TABLE multi_jobs_tmp RENAME TO multi_jobs;
+
+CREATE INDEX idx_jobs_client_id_time
+    ON jobs (client_id, finished_at DESC);
+
+CREATE INDEX idx_jobs_multi_id
+    ON jobs (multi_job_id);
+
+CREATE INDEX idx_jobs_client_id_status
+    ON jobs (client_id, status);
+
+CREATE INDEX idx_multi_jobs_schedule_id
+    ON multi_jobs (schedule_id, started_at DESC);
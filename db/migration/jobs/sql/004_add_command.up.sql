@@ -0,0 +1,15 @@
+ALTER TABLE jobs ADD COLUMN command TEXT NOT NULL DEFAULT '';
+ALTER TABLE multi_jobs ADD COLUMN command TEXT NOT NULL DEFAULT '';
+
+-- copy the command of existing jobs from details, where it's stored as a JSON string followed by the "shell" field.
+-- Special characters of copied commands remain JSON escaped.
+UPDATE jobs SET command = substr(details, instr(details, '"command":"') + 11, instr(details, '","shell":') - instr(details, '"command":"') - 11)
+    WHERE instr(details, '"command":"') > 0 AND instr(details, '","shell":') > instr(details, '"command":"');
+UPDATE multi_jobs SET command = substr(details, instr(details, '"command":"') + 11, instr(details, '","shell":') - instr(details, '"command":"') - 11)
+    WHERE instr(details, '"command":"') > 0 AND instr(details, '","shell":') > instr(details, '"command":"');
+
+CREATE INDEX idx_jobs_client_id_started_at
+    ON jobs (client_id, started_at DESC);
+
+CREATE INDEX idx_multi_jobs_started_at
+    ON multi_jobs (started_at DESC);
@@ -0,0 +1,8 @@
+CREATE TABLE scripts (
+    id TEXT NOT NULL,
+    version INTEGER NOT NULL,
+    created_at DATETIME NOT NULL,
+    created_by TEXT NOT NULL,
+    details TEXT NOT NULL,
+    PRIMARY KEY (id, version)
+) WITHOUT ROWID;
@@ -0,0 +1,6 @@
+CREATE TABLE schedules (
+    id TEXT PRIMARY KEY NOT NULL,
+    created_at DATETIME NOT NULL,
+    created_by TEXT NOT NULL,
+    details TEXT NOT NULL
+);
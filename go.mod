@@ -28,7 +28,7 @@ require (
 	github.com/spf13/cobra v1.0.0
 	github.com/spf13/viper v1.7.1
 	github.com/stretchr/testify v1.6.1
-	github.com/tomasen/realip v0.0.0-20180522021738-f0c99a92ddce
+	github.com/tomasen/realip v0.0.0-20180522021738-f0c99a92ddce // indirect
 	golang.org/x/crypto v0.0.0-20200820211705-5c72a883971a
 	golang.org/x/net v0.0.0-20201029221708-28c70e62bb1d
 	golang.org/x/sync v0.0.0-20200625203802-6e8e738ad208
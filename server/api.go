@@ -1,11 +1,15 @@
 package chserver
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"regexp"
 	"strconv"
 	"strings"
@@ -13,12 +17,13 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
-	"github.com/tomasen/realip"
 	"golang.org/x/crypto/ssh"
 
 	"github.com/cloudradar-monitoring/rport/server/api"
 	"github.com/cloudradar-monitoring/rport/server/api/jobs"
 	"github.com/cloudradar-monitoring/rport/server/api/middleware"
+	"github.com/cloudradar-monitoring/rport/server/api/users"
+	"github.com/cloudradar-monitoring/rport/server/auditlog"
 	"github.com/cloudradar-monitoring/rport/server/cgroups"
 	"github.com/cloudradar-monitoring/rport/server/clients"
 	"github.com/cloudradar-monitoring/rport/server/clientsauth"
@@ -40,10 +45,18 @@ const (
 	ErrCodeMissingRouteVar = "ERR_CODE_MISSING_ROUTE_VAR"
 	ErrCodeInvalidRequest  = "ERR_CODE_INVALID_REQUEST"
 	ErrCodeAlreadyExist    = "ERR_CODE_ALREADY_EXIST"
+	ErrCodeClientActive    = "ERR_CODE_CLIENT_ACTIVE"
+	ErrCodeJobNotRunning   = "ERR_CODE_JOB_NOT_RUNNING"
 )
 
 var validInputShell = []string{"cmd", "powershell"}
 
+// defaultTimeoutGraceSec is used for jobs killed on timeout if a grace period is not specified.
+const defaultTimeoutGraceSec = 10
+
+// defaultQueueExpirySec is used for jobs queued for a disconnected client if an expiry is not specified.
+const defaultQueueExpirySec = 24 * 60 * 60
+
 var generateNewJobID = func() string {
 	return random.UUID4()
 }
@@ -56,14 +69,35 @@ var apiUpgrader = websocket.Upgrader{
 
 type JobProvider interface {
 	GetByJID(clientID, jid string) (*models.Job, error)
-	GetSummariesByClientID(clientID string) ([]*models.JobSummary, error)
+	ListSummaries(clientID string, opts *jobs.ListOptions) ([]*models.JobSummary, error)
+	CountSummaries(clientID string, opts *jobs.ListOptions) (int, error)
 	GetByMultiJobID(jid string) ([]*models.Job, error)
 	// SaveJob creates or updates a job
 	SaveJob(job *models.Job) error
 	// CreateJob creates a new job. If already exist with a given JID - do nothing and return nil
 	CreateJob(job *models.Job) error
+	// AppendOutput appends a chunk of output to a running job. If a running job is not found - do nothing and return nil
+	AppendOutput(output *models.JobOutput) error
+	// GetQueuedByClientID returns jobs that wait on the server for a given client to connect
+	GetQueuedByClientID(clientID string) ([]*models.Job, error)
+	// ClaimQueuedJob marks a job that waits on the server for a client as being sent. Returns false if it's not waiting anymore
+	ClaimQueuedJob(jid string) (bool, error)
+	// CancelQueuedJob marks a job that waits on the server for a client as cancelled. Returns false if it's not waiting anymore
+	CancelQueuedJob(jid string, finishedAt time.Time) (bool, error)
+	// UpdateQueuedJob updates a claimed job with a result of sending it. If it's not being sent anymore - do nothing and return nil
+	UpdateQueuedJob(job *models.Job) error
+	// ExpireQueuedJobs marks jobs that wait on the server for a client longer than their expiry time as expired
+	ExpireQueuedJobs(now time.Time) (int64, error)
+	// DeleteFinishedBefore deletes jobs finished before a given time
+	DeleteFinishedBefore(t time.Time) (int64, error)
+	// DeleteExceedingPerClient deletes finished jobs of each client except a given number of the latest ones
+	DeleteExceedingPerClient(maxCount int) (int64, error)
+	DeleteFinishedByClientID(clientID string) (int64, error)
+	Vacuum() error
 	GetMultiJob(jid string) (*models.MultiJob, error)
-	GetAllMultiJobSummaries() ([]*models.MultiJobSummary, error)
+	ListMultiJobSummaries(opts *jobs.ListOptions) ([]*models.MultiJobSummary, error)
+	CountMultiJobSummaries(opts *jobs.ListOptions) (int, error)
+	GetMultiJobSummariesByScheduleID(scheduleID string) ([]*models.MultiJobSummary, error)
 	SaveMultiJob(multiJob *models.MultiJob) error
 	Close() error
 }
@@ -86,6 +120,86 @@ func (al *APIListener) wrapWithAuthMiddleware(f http.Handler) http.HandlerFunc {
 	}
 }
 
+// permissionsMiddleware responds with 403 if a current user isn't granted a given permission.
+func (al *APIListener) permissionsMiddleware(permission users.Permission, f http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// auth is disabled in tests, so there is no user to check permissions for
+		if al.insecureForTests {
+			f(w, r)
+			return
+		}
+
+		curUser, err := al.getCurUser(r.Context())
+		if err != nil {
+			al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+			return
+		}
+		if curUser == nil {
+			al.jsonErrorResponse(w, http.StatusUnauthorized, errUnauthorized)
+			return
+		}
+
+		if !al.config.GroupPermissions().Has(curUser, permission) {
+			al.jsonErrorResponseWithTitle(w, http.StatusForbidden, fmt.Sprintf("Access denied. Permission %q is required.", permission))
+			return
+		}
+
+		f(w, r)
+	}
+}
+
+// adminMiddleware responds with 403 if a current user isn't a member of Administrators group.
+func (al *APIListener) adminMiddleware(f http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// auth is disabled in tests, so there is no user to check
+		if al.insecureForTests {
+			f(w, r)
+			return
+		}
+
+		curUser, err := al.getCurUser(r.Context())
+		if err != nil {
+			al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+			return
+		}
+		if curUser == nil {
+			al.jsonErrorResponse(w, http.StatusUnauthorized, errUnauthorized)
+			return
+		}
+
+		if !curUser.IsAdmin() {
+			al.jsonErrorResponseWithTitle(w, http.StatusForbidden, fmt.Sprintf("Access denied. Only members of %q group are allowed.", users.Administrators))
+			return
+		}
+
+		f(w, r)
+	}
+}
+
+// clientGroupsMiddleware allows changing client groups to users with the client-groups permission. If
+// 'restrict_clients_by_group' is enabled, only administrators are allowed, because client groups define the clients
+// other users have access to.
+func (al *APIListener) clientGroupsMiddleware(f http.HandlerFunc) http.HandlerFunc {
+	adminOnly := al.adminMiddleware(f)
+	permitted := al.permissionsMiddleware(users.PermissionClientGroups, f)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if al.config.API.RestrictClientsByGroup {
+			adminOnly(w, r)
+			return
+		}
+		permitted(w, r)
+	}
+}
+
+// getCurUser returns a user who performs a request or nil if the user is not found.
+func (al *APIListener) getCurUser(ctx context.Context) (*users.User, error) {
+	username := api.GetUser(ctx, al.Logger)
+	if username == "" {
+		return nil, nil
+	}
+	return al.userSrv.GetByUsername(username)
+}
+
 func (al *APIListener) initRouter() {
 	r := mux.NewRouter()
 	sub := r.PathPrefix("/api/v1").Subrouter()
@@ -94,22 +208,45 @@ func (al *APIListener) initRouter() {
 	sub.HandleFunc("/me", al.handleGetMe).Methods(http.MethodGet)
 	sub.HandleFunc("/me/ip", al.handleGetIP).Methods(http.MethodGet)
 	sub.HandleFunc("/clients", al.handleGetClients).Methods(http.MethodGet)
-	sub.HandleFunc("/clients/{client_id}/tunnels", al.handlePutClientTunnel).Methods(http.MethodPut)
-	sub.HandleFunc("/clients/{client_id}/tunnels/{tunnel_id}", al.handleDeleteClientTunnel).Methods(http.MethodDelete)
-	sub.HandleFunc("/clients/{client_id}/commands", al.handlePostCommand).Methods(http.MethodPost)
+	sub.HandleFunc("/clients/{client_id}", al.handleGetClient).Methods(http.MethodGet)
+	sub.HandleFunc("/clients/{client_id}", al.adminMiddleware(al.handleDeleteClient)).Methods(http.MethodDelete)
+	sub.HandleFunc("/clients/{client_id}/tunnels", al.permissionsMiddleware(users.PermissionTunnels, al.handlePutClientTunnel)).Methods(http.MethodPut)
+	sub.HandleFunc("/clients/{client_id}/tunnels/{tunnel_id}", al.handleGetClientTunnel).Methods(http.MethodGet)
+	sub.HandleFunc("/clients/{client_id}/tunnels/{tunnel_id}", al.permissionsMiddleware(users.PermissionTunnels, al.handleDeleteClientTunnel)).Methods(http.MethodDelete)
+	sub.HandleFunc("/clients/{client_id}/commands", al.permissionsMiddleware(users.PermissionCommands, al.handlePostCommand)).Methods(http.MethodPost)
 	sub.HandleFunc("/clients/{client_id}/commands", al.handleGetCommands).Methods(http.MethodGet)
+	sub.HandleFunc("/clients/{client_id}/commands", al.adminMiddleware(al.handleDeleteCommands)).Methods(http.MethodDelete)
 	sub.HandleFunc("/clients/{client_id}/commands/{job_id}", al.handleGetCommand).Methods(http.MethodGet)
+	sub.HandleFunc("/clients/{client_id}/commands/{job_id}", al.permissionsMiddleware(users.PermissionCommands, al.handleDeleteCommand)).Methods(http.MethodDelete)
+	sub.HandleFunc("/clients/{client_id}/commands/{job_id}/output", al.handleGetCommandOutput).Methods(http.MethodGet)
+	sub.HandleFunc("/clients/{client_id}/commands/{job_id}/rerun", al.permissionsMiddleware(users.PermissionCommands, al.handlePostCommandRerun)).Methods(http.MethodPost)
 	sub.HandleFunc("/client-groups", al.handleGetClientGroups).Methods(http.MethodGet)
-	sub.HandleFunc("/client-groups", al.handlePostClientGroups).Methods(http.MethodPost)
-	sub.HandleFunc("/client-groups/{group_id}", al.handlePutClientGroup).Methods(http.MethodPut)
+	sub.HandleFunc("/client-groups", al.clientGroupsMiddleware(al.handlePostClientGroups)).Methods(http.MethodPost)
+	sub.HandleFunc("/client-groups/{group_id}", al.clientGroupsMiddleware(al.handlePutClientGroup)).Methods(http.MethodPut)
 	sub.HandleFunc("/client-groups/{group_id}", al.handleGetClientGroup).Methods(http.MethodGet)
-	sub.HandleFunc("/client-groups/{group_id}", al.handleDeleteClientGroup).Methods(http.MethodDelete)
-	sub.HandleFunc("/commands", al.handlePostMultiClientCommand).Methods(http.MethodPost)
+	sub.HandleFunc("/client-groups/{group_id}", al.clientGroupsMiddleware(al.handleDeleteClientGroup)).Methods(http.MethodDelete)
+	sub.HandleFunc("/library/scripts", al.handleGetLibraryScripts).Methods(http.MethodGet)
+	sub.HandleFunc("/library/scripts", al.permissionsMiddleware(users.PermissionLibrary, al.handlePostLibraryScripts)).Methods(http.MethodPost)
+	sub.HandleFunc("/library/scripts/{script_id}", al.handleGetLibraryScript).Methods(http.MethodGet)
+	sub.HandleFunc("/library/scripts/{script_id}", al.permissionsMiddleware(users.PermissionLibrary, al.handlePutLibraryScript)).Methods(http.MethodPut)
+	sub.HandleFunc("/library/scripts/{script_id}", al.permissionsMiddleware(users.PermissionLibrary, al.handleDeleteLibraryScript)).Methods(http.MethodDelete)
+	sub.HandleFunc("/schedules", al.handleGetSchedules).Methods(http.MethodGet)
+	sub.HandleFunc("/schedules", al.permissionsMiddleware(users.PermissionCommands, al.handlePostSchedules)).Methods(http.MethodPost)
+	sub.HandleFunc("/schedules/{schedule_id}", al.handleGetSchedule).Methods(http.MethodGet)
+	sub.HandleFunc("/schedules/{schedule_id}", al.permissionsMiddleware(users.PermissionCommands, al.handlePutSchedule)).Methods(http.MethodPut)
+	sub.HandleFunc("/schedules/{schedule_id}", al.permissionsMiddleware(users.PermissionCommands, al.handleDeleteSchedule)).Methods(http.MethodDelete)
+	sub.HandleFunc("/schedules/{schedule_id}/enable", al.permissionsMiddleware(users.PermissionCommands, al.handlePostScheduleEnable)).Methods(http.MethodPost)
+	sub.HandleFunc("/schedules/{schedule_id}/disable", al.permissionsMiddleware(users.PermissionCommands, al.handlePostScheduleDisable)).Methods(http.MethodPost)
+	sub.HandleFunc("/schedules/{schedule_id}/runs", al.handleGetScheduleRuns).Methods(http.MethodGet)
+	sub.HandleFunc("/commands", al.permissionsMiddleware(users.PermissionCommands, al.handlePostMultiClientCommand)).Methods(http.MethodPost)
 	sub.HandleFunc("/commands", al.handleGetMultiClientCommands).Methods(http.MethodGet)
 	sub.HandleFunc("/commands/{job_id}", al.handleGetMultiClientCommand).Methods(http.MethodGet)
+	sub.HandleFunc("/commands/{job_id}", al.permissionsMiddleware(users.PermissionCommands, al.handleDeleteMultiClientCommand)).Methods(http.MethodDelete)
+	sub.HandleFunc("/commands/{job_id}/rerun", al.permissionsMiddleware(users.PermissionCommands, al.handlePostMultiClientCommandRerun)).Methods(http.MethodPost)
 	sub.HandleFunc("/clients-auth", al.handleGetClientsAuth).Methods(http.MethodGet)
-	sub.HandleFunc("/clients-auth", al.handlePostClientsAuth).Methods(http.MethodPost)
-	sub.HandleFunc("/clients-auth/{client_auth_id}", al.handleDeleteClientAuth).Methods(http.MethodDelete)
+	sub.HandleFunc("/clients-auth", al.permissionsMiddleware(users.PermissionClientsAuth, al.handlePostClientsAuth)).Methods(http.MethodPost)
+	sub.HandleFunc("/clients-auth/{client_auth_id}", al.permissionsMiddleware(users.PermissionClientsAuth, al.handleDeleteClientAuth)).Methods(http.MethodDelete)
+	sub.HandleFunc("/auditlog", al.adminMiddleware(al.handleGetAuditLog)).Methods(http.MethodGet)
 
 	// add authorization middleware
 	if !al.insecureForTests {
@@ -125,7 +262,7 @@ func (al *APIListener) initRouter() {
 
 	// web sockets
 	// common auth middleware is not used due to JS issue https://stackoverflow.com/questions/22383089/is-it-possible-to-use-bearer-authentication-for-websocket-upgrade-requests
-	sub.HandleFunc("/ws/commands", al.wsAuth(http.HandlerFunc(al.handleCommandsWS))).Methods(http.MethodGet)
+	sub.HandleFunc("/ws/commands", al.wsAuth(al.permissionsMiddleware(users.PermissionCommands, al.handleCommandsWS))).Methods(http.MethodGet)
 
 	// only for test purpose
 	// TODO: remove
@@ -154,6 +291,23 @@ func (al *APIListener) writeJSONResponse(w http.ResponseWriter, statusCode int,
 	}
 }
 
+// apiError is an error of handling an API request. It contains the status code and the title of the error response.
+type apiError struct {
+	code  int
+	title string
+	err   error
+}
+
+func (e *apiError) Error() string {
+	if e.err == nil {
+		return e.title
+	}
+	if e.title == "" {
+		return e.err.Error()
+	}
+	return fmt.Sprintf("%s %v", e.title, e.err)
+}
+
 func (al *APIListener) jsonErrorResponse(w http.ResponseWriter, statusCode int, err error) {
 	al.writeJSONResponse(w, statusCode, api.NewErrorPayload(err))
 }
@@ -191,6 +345,8 @@ func (al *APIListener) handleGetLogin(w http.ResponseWriter, req *http.Request)
 		return
 	}
 
+	al.auditLog.Entry(auditlog.ApplicationAuth, auditlog.ActionLogin).WithHTTPRequest(req).Save()
+
 	response := api.NewSuccessPayload(map[string]string{"token": tokenStr})
 	al.writeJSONResponse(w, http.StatusOK, response)
 }
@@ -214,6 +370,7 @@ func (al *APIListener) handlePostLogin(w http.ResponseWriter, req *http.Request)
 		return
 	}
 	if !authorized {
+		al.auditLog.Entry(auditlog.ApplicationAuth, auditlog.ActionLoginFailed).WithUsername(user).WithRemoteIP(req).Save()
 		al.jsonErrorResponse(w, http.StatusUnauthorized, fmt.Errorf("unauthorized"))
 		return
 	}
@@ -224,6 +381,8 @@ func (al *APIListener) handlePostLogin(w http.ResponseWriter, req *http.Request)
 		return
 	}
 
+	al.auditLog.Entry(auditlog.ApplicationAuth, auditlog.ActionLogin).WithUsername(user).WithRemoteIP(req).Save()
+
 	response := api.NewSuccessPayload(map[string]string{"token": tokenStr})
 	al.writeJSONResponse(w, http.StatusOK, response)
 }
@@ -329,16 +488,121 @@ func (al *APIListener) handleGetClients(w http.ResponseWriter, req *http.Request
 		return
 	}
 
-	clients, err := al.clientService.GetAll()
+	access, err := al.getClientAccess(req.Context())
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	filters, err := api.ParseFilters(req.URL.Query(), clients.FilterFields...)
+	if err != nil {
+		al.jsonErrorResponseWithErrCode(w, http.StatusBadRequest, ErrCodeInvalidRequest, err.Error())
+		return
+	}
+
+	fields, err := api.ParseFields(req.URL.Query(), "clients", clientPayloadFields...)
+	if err != nil {
+		al.jsonErrorResponseWithErrCode(w, http.StatusBadRequest, ErrCodeInvalidRequest, err.Error())
+		return
+	}
+
+	// by default all clients are returned
+	pagination, err := api.ParsePagination(req.URL.Query(), 0, clientsMaxLimit)
+	if err != nil {
+		al.jsonErrorResponseWithErrCode(w, http.StatusBadRequest, ErrCodeInvalidRequest, err.Error())
+		return
+	}
+
+	allClients, err := al.clientService.GetAll()
 	if err != nil {
 		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
 		return
 	}
+	matching := clients.Filter(access.Filter(allClients), filters)
+
+	sortFunc(matching, desc)
+
+	start, end := pagination.Bounds(len(matching))
+	clientsPayload := convertToClientsPayload(matching[start:end])
+
+	var data interface{} = clientsPayload
+	if len(fields) > 0 {
+		selected := make([]map[string]interface{}, 0, len(clientsPayload))
+		for _, cur := range clientsPayload {
+			selected = append(selected, api.SelectFields(cur, fields))
+		}
+		data = selected
+	}
+
+	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayloadWithMeta(data, api.Meta{Count: len(matching)}))
+}
+
+const clientsMaxLimit = 1000
+
+// clientPayloadFields are JSON fields of ClientPayload that can be requested by 'fields[clients]' query param.
+var clientPayloadFields = []string{
+	"id",
+	"name",
+	"os",
+	"os_arch",
+	"os_family",
+	"os_kernel",
+	"hostname",
+	"ipv4",
+	"ipv6",
+	"tags",
+	"version",
+	"address",
+	"tunnels",
+	"disconnected_at",
+	"connection_state",
+	"client_auth_id",
+}
+
+func (al *APIListener) handleDeleteClient(w http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	cid := vars[routeParamClientID]
+	if cid == "" {
+		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, fmt.Sprintf("Missing %q route param.", routeParamClientID))
+		return
+	}
+
+	client, err := al.clientService.GetByID(cid)
+	if err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", fmt.Sprintf("Failed to find a client with id=%q.", cid), err)
+		return
+	}
+	if client == nil {
+		al.jsonErrorResponseWithTitle(w, http.StatusNotFound, fmt.Sprintf("Client with id=%q not found.", cid))
+		return
+	}
+	if !al.allowClientAccess(w, req, client) {
+		return
+	}
+
+	err = al.clientService.DeleteOffline(req.Context(), cid, al.clientProvider)
+	switch err {
+	case nil:
+	case errClientNotFound:
+		al.jsonErrorResponseWithTitle(w, http.StatusNotFound, fmt.Sprintf("Client with id=%q not found.", cid))
+		return
+	case errClientActive:
+		al.jsonErrorResponseWithErrCode(w, http.StatusConflict, ErrCodeClientActive, fmt.Sprintf("Client with id=%q is active and can't be deleted. Only disconnected clients can be deleted.", cid))
+		return
+	default:
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", fmt.Sprintf("Failed to delete a client with id=%q.", cid), err)
+		return
+	}
+
+	al.auditLog.Entry(auditlog.ApplicationClient, auditlog.ActionDelete).
+		WithHTTPRequest(req).
+		WithID(cid).
+		WithClientID(cid).
+		Save()
 
-	sortFunc(clients, desc)
+	al.Infof("Client %q deleted.", cid)
 
-	clientsPayload := convertToClientsPayload(clients)
-	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(clientsPayload))
+	w.WriteHeader(http.StatusNoContent)
 }
 
 type ClientPayload struct {
@@ -363,28 +627,72 @@ type ClientPayload struct {
 func convertToClientsPayload(clients []*clients.Client) []ClientPayload {
 	r := make([]ClientPayload, 0, len(clients))
 	for _, cur := range clients {
-		r = append(r, ClientPayload{
-			ID:              cur.ID,
-			Name:            cur.Name,
-			OS:              cur.OS,
-			OSArch:          cur.OSArch,
-			OSFamily:        cur.OSFamily,
-			OSKernel:        cur.OSKernel,
-			Hostname:        cur.Hostname,
-			IPv4:            cur.IPv4,
-			IPv6:            cur.IPv6,
-			Tags:            cur.Tags,
-			Version:         cur.Version,
-			Address:         cur.Address,
-			Tunnels:         cur.Tunnels,
-			DisconnectedAt:  cur.DisconnectedAt,
-			ConnectionState: cur.ConnectionState(),
-			ClientAuthID:    cur.ClientAuthID,
-		})
+		r = append(r, convertToClientPayload(cur))
 	}
 	return r
 }
 
+func convertToClientPayload(client *clients.Client) ClientPayload {
+	return ClientPayload{
+		ID:              client.ID,
+		Name:            client.Name,
+		OS:              client.OS,
+		OSArch:          client.OSArch,
+		OSFamily:        client.OSFamily,
+		OSKernel:        client.OSKernel,
+		Hostname:        client.Hostname,
+		IPv4:            client.IPv4,
+		IPv6:            client.IPv6,
+		Tags:            client.Tags,
+		Version:         client.Version,
+		Address:         client.Address,
+		Tunnels:         client.Tunnels,
+		DisconnectedAt:  client.DisconnectedAt,
+		ConnectionState: client.ConnectionState(),
+		ClientAuthID:    client.ClientAuthID,
+	}
+}
+
+// ClientDetailsPayload represents a single client with its jobs.
+type ClientDetailsPayload struct {
+	ClientPayload
+	Jobs []*models.JobSummary `json:"jobs"`
+}
+
+func (al *APIListener) handleGetClient(w http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	cid := vars[routeParamClientID]
+	if cid == "" {
+		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, fmt.Sprintf("Missing %q route param.", routeParamClientID))
+		return
+	}
+
+	client, err := al.clientService.GetByID(cid)
+	if err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", fmt.Sprintf("Failed to find a client with id=%q.", cid), err)
+		return
+	}
+	if client == nil {
+		al.jsonErrorResponseWithTitle(w, http.StatusNotFound, fmt.Sprintf("Client with id=%q not found.", cid))
+		return
+	}
+	if !al.allowClientAccess(w, req, client) {
+		return
+	}
+
+	jobSummaries, err := al.jobProvider.ListSummaries(cid, &jobs.ListOptions{})
+	if err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", fmt.Sprintf("Failed to get client jobs: client_id=%q.", cid), err)
+		return
+	}
+
+	resp := ClientDetailsPayload{
+		ClientPayload: convertToClientPayload(client),
+		Jobs:          jobSummaries,
+	}
+	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(resp))
+}
+
 func getCorrespondingSortFunc(sortStr string) (sortFunc func(a []*clients.Client, desc bool), desc bool, err error) {
 	var sortField string
 	if strings.HasPrefix(sortStr, "-") {
@@ -441,6 +749,9 @@ func (al *APIListener) handlePutClientTunnel(w http.ResponseWriter, req *http.Re
 		al.jsonErrorResponseWithTitle(w, http.StatusNotFound, fmt.Sprintf("client with id %s not found", clientID))
 		return
 	}
+	if !al.allowClientAccess(w, req, client) {
+		return
+	}
 
 	localAddr := req.URL.Query().Get("local")
 	remoteAddr := req.URL.Query().Get("remote")
@@ -454,6 +765,14 @@ func (al *APIListener) handlePutClientTunnel(w http.ResponseWriter, req *http.Re
 		return
 	}
 
+	if protocol := req.URL.Query().Get("protocol"); protocol != "" {
+		if err := chshare.ValidateProtocol(protocol); err != nil {
+			al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		remote.Protocol = protocol
+	}
+
 	aclStr := req.URL.Query().Get("acl")
 	if _, err = clients.ParseTunnelACL(aclStr); err != nil {
 		al.jsonErrorResponseWithErrCode(w, http.StatusBadRequest, ErrCodeInvalidACL, fmt.Sprintf("Invalid ACL: %s", err))
@@ -472,19 +791,49 @@ func (al *APIListener) handlePutClientTunnel(w http.ResponseWriter, req *http.Re
 		remote.Scheme = &schemeStr
 	}
 
+	if err := parseHTTPProxyParams(req.URL.Query(), remote); err != nil {
+		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if remote.HTTPProxy && al.clientService.tunnelProxyConfig == nil {
+		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, "HTTP proxy tunnels are disabled: 'tunnel_proxy_cert_file' and 'tunnel_proxy_key_file' should be set.")
+		return
+	}
+
+	if idleTimeoutStr := req.URL.Query().Get("idle_timeout"); idleTimeoutStr != "" {
+		idleTimeout, err := time.ParseDuration(idleTimeoutStr)
+		if err != nil || idleTimeout < time.Second {
+			al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, fmt.Sprintf("Invalid idle timeout %q: expected a duration of at least 1s, e.g. 30m.", idleTimeoutStr))
+			return
+		}
+		remote.IdleTimeoutSec = int(idleTimeout / time.Second)
+	}
+
+	autoCloseAt, err := parseTimeQueryParam(req.URL.Query(), "auto_close_at")
+	if err != nil {
+		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if autoCloseAt != nil && !autoCloseAt.After(time.Now()) {
+		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, fmt.Sprintf("Invalid auto close time %s: should be in the future.", autoCloseAt.Format(time.RFC3339)))
+		return
+	}
+	remote.AutoCloseAt = autoCloseAt
+
 	if existing := client.FindTunnelByRemote(remote); existing != nil {
 		al.jsonErrorResponseWithErrCode(w, http.StatusBadRequest, ErrCodeTunnelExist, "Tunnel already exist.")
 		return
 	}
 
 	for _, t := range client.Tunnels {
-		if t.Remote.Remote() == remote.Remote() {
+		if t.Remote.SameTarget(remote) {
 			al.jsonErrorResponseWithErrCode(w, http.StatusBadRequest, ErrCodeTunnelToPortExist, fmt.Sprintf("Tunnel to port %s already exist.", remote.RemotePort))
 			return
 		}
 	}
 
-	if checkPortStr := req.URL.Query().Get("check_port"); checkPortStr != "0" {
+	// udp ports can't be checked for reachability, datagrams may be silently dropped
+	if checkPortStr := req.URL.Query().Get("check_port"); checkPortStr != "0" && !remote.IsUDP() {
 		if !al.checkRemotePort(w, *remote, client.Connection) {
 			return
 		}
@@ -503,10 +852,50 @@ func (al *APIListener) handlePutClientTunnel(w http.ResponseWriter, req *http.Re
 		al.jsonErrorResponse(w, http.StatusConflict, fmt.Errorf("can't create tunnel: %s", err))
 		return
 	}
+
+	al.auditLog.Entry(auditlog.ApplicationClientTunnel, auditlog.ActionCreate).
+		WithHTTPRequest(req).
+		WithID(tunnels[0].ID).
+		WithClientID(clientID).
+		WithParams(tunnels[0].Remote).
+		Save()
+
 	response := api.NewSuccessPayload(tunnels[0])
 	al.writeJSONResponse(w, http.StatusOK, response)
 }
 
+// parseHTTPProxyParams sets http proxy options of a given remote requested by given query params.
+func parseHTTPProxyParams(values url.Values, remote *chshare.Remote) error {
+	var err error
+	if httpProxyStr := values.Get("http_proxy"); httpProxyStr != "" {
+		remote.HTTPProxy, err = strconv.ParseBool(httpProxyStr)
+		if err != nil {
+			return fmt.Errorf("invalid %q query param: expected a boolean value, actual %q", "http_proxy", httpProxyStr)
+		}
+	}
+	if proxyAuthStr := values.Get("proxy_auth"); proxyAuthStr != "" {
+		remote.ProxyAuth, err = strconv.ParseBool(proxyAuthStr)
+		if err != nil {
+			return fmt.Errorf("invalid %q query param: expected a boolean value, actual %q", "proxy_auth", proxyAuthStr)
+		}
+	}
+	remote.HostHeader = values.Get("host_header")
+
+	if !remote.HTTPProxy {
+		if remote.ProxyAuth || remote.HostHeader != "" {
+			return errors.New("'proxy_auth' and 'host_header' can be set only if 'http_proxy' is enabled")
+		}
+		return nil
+	}
+	if remote.IsUDP() {
+		return errors.New("http proxy is not supported for udp tunnels")
+	}
+	if remote.Scheme != nil && *remote.Scheme != "http" && *remote.Scheme != "https" {
+		return fmt.Errorf("invalid scheme %q for http proxy: expected 'http' or 'https'", *remote.Scheme)
+	}
+	return nil
+}
+
 func (al *APIListener) checkLocalPort(w http.ResponseWriter, localPort string) bool {
 	lport, err := strconv.Atoi(localPort)
 	if err != nil {
@@ -558,6 +947,45 @@ func (al *APIListener) checkRemotePort(w http.ResponseWriter, remote chshare.Rem
 	return true
 }
 
+// handleGetClientTunnel returns a tunnel of a client including its usage statistics.
+func (al *APIListener) handleGetClientTunnel(w http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	clientID := vars[routeParamClientID]
+	if clientID == "" {
+		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, "client id is missing")
+		return
+	}
+
+	client, err := al.clientService.GetByID(clientID)
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+	if client == nil {
+		al.jsonErrorResponseWithTitle(w, http.StatusNotFound, fmt.Sprintf("client with id %s not found", clientID))
+		return
+	}
+	if !al.allowClientAccess(w, req, client) {
+		return
+	}
+
+	tunnelID := vars["tunnel_id"]
+	if tunnelID == "" {
+		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, "tunnel id is missing")
+		return
+	}
+
+	client.Lock()
+	tunnel := client.FindTunnel(tunnelID)
+	client.Unlock()
+	if tunnel == nil {
+		al.jsonErrorResponseWithTitle(w, http.StatusNotFound, "tunnel not found")
+		return
+	}
+
+	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(tunnel))
+}
+
 func (al *APIListener) handleDeleteClientTunnel(w http.ResponseWriter, req *http.Request) {
 	vars := mux.Vars(req)
 	clientID, exists := vars[routeParamClientID]
@@ -575,6 +1003,9 @@ func (al *APIListener) handleDeleteClientTunnel(w http.ResponseWriter, req *http
 		al.jsonErrorResponseWithTitle(w, http.StatusNotFound, fmt.Sprintf("client with id %s not found", clientID))
 		return
 	}
+	if !al.allowClientAccess(w, req, client) {
+		return
+	}
 
 	tunnelID, exists := vars["tunnel_id"]
 	if !exists || tunnelID == "" {
@@ -592,7 +1023,14 @@ func (al *APIListener) handleDeleteClientTunnel(w http.ResponseWriter, req *http
 		return
 	}
 
-	client.TerminateTunnel(tunnel)
+	client.TerminateTunnel(tunnel, false)
+
+	al.auditLog.Entry(auditlog.ApplicationClientTunnel, auditlog.ActionDelete).
+		WithHTTPRequest(req).
+		WithID(tunnelID).
+		WithClientID(clientID).
+		WithParams(tunnel.Remote).
+		Save()
 
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -616,11 +1054,13 @@ func (al *APIListener) handleGetMe(w http.ResponseWriter, req *http.Request) {
 	}
 
 	me := struct {
-		User   string   `json:"user"`
-		Groups []string `json:"groups"`
+		User        string             `json:"user"`
+		Groups      []string           `json:"groups"`
+		Permissions []users.Permission `json:"permissions"`
 	}{
-		User:   user.Username,
-		Groups: user.Groups,
+		User:        user.Username,
+		Groups:      user.Groups,
+		Permissions: al.config.GroupPermissions().Get(user),
 	}
 	response := api.NewSuccessPayload(me)
 	al.writeJSONResponse(w, http.StatusOK, response)
@@ -630,7 +1070,7 @@ func (al *APIListener) handleGetIP(w http.ResponseWriter, req *http.Request) {
 	ipResp := struct {
 		IP string `json:"ip"`
 	}{
-		IP: realip.FromRequest(req),
+		IP: al.config.TrustedProxies().RemoteIP(req),
 	}
 	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(ipResp))
 }
@@ -694,6 +1134,11 @@ func (al *APIListener) handlePostClientsAuth(w http.ResponseWriter, req *http.Re
 
 	al.Infof("ClientAuth %q created.", newClient.ID)
 
+	al.auditLog.Entry(auditlog.ApplicationClientsAuth, auditlog.ActionCreate).
+		WithHTTPRequest(req).
+		WithID(newClient.ID).
+		Save()
+
 	w.WriteHeader(http.StatusCreated)
 }
 
@@ -750,6 +1195,12 @@ func (al *APIListener) handleDeleteClientAuth(w http.ResponseWriter, req *http.R
 	}
 	al.Infof("ClientAuth %q deleted.", clientAuthID)
 
+	al.auditLog.Entry(auditlog.ApplicationClientsAuth, auditlog.ActionDelete).
+		WithHTTPRequest(req).
+		WithID(clientAuthID).
+		WithParams(map[string]interface{}{"force": force}).
+		Save()
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -785,6 +1236,28 @@ func (al *APIListener) allowClientAuthWrite(w http.ResponseWriter) bool {
 	return true
 }
 
+// cmdRequest is a request to execute a command on a single client.
+type cmdRequest struct {
+	Command         string            `json:"command"`
+	Shell           string            `json:"shell"`
+	Interpreter     string            `json:"interpreter"`
+	Script          string            `json:"script"`
+	ScriptID        string            `json:"script_id"`
+	ScriptVersion   int               `json:"script_version"`
+	Params          map[string]string `json:"params"`
+	Cwd             string            `json:"cwd"`
+	Env             map[string]string `json:"env"`
+	RunAsUser       string            `json:"run_as_user"`
+	TimeoutSec      int               `json:"timeout_sec"`
+	TimeoutPolicy   string            `json:"timeout_policy"`
+	TimeoutGraceSec int               `json:"timeout_grace_sec"`
+	QueueIfOffline  bool              `json:"queue_if_offline"`
+	QueueExpirySec  int               `json:"queue_expiry_sec"`
+	// SuccessExitCodes cannot be requested for a single client, they are kept only when a child job of
+	// a multi-client job is re-run.
+	SuccessExitCodes []int `json:"-"`
+}
+
 func (al *APIListener) handlePostCommand(w http.ResponseWriter, req *http.Request) {
 	vars := mux.Vars(req)
 	cid := vars[routeParamClientID]
@@ -793,11 +1266,7 @@ func (al *APIListener) handlePostCommand(w http.ResponseWriter, req *http.Reques
 		return
 	}
 
-	reqBody := struct {
-		Command    string `json:"command"`
-		Shell      string `json:"shell"`
-		TimeoutSec int    `json:"timeout_sec"`
-	}{}
+	reqBody := cmdRequest{}
 	dec := json.NewDecoder(req.Body)
 	dec.DisallowUnknownFields()
 	err := dec.Decode(&reqBody)
@@ -808,28 +1277,73 @@ func (al *APIListener) handlePostCommand(w http.ResponseWriter, req *http.Reques
 		al.jsonErrorResponseWithError(w, http.StatusBadRequest, "", "Invalid JSON data.", err)
 		return
 	}
-	if reqBody.Command == "" {
+	if reqBody.Command == "" && reqBody.Script == "" && reqBody.ScriptID == "" {
 		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, "Command cannot be empty.")
 		return
 	}
+	if err := validateLibraryScript(reqBody.ScriptID, reqBody.ScriptVersion, reqBody.Params, reqBody.Command, reqBody.Shell, reqBody.Script, reqBody.Interpreter); err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusBadRequest, "", "Invalid script.", err)
+		return
+	}
+	if err := validateScript(reqBody.Command, reqBody.Shell, reqBody.Script, reqBody.Interpreter); err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusBadRequest, "", "Invalid script.", err)
+		return
+	}
 	if err := validateShell(reqBody.Shell); err != nil {
 		al.jsonErrorResponseWithError(w, http.StatusBadRequest, "", "Invalid shell.", err)
 		return
 	}
+	if err := validateEnv(reqBody.Env); err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusBadRequest, "", "Invalid env.", err)
+		return
+	}
+
+	if err := al.resolveLibraryScript(req.Context(), reqBody.ScriptID, reqBody.Params, &reqBody.ScriptVersion, &reqBody.Env, &reqBody.Script, &reqBody.Interpreter); err != nil {
+		al.jsonErrorResponseWithError(w, err.code, "", err.title, err.err)
+		return
+	}
+
+	if err := validateTimeoutPolicy(reqBody.TimeoutPolicy); err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusBadRequest, "", "Invalid timeout policy.", err)
+		return
+	}
 
+	al.startJob(w, req, cid, &reqBody)
+}
+
+// startJob sends a job to execute a given request with a resolved library script, if any, to a client with a given ID
+// or queues it if the client is disconnected and it's requested. Writes the response with the job ID.
+func (al *APIListener) startJob(w http.ResponseWriter, req *http.Request, cid string, reqBody *cmdRequest) {
 	if reqBody.TimeoutSec <= 0 {
 		reqBody.TimeoutSec = al.config.Server.RunRemoteCmdTimeoutSec
 	}
+	if reqBody.TimeoutPolicy == models.JobTimeoutPolicyKill && reqBody.TimeoutGraceSec <= 0 {
+		reqBody.TimeoutGraceSec = defaultTimeoutGraceSec
+	}
+	if reqBody.QueueExpirySec != 0 && !reqBody.QueueIfOffline {
+		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, "Queue expiry can be specified only together with queue_if_offline.")
+		return
+	}
+	if reqBody.QueueExpirySec < 0 {
+		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, fmt.Sprintf("Invalid queue expiry: %d.", reqBody.QueueExpirySec))
+		return
+	}
+	if reqBody.QueueExpirySec == 0 {
+		reqBody.QueueExpirySec = defaultQueueExpirySec
+	}
 
-	client, err := al.clientService.GetActiveByID(cid)
+	client, err := al.clientService.GetByID(cid)
 	if err != nil {
-		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", fmt.Sprintf("Failed to find an active client with id=%q.", cid), err)
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", fmt.Sprintf("Failed to find a client with id=%q.", cid), err)
 		return
 	}
-	if client == nil {
+	if client == nil || client.DisconnectedAt != nil && !reqBody.QueueIfOffline {
 		al.jsonErrorResponseWithTitle(w, http.StatusNotFound, fmt.Sprintf("Active client with id=%q not found.", cid))
 		return
 	}
+	if !al.allowClientAccess(w, req, client) {
+		return
+	}
 
 	// send the command to the client
 	// Send a job with all possible info in order to get the full-populated job back (in client-listener) when it's done.
@@ -839,34 +1353,62 @@ func (al *APIListener) handlePostCommand(w http.ResponseWriter, req *http.Reques
 			JID:        generateNewJobID(),
 			FinishedAt: nil,
 		},
-		ClientID:   cid,
-		Command:    reqBody.Command,
-		Shell:      reqBody.Shell,
-		CreatedBy:  api.GetUser(req.Context(), al.Logger),
-		TimeoutSec: reqBody.TimeoutSec,
-		Result:     nil,
-	}
-	sshResp := &comm.RunCmdResponse{}
-	err = comm.SendRequestAndGetResponse(client.Connection, comm.RequestTypeRunCmd, curJob, sshResp)
-	if err != nil {
-		if _, ok := err.(*comm.ClientError); ok {
-			al.jsonErrorResponseWithTitle(w, http.StatusConflict, err.Error())
-		} else {
-			al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", "Failed to execute remote command.", err)
+		ClientID:         cid,
+		Command:          reqBody.Command,
+		Shell:            reqBody.Shell,
+		Interpreter:      reqBody.Interpreter,
+		Script:           reqBody.Script,
+		ScriptHash:       scriptHash(reqBody.Script),
+		ScriptID:         reqBody.ScriptID,
+		ScriptVersion:    reqBody.ScriptVersion,
+		Cwd:              reqBody.Cwd,
+		Env:              reqBody.Env,
+		RunAsUser:        reqBody.RunAsUser,
+		CreatedBy:        api.GetUser(req.Context(), al.Logger),
+		TimeoutSec:       reqBody.TimeoutSec,
+		TimeoutPolicy:    reqBody.TimeoutPolicy,
+		TimeoutGraceSec:  reqBody.TimeoutGraceSec,
+		SuccessExitCodes: reqBody.SuccessExitCodes,
+		Result:           nil,
+	}
+	if client.DisconnectedAt != nil {
+		// keep the job on the server until the client connects
+		curJob.Status = models.JobStatusQueued
+		curJob.StartedAt = time.Now()
+		expiresAt := curJob.StartedAt.Add(time.Duration(reqBody.QueueExpirySec) * time.Second)
+		curJob.ExpiresAt = &expiresAt
+	} else {
+		sshResp := &comm.RunCmdResponse{}
+		err = comm.SendRequestAndGetResponse(client.Connection, comm.RequestTypeRunCmd, curJob, sshResp)
+		if err != nil {
+			if _, ok := err.(*comm.ClientError); ok {
+				al.jsonErrorResponseWithTitle(w, http.StatusConflict, err.Error())
+			} else {
+				al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", "Failed to execute remote command.", err)
+			}
+			return
 		}
-		return
-	}
 
-	// set fields received in response
-	curJob.PID = &sshResp.Pid
-	curJob.StartedAt = sshResp.StartedAt
-	curJob.Status = models.JobStatusRunning
+		// set fields received in response
+		applyRunCmdResponse(&curJob, sshResp)
+	}
 
 	if err := al.jobProvider.CreateJob(&curJob); err != nil {
 		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", "Failed to persist a new job.", err)
 		return
 	}
 
+	al.auditLog.Entry(auditlog.ApplicationClientCommand, auditlog.ActionCreate).
+		WithHTTPRequest(req).
+		WithID(curJob.JID).
+		WithClientID(cid).
+		WithParams(&cmdAuditParams{
+			Command:     reqBody.Command,
+			Interpreter: reqBody.Interpreter,
+			ScriptID:    reqBody.ScriptID,
+		}).
+		Save()
+
 	resp := struct {
 		JID string `json:"jid"`
 	}{
@@ -889,22 +1431,80 @@ func validateShell(shell string) error {
 	return fmt.Errorf("expected shell to be one of: %s, actual: %s", validInputShell, shell)
 }
 
-func (al *APIListener) handleGetCommands(w http.ResponseWriter, req *http.Request) {
-	vars := mux.Vars(req)
-	cid := vars[routeParamClientID]
-	if cid == "" {
-		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, fmt.Sprintf("Missing %q route param.", routeParamClientID))
-		return
+// validateScript checks that either a command or a script is requested and a script interpreter is used only for a script.
+func validateScript(command, shell, script, interpreter string) error {
+	if script == "" {
+		if interpreter != "" {
+			return errors.New("interpreter can be specified only for a script")
+		}
+		return nil
 	}
-
-	res, err := al.jobProvider.GetSummariesByClientID(cid)
-	if err != nil {
-		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", fmt.Sprintf("Failed to get client jobs: client_id=%q.", cid), err)
+	if command != "" {
+		return errors.New("command and script cannot be specified together")
+	}
+	if shell != "" {
+		return errors.New("shell cannot be specified for a script, use interpreter instead")
+	}
+	return nil
+}
+
+// scriptHash returns a hex encoded SHA-256 hash of a given script or an empty string if there is no script.
+func scriptHash(script string) string {
+	if script == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(script))
+	return hex.EncodeToString(sum[:])
+}
+
+func validateEnv(env map[string]string) error {
+	for name := range env {
+		if name == "" || strings.ContainsAny(name, "=\x00") {
+			return fmt.Errorf("invalid environment variable name: %q", name)
+		}
+	}
+	return nil
+}
+
+func validateTimeoutPolicy(policy string) error {
+	switch policy {
+	case "", models.JobTimeoutPolicyStopObserving, models.JobTimeoutPolicyKill:
+		return nil
+	}
+	return fmt.Errorf("expected timeout policy to be one of: %s, actual: %s", []string{models.JobTimeoutPolicyStopObserving, models.JobTimeoutPolicyKill}, policy)
+}
+
+func (al *APIListener) handleGetCommands(w http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	cid := vars[routeParamClientID]
+	if cid == "" {
+		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, fmt.Sprintf("Missing %q route param.", routeParamClientID))
 		return
 	}
 
-	jobs.SortByFinishedAt(res, true)
-	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(res))
+	if !al.allowClientIDAccess(w, req, cid) {
+		return
+	}
+
+	opts, err := parseJobListOptions(req.URL.Query(), jobs.JobFilterFields, jobs.JobSortFields, true)
+	if err != nil {
+		al.jsonErrorResponseWithErrCode(w, http.StatusBadRequest, ErrCodeInvalidRequest, err.Error())
+		return
+	}
+
+	res, err := al.jobProvider.ListSummaries(cid, opts)
+	if err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", fmt.Sprintf("Failed to get client jobs: client_id=%q.", cid), err)
+		return
+	}
+
+	count, err := al.jobProvider.CountSummaries(cid, opts)
+	if err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", fmt.Sprintf("Failed to count client jobs: client_id=%q.", cid), err)
+		return
+	}
+
+	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayloadWithMeta(res, api.Meta{Count: count}))
 }
 
 func (al *APIListener) handleGetCommand(w http.ResponseWriter, req *http.Request) {
@@ -920,12 +1520,16 @@ func (al *APIListener) handleGetCommand(w http.ResponseWriter, req *http.Request
 		return
 	}
 
+	if !al.allowClientIDAccess(w, req, cid) {
+		return
+	}
+
 	job, err := al.jobProvider.GetByJID(cid, jid)
 	if err != nil {
 		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", fmt.Sprintf("Failed to find a job[id=%q].", jid), err)
 		return
 	}
-	if job == nil {
+	if job == nil || job.ClientID != cid {
 		al.jsonErrorResponseWithTitle(w, http.StatusNotFound, fmt.Sprintf("Job[id=%q] not found.", jid))
 		return
 	}
@@ -933,23 +1537,302 @@ func (al *APIListener) handleGetCommand(w http.ResponseWriter, req *http.Request
 	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(job))
 }
 
+// handleDeleteCommand cancels a running job. The client kills the command together with its child processes.
+func (al *APIListener) handleDeleteCommand(w http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	cid := vars[routeParamClientID]
+	if cid == "" {
+		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, fmt.Sprintf("Missing %q route param.", routeParamClientID))
+		return
+	}
+	jid := vars[routeParamJobID]
+	if jid == "" {
+		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, fmt.Sprintf("Missing %q route param.", routeParamJobID))
+		return
+	}
+
+	client, err := al.clientService.GetByID(cid)
+	if err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", fmt.Sprintf("Failed to find a client with id=%q.", cid), err)
+		return
+	}
+	if client == nil {
+		al.jsonErrorResponseWithTitle(w, http.StatusNotFound, fmt.Sprintf("Client with id=%q not found.", cid))
+		return
+	}
+	if !al.allowClientAccess(w, req, client) {
+		return
+	}
+
+	job, err := al.jobProvider.GetByJID(cid, jid)
+	if err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", fmt.Sprintf("Failed to find a job[id=%q].", jid), err)
+		return
+	}
+	if job == nil || job.ClientID != cid {
+		al.jsonErrorResponseWithTitle(w, http.StatusNotFound, fmt.Sprintf("Job[id=%q] not found.", jid))
+		return
+	}
+	if !isJobCancelable(job) {
+		al.jsonErrorResponseWithErrCode(w, http.StatusConflict, ErrCodeJobNotRunning, fmt.Sprintf("Job[id=%q] is not running, status: %s.", jid, job.Status))
+		return
+	}
+
+	cancelled, err := al.cancelServerQueuedJob(job)
+	if err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", fmt.Sprintf("Failed to cancel a job[id=%q].", jid), err)
+		return
+	}
+	if !cancelled {
+		if client.DisconnectedAt != nil {
+			al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, fmt.Sprintf("Client with id=%q is not active.", cid))
+			return
+		}
+
+		err = al.cancelJob(job, client.Connection)
+		if err != nil {
+			if _, ok := err.(*comm.ClientError); ok {
+				al.jsonErrorResponseWithDetail(w, http.StatusConflict, ErrCodeJobNotRunning, fmt.Sprintf("Failed to cancel a job[id=%q].", jid), err.Error())
+				return
+			}
+			al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", fmt.Sprintf("Failed to cancel a job[id=%q].", jid), err)
+			return
+		}
+	}
+
+	al.auditLog.Entry(auditlog.ApplicationClientCommand, auditlog.ActionCancel).
+		WithHTTPRequest(req).
+		WithID(jid).
+		WithClientID(cid).
+		Save()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// isJobCancelable returns true if a command of a given job can still be running on a client.
+func isJobCancelable(job *models.Job) bool {
+	// unknown status means the client stopped observing the command but it can still be running
+	return job.Status == models.JobStatusRunning || job.Status == models.JobStatusQueued || job.Status == models.JobStatusSending ||
+		job.Status == models.JobStatusUnknown
+}
+
+// cancelServerQueuedJob cancels a job that waits on the server for its client to connect without contacting the client.
+// Returns false if the job is not waiting on the server, e.g. it's already sent to the client.
+func (al *APIListener) cancelServerQueuedJob(job *models.Job) (bool, error) {
+	if job.Status != models.JobStatusQueued || job.ExpiresAt == nil {
+		return false, nil
+	}
+	cancelled, err := al.jobProvider.CancelQueuedJob(job.JID, time.Now())
+	if err != nil {
+		return false, err
+	}
+	if cancelled {
+		al.Infof("%s, Queued command cancelled.", job.LogPrefix())
+	}
+	return cancelled, nil
+}
+
+// cancelJob sends a request to a client to kill a command of a given job.
+func (al *APIListener) cancelJob(job *models.Job, conn ssh.Conn) error {
+	resp := &comm.CancelCmdResponse{}
+	err := comm.SendRequestAndGetResponse(conn, comm.RequestTypeCancelCmd, &comm.CancelCmdRequest{JID: job.JID}, resp)
+	if err != nil {
+		return err
+	}
+	al.Infof("%s, Command with PID %d cancelled.", job.LogPrefix(), resp.Pid)
+
+	// a client sends the result of a cancelled command only if it still observes it
+	if job.Status == models.JobStatusUnknown {
+		now := time.Now()
+		job.Status = models.JobStatusCancelled
+		job.FinishedAt = &now
+		if err := al.jobProvider.SaveJob(job); err != nil {
+			return fmt.Errorf("failed to save cancelled job: %v", err)
+		}
+	}
+	return nil
+}
+
+type jobOutputPayload struct {
+	JID    string `json:"jid"`
+	Status string `json:"status"`
+	StdOut string `json:"stdout"`
+	StdErr string `json:"stderr"`
+}
+
+func newJobOutputPayload(job *models.Job) *jobOutputPayload {
+	res := &jobOutputPayload{
+		JID:    job.JID,
+		Status: job.Status,
+	}
+	if job.Result != nil {
+		res.StdOut = job.Result.StdOut
+		res.StdErr = job.Result.StdErr
+	}
+	return res
+}
+
+// handleGetCommandOutput returns the output of a job collected so far. With 'follow' query param it keeps streaming
+// new output chunks as newline delimited JSON objects until the job is finished.
+func (al *APIListener) handleGetCommandOutput(w http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	cid := vars[routeParamClientID]
+	if cid == "" {
+		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, fmt.Sprintf("Missing %q route param.", routeParamClientID))
+		return
+	}
+	jid := vars[routeParamJobID]
+	if jid == "" {
+		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, fmt.Sprintf("Missing %q route param.", routeParamJobID))
+		return
+	}
+
+	follow := false
+	if followStr := req.URL.Query().Get("follow"); followStr != "" {
+		var err error
+		follow, err = strconv.ParseBool(followStr)
+		if err != nil {
+			al.jsonErrorResponseWithErrCode(w, http.StatusBadRequest, ErrCodeInvalidRequest, fmt.Sprintf("Invalid follow param %v.", followStr))
+			return
+		}
+	}
+
+	if !al.allowClientIDAccess(w, req, cid) {
+		return
+	}
+
+	var job *models.Job
+	loadJob := func() error {
+		var err error
+		job, err = al.jobProvider.GetByJID(cid, jid)
+		if job != nil && job.ClientID != cid {
+			job = nil
+		}
+		return err
+	}
+	if !follow {
+		if err := loadJob(); err != nil {
+			al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", fmt.Sprintf("Failed to find a job[id=%q].", jid), err)
+			return
+		}
+		if job == nil {
+			al.jsonErrorResponseWithTitle(w, http.StatusNotFound, fmt.Sprintf("Job[id=%q] not found.", jid))
+			return
+		}
+		al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(newJobOutputPayload(job)))
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		al.jsonErrorResponseWithTitle(w, http.StatusInternalServerError, "Streaming is not supported.")
+		return
+	}
+
+	// subscribe before checking the job status to not miss output sent in between
+	outputs, err := al.Server.jobOutputs.Subscribe(cid, jid, loadJob)
+	if err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", fmt.Sprintf("Failed to find a job[id=%q].", jid), err)
+		return
+	}
+	defer al.Server.jobOutputs.Unsubscribe(jid, outputs)
+	if job == nil {
+		al.jsonErrorResponseWithTitle(w, http.StatusNotFound, fmt.Sprintf("Job[id=%q] not found.", jid))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+	writeLine := func(line *jobOutputPayload) bool {
+		if err := enc.Encode(line); err != nil {
+			al.Debugf("Job[id=%q], failed to write output: %v", jid, err)
+			return false
+		}
+		flusher.Flush()
+		return true
+	}
+
+	if !writeLine(newJobOutputPayload(job)) || job.Status != models.JobStatusRunning {
+		return
+	}
+	// subscribers are finished when the client disconnects, so check it's still connected after subscribing
+	if client, err := al.clientService.GetActiveByID(cid); err != nil || client == nil {
+		return
+	}
+	for {
+		select {
+		case output, ok := <-outputs:
+			if !ok {
+				// the job is finished, send its final status
+				if err := loadJob(); err != nil || job == nil {
+					al.Errorf("Job[id=%q], failed to get a finished job: %v", jid, err)
+					return
+				}
+				writeLine(&jobOutputPayload{JID: jid, Status: job.Status})
+				return
+			}
+			if !writeLine(&jobOutputPayload{JID: jid, Status: models.JobStatusRunning, StdOut: output.StdOut, StdErr: output.StdErr}) {
+				return
+			}
+		case <-req.Context().Done():
+			return
+		}
+	}
+}
+
 type newJobResponse struct {
 	JID string `json:"jid"`
 }
 
 type multiClientCmdRequest struct {
-	ClientIDs           []string `json:"client_ids"`
-	GroupIDs            []string `json:"group_ids"`
-	Command             string   `json:"command"`
-	Shell               string   `json:"shell"`
-	TimeoutSec          int      `json:"timeout_sec"`
-	ExecuteConcurrently bool     `json:"execute_concurrently"`
-	AbortOnError        *bool    `json:"abort_on_error"` // pointer is used because it's default value is true. Otherwise it would be more difficult to check whether this field is missing or not
+	ClientIDs           []string          `json:"client_ids"`
+	GroupIDs            []string          `json:"group_ids"`
+	Command             string            `json:"command"`
+	Shell               string            `json:"shell"`
+	Interpreter         string            `json:"interpreter"`
+	Script              string            `json:"script"`
+	ScriptID            string            `json:"script_id"`
+	ScriptVersion       int               `json:"script_version"`
+	Params              map[string]string `json:"params"`
+	Cwd                 string            `json:"cwd"`
+	Env                 map[string]string `json:"env"`
+	RunAsUser           string            `json:"run_as_user"`
+	TimeoutSec          int               `json:"timeout_sec"`
+	TimeoutPolicy       string            `json:"timeout_policy"`
+	TimeoutGraceSec     int               `json:"timeout_grace_sec"`
+	SuccessExitCodes    []int             `json:"success_exit_codes"`
+	ExecuteConcurrently bool              `json:"execute_concurrently"`
+	AbortOnError        *bool             `json:"abort_on_error"` // pointer is used because it's default value is true. Otherwise it would be more difficult to check whether this field is missing or not
+	BatchSize           int               `json:"batch_size"`
+	BatchPercent        int               `json:"batch_percent"`
+	BatchByGroup        bool              `json:"batch_by_group"`
+	BatchPauseSec       int               `json:"batch_pause_sec"`
+	MaxFailurePercent   *int              `json:"max_failure_percent"`
+}
+
+// cmdAuditParams are params of a command recorded in the audit log. Script content, script params and env values
+// often contain secrets, so they are never recorded.
+type cmdAuditParams struct {
+	ClientIDs   []string `json:"client_ids,omitempty"`
+	GroupIDs    []string `json:"group_ids,omitempty"`
+	Command     string   `json:"command,omitempty"`
+	Interpreter string   `json:"interpreter,omitempty"`
+	ScriptID    string   `json:"script_id,omitempty"`
+}
+
+func (r *multiClientCmdRequest) auditParams() *cmdAuditParams {
+	return &cmdAuditParams{
+		ClientIDs:   r.ClientIDs,
+		GroupIDs:    r.GroupIDs,
+		Command:     r.Command,
+		Interpreter: r.Interpreter,
+		ScriptID:    r.ScriptID,
+	}
 }
 
 // TODO: refactor to reuse similar code for REST API and WebSocket to execute cmds if both will be supported
 func (al *APIListener) handlePostMultiClientCommand(w http.ResponseWriter, req *http.Request) {
-	ctx := req.Context()
 	reqBody := multiClientCmdRequest{}
 	dec := json.NewDecoder(req.Body)
 	dec.DisallowUnknownFields()
@@ -961,162 +1844,247 @@ func (al *APIListener) handlePostMultiClientCommand(w http.ResponseWriter, req *
 		al.jsonErrorResponseWithError(w, http.StatusBadRequest, "", "Invalid JSON data.", err)
 		return
 	}
-	if reqBody.Command == "" {
-		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, "Command cannot be empty.")
+	if title, err := validateMultiClientCmdRequest(&reqBody); title != "" {
+		al.jsonErrorResponseWithError(w, http.StatusBadRequest, "", title, err)
 		return
 	}
-	if err := validateShell(reqBody.Shell); err != nil {
-		al.jsonErrorResponseWithError(w, http.StatusBadRequest, "", "Invalid shell.", err)
+
+	if err := al.resolveLibraryScript(req.Context(), reqBody.ScriptID, reqBody.Params, &reqBody.ScriptVersion, &reqBody.Env, &reqBody.Script, &reqBody.Interpreter); err != nil {
+		al.jsonErrorResponseWithError(w, err.code, "", err.title, err.err)
+		return
+	}
+
+	al.startMultiClientJob(w, req, &reqBody, 2)
+}
+
+// startMultiClientJob creates a multi-client job to execute a given request with a resolved library script, if any,
+// and writes the response with the job ID. minClients is a min number of clients the job should target.
+func (al *APIListener) startMultiClientJob(w http.ResponseWriter, req *http.Request, reqBody *multiClientCmdRequest, minClients int) {
+	multiJob, err := al.createMultiClientJob(req.Context(), reqBody, minClients, nil)
+	if err != nil {
+		al.jsonErrorResponseWithError(w, err.code, "", err.title, err.err)
 		return
 	}
 
+	al.auditLog.Entry(auditlog.ApplicationMultiClientCommand, auditlog.ActionCreate).
+		WithHTTPRequest(req).
+		WithID(multiJob.JID).
+		WithParams(reqBody.auditParams()).
+		Save()
+
+	resp := newJobResponse{
+		JID: multiJob.JID,
+	}
+	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(resp))
+}
+
+// createMultiClientJob creates and starts a multi-client job to execute a given request with a resolved library script,
+// if any, on behalf of the user of a given context. A job of a schedule with a given ID skips clients that are not
+// found, not accessible or not active instead of failing, as clients of a schedule may change after it's created.
+func (al *APIListener) createMultiClientJob(ctx context.Context, reqBody *multiClientCmdRequest, minClients int, scheduleID *string) (*models.MultiJob, *apiError) {
 	if reqBody.TimeoutSec <= 0 {
 		reqBody.TimeoutSec = al.config.Server.RunRemoteCmdTimeoutSec
 	}
+	if reqBody.TimeoutPolicy == models.JobTimeoutPolicyKill && reqBody.TimeoutGraceSec <= 0 {
+		reqBody.TimeoutGraceSec = defaultTimeoutGraceSec
+	}
+
+	access, err := al.getClientAccess(ctx)
+	if err != nil {
+		return nil, &apiError{code: http.StatusInternalServerError, err: err}
+	}
 
 	var groups []*cgroups.ClientGroup
 	for _, groupID := range reqBody.GroupIDs {
 		group, err := al.clientGroupProvider.Get(ctx, groupID)
 		if err != nil {
-			al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", fmt.Sprintf("Failed to get a client group with id=%q.", groupID), err)
-			return
+			return nil, &apiError{code: http.StatusInternalServerError, title: fmt.Sprintf("Failed to get a client group with id=%q.", groupID), err: err}
 		}
 		if group == nil {
-			al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, fmt.Sprintf("Unknown group with id=%q.", groupID))
-			return
+			return nil, &apiError{code: http.StatusBadRequest, title: fmt.Sprintf("Unknown group with id=%q.", groupID)}
 		}
 		groups = append(groups, group)
 	}
-	groupClients := al.clientService.GetActiveByGroups(groups)
+	// use only group clients the user has access to
+	groupClients := access.Filter(al.clientService.GetActiveByGroups(groups))
 
-	if len(reqBody.GroupIDs) > 0 && len(groupClients) == 0 && len(reqBody.ClientIDs) == 0 {
-		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, "No active clients belong to the selected group(s).")
-		return
-	}
-
-	minClients := 2
-	if len(reqBody.ClientIDs) < minClients && len(groupClients) == 0 {
-		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, fmt.Sprintf("At least %d clients should be specified.", minClients))
-		return
+	if scheduleID == nil {
+		if len(reqBody.GroupIDs) > 0 && len(groupClients) == 0 && len(reqBody.ClientIDs) == 0 {
+			return nil, &apiError{code: http.StatusBadRequest, title: "No active clients belong to the selected group(s)."}
+		}
+		if len(reqBody.ClientIDs) < minClients && len(groupClients) == 0 {
+			return nil, &apiError{code: http.StatusBadRequest, title: fmt.Sprintf("At least %d clients should be specified.", minClients)}
+		}
 	}
 
 	clientsConn := make(map[string]ssh.Conn)
+	var clientIDs []string
 	for _, cid := range reqBody.ClientIDs {
 		client, err := al.clientService.GetByID(cid)
 		if err != nil {
-			al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", fmt.Sprintf("Failed to find a client with id=%q.", cid), err)
-			return
+			return nil, &apiError{code: http.StatusInternalServerError, title: fmt.Sprintf("Failed to find a client with id=%q.", cid), err: err}
+		}
+		if scheduleID != nil && (client == nil || !access.Allowed(client) || client.DisconnectedAt != nil) {
+			al.Infof("Schedule[id=%q]: client with id=%q is skipped as it's not found, not accessible or not active.", *scheduleID, cid)
+			continue
 		}
 		if client == nil {
-			al.jsonErrorResponseWithTitle(w, http.StatusNotFound, fmt.Sprintf("Client with id=%q not found.", cid))
-			return
+			return nil, &apiError{code: http.StatusNotFound, title: fmt.Sprintf("Client with id=%q not found.", cid)}
+		}
+		if !access.Allowed(client) {
+			return nil, &apiError{code: http.StatusForbidden, title: fmt.Sprintf("Access denied to client with id=%q.", cid)}
 		}
 		if client.DisconnectedAt != nil {
-			al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, fmt.Sprintf("Client with id=%q is not active.", cid))
-			return
+			return nil, &apiError{code: http.StatusBadRequest, title: fmt.Sprintf("Client with id=%q is not active.", cid)}
 		}
 		clientsConn[cid] = client.Connection
+		clientIDs = append(clientIDs, cid)
 	}
 
-	var orderedClientIDs []string
-	orderedClientIDs = append(orderedClientIDs, reqBody.ClientIDs...)
 	for _, groupClient := range groupClients {
 		if clientsConn[groupClient.ID] == nil {
 			clientsConn[groupClient.ID] = groupClient.Connection
-			orderedClientIDs = append(orderedClientIDs, groupClient.ID)
 		}
 	}
+	if len(clientsConn) == 0 {
+		return nil, &apiError{code: http.StatusBadRequest, title: "No active clients to run the command on."}
+	}
+
+	multiJob := newMultiJob(generateNewJobID(), reqBody, api.GetUser(ctx, al.Logger))
+	batches := newBatches(multiJob, clientIDs, groups, groupClients)
+	multiJob.Batches = len(batches)
+	multiJob.ScheduleID = scheduleID
+	if err := al.jobProvider.SaveMultiJob(multiJob); err != nil {
+		return nil, &apiError{code: http.StatusInternalServerError, title: "Failed to persist a new multi-client job.", err: err}
+	}
+
+	al.Debugf("Multi-client Job[id=%q] created to execute remote command on clients %s.", multiJob.JID, batches)
+
+	go al.executeMultiClientJob(multiJob, clientsConn, batches)
+	return multiJob, nil
+}
+
+// validateMultiClientCmdRequest checks fields of a given request to execute a multi-client command.
+// Returns a title of an error to show to a user and the error itself, if any.
+func validateMultiClientCmdRequest(reqBody *multiClientCmdRequest) (string, error) {
+	if reqBody.Command == "" && reqBody.Script == "" && reqBody.ScriptID == "" {
+		return "Command cannot be empty.", nil
+	}
+	if err := validateLibraryScript(reqBody.ScriptID, reqBody.ScriptVersion, reqBody.Params, reqBody.Command, reqBody.Shell, reqBody.Script, reqBody.Interpreter); err != nil {
+		return "Invalid script.", err
+	}
+	if err := validateScript(reqBody.Command, reqBody.Shell, reqBody.Script, reqBody.Interpreter); err != nil {
+		return "Invalid script.", err
+	}
+	if err := validateShell(reqBody.Shell); err != nil {
+		return "Invalid shell.", err
+	}
+	if err := validateEnv(reqBody.Env); err != nil {
+		return "Invalid env.", err
+	}
+	if err := validateTimeoutPolicy(reqBody.TimeoutPolicy); err != nil {
+		return "Invalid timeout policy.", err
+	}
+	if err := validateRolloutStrategy(reqBody); err != nil {
+		return "Invalid rollout strategy.", err
+	}
+	return "", nil
+}
 
+// newMultiJob returns a new multi-client job with a given ID to execute a given request.
+func newMultiJob(jid string, reqBody *multiClientCmdRequest, createdBy string) *models.MultiJob {
 	// by default abortOnErr is true
 	abortOnErr := true
 	if reqBody.AbortOnError != nil {
 		abortOnErr = *reqBody.AbortOnError
 	}
 
-	multiJob := &models.MultiJob{
+	return &models.MultiJob{
 		MultiJobSummary: models.MultiJobSummary{
-			JID:       generateNewJobID(),
+			JID:       jid,
 			StartedAt: time.Now(),
-			CreatedBy: api.GetUser(req.Context(), al.Logger),
+			CreatedBy: createdBy,
 		},
-		ClientIDs:  reqBody.ClientIDs,
-		GroupIDs:   reqBody.GroupIDs,
-		Command:    reqBody.Command,
-		Shell:      reqBody.Shell,
-		TimeoutSec: reqBody.TimeoutSec,
-		Concurrent: reqBody.ExecuteConcurrently,
-		AbortOnErr: abortOnErr,
+		ClientIDs:         reqBody.ClientIDs,
+		GroupIDs:          reqBody.GroupIDs,
+		Command:           reqBody.Command,
+		Shell:             reqBody.Shell,
+		Interpreter:       reqBody.Interpreter,
+		Script:            reqBody.Script,
+		ScriptHash:        scriptHash(reqBody.Script),
+		ScriptID:          reqBody.ScriptID,
+		ScriptVersion:     reqBody.ScriptVersion,
+		Cwd:               reqBody.Cwd,
+		Env:               reqBody.Env,
+		RunAsUser:         reqBody.RunAsUser,
+		TimeoutSec:        reqBody.TimeoutSec,
+		TimeoutPolicy:     reqBody.TimeoutPolicy,
+		TimeoutGraceSec:   reqBody.TimeoutGraceSec,
+		SuccessExitCodes:  reqBody.SuccessExitCodes,
+		Concurrent:        reqBody.ExecuteConcurrently,
+		AbortOnErr:        abortOnErr,
+		BatchSize:         reqBody.BatchSize,
+		BatchPercent:      reqBody.BatchPercent,
+		BatchByGroup:      reqBody.BatchByGroup,
+		BatchPauseSec:     reqBody.BatchPauseSec,
+		MaxFailurePercent: reqBody.MaxFailurePercent,
 	}
-	if err := al.jobProvider.SaveMultiJob(multiJob); err != nil {
-		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", "Failed to persist a new multi-client job.", err)
-		return
-	}
-
-	resp := newJobResponse{
-		JID: multiJob.JID,
-	}
-	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(resp))
-
-	al.Debugf("Multi-client Job[id=%q] created to execute remote command on clients %s, groups %s: %q.", multiJob.JID, reqBody.ClientIDs, reqBody.GroupIDs, reqBody.Command)
-
-	go al.executeMultiClientJob(multiJob, clientsConn, orderedClientIDs)
 }
 
-func (al *APIListener) executeMultiClientJob(job *models.MultiJob, clientsConn map[string]ssh.Conn, orderedClientIDs []string) {
-	// for sequential execution - create a channel to get the job result
-	var curJobDoneChannel chan *models.Job
-	if !job.Concurrent {
-		curJobDoneChannel = make(chan *models.Job)
-		al.jobsDoneChannel.Set(job.JID, curJobDoneChannel)
-		defer func() {
-			close(curJobDoneChannel)
-			al.jobsDoneChannel.Del(job.JID)
-		}()
-	}
-	for _, cid := range orderedClientIDs {
-		conn := clientsConn[cid]
-		if job.Concurrent {
-			go al.createAndRunJob(job.JID, cid, job.Command, job.Shell, job.CreatedBy, job.TimeoutSec, conn)
-		} else {
-			success := al.createAndRunJob(job.JID, cid, job.Command, job.Shell, job.CreatedBy, job.TimeoutSec, conn)
-			if !success {
-				if job.AbortOnErr {
-					break
-				}
-				continue
-			}
+func (al *APIListener) executeMultiClientJob(job *models.MultiJob, clientsConn map[string]ssh.Conn, batches [][]string) {
+	al.multiJobsCancel.Start(job.JID)
+	defer al.multiJobsCancel.Finish(job.JID)
 
-			// in tests skip next part to avoid waiting
-			if al.insecureForTests {
-				continue
-			}
+	al.runBatches(job, batches, func(cid string) bool {
+		return al.createAndRunJob(newChildJob(job, generateNewJobID(), cid), clientsConn[cid])
+	}, nil)
 
-			// wait until command is finished
-			jobResult := <-curJobDoneChannel
-			if job.AbortOnErr && jobResult.Status == models.JobStatusFailed {
-				break
-			}
-		}
-	}
 	if al.testDone != nil {
 		al.testDone <- true
 	}
 }
 
-func (al *APIListener) createAndRunJob(jid, cid, cmd, shell, createdBy string, timeoutSec int, conn ssh.Conn) bool {
-	// send the command to the client
-	curJob := models.Job{
+// applyRunCmdResponse sets fields of a job accepted by a client. A queued job gets PID and start time when the client
+// starts it and notifies the server about it.
+func applyRunCmdResponse(job *models.Job, resp *comm.RunCmdResponse) {
+	if resp.Queued {
+		job.Status = models.JobStatusQueued
+		return
+	}
+	job.PID = &resp.Pid
+	job.StartedAt = resp.StartedAt // override with the start time of the command
+	job.Status = models.JobStatusRunning
+}
+
+// newChildJob returns a new job of a given multi-client job to run on a given client.
+func newChildJob(multiJob *models.MultiJob, jid, cid string) *models.Job {
+	return &models.Job{
 		JobSummary: models.JobSummary{
-			JID: generateNewJobID(),
+			JID: jid,
 		},
-		StartedAt:  time.Now(),
-		ClientID:   cid,
-		Command:    cmd,
-		Shell:      shell,
-		CreatedBy:  createdBy,
-		TimeoutSec: timeoutSec,
-		MultiJobID: &jid,
+		StartedAt:        time.Now(),
+		ClientID:         cid,
+		Command:          multiJob.Command,
+		Shell:            multiJob.Shell,
+		Interpreter:      multiJob.Interpreter,
+		Script:           multiJob.Script,
+		ScriptHash:       multiJob.ScriptHash,
+		ScriptID:         multiJob.ScriptID,
+		ScriptVersion:    multiJob.ScriptVersion,
+		Cwd:              multiJob.Cwd,
+		Env:              multiJob.Env,
+		RunAsUser:        multiJob.RunAsUser,
+		CreatedBy:        multiJob.CreatedBy,
+		TimeoutSec:       multiJob.TimeoutSec,
+		TimeoutPolicy:    multiJob.TimeoutPolicy,
+		TimeoutGraceSec:  multiJob.TimeoutGraceSec,
+		SuccessExitCodes: multiJob.SuccessExitCodes,
+		MultiJobID:       &multiJob.JID,
 	}
+}
+
+func (al *APIListener) createAndRunJob(curJob *models.Job, conn ssh.Conn) bool {
+	// send the command to the client
 	sshResp := &comm.RunCmdResponse{}
 	err := comm.SendRequestAndGetResponse(conn, comm.RequestTypeRunCmd, curJob, sshResp)
 	// return an error after saving the job
@@ -1129,12 +2097,10 @@ func (al *APIListener) createAndRunJob(jid, cid, cmd, shell, createdBy string, t
 		curJob.Error = err.Error()
 	} else {
 		// success, set fields received in response
-		curJob.PID = &sshResp.Pid
-		curJob.StartedAt = sshResp.StartedAt // override with the start time of the command
-		curJob.Status = models.JobStatusRunning
+		applyRunCmdResponse(curJob, sshResp)
 	}
 
-	if dbErr := al.jobProvider.CreateJob(&curJob); dbErr != nil {
+	if dbErr := al.jobProvider.CreateJob(curJob); dbErr != nil {
 		// just log it, cmd is running, when it's finished it can be saved on result return
 		al.Errorf("multi_client_id=%q, client_id=%q, Failed to persist a child job: %v", *curJob.MultiJobID, curJob.ClientID, dbErr)
 	}
@@ -1160,18 +2126,28 @@ func (al *APIListener) handleCommandsWS(w http.ResponseWriter, req *http.Request
 		return
 	}
 
-	if inboundMsg.Command == "" {
-		uiConnTS.WriteError("Command cannot be empty.", nil)
+	if title, err := validateMultiClientCmdRequest(&inboundMsg); title != "" {
+		uiConnTS.WriteError(title, err)
 		return
 	}
-	if err := validateShell(inboundMsg.Shell); err != nil {
-		uiConnTS.WriteError("Invalid shell.", err)
+
+	if err := al.resolveLibraryScript(ctx, inboundMsg.ScriptID, inboundMsg.Params, &inboundMsg.ScriptVersion, &inboundMsg.Env, &inboundMsg.Script, &inboundMsg.Interpreter); err != nil {
+		uiConnTS.WriteError(err.title, err.err)
 		return
 	}
 
 	if inboundMsg.TimeoutSec <= 0 {
 		inboundMsg.TimeoutSec = al.config.Server.RunRemoteCmdTimeoutSec
 	}
+	if inboundMsg.TimeoutPolicy == models.JobTimeoutPolicyKill && inboundMsg.TimeoutGraceSec <= 0 {
+		inboundMsg.TimeoutGraceSec = defaultTimeoutGraceSec
+	}
+
+	access, err := al.getClientAccess(ctx)
+	if err != nil {
+		uiConnTS.WriteError("Failed to get clients accessible by the user.", err)
+		return
+	}
 
 	var groups []*cgroups.ClientGroup
 	for _, groupID := range inboundMsg.GroupIDs {
@@ -1186,7 +2162,8 @@ func (al *APIListener) handleCommandsWS(w http.ResponseWriter, req *http.Request
 		}
 		groups = append(groups, group)
 	}
-	groupClients := al.clientService.GetActiveByGroups(groups)
+	// use only group clients the user has access to
+	groupClients := access.Filter(al.clientService.GetActiveByGroups(groups))
 
 	if len(inboundMsg.GroupIDs) > 0 && len(groupClients) == 0 && len(inboundMsg.ClientIDs) == 0 {
 		uiConnTS.WriteError("No active clients belong to the selected group(s).", nil)
@@ -1209,6 +2186,10 @@ func (al *APIListener) handleCommandsWS(w http.ResponseWriter, req *http.Request
 			uiConnTS.WriteError(fmt.Sprintf("Client with id=%q not found.", cid), nil)
 			return
 		}
+		if !access.Allowed(client) {
+			uiConnTS.WriteError(fmt.Sprintf("Access denied to client with id=%q.", cid), nil)
+			return
+		}
 		if client.DisconnectedAt != nil {
 			uiConnTS.WriteError(fmt.Sprintf("Client with id=%q is not active.", cid), nil)
 			return
@@ -1216,84 +2197,88 @@ func (al *APIListener) handleCommandsWS(w http.ResponseWriter, req *http.Request
 		clientsConn[cid] = client.Connection
 	}
 
-	var orderedClientIDs []string
-	orderedClientIDs = append(orderedClientIDs, inboundMsg.ClientIDs...)
 	for _, groupClient := range groupClients {
 		if clientsConn[groupClient.ID] == nil {
 			clientsConn[groupClient.ID] = groupClient.Connection
-			orderedClientIDs = append(orderedClientIDs, groupClient.ID)
 		}
 	}
 
 	jid := generateNewJobID()
 	al.Server.uiJobWebSockets.Set(jid, uiConnTS)
 	defer al.Server.uiJobWebSockets.Delete(jid)
+	al.multiJobsCancel.Start(jid)
+	defer al.multiJobsCancel.Finish(jid)
 
 	createdBy := api.GetUser(req.Context(), al.Logger)
 	if len(inboundMsg.ClientIDs) > 1 || len(groupClients) > 0 {
-		// by default abortOnErr is true
-		abortOnErr := true
-		if inboundMsg.AbortOnError != nil {
-			abortOnErr = *inboundMsg.AbortOnError
-		}
-
-		multiJob := &models.MultiJob{
-			MultiJobSummary: models.MultiJobSummary{
-				JID:       jid,
-				StartedAt: time.Now(),
-				CreatedBy: createdBy,
-			},
-			ClientIDs:  inboundMsg.ClientIDs,
-			GroupIDs:   inboundMsg.GroupIDs,
-			Command:    inboundMsg.Command,
-			Shell:      inboundMsg.Shell,
-			TimeoutSec: inboundMsg.TimeoutSec,
-			Concurrent: inboundMsg.ExecuteConcurrently,
-			AbortOnErr: abortOnErr,
-		}
+		multiJob := newMultiJob(jid, &inboundMsg, createdBy)
+		batches := newBatches(multiJob, inboundMsg.ClientIDs, groups, groupClients)
+		multiJob.Batches = len(batches)
 		if err := al.jobProvider.SaveMultiJob(multiJob); err != nil {
 			uiConnTS.WriteError("Failed to persist a new multi-client job.", err)
 			return
 		}
 
+		al.auditLog.Entry(auditlog.ApplicationMultiClientCommand, auditlog.ActionCreate).
+			WithHTTPRequest(req).
+			WithID(multiJob.JID).
+			WithParams(inboundMsg.auditParams()).
+			Save()
+
 		al.Debugf("Multi-client Job[id=%q] created to execute remote command on clients %s, groups %s: %q.", multiJob.JID, inboundMsg.ClientIDs, inboundMsg.GroupIDs, inboundMsg.Command)
 		uiConnTS.SetWritesBeforeClose(len(clientsConn))
 
-		// for sequential execution - create a channel to get the job result
-		var curJobDoneChannel chan *models.Job
-		if !multiJob.Concurrent {
-			curJobDoneChannel = make(chan *models.Job)
-			al.jobsDoneChannel.Set(multiJob.JID, curJobDoneChannel)
-			defer func() {
-				close(curJobDoneChannel)
-				al.jobsDoneChannel.Del(multiJob.JID)
-			}()
-		}
-
-		for _, cid := range orderedClientIDs {
-			conn := clientsConn[cid]
-			curJID := generateNewJobID()
-			if multiJob.Concurrent {
-				go al.createAndRunJobWS(uiConnTS, &jid, curJID, cid, multiJob.Command, multiJob.Shell, createdBy, multiJob.TimeoutSec, conn)
-			} else {
-				success := al.createAndRunJobWS(uiConnTS, &jid, curJID, cid, multiJob.Command, multiJob.Shell, createdBy, multiJob.TimeoutSec, conn)
-				if !success {
-					if multiJob.AbortOnErr {
-						uiConnTS.Close()
-						return
-					}
-					continue
-				}
-				// wait until command is finished
-				jobResult := <-curJobDoneChannel
-				if multiJob.AbortOnErr && jobResult.Status == models.JobStatusFailed {
-					uiConnTS.Close()
-					return
-				}
+		ok := al.runBatches(multiJob, batches, func(cid string) bool {
+			return al.createAndRunJobWS(uiConnTS, newChildJob(multiJob, generateNewJobID(), cid), clientsConn[cid])
+		}, func(batch *models.MultiJobBatch) {
+			batchBytes, err := json.Marshal(batch)
+			if err != nil {
+				al.Errorf("Multi-client Job[id=%q]: failed to encode batch progress: %v", batch.MultiJobID, err)
+				return
+			}
+			if err := uiConnTS.WriteIntermediateMessage(websocket.TextMessage, batchBytes); err != nil {
+				al.Errorf("Multi-client Job[id=%q]: failed to write batch progress to UI Web Socket: %v", batch.MultiJobID, err)
 			}
+		})
+		if !ok {
+			uiConnTS.Close()
+			return
 		}
 	} else {
-		al.createAndRunJobWS(uiConnTS, nil, jid, inboundMsg.ClientIDs[0], inboundMsg.Command, inboundMsg.Shell, createdBy, inboundMsg.TimeoutSec, clientsConn[inboundMsg.ClientIDs[0]])
+		al.auditLog.Entry(auditlog.ApplicationClientCommand, auditlog.ActionCreate).
+			WithHTTPRequest(req).
+			WithID(jid).
+			WithClientID(inboundMsg.ClientIDs[0]).
+			WithParams(&cmdAuditParams{
+				Command:     inboundMsg.Command,
+				Interpreter: inboundMsg.Interpreter,
+				ScriptID:    inboundMsg.ScriptID,
+			}).
+			Save()
+
+		curJob := &models.Job{
+			JobSummary: models.JobSummary{
+				JID: jid,
+			},
+			StartedAt:        time.Now(),
+			ClientID:         inboundMsg.ClientIDs[0],
+			Command:          inboundMsg.Command,
+			Shell:            inboundMsg.Shell,
+			Interpreter:      inboundMsg.Interpreter,
+			Script:           inboundMsg.Script,
+			ScriptHash:       scriptHash(inboundMsg.Script),
+			ScriptID:         inboundMsg.ScriptID,
+			ScriptVersion:    inboundMsg.ScriptVersion,
+			Cwd:              inboundMsg.Cwd,
+			Env:              inboundMsg.Env,
+			RunAsUser:        inboundMsg.RunAsUser,
+			CreatedBy:        createdBy,
+			TimeoutSec:       inboundMsg.TimeoutSec,
+			TimeoutPolicy:    inboundMsg.TimeoutPolicy,
+			TimeoutGraceSec:  inboundMsg.TimeoutGraceSec,
+			SuccessExitCodes: inboundMsg.SuccessExitCodes,
+		}
+		al.createAndRunJobWS(uiConnTS, curJob, clientsConn[curJob.ClientID])
 	}
 
 	// check for Close message from client to close the connection
@@ -1311,19 +2296,7 @@ func (al *APIListener) handleCommandsWS(w http.ResponseWriter, req *http.Request
 	uiConnTS.Close()
 }
 
-func (al *APIListener) createAndRunJobWS(uiConnTS *ws.ConcurrentWebSocket, multiJobID *string, jid, cid, cmd, shell, createdBy string, timeoutSec int, clientConn ssh.Conn) bool {
-	curJob := models.Job{
-		JobSummary: models.JobSummary{
-			JID: jid,
-		},
-		StartedAt:  time.Now(),
-		ClientID:   cid,
-		Command:    cmd,
-		Shell:      shell,
-		CreatedBy:  createdBy,
-		TimeoutSec: timeoutSec,
-		MultiJobID: multiJobID,
-	}
+func (al *APIListener) createAndRunJobWS(uiConnTS *ws.ConcurrentWebSocket, curJob *models.Job, clientConn ssh.Conn) bool {
 	logPrefix := curJob.LogPrefix()
 
 	// send the command to the client
@@ -1343,17 +2316,15 @@ func (al *APIListener) createAndRunJobWS(uiConnTS *ws.ConcurrentWebSocket, multi
 		al.Debugf("%s, Job was sent to execute remote command: %q.", logPrefix, curJob.Command)
 
 		// success, set fields received in response
-		curJob.PID = &sshResp.Pid
-		curJob.StartedAt = sshResp.StartedAt // override with the start time of the command
-		curJob.Status = models.JobStatusRunning
+		applyRunCmdResponse(curJob, sshResp)
 	}
 
 	// do not save the failed job if it's a single-client job
-	if err != nil && multiJobID == nil {
+	if err != nil && curJob.MultiJobID == nil {
 		return false
 	}
 
-	if dbErr := al.jobProvider.CreateJob(&curJob); dbErr != nil {
+	if dbErr := al.jobProvider.CreateJob(curJob); dbErr != nil {
 		// just log it, cmd is running, when it's finished it can be saved on result return
 		al.Errorf("%s, Failed to persist job: %v", logPrefix, dbErr)
 	}
@@ -1379,17 +2350,151 @@ func (al *APIListener) handleGetMultiClientCommand(w http.ResponseWriter, req *h
 		return
 	}
 
+	access, err := al.getClientAccess(req.Context())
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+	allowedIDs, err := al.allowedClientIDs(access)
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+	if allowedIDs != nil {
+		filterMultiJob(job, allowedIDs)
+		if len(job.Jobs) == 0 && len(job.ClientIDs) == 0 {
+			al.jsonErrorResponseWithTitle(w, http.StatusForbidden, fmt.Sprintf("Access denied to multi-client job[id=%q].", jid))
+			return
+		}
+	}
+
 	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(job))
 }
 
+// handleDeleteMultiClientCommand cancels a multi-client job. Running commands are killed and not yet started ones are not started anymore.
+func (al *APIListener) handleDeleteMultiClientCommand(w http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	jid := vars[routeParamJobID]
+	if jid == "" {
+		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, fmt.Sprintf("Missing %q route param.", routeParamJobID))
+		return
+	}
+
+	multiJob, err := al.jobProvider.GetMultiJob(jid)
+	if err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", fmt.Sprintf("Failed to find a multi-client job[id=%q].", jid), err)
+		return
+	}
+	if multiJob == nil {
+		al.jsonErrorResponseWithTitle(w, http.StatusNotFound, fmt.Sprintf("Multi-client Job[id=%q] not found.", jid))
+		return
+	}
+
+	access, err := al.getClientAccess(req.Context())
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+	allowedClients, err := al.allowedClientIDSet(access)
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+	// cancelling stops the whole job, so it's allowed only to users with access to all its clients
+	targetIDs := append([]string{}, multiJob.ClientIDs...)
+	for _, job := range multiJob.Jobs {
+		targetIDs = append(targetIDs, job.ClientID)
+	}
+	if !targetsAllowed(access, allowedClients, targetIDs, multiJob.GroupIDs) {
+		al.jsonErrorResponseWithTitle(w, http.StatusForbidden, fmt.Sprintf("Access denied to multi-client job[id=%q].", jid))
+		return
+	}
+
+	var toCancel, queued []*models.Job
+	clientsConn := make(map[string]ssh.Conn)
+	for _, job := range multiJob.Jobs {
+		if !isJobCancelable(job) {
+			continue
+		}
+		if job.Status == models.JobStatusQueued && job.ExpiresAt != nil {
+			queued = append(queued, job)
+			continue
+		}
+		client, err := al.clientService.GetByID(job.ClientID)
+		if err != nil {
+			al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", fmt.Sprintf("Failed to find a client with id=%q.", job.ClientID), err)
+			return
+		}
+		if client == nil || client.DisconnectedAt != nil {
+			al.Debugf("%s, Client is not active, skip cancelling.", job.LogPrefix())
+			continue
+		}
+		toCancel = append(toCancel, job)
+		clientsConn[job.ClientID] = client.Connection
+	}
+
+	inProgress := al.multiJobsCancel.Cancel(jid)
+	if !inProgress && len(toCancel) == 0 && len(queued) == 0 {
+		al.jsonErrorResponseWithErrCode(w, http.StatusConflict, ErrCodeJobNotRunning, fmt.Sprintf("Multi-client Job[id=%q] is not running.", jid))
+		return
+	}
+
+	var errMsgs []string
+	for _, job := range queued {
+		if _, err := al.cancelServerQueuedJob(job); err != nil {
+			errMsgs = append(errMsgs, fmt.Sprintf("job[id=%q]: %v", job.JID, err))
+		}
+	}
+	for _, job := range toCancel {
+		if err := al.cancelJob(job, clientsConn[job.ClientID]); err != nil {
+			errMsgs = append(errMsgs, fmt.Sprintf("job[id=%q]: %v", job.JID, err))
+		}
+	}
+
+	al.auditLog.Entry(auditlog.ApplicationMultiClientCommand, auditlog.ActionCancel).
+		WithHTTPRequest(req).
+		WithID(jid).
+		Save()
+
+	if len(errMsgs) > 0 {
+		al.jsonErrorResponseWithDetail(w, http.StatusInternalServerError, "", fmt.Sprintf("Failed to cancel some jobs of a multi-client job[id=%q].", jid), strings.Join(errMsgs, "; "))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func (al *APIListener) handleGetMultiClientCommands(w http.ResponseWriter, req *http.Request) {
-	res, err := al.jobProvider.GetAllMultiJobSummaries()
+	opts, err := parseJobListOptions(req.URL.Query(), jobs.MultiJobFilterFields, jobs.MultiJobSortFields, false)
+	if err != nil {
+		al.jsonErrorResponseWithErrCode(w, http.StatusBadRequest, ErrCodeInvalidRequest, err.Error())
+		return
+	}
+
+	access, err := al.getClientAccess(req.Context())
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+	opts.ClientIDs, err = al.allowedClientIDs(access)
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	res, err := al.jobProvider.ListMultiJobSummaries(opts)
 	if err != nil {
 		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", "Failed to get multi-client jobs.", err)
 		return
 	}
 
-	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(res))
+	count, err := al.jobProvider.CountMultiJobSummaries(opts)
+	if err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", "Failed to count multi-client jobs.", err)
+		return
+	}
+
+	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayloadWithMeta(res, api.Meta{Count: count}))
 }
 
 func (al *APIListener) handlePostClientGroups(w http.ResponseWriter, req *http.Request) {
@@ -1415,6 +2520,12 @@ func (al *APIListener) handlePostClientGroups(w http.ResponseWriter, req *http.R
 		return
 	}
 
+	al.auditLog.Entry(auditlog.ApplicationClientGroups, auditlog.ActionCreate).
+		WithHTTPRequest(req).
+		WithID(group.ID).
+		WithParams(group.Params).
+		Save()
+
 	w.WriteHeader(http.StatusCreated)
 	al.Debugf("Client Group [id=%q] created.", group.ID)
 }
@@ -1454,6 +2565,12 @@ func (al *APIListener) handlePutClientGroup(w http.ResponseWriter, req *http.Req
 		return
 	}
 
+	al.auditLog.Entry(auditlog.ApplicationClientGroups, auditlog.ActionUpdate).
+		WithHTTPRequest(req).
+		WithID(group.ID).
+		WithParams(group.Params).
+		Save()
+
 	w.WriteHeader(http.StatusNoContent)
 	al.Debugf("Client Group [id=%q] updated.", group.ID)
 }
@@ -1523,6 +2640,11 @@ func (al *APIListener) handleDeleteClientGroup(w http.ResponseWriter, req *http.
 		return
 	}
 
+	al.auditLog.Entry(auditlog.ApplicationClientGroups, auditlog.ActionDelete).
+		WithHTTPRequest(req).
+		WithID(id).
+		Save()
+
 	w.WriteHeader(http.StatusNoContent)
 	al.Debugf("Client Group [id=%q] deleted.", id)
 }
@@ -0,0 +1,24 @@
+package api
+
+import (
+	"reflect"
+	"strings"
+)
+
+// SelectFields returns a map that contains only given fields of a given struct. Fields are identified by their
+// JSON names, so the result is encoded the same way as the struct but without omitted fields.
+func SelectFields(obj interface{}, fields []string) map[string]interface{} {
+	res := make(map[string]interface{}, len(fields))
+	v := reflect.Indirect(reflect.ValueOf(obj))
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		if isSupportedField(name, fields) {
+			res[name] = v.Field(i).Interface()
+		}
+	}
+	return res
+}
@@ -52,19 +52,10 @@ func (p *SqliteProvider) GetByMultiJobID(jid string) ([]*models.Job, error) {
 	return convertJobs(res), nil
 }
 
-func (p *SqliteProvider) GetSummariesByClientID(clientID string) ([]*models.JobSummary, error) {
-	var res []*jobSummarySqlite
-	err := p.db.Select(&res, "SELECT jid, finished_at, status FROM jobs WHERE client_id=?", clientID)
-	if err != nil {
-		return nil, err
-	}
-	return convertJSs(res), nil
-}
-
 // SaveJob creates a new or updates an existing job.
 func (p *SqliteProvider) SaveJob(job *models.Job) error {
-	_, err := p.db.NamedExec(`INSERT OR REPLACE INTO jobs (jid, status, started_at, finished_at, created_by, client_id, multi_job_id, details)
-														VALUES (:jid, :status, :started_at, :finished_at, :created_by, :client_id, :multi_job_id, :details)`,
+	_, err := p.db.NamedExec(`INSERT OR REPLACE INTO jobs (jid, status, started_at, finished_at, created_by, client_id, multi_job_id, expires_at, command, details)
+														VALUES (:jid, :status, :started_at, :finished_at, :created_by, :client_id, :multi_job_id, :expires_at, :command, :details)`,
 		convertToSqlite(job))
 	if err == nil {
 		p.log.Debugf("Job saved successfully: %v", *job)
@@ -74,8 +65,8 @@ func (p *SqliteProvider) SaveJob(job *models.Job) error {
 
 // CreateJob creates a new job. If already exists with the same ID - does nothing and returns nil.
 func (p *SqliteProvider) CreateJob(job *models.Job) error {
-	_, err := p.db.NamedExec(`INSERT INTO jobs (jid, status, started_at, finished_at, created_by, client_id, multi_job_id, details)
-											VALUES (:jid, :status, :started_at, :finished_at, :created_by, :client_id, :multi_job_id, :details)`,
+	_, err := p.db.NamedExec(`INSERT INTO jobs (jid, status, started_at, finished_at, created_by, client_id, multi_job_id, expires_at, command, details)
+											VALUES (:jid, :status, :started_at, :finished_at, :created_by, :client_id, :multi_job_id, :expires_at, :command, :details)`,
 		convertToSqlite(job))
 	if err != nil {
 		// check if it's "already exist" err
@@ -90,6 +81,106 @@ func (p *SqliteProvider) CreateJob(job *models.Job) error {
 	return err
 }
 
+// GetQueuedByClientID returns jobs that wait on the server for a client with a given ID to connect sorted by
+// started_at, jid order.
+func (p *SqliteProvider) GetQueuedByClientID(clientID string) ([]*models.Job, error) {
+	var res []*jobSqlite
+	err := p.db.Select(&res, "SELECT * FROM jobs WHERE client_id=? AND status=? AND expires_at IS NOT NULL ORDER BY DATETIME(started_at), jid", clientID, models.JobStatusQueued)
+	if err != nil {
+		return nil, err
+	}
+	return convertJobs(res), nil
+}
+
+// ClaimQueuedJob marks a job that waits on the server for a client as being sent to the client. Returns false if the
+// job is not waiting anymore, e.g. it's already claimed by another connection of the client or expired.
+func (p *SqliteProvider) ClaimQueuedJob(jid string) (bool, error) {
+	res, err := p.db.Exec(
+		"UPDATE jobs SET status=? WHERE jid=? AND status=? AND expires_at IS NOT NULL",
+		models.JobStatusSending, jid, models.JobStatusQueued,
+	)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// CancelQueuedJob marks a job that waits on the server for a client as cancelled. Returns false if the job is not
+// waiting anymore, e.g. it's already claimed to be sent to the client.
+func (p *SqliteProvider) CancelQueuedJob(jid string, finishedAt time.Time) (bool, error) {
+	res, err := p.db.Exec(
+		"UPDATE jobs SET status=?, finished_at=? WHERE jid=? AND status=? AND expires_at IS NOT NULL",
+		models.JobStatusCancelled, finishedAt, jid, models.JobStatusQueued,
+	)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// UpdateQueuedJob updates a job claimed by ClaimQueuedJob with a result of sending it to the client. If the job is not
+// being sent anymore, e.g. it's already updated by the client - does nothing and returns nil.
+func (p *SqliteProvider) UpdateQueuedJob(job *models.Job) error {
+	res := convertToSqlite(job)
+	_, err := p.db.Exec(
+		"UPDATE jobs SET status=?, started_at=?, finished_at=?, expires_at=?, details=? WHERE jid=? AND status=? AND expires_at IS NOT NULL",
+		res.Status, res.StartedAt, res.FinishedAt, res.ExpiresAt, res.Details, res.JID, models.JobStatusSending,
+	)
+	return err
+}
+
+// ExpireQueuedJobs marks jobs that wait on the server for a client longer than their expiry time as expired.
+// Returns a number of expired jobs.
+func (p *SqliteProvider) ExpireQueuedJobs(now time.Time) (int64, error) {
+	res, err := p.db.Exec(
+		"UPDATE jobs SET status=?, finished_at=? WHERE status=? AND expires_at IS NOT NULL AND DATETIME(expires_at) <= DATETIME(?)",
+		models.JobStatusExpired, now, models.JobStatusQueued, now,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// AppendOutput appends a chunk of output to a running job. If the job doesn't exist or is already finished - does nothing and returns nil.
+func (p *SqliteProvider) AppendOutput(output *models.JobOutput) error {
+	tx, err := p.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res := &jobSqlite{}
+	err = tx.Get(res, "SELECT * FROM jobs WHERE jid=? AND status=?", output.JID, models.JobStatusRunning)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			p.log.Debugf("Running job not found to append output: %s", output.JID)
+			return nil
+		}
+		return err
+	}
+
+	if res.Details.Result == nil {
+		res.Details.Result = &models.JobResult{}
+	}
+	res.Details.Result.StdOut += output.StdOut
+	res.Details.Result.StdErr += output.StdErr
+
+	_, err = tx.Exec("UPDATE jobs SET details=? WHERE jid=?", res.Details, output.JID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
 func (p *SqliteProvider) Close() error {
 	return p.db.Close()
 }
@@ -100,6 +191,8 @@ type jobSqlite struct {
 	CreatedBy  string         `db:"created_by"`
 	ClientID   string         `db:"client_id"`
 	MultiJobID sql.NullString `db:"multi_job_id"`
+	ExpiresAt  sql.NullTime   `db:"expires_at"`
+	Command    string         `db:"command"` // a copy of the command from details to filter by
 	Details    *jobDetails    `db:"details"`
 }
 
@@ -110,12 +203,24 @@ type jobSummarySqlite struct {
 }
 
 type jobDetails struct {
-	Command    string            `json:"command"`
-	Shell      string            `json:"shell"`
-	PID        *int              `json:"pid"`
-	TimeoutSec int               `json:"timeout_sec"`
-	Error      string            `json:"error"`
-	Result     *models.JobResult `json:"result"`
+	Command          string            `json:"command"`
+	Shell            string            `json:"shell"`
+	Interpreter      string            `json:"interpreter"`
+	Script           string            `json:"script"`
+	ScriptHash       string            `json:"script_hash"`
+	ScriptID         string            `json:"script_id"`
+	ScriptVersion    int               `json:"script_version"`
+	Cwd              string            `json:"cwd"`
+	Env              map[string]string `json:"env"`
+	RunAsUser        string            `json:"run_as_user"`
+	PID              *int              `json:"pid"`
+	TimeoutSec       int               `json:"timeout_sec"`
+	TimeoutPolicy    string            `json:"timeout_policy"`
+	TimeoutGraceSec  int               `json:"timeout_grace_sec"`
+	SuccessExitCodes []int             `json:"success_exit_codes"`
+	Error            string            `json:"error"`
+	ExitCode         *int              `json:"exit_code"`
+	Result           *models.JobResult `json:"result"`
 }
 
 func (d *jobDetails) Scan(value interface{}) error {
@@ -166,20 +271,35 @@ func convertJSs(list []*jobSummarySqlite) []*models.JobSummary {
 func (j *jobSqlite) convert() *models.Job {
 	js := j.jobSummarySqlite.convert()
 	res := &models.Job{
-		JobSummary: *js,
-		ClientID:   j.ClientID,
-		StartedAt:  j.StartedAt,
-		CreatedBy:  j.CreatedBy,
-		Command:    j.Details.Command,
-		Shell:      j.Details.Shell,
-		PID:        j.Details.PID,
-		TimeoutSec: j.Details.TimeoutSec,
-		Result:     j.Details.Result,
-		Error:      j.Details.Error,
+		JobSummary:       *js,
+		ClientID:         j.ClientID,
+		StartedAt:        j.StartedAt,
+		CreatedBy:        j.CreatedBy,
+		Command:          j.Details.Command,
+		Shell:            j.Details.Shell,
+		Interpreter:      j.Details.Interpreter,
+		Script:           j.Details.Script,
+		ScriptHash:       j.Details.ScriptHash,
+		ScriptID:         j.Details.ScriptID,
+		ScriptVersion:    j.Details.ScriptVersion,
+		Cwd:              j.Details.Cwd,
+		Env:              j.Details.Env,
+		RunAsUser:        j.Details.RunAsUser,
+		PID:              j.Details.PID,
+		TimeoutSec:       j.Details.TimeoutSec,
+		TimeoutPolicy:    j.Details.TimeoutPolicy,
+		TimeoutGraceSec:  j.Details.TimeoutGraceSec,
+		SuccessExitCodes: j.Details.SuccessExitCodes,
+		ExitCode:         j.Details.ExitCode,
+		Result:           j.Details.Result,
+		Error:            j.Details.Error,
 	}
 	if j.MultiJobID.Valid {
 		res.MultiJobID = &j.MultiJobID.String
 	}
+	if j.ExpiresAt.Valid {
+		res.ExpiresAt = &j.ExpiresAt.Time
+	}
 	return res
 }
 
@@ -200,13 +320,26 @@ func convertToSqlite(job *models.Job) *jobSqlite {
 		StartedAt: job.StartedAt,
 		CreatedBy: job.CreatedBy,
 		ClientID:  job.ClientID,
+		Command:   job.Command,
 		Details: &jobDetails{
-			Command:    job.Command,
-			Shell:      job.Shell,
-			PID:        job.PID,
-			TimeoutSec: job.TimeoutSec,
-			Result:     job.Result,
-			Error:      job.Error,
+			Command:          job.Command,
+			Shell:            job.Shell,
+			Interpreter:      job.Interpreter,
+			Script:           job.Script,
+			ScriptHash:       job.ScriptHash,
+			ScriptID:         job.ScriptID,
+			ScriptVersion:    job.ScriptVersion,
+			Cwd:              job.Cwd,
+			Env:              job.Env,
+			RunAsUser:        job.RunAsUser,
+			PID:              job.PID,
+			TimeoutSec:       job.TimeoutSec,
+			TimeoutPolicy:    job.TimeoutPolicy,
+			TimeoutGraceSec:  job.TimeoutGraceSec,
+			SuccessExitCodes: job.SuccessExitCodes,
+			ExitCode:         job.ExitCode,
+			Result:           job.Result,
+			Error:            job.Error,
 		},
 	}
 	if job.MultiJobID != nil {
@@ -215,5 +348,8 @@ func convertToSqlite(job *models.Job) *jobSqlite {
 	if job.FinishedAt != nil {
 		res.jobSummarySqlite.FinishedAt = sql.NullTime{Time: *job.FinishedAt, Valid: true}
 	}
+	if job.ExpiresAt != nil {
+		res.ExpiresAt = sql.NullTime{Time: *job.ExpiresAt, Valid: true}
+	}
 	return res
 }
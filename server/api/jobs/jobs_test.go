@@ -51,16 +51,16 @@ func TestJobsSqliteProvider(t *testing.T) {
 	require.Nil(t, gotJob4)
 
 	// verify job summaries
-	gotJSc1, err := p.GetSummariesByClientID(job1.ClientID)
+	gotJSc1, err := p.ListSummaries(job1.ClientID, &ListOptions{})
 	require.NoError(t, err)
 	assert.ElementsMatch(t, []*models.JobSummary{&job1.JobSummary, &job2.JobSummary}, gotJSc1)
 
-	gotJSc2, err := p.GetSummariesByClientID(job3.ClientID)
+	gotJSc2, err := p.ListSummaries(job3.ClientID, &ListOptions{})
 	require.NoError(t, err)
 	assert.ElementsMatch(t, []*models.JobSummary{&job3.JobSummary}, gotJSc2)
 
 	// verify job summaries not found
-	gotJSc3, err := p.GetSummariesByClientID("unknown-cid")
+	gotJSc3, err := p.ListSummaries("unknown-cid", &ListOptions{})
 	require.NoError(t, err)
 	require.Empty(t, gotJSc3)
 
@@ -79,7 +79,7 @@ func TestJobsSqliteProvider(t *testing.T) {
 	require.NotNil(t, gotJob1)
 	assert.Equal(t, job1, gotJob1)
 
-	gotJSc1, err = p.GetSummariesByClientID(job1.ClientID)
+	gotJSc1, err = p.ListSummaries(job1.ClientID, &ListOptions{})
 	require.NoError(t, err)
 	assert.ElementsMatch(t, []*models.JobSummary{&job1.JobSummary, &job2.JobSummary}, gotJSc1)
 }
@@ -129,3 +129,141 @@ func TestCreateJob(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, job, gotJob)
 }
+
+func TestCancelQueuedJob(t *testing.T) {
+	p, err := NewSqliteProvider(":memory:", testLog)
+	require.NoError(t, err)
+	defer p.Close()
+
+	now := time.Date(2020, 10, 10, 12, 0, 0, 0, time.UTC)
+	cid := "client-1"
+	queuedJob := jb.New(t).ClientID(cid).Status(models.JobStatusQueued).Result(nil).ExpiresAt(now.Add(time.Hour)).Build()
+	clientQueuedJob := jb.New(t).ClientID(cid).Status(models.JobStatusQueued).Result(nil).Build() // queued on the client
+	require.NoError(t, p.CreateJob(queuedJob))
+	require.NoError(t, p.CreateJob(clientQueuedJob))
+
+	cancelled, err := p.CancelQueuedJob(queuedJob.JID, now)
+	require.NoError(t, err)
+	assert.True(t, cancelled)
+	cancelled, err = p.CancelQueuedJob(clientQueuedJob.JID, now)
+	require.NoError(t, err)
+	assert.False(t, cancelled)
+
+	gotJob, err := p.GetByJID(cid, queuedJob.JID)
+	require.NoError(t, err)
+	assert.Equal(t, models.JobStatusCancelled, gotJob.Status)
+	require.NotNil(t, gotJob.FinishedAt)
+	assert.True(t, now.Equal(*gotJob.FinishedAt))
+
+	// a cancelled job is not sent to the client anymore
+	gotJobs, err := p.GetQueuedByClientID(cid)
+	require.NoError(t, err)
+	assert.Empty(t, gotJobs)
+	claimed, err := p.ClaimQueuedJob(queuedJob.JID)
+	require.NoError(t, err)
+	assert.False(t, claimed)
+}
+
+func TestQueuedJobs(t *testing.T) {
+	p, err := NewSqliteProvider(":memory:", testLog)
+	require.NoError(t, err)
+	defer p.Close()
+
+	now := time.Date(2020, 10, 10, 12, 0, 0, 0, time.UTC)
+	cid := "client-1"
+	job1 := jb.New(t).ClientID(cid).Status(models.JobStatusQueued).Result(nil).StartedAt(now.Add(-2 * time.Hour)).ExpiresAt(now.Add(-time.Hour)).Build()
+	job2 := jb.New(t).ClientID(cid).Status(models.JobStatusQueued).Result(nil).StartedAt(now.Add(-time.Hour)).ExpiresAt(now.Add(time.Hour)).Build()
+	job3 := jb.New(t).ClientID(cid).Status(models.JobStatusQueued).Result(nil).Build()                 // queued on the client
+	job4 := jb.New(t).Status(models.JobStatusQueued).Result(nil).ExpiresAt(now.Add(time.Hour)).Build() // different client ID
+	for _, job := range []*models.Job{job1, job2, job3, job4} {
+		require.NoError(t, p.CreateJob(job))
+	}
+
+	gotJobs, err := p.GetQueuedByClientID(cid)
+	require.NoError(t, err)
+	assert.Equal(t, []*models.Job{job1, job2}, gotJobs)
+
+	// a job updated by the client is not overridden
+	finishedJob2 := *job2
+	finishedJob2.Status = models.JobStatusSuccessful
+	finishedJob2.ExpiresAt = nil
+	require.NoError(t, p.SaveJob(&finishedJob2))
+	runningJob2 := *job2
+	runningJob2.Status = models.JobStatusRunning
+	runningJob2.ExpiresAt = nil
+	require.NoError(t, p.UpdateQueuedJob(&runningJob2))
+
+	gotJob2, err := p.GetByJID(cid, job2.JID)
+	require.NoError(t, err)
+	assert.Equal(t, &finishedJob2, gotJob2)
+
+	// a finished job can't be claimed
+	claimed, err := p.ClaimQueuedJob(job2.JID)
+	require.NoError(t, err)
+	assert.False(t, claimed)
+
+	// update a queued job
+	require.NoError(t, p.SaveJob(job2))
+	claimed, err = p.ClaimQueuedJob(job2.JID)
+	require.NoError(t, err)
+	assert.True(t, claimed)
+	claimed, err = p.ClaimQueuedJob(job2.JID)
+	require.NoError(t, err)
+	assert.False(t, claimed, "a job can be claimed only once")
+
+	gotJobs, err = p.GetQueuedByClientID(cid)
+	require.NoError(t, err)
+	assert.Equal(t, []*models.Job{job1}, gotJobs)
+
+	require.NoError(t, p.UpdateQueuedJob(&runningJob2))
+
+	gotJob2, err = p.GetByJID(cid, job2.JID)
+	require.NoError(t, err)
+	assert.Equal(t, &runningJob2, gotJob2)
+
+	gotJobs, err = p.GetQueuedByClientID(cid)
+	require.NoError(t, err)
+	assert.Equal(t, []*models.Job{job1}, gotJobs)
+
+	// expire
+	gotCount, err := p.ExpireQueuedJobs(now)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, gotCount)
+
+	gotJobs, err = p.GetQueuedByClientID(cid)
+	require.NoError(t, err)
+	assert.Empty(t, gotJobs)
+
+	gotJob1, err := p.GetByJID(cid, job1.JID)
+	require.NoError(t, err)
+	assert.Equal(t, models.JobStatusExpired, gotJob1.Status)
+	require.NotNil(t, gotJob1.FinishedAt)
+	assert.True(t, now.Equal(*gotJob1.FinishedAt))
+}
+
+func TestAppendOutput(t *testing.T) {
+	p, err := NewSqliteProvider(":memory:", testLog)
+	require.NoError(t, err)
+	defer p.Close()
+
+	runningJob := jb.New(t).Status(models.JobStatusRunning).Result(nil).Build()
+	finishedJob := jb.New(t).Status(models.JobStatusSuccessful).Build()
+	require.NoError(t, p.SaveJob(runningJob))
+	require.NoError(t, p.SaveJob(finishedJob))
+
+	// append output to the running job
+	require.NoError(t, p.AppendOutput(&models.JobOutput{JID: runningJob.JID, StdOut: "out1 ", StdErr: "err1 "}))
+	require.NoError(t, p.AppendOutput(&models.JobOutput{JID: runningJob.JID, StdOut: "out2"}))
+	gotJob, err := p.GetByJID(runningJob.ClientID, runningJob.JID)
+	require.NoError(t, err)
+	runningJob.Result = &models.JobResult{StdOut: "out1 out2", StdErr: "err1 "}
+	assert.Equal(t, runningJob, gotJob)
+
+	// verify finished and unknown jobs are not changed
+	require.NoError(t, p.AppendOutput(&models.JobOutput{JID: finishedJob.JID, StdOut: "out"}))
+	gotJob, err = p.GetByJID(finishedJob.ClientID, finishedJob.JID)
+	require.NoError(t, err)
+	assert.Equal(t, finishedJob, gotJob)
+
+	require.NoError(t, p.AppendOutput(&models.JobOutput{JID: "unknown-jid", StdOut: "out"}))
+}
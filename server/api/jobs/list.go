@@ -0,0 +1,199 @@
+package jobs
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cloudradar-monitoring/rport/server/api"
+	"github.com/cloudradar-monitoring/rport/share/models"
+)
+
+// JobFilterFields are fields jobs of a client can be filtered by.
+var JobFilterFields = []string{"status", "created_by"}
+
+// MultiJobFilterFields are fields multi-client jobs can be filtered by.
+var MultiJobFilterFields = []string{"created_by", "schedule_id"}
+
+// JobSortFields are fields jobs of a client can be sorted by.
+var JobSortFields = []string{"finished_at", "started_at", "status", "created_by"}
+
+// MultiJobSortFields are fields multi-client jobs can be sorted by.
+var MultiJobSortFields = []string{"started_at", "created_by"}
+
+// ListOptions defines what jobs to return.
+type ListOptions struct {
+	// Filters contains accepted values by field, see JobFilterFields and MultiJobFilterFields.
+	Filters map[string][]string
+	// Command is a substring the command should contain.
+	Command       string
+	StartedSince  *time.Time
+	StartedUntil  *time.Time
+	FinishedSince *time.Time // not applicable to multi-client jobs
+	FinishedUntil *time.Time // not applicable to multi-client jobs
+	// ClientIDs restricts multi-client jobs to the ones with jobs of given clients. nil means no restriction.
+	// Not applicable to jobs of a client.
+	ClientIDs []string
+	// Sort is a field to sort by, see JobSortFields and MultiJobSortFields. Empty means the default order.
+	Sort       string
+	SortDesc   bool
+	Pagination *api.Pagination
+}
+
+// ListSummaries returns summaries of jobs of a client with a given ID that match given options. By default jobs are
+// sorted by finished_at(desc) with not finished jobs first.
+func (p *SqliteProvider) ListSummaries(clientID string, opts *ListOptions) ([]*models.JobSummary, error) {
+	where, params := buildWhere(opts, JobFilterFields)
+	sortField := opts.Sort
+	desc := opts.SortDesc
+	if sortField == "" {
+		sortField, desc = "finished_at", true
+	}
+	orderBy, err := buildOrderBy(sortField, desc, JobSortFields)
+	if err != nil {
+		return nil, err
+	}
+	query := "SELECT jid, finished_at, status FROM jobs WHERE client_id=?" + where + orderBy
+	query, params = withPagination(query, append([]interface{}{clientID}, params...), opts.Pagination)
+
+	var res []*jobSummarySqlite
+	err = p.db.Select(&res, query, params...)
+	if err != nil {
+		return nil, err
+	}
+	return convertJSs(res), nil
+}
+
+// CountSummaries returns a number of jobs of a client with a given ID that match given options.
+func (p *SqliteProvider) CountSummaries(clientID string, opts *ListOptions) (int, error) {
+	where, params := buildWhere(opts, JobFilterFields)
+	var res int
+	err := p.db.Get(&res, "SELECT COUNT(*) FROM jobs WHERE client_id=?"+where, append([]interface{}{clientID}, params...)...)
+	return res, err
+}
+
+// ListMultiJobSummaries returns summaries of multi-client jobs that match given options. By default jobs are sorted
+// by started_at(desc), jid order.
+func (p *SqliteProvider) ListMultiJobSummaries(opts *ListOptions) ([]*models.MultiJobSummary, error) {
+	where, params := buildMultiJobWhere(opts)
+	sortField := opts.Sort
+	desc := opts.SortDesc
+	if sortField == "" {
+		sortField, desc = "started_at", true
+	}
+	orderBy, err := buildOrderBy(sortField, desc, MultiJobSortFields)
+	if err != nil {
+		return nil, err
+	}
+	query := "SELECT jid, started_at, created_by, schedule_id FROM multi_jobs WHERE 1=1" + where + orderBy
+	query, params = withPagination(query, params, opts.Pagination)
+
+	var res []*multiJobSummarySqlite
+	err = p.db.Select(&res, query, params...)
+	if err != nil {
+		return nil, err
+	}
+	return convertMultiJSs(res), nil
+}
+
+// CountMultiJobSummaries returns a number of multi-client jobs that match given options.
+func (p *SqliteProvider) CountMultiJobSummaries(opts *ListOptions) (int, error) {
+	where, params := buildMultiJobWhere(opts)
+	var res int
+	err := p.db.Get(&res, "SELECT COUNT(*) FROM multi_jobs WHERE 1=1"+where, params...)
+	return res, err
+}
+
+// buildWhere returns conditions to append to a WHERE clause and their params.
+func buildWhere(opts *ListOptions, filterFields []string) (string, []interface{}) {
+	var conditions []string
+	var params []interface{}
+	// iterate over supported fields to have a deterministic query and to use only known column names
+	for _, field := range filterFields {
+		values := opts.Filters[field]
+		if len(values) == 0 {
+			continue
+		}
+		conditions = append(conditions, fmt.Sprintf("%s IN (?%s)", field, strings.Repeat(", ?", len(values)-1)))
+		for _, v := range values {
+			params = append(params, v)
+		}
+	}
+	if opts.Command != "" {
+		conditions = append(conditions, "instr(command, ?) > 0")
+		params = append(params, opts.Command)
+	}
+	// times can be stored with different time zones, so compare them converted to UTC
+	addTimeCondition := func(condition string, t *time.Time) {
+		if t != nil {
+			conditions = append(conditions, condition)
+			params = append(params, *t)
+		}
+	}
+	addTimeCondition("DATETIME(started_at) >= DATETIME(?)", opts.StartedSince)
+	addTimeCondition("DATETIME(started_at) <= DATETIME(?)", opts.StartedUntil)
+	addTimeCondition("DATETIME(finished_at) >= DATETIME(?)", opts.FinishedSince)
+	addTimeCondition("DATETIME(finished_at) <= DATETIME(?)", opts.FinishedUntil)
+
+	if len(conditions) == 0 {
+		return "", params
+	}
+	return " AND " + strings.Join(conditions, " AND "), params
+}
+
+// buildMultiJobWhere returns conditions to append to a WHERE clause of multi-client jobs and their params.
+func buildMultiJobWhere(opts *ListOptions) (string, []interface{}) {
+	where, params := buildWhere(opts, MultiJobFilterFields)
+	if opts.ClientIDs == nil {
+		return where, params
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(opts.ClientIDs)), ", ")
+	where += fmt.Sprintf(" AND jid IN (SELECT multi_job_id FROM jobs WHERE client_id IN (%s))", placeholders)
+	for _, id := range opts.ClientIDs {
+		params = append(params, id)
+	}
+	return where, params
+}
+
+// ValidateSortField returns an error if jobs can't be sorted by a given field, see JobSortFields and MultiJobSortFields.
+func ValidateSortField(field string, sortFields []string) error {
+	for _, cur := range sortFields {
+		if cur == field {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported sort field %q, expected one of: %s", field, strings.Join(sortFields, ", "))
+}
+
+// buildOrderBy returns an ORDER BY clause to sort by a given field which should be one of sort fields. Jobs that are
+// not finished yet are treated as the latest ones.
+func buildOrderBy(field string, desc bool, sortFields []string) (string, error) {
+	if err := ValidateSortField(field, sortFields); err != nil {
+		return "", err
+	}
+
+	dir := ""
+	if desc {
+		dir = " DESC"
+	}
+	switch field {
+	case "finished_at":
+		return fmt.Sprintf(" ORDER BY finished_at IS NULL%s, DATETIME(finished_at)%s, jid", dir, dir), nil
+	case "started_at":
+		return fmt.Sprintf(" ORDER BY DATETIME(started_at)%s, jid", dir), nil
+	default:
+		return fmt.Sprintf(" ORDER BY %s%s, DATETIME(started_at) DESC, jid", field, dir), nil
+	}
+}
+
+func withPagination(query string, params []interface{}, pagination *api.Pagination) (string, []interface{}) {
+	if pagination == nil || pagination.Limit == 0 && pagination.Offset == 0 {
+		return query, params
+	}
+	// negative limit means no limit
+	limit := -1
+	if pagination.Limit > 0 {
+		limit = pagination.Limit
+	}
+	return query + " LIMIT ? OFFSET ?", append(params, limit, pagination.Offset)
+}
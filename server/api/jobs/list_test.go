@@ -0,0 +1,187 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudradar-monitoring/rport/server/api"
+	"github.com/cloudradar-monitoring/rport/server/test/jb"
+	"github.com/cloudradar-monitoring/rport/share/models"
+)
+
+func TestListSummaries(t *testing.T) {
+	p, err := NewSqliteProvider(":memory:", testLog)
+	require.NoError(t, err)
+	defer p.Close()
+
+	t1 := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+	cid := "client-1"
+	job1 := jb.New(t).JID("1").ClientID(cid).Status(models.JobStatusSuccessful).StartedAt(t1).FinishedAt(t1.Add(time.Minute)).Build()
+	job2 := jb.New(t).JID("2").ClientID(cid).Status(models.JobStatusFailed).StartedAt(t1.Add(time.Hour)).FinishedAt(t1.Add(2 * time.Hour)).Build()
+	job2.Command = "/usr/bin/apt-get upgrade"
+	job3 := jb.New(t).JID("3").ClientID(cid).Status(models.JobStatusRunning).StartedAt(t1.Add(3 * time.Hour).In(time.FixedZone("CET", 3600))).Build()
+	job3.CreatedBy = "admin"
+	otherClientJob := jb.New(t).JID("4").ClientID("client-2").Status(models.JobStatusFailed).StartedAt(t1).Build()
+	for _, job := range []*models.Job{job1, job2, job3, otherClientJob} {
+		require.NoError(t, p.SaveJob(job))
+	}
+	since := t1.Add(30 * time.Minute)
+
+	testCases := []struct {
+		name      string
+		opts      *ListOptions
+		wantJIDs  []string
+		wantCount int
+	}{
+		{
+			name:      "default order",
+			opts:      &ListOptions{},
+			wantJIDs:  []string{"3", "2", "1"},
+			wantCount: 3,
+		},
+		{
+			name:      "by status",
+			opts:      &ListOptions{Filters: map[string][]string{"status": {models.JobStatusFailed, models.JobStatusRunning}}},
+			wantJIDs:  []string{"3", "2"},
+			wantCount: 2,
+		},
+		{
+			name:      "by created_by",
+			opts:      &ListOptions{Filters: map[string][]string{"created_by": {"admin"}}},
+			wantJIDs:  []string{"3"},
+			wantCount: 1,
+		},
+		{
+			name:      "by command",
+			opts:      &ListOptions{Command: "apt-get"},
+			wantJIDs:  []string{"2"},
+			wantCount: 1,
+		},
+		{
+			name:      "by started_at",
+			opts:      &ListOptions{StartedSince: &since, Sort: "started_at"},
+			wantJIDs:  []string{"2", "3"},
+			wantCount: 2,
+		},
+		{
+			name:      "by finished_at",
+			opts:      &ListOptions{FinishedUntil: &since},
+			wantJIDs:  []string{"1"},
+			wantCount: 1,
+		},
+		{
+			name:      "sort by status with page",
+			opts:      &ListOptions{Sort: "status", Pagination: &api.Pagination{Limit: 1, Offset: 1}},
+			wantJIDs:  []string{"3"},
+			wantCount: 3,
+		},
+		{
+			name:      "offset without limit",
+			opts:      &ListOptions{Pagination: &api.Pagination{Offset: 2}},
+			wantJIDs:  []string{"1"},
+			wantCount: 3,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := p.ListSummaries(cid, tc.opts)
+			require.NoError(t, err)
+			var gotJIDs []string
+			for _, cur := range got {
+				gotJIDs = append(gotJIDs, cur.JID)
+			}
+			assert.Equal(t, tc.wantJIDs, gotJIDs)
+
+			gotCount, err := p.CountSummaries(cid, tc.opts)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantCount, gotCount)
+		})
+	}
+
+	_, err = p.ListSummaries(cid, &ListOptions{Sort: "details"})
+	assert.EqualError(t, err, `unsupported sort field "details", expected one of: finished_at, started_at, status, created_by`)
+}
+
+func TestListMultiJobSummaries(t *testing.T) {
+	p, err := NewSqliteProvider(":memory:", testLog)
+	require.NoError(t, err)
+	defer p.Close()
+
+	t1 := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+	job1 := jb.NewMulti(t).JID("1").StartedAt(t1).Build()
+	job2 := jb.NewMulti(t).JID("2").StartedAt(t1.Add(time.Hour)).ScheduleID("schedule-1").Build()
+	job2.Command = "/usr/bin/apt-get upgrade"
+	job3 := jb.NewMulti(t).JID("3").StartedAt(t1.Add(2 * time.Hour)).Build()
+	job3.CreatedBy = "admin"
+	for _, job := range []*models.MultiJob{job1, job2, job3} {
+		require.NoError(t, p.SaveMultiJob(job))
+	}
+	require.NoError(t, p.CreateJob(jb.New(t).ClientID("client-1").MultiJobID("1").Build()))
+	require.NoError(t, p.CreateJob(jb.New(t).ClientID("client-2").MultiJobID("2").Build()))
+	require.NoError(t, p.CreateJob(jb.New(t).ClientID("client-2").MultiJobID("3").Build()))
+	until := t1.Add(time.Hour)
+
+	testCases := []struct {
+		name      string
+		opts      *ListOptions
+		wantJIDs  []string
+		wantCount int
+	}{
+		{
+			name:      "default order",
+			opts:      &ListOptions{},
+			wantJIDs:  []string{"3", "2", "1"},
+			wantCount: 3,
+		},
+		{
+			name:      "by schedule and command",
+			opts:      &ListOptions{Filters: map[string][]string{"schedule_id": {"schedule-1"}}, Command: "upgrade"},
+			wantJIDs:  []string{"2"},
+			wantCount: 1,
+		},
+		{
+			name:      "by started_at sorted by created_by",
+			opts:      &ListOptions{StartedUntil: &until, Sort: "created_by"},
+			wantJIDs:  []string{"2", "1"},
+			wantCount: 2,
+		},
+		{
+			name:      "by clients",
+			opts:      &ListOptions{ClientIDs: []string{"client-1", "client-3"}},
+			wantJIDs:  []string{"1"},
+			wantCount: 1,
+		},
+		{
+			name:      "no clients",
+			opts:      &ListOptions{ClientIDs: []string{}},
+			wantJIDs:  nil,
+			wantCount: 0,
+		},
+		{
+			name:      "page",
+			opts:      &ListOptions{Sort: "started_at", Pagination: &api.Pagination{Limit: 2}},
+			wantJIDs:  []string{"1", "2"},
+			wantCount: 3,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := p.ListMultiJobSummaries(tc.opts)
+			require.NoError(t, err)
+			var gotJIDs []string
+			for _, cur := range got {
+				gotJIDs = append(gotJIDs, cur.JID)
+			}
+			assert.Equal(t, tc.wantJIDs, gotJIDs)
+
+			gotCount, err := p.CountMultiJobSummaries(tc.opts)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantCount, gotCount)
+		})
+	}
+}
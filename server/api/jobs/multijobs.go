@@ -33,10 +33,10 @@ func (p *SqliteProvider) GetMultiJob(jid string) (*models.MultiJob, error) {
 	return multiJob, nil
 }
 
-// GetAllMultiJobSummaries returns a list of summaries of all multi-clients jobs sorted by started_at(desc), jid order.
-func (p *SqliteProvider) GetAllMultiJobSummaries() ([]*models.MultiJobSummary, error) {
+// GetMultiJobSummariesByScheduleID returns a list of summaries of multi-clients jobs started by a given schedule sorted by started_at(desc), jid order.
+func (p *SqliteProvider) GetMultiJobSummariesByScheduleID(scheduleID string) ([]*models.MultiJobSummary, error) {
 	var res []*multiJobSummarySqlite
-	err := p.db.Select(&res, "SELECT jid, started_at, created_by FROM multi_jobs ORDER BY DATETIME(started_at) DESC, jid")
+	err := p.db.Select(&res, "SELECT jid, started_at, created_by, schedule_id FROM multi_jobs WHERE schedule_id=? ORDER BY DATETIME(started_at) DESC, jid", scheduleID)
 	if err != nil {
 		return nil, err
 	}
@@ -45,8 +45,8 @@ func (p *SqliteProvider) GetAllMultiJobSummaries() ([]*models.MultiJobSummary, e
 
 // SaveMultiJob creates a new or updates an existing multi-client job (without child jobs).
 func (p *SqliteProvider) SaveMultiJob(job *models.MultiJob) error {
-	_, err := p.db.NamedExec(`INSERT OR REPLACE INTO multi_jobs (jid, started_at, created_by, details)
-															  VALUES (:jid, :started_at, :created_by, :details)`,
+	_, err := p.db.NamedExec(`INSERT OR REPLACE INTO multi_jobs (jid, started_at, created_by, schedule_id, command, details)
+															  VALUES (:jid, :started_at, :created_by, :schedule_id, :command, :details)`,
 		convertMultiJobToSqlite(job))
 	if err == nil {
 		p.log.Debugf("Multi-client Job saved successfully: %v", *job)
@@ -56,23 +56,44 @@ func (p *SqliteProvider) SaveMultiJob(job *models.MultiJob) error {
 
 type multiJobSqlite struct {
 	multiJobSummarySqlite
+	Command string                `db:"command"` // a copy of the command from details to filter by
 	Details *multiJobDetailSqlite `db:"details"`
 }
 
 type multiJobSummarySqlite struct {
-	JID       string    `db:"jid"`
-	StartedAt time.Time `db:"started_at"`
-	CreatedBy string    `db:"created_by"`
+	JID        string         `db:"jid"`
+	StartedAt  time.Time      `db:"started_at"`
+	CreatedBy  string         `db:"created_by"`
+	ScheduleID sql.NullString `db:"schedule_id"`
 }
 
 type multiJobDetailSqlite struct {
-	ClientIDs  []string `json:"client_ids"`
-	GroupIDs   []string `json:"group_ids"`
-	Command    string   `json:"command"`
-	Shell      string   `json:"shell"`
-	TimeoutSec int      `json:"timeout_sec"`
-	Concurrent bool     `json:"concurrent"`
-	AbortOnErr bool     `json:"abort_on_err"`
+	ClientIDs         []string          `json:"client_ids"`
+	GroupIDs          []string          `json:"group_ids"`
+	Command           string            `json:"command"`
+	Shell             string            `json:"shell"`
+	Interpreter       string            `json:"interpreter"`
+	Script            string            `json:"script"`
+	ScriptHash        string            `json:"script_hash"`
+	ScriptID          string            `json:"script_id"`
+	ScriptVersion     int               `json:"script_version"`
+	Cwd               string            `json:"cwd"`
+	Env               map[string]string `json:"env"`
+	RunAsUser         string            `json:"run_as_user"`
+	TimeoutSec        int               `json:"timeout_sec"`
+	TimeoutPolicy     string            `json:"timeout_policy"`
+	TimeoutGraceSec   int               `json:"timeout_grace_sec"`
+	SuccessExitCodes  []int             `json:"success_exit_codes"`
+	Concurrent        bool              `json:"concurrent"`
+	AbortOnErr        bool              `json:"abort_on_err"`
+	BatchSize         int               `json:"batch_size"`
+	BatchPercent      int               `json:"batch_percent"`
+	BatchByGroup      bool              `json:"batch_by_group"`
+	BatchPauseSec     int               `json:"batch_pause_sec"`
+	MaxFailurePercent *int              `json:"max_failure_percent"`
+	Batches           int               `json:"batches"`
+	CurrentBatch      int               `json:"current_batch"`
+	Error             string            `json:"error"`
 }
 
 func (d *multiJobDetailSqlite) Scan(value interface{}) error {
@@ -102,11 +123,15 @@ func (d *multiJobDetailSqlite) Value() (driver.Value, error) {
 }
 
 func (js *multiJobSummarySqlite) convert() *models.MultiJobSummary {
-	return &models.MultiJobSummary{
+	res := &models.MultiJobSummary{
 		JID:       js.JID,
 		StartedAt: js.StartedAt,
 		CreatedBy: js.CreatedBy,
 	}
+	if js.ScheduleID.Valid {
+		res.ScheduleID = &js.ScheduleID.String
+	}
+	return res
 }
 
 func convertMultiJSs(list []*multiJobSummarySqlite) []*models.MultiJobSummary {
@@ -121,32 +146,75 @@ func (j *multiJobSqlite) convert() *models.MultiJob {
 	js := j.multiJobSummarySqlite.convert()
 	d := j.Details
 	return &models.MultiJob{
-		MultiJobSummary: *js,
-		ClientIDs:       d.ClientIDs,
-		GroupIDs:        d.GroupIDs,
-		Command:         d.Command,
-		Shell:           d.Shell,
-		TimeoutSec:      d.TimeoutSec,
-		Concurrent:      d.Concurrent,
-		AbortOnErr:      d.AbortOnErr,
+		MultiJobSummary:   *js,
+		ClientIDs:         d.ClientIDs,
+		GroupIDs:          d.GroupIDs,
+		Command:           d.Command,
+		Shell:             d.Shell,
+		Interpreter:       d.Interpreter,
+		Script:            d.Script,
+		ScriptHash:        d.ScriptHash,
+		ScriptID:          d.ScriptID,
+		ScriptVersion:     d.ScriptVersion,
+		Cwd:               d.Cwd,
+		Env:               d.Env,
+		RunAsUser:         d.RunAsUser,
+		TimeoutSec:        d.TimeoutSec,
+		TimeoutPolicy:     d.TimeoutPolicy,
+		TimeoutGraceSec:   d.TimeoutGraceSec,
+		SuccessExitCodes:  d.SuccessExitCodes,
+		Concurrent:        d.Concurrent,
+		AbortOnErr:        d.AbortOnErr,
+		BatchSize:         d.BatchSize,
+		BatchPercent:      d.BatchPercent,
+		BatchByGroup:      d.BatchByGroup,
+		BatchPauseSec:     d.BatchPauseSec,
+		MaxFailurePercent: d.MaxFailurePercent,
+		Batches:           d.Batches,
+		CurrentBatch:      d.CurrentBatch,
+		Error:             d.Error,
 	}
 }
 
 func convertMultiJobToSqlite(job *models.MultiJob) *multiJobSqlite {
-	return &multiJobSqlite{
+	res := &multiJobSqlite{
 		multiJobSummarySqlite: multiJobSummarySqlite{
 			JID:       job.JID,
 			StartedAt: job.StartedAt,
 			CreatedBy: job.CreatedBy,
 		},
+		Command: job.Command,
 		Details: &multiJobDetailSqlite{
-			ClientIDs:  job.ClientIDs,
-			GroupIDs:   job.GroupIDs,
-			Command:    job.Command,
-			Shell:      job.Shell,
-			TimeoutSec: job.TimeoutSec,
-			Concurrent: job.Concurrent,
-			AbortOnErr: job.AbortOnErr,
+			ClientIDs:         job.ClientIDs,
+			GroupIDs:          job.GroupIDs,
+			Command:           job.Command,
+			Shell:             job.Shell,
+			Interpreter:       job.Interpreter,
+			Script:            job.Script,
+			ScriptHash:        job.ScriptHash,
+			ScriptID:          job.ScriptID,
+			ScriptVersion:     job.ScriptVersion,
+			Cwd:               job.Cwd,
+			Env:               job.Env,
+			RunAsUser:         job.RunAsUser,
+			TimeoutSec:        job.TimeoutSec,
+			TimeoutPolicy:     job.TimeoutPolicy,
+			TimeoutGraceSec:   job.TimeoutGraceSec,
+			SuccessExitCodes:  job.SuccessExitCodes,
+			Concurrent:        job.Concurrent,
+			AbortOnErr:        job.AbortOnErr,
+			BatchSize:         job.BatchSize,
+			BatchPercent:      job.BatchPercent,
+			BatchByGroup:      job.BatchByGroup,
+			BatchPauseSec:     job.BatchPauseSec,
+			MaxFailurePercent: job.MaxFailurePercent,
+			Batches:           job.Batches,
+			CurrentBatch:      job.CurrentBatch,
+			Error:             job.Error,
 		},
 	}
+	if job.ScheduleID != nil {
+		res.ScheduleID = sql.NullString{String: *job.ScheduleID, Valid: true}
+	}
+	return res
 }
@@ -17,7 +17,7 @@ func TestMultiJobsSqliteProvider(t *testing.T) {
 	defer p.Close()
 
 	// verify job summaries not found
-	gotJSs, err := p.GetAllMultiJobSummaries()
+	gotJSs, err := p.ListMultiJobSummaries(&ListOptions{})
 	require.NoError(t, err)
 	require.Empty(t, gotJSs)
 
@@ -60,7 +60,7 @@ func TestMultiJobsSqliteProvider(t *testing.T) {
 	require.Nil(t, gotJob4)
 
 	// verify job summaries
-	gotJSs, err = p.GetAllMultiJobSummaries()
+	gotJSs, err = p.ListMultiJobSummaries(&ListOptions{})
 	require.NoError(t, err)
 	assert.EqualValues(t, []*models.MultiJobSummary{&job2.MultiJobSummary, &job3.MultiJobSummary, &job1.MultiJobSummary}, gotJSs)
 
@@ -75,7 +75,34 @@ func TestMultiJobsSqliteProvider(t *testing.T) {
 	require.NotNil(t, gotJob1)
 	assert.Equal(t, job1, gotJob1)
 
-	gotJSs, err = p.GetAllMultiJobSummaries()
+	gotJSs, err = p.ListMultiJobSummaries(&ListOptions{})
 	require.NoError(t, err)
 	assert.EqualValues(t, []*models.MultiJobSummary{&job1.MultiJobSummary, &job2.MultiJobSummary, &job3.MultiJobSummary}, gotJSs)
 }
+
+func TestGetMultiJobSummariesByScheduleID(t *testing.T) {
+	p, err := NewSqliteProvider(":memory:", testLog)
+	require.NoError(t, err)
+	defer p.Close()
+
+	t1 := time.Now().UTC()
+	job1 := jb.NewMulti(t).ScheduleID("schedule-1").StartedAt(t1.Add(-time.Hour)).Build()
+	job2 := jb.NewMulti(t).ScheduleID("schedule-1").StartedAt(t1).Build()
+	job3 := jb.NewMulti(t).ScheduleID("schedule-2").StartedAt(t1).Build()
+	job4 := jb.NewMulti(t).StartedAt(t1).Build()
+	for _, j := range []*models.MultiJob{job1, job2, job3, job4} {
+		require.NoError(t, p.SaveMultiJob(j))
+	}
+
+	gotJSs, err := p.GetMultiJobSummariesByScheduleID("schedule-1")
+	require.NoError(t, err)
+	assert.EqualValues(t, []*models.MultiJobSummary{&job2.MultiJobSummary, &job1.MultiJobSummary}, gotJSs)
+
+	gotJob3, err := p.GetMultiJob(job3.JID)
+	require.NoError(t, err)
+	assert.Equal(t, job3, gotJob3)
+
+	gotJSs, err = p.GetMultiJobSummariesByScheduleID("unknown")
+	require.NoError(t, err)
+	assert.Empty(t, gotJSs)
+}
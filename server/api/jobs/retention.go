@@ -0,0 +1,90 @@
+package jobs
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// DeleteFinishedBefore deletes jobs finished before a given time and multi-client jobs that have no jobs left.
+// Returns a number of deleted jobs.
+func (p *SqliteProvider) DeleteFinishedBefore(t time.Time) (int64, error) {
+	return p.deleteJobs("finished_at IS NOT NULL AND DATETIME(finished_at) < DATETIME(?)", t)
+}
+
+// DeleteExceedingPerClient deletes finished jobs of each client except a given number of the latest ones and
+// multi-client jobs that have no jobs left. Returns a number of deleted jobs.
+func (p *SqliteProvider) DeleteExceedingPerClient(maxCount int) (int64, error) {
+	return p.deleteJobs(
+		`jid IN (
+			SELECT jid FROM (
+				SELECT jid, ROW_NUMBER() OVER (PARTITION BY client_id ORDER BY DATETIME(started_at) DESC, jid DESC) AS num
+				FROM jobs WHERE finished_at IS NOT NULL
+			) WHERE num > ?
+		)`,
+		maxCount,
+	)
+}
+
+// DeleteFinishedByClientID deletes all finished jobs of a client with a given ID and multi-client jobs that have no
+// jobs left. Returns a number of deleted jobs.
+func (p *SqliteProvider) DeleteFinishedByClientID(clientID string) (int64, error) {
+	return p.deleteJobs("client_id=? AND finished_at IS NOT NULL", clientID)
+}
+
+// deleteJobs deletes jobs that match a given condition and, in the same transaction, their multi-client jobs that
+// have no jobs left. Returns a number of deleted jobs.
+func (p *SqliteProvider) deleteJobs(where string, params ...interface{}) (int64, error) {
+	tx, err := p.db.Beginx()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var multiJobIDs []string
+	err = tx.Select(&multiJobIDs, "SELECT DISTINCT multi_job_id FROM jobs WHERE multi_job_id IS NOT NULL AND "+where, params...)
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := tx.Exec("DELETE FROM jobs WHERE "+where, params...)
+	if err != nil {
+		return 0, err
+	}
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	if err := deleteOrphanMultiJobs(tx, multiJobIDs); err != nil {
+		return 0, err
+	}
+	return deleted, tx.Commit()
+}
+
+// deleteOrphanMultiJobs deletes multi-client jobs with given IDs that have no jobs left. Multi-client jobs are checked
+// by IDs to not delete the ones that are just started and have no jobs yet.
+func deleteOrphanMultiJobs(tx *sqlx.Tx, multiJobIDs []string) error {
+	if len(multiJobIDs) == 0 {
+		return nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(multiJobIDs)), ", ")
+	params := make([]interface{}, 0, len(multiJobIDs))
+	for _, id := range multiJobIDs {
+		params = append(params, id)
+	}
+	_, err := tx.Exec(
+		fmt.Sprintf(`DELETE FROM multi_jobs WHERE jid IN (%s)
+			AND NOT EXISTS (SELECT 1 FROM jobs WHERE jobs.multi_job_id = multi_jobs.jid)`, placeholders),
+		params...,
+	)
+	return err
+}
+
+// Vacuum rebuilds the database to reclaim the space of deleted jobs.
+func (p *SqliteProvider) Vacuum() error {
+	_, err := p.db.Exec("VACUUM")
+	return err
+}
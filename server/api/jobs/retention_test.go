@@ -0,0 +1,81 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudradar-monitoring/rport/server/test/jb"
+	"github.com/cloudradar-monitoring/rport/share/models"
+)
+
+func TestRetention(t *testing.T) {
+	p, err := NewSqliteProvider(":memory:", testLog)
+	require.NoError(t, err)
+	defer p.Close()
+
+	t1 := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC)
+	multiJob := jb.NewMulti(t).JID("multi-1").StartedAt(t1).Build()
+	recentMultiJob := jb.NewMulti(t).JID("multi-2").StartedAt(t1.Add(2 * time.Hour)).Build()
+	latestMultiJob := jb.NewMulti(t).JID("multi-3").StartedAt(t1.Add(3 * time.Hour)).Build()
+	runningMultiJob := jb.NewMulti(t).JID("multi-4").StartedAt(t1).Build()
+	emptyMultiJob := jb.NewMulti(t).JID("multi-5").StartedAt(t1).Build() // just started, has no jobs yet
+	for _, job := range []*models.MultiJob{multiJob, recentMultiJob, latestMultiJob, runningMultiJob, emptyMultiJob} {
+		require.NoError(t, p.SaveMultiJob(job))
+	}
+
+	old := jb.New(t).JID("old").ClientID("client-1").MultiJobID(multiJob.JID).Status(models.JobStatusSuccessful).StartedAt(t1).FinishedAt(t1.Add(time.Minute)).Build()
+	running := jb.New(t).JID("running").ClientID("client-1").MultiJobID(runningMultiJob.JID).Status(models.JobStatusRunning).StartedAt(t1).Build()
+	recent1 := jb.New(t).JID("recent-1").ClientID("client-1").MultiJobID(recentMultiJob.JID).Status(models.JobStatusFailed).StartedAt(t1.Add(2 * time.Hour)).FinishedAt(t1.Add(3 * time.Hour)).Build()
+	recent2 := jb.New(t).JID("recent-2").ClientID("client-1").MultiJobID(latestMultiJob.JID).Status(models.JobStatusSuccessful).StartedAt(t1.Add(3 * time.Hour)).FinishedAt(t1.Add(3 * time.Hour)).Build()
+	otherClient := jb.New(t).JID("other").ClientID("client-2").Status(models.JobStatusSuccessful).StartedAt(t1.Add(2 * time.Hour)).FinishedAt(t1.Add(3 * time.Hour)).Build()
+	for _, job := range []*models.Job{old, running, recent1, recent2, otherClient} {
+		require.NoError(t, p.SaveJob(job))
+	}
+
+	assertJIDs := func(clientID string, want ...string) {
+		got, err := p.ListSummaries(clientID, &ListOptions{Sort: "started_at"})
+		require.NoError(t, err)
+		var gotJIDs []string
+		for _, cur := range got {
+			gotJIDs = append(gotJIDs, cur.JID)
+		}
+		assert.ElementsMatch(t, want, gotJIDs)
+	}
+	assertMultiJIDs := func(want ...string) {
+		got, err := p.ListMultiJobSummaries(&ListOptions{})
+		require.NoError(t, err)
+		var gotJIDs []string
+		for _, cur := range got {
+			gotJIDs = append(gotJIDs, cur.JID)
+		}
+		assert.ElementsMatch(t, want, gotJIDs)
+	}
+
+	// by age
+	deleted, err := p.DeleteFinishedBefore(t1.Add(time.Hour))
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, deleted)
+	assertJIDs("client-1", "running", "recent-1", "recent-2")
+	assertMultiJIDs("multi-2", "multi-3", "multi-4", "multi-5")
+
+	// by count
+	deleted, err = p.DeleteExceedingPerClient(1)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, deleted)
+	assertJIDs("client-1", "running", "recent-2")
+	assertJIDs("client-2", "other")
+	assertMultiJIDs("multi-3", "multi-4", "multi-5")
+
+	// purge
+	deleted, err = p.DeleteFinishedByClientID("client-1")
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, deleted)
+	assertJIDs("client-1", "running")
+	assertJIDs("client-2", "other")
+	assertMultiJIDs("multi-4", "multi-5")
+
+	assert.NoError(t, p.Vacuum())
+}
@@ -12,6 +12,19 @@ func NewSuccessPayload(data interface{}) SuccessPayload {
 	}
 }
 
+// Meta contains additional info about a returned list.
+type Meta struct {
+	// Count is a total number of items matching the request regardless of pagination.
+	Count int `json:"count"`
+}
+
+func NewSuccessPayloadWithMeta(data interface{}, meta interface{}) SuccessPayload {
+	return SuccessPayload{
+		Data: data,
+		Meta: meta,
+	}
+}
+
 // ErrorPayload represents a uniform format for all error API responses.
 type ErrorPayload struct {
 	Errors []ErrorPayloadItem `json:"errors"`
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	queryParamPageLimit  = "page[limit]"
+	queryParamPageOffset = "page[offset]"
+
+	filterPrefix = "filter["
+	filterSuffix = "]"
+
+	fieldsPrefix = "fields["
+	fieldsSuffix = "]"
+)
+
+// Pagination represents requested page of a list. Zero Limit means no limit.
+type Pagination struct {
+	Limit  int
+	Offset int
+}
+
+// Bounds returns start and end indexes of a requested page in a list of a given length.
+func (p *Pagination) Bounds(total int) (start, end int) {
+	start = p.Offset
+	if start > total {
+		start = total
+	}
+	end = total
+	if p.Limit > 0 && start+p.Limit < total {
+		end = start + p.Limit
+	}
+	return start, end
+}
+
+// ParsePagination returns a requested page from given query params.
+// defaultLimit is used when 'page[limit]' is missing, zero means no limit. A limit can't exceed maxLimit.
+func ParsePagination(values url.Values, defaultLimit, maxLimit int) (*Pagination, error) {
+	res := &Pagination{
+		Limit: defaultLimit,
+	}
+
+	if limitStr := values.Get(queryParamPageLimit); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 {
+			return nil, fmt.Errorf("invalid %q query param: expected positive integer, got %q", queryParamPageLimit, limitStr)
+		}
+		if limit > maxLimit {
+			return nil, fmt.Errorf("invalid %q query param: max value is %d, got %d", queryParamPageLimit, maxLimit, limit)
+		}
+		res.Limit = limit
+	}
+
+	if offsetStr := values.Get(queryParamPageOffset); offsetStr != "" {
+		offset, err := strconv.Atoi(offsetStr)
+		if err != nil || offset < 0 {
+			return nil, fmt.Errorf("invalid %q query param: expected non-negative integer, got %q", queryParamPageOffset, offsetStr)
+		}
+		res.Offset = offset
+	}
+
+	return res, nil
+}
+
+// ParseFilters returns values of 'filter[<field>]' query params by field name. Multiple values can be given
+// separated by comma. Returns an error if a field is not one of supported fields.
+func ParseFilters(values url.Values, supportedFields ...string) (map[string][]string, error) {
+	res := make(map[string][]string)
+	for key, vals := range values {
+		if !strings.HasPrefix(key, filterPrefix) || !strings.HasSuffix(key, filterSuffix) {
+			continue
+		}
+		field := strings.TrimSuffix(strings.TrimPrefix(key, filterPrefix), filterSuffix)
+		if !isSupportedField(field, supportedFields) {
+			return nil, fmt.Errorf("unsupported filter field %q, expected one of: %s", field, strings.Join(supportedFields, ", "))
+		}
+		for _, v := range vals {
+			for _, cur := range strings.Split(v, ",") {
+				if cur = strings.TrimSpace(cur); cur != "" {
+					res[field] = append(res[field], cur)
+				}
+			}
+		}
+	}
+	return res, nil
+}
+
+// ParseFields returns fields requested by 'fields[<resource>]' query param. Multiple fields are separated by comma.
+// Returns nil if the param is missing and an error if a field is not one of supported fields.
+func ParseFields(values url.Values, resource string, supportedFields ...string) ([]string, error) {
+	fieldsStr := values.Get(fieldsPrefix + resource + fieldsSuffix)
+	if fieldsStr == "" {
+		return nil, nil
+	}
+	var res []string
+	for _, field := range strings.Split(fieldsStr, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if !isSupportedField(field, supportedFields) {
+			return nil, fmt.Errorf("unsupported field %q, expected one of: %s", field, strings.Join(supportedFields, ", "))
+		}
+		res = append(res, field)
+	}
+	return res, nil
+}
+
+func isSupportedField(field string, supportedFields []string) bool {
+	for _, cur := range supportedFields {
+		if field == cur {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePagination(t *testing.T) {
+	testCases := []struct {
+		name string
+
+		query string
+
+		wantPagination *Pagination
+		wantErr        string
+	}{
+		{
+			name:           "defaults",
+			query:          "",
+			wantPagination: &Pagination{Limit: 10, Offset: 0},
+		},
+		{
+			name:           "limit and offset",
+			query:          "page[limit]=5&page[offset]=15",
+			wantPagination: &Pagination{Limit: 5, Offset: 15},
+		},
+		{
+			name:    "invalid limit",
+			query:   "page[limit]=0",
+			wantErr: `invalid "page[limit]" query param: expected positive integer, got "0"`,
+		},
+		{
+			name:    "limit exceeds max",
+			query:   "page[limit]=101",
+			wantErr: `invalid "page[limit]" query param: max value is 100, got 101`,
+		},
+		{
+			name:    "invalid offset",
+			query:   "page[offset]=-1",
+			wantErr: `invalid "page[offset]" query param: expected non-negative integer, got "-1"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			values, err := url.ParseQuery(tc.query)
+			require.NoError(t, err)
+
+			gotPagination, gotErr := ParsePagination(values, 10, 100)
+
+			if tc.wantErr != "" {
+				require.EqualError(t, gotErr, tc.wantErr)
+			} else {
+				require.NoError(t, gotErr)
+				assert.Equal(t, tc.wantPagination, gotPagination)
+			}
+		})
+	}
+}
+
+func TestParseFilters(t *testing.T) {
+	testCases := []struct {
+		name string
+
+		query string
+
+		wantFilters map[string][]string
+		wantErr     string
+	}{
+		{
+			name:        "no filters",
+			query:       "sort=id",
+			wantFilters: map[string][]string{},
+		},
+		{
+			name:  "several filters and values",
+			query: "filter[name]=a,b&filter[os]=linux&filter[name]=c&sort=id",
+			wantFilters: map[string][]string{
+				"name": {"a", "b", "c"},
+				"os":   {"linux"},
+			},
+		},
+		{
+			name:    "unsupported field",
+			query:   "filter[unknown]=a",
+			wantErr: `unsupported filter field "unknown", expected one of: name, os`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			values, err := url.ParseQuery(tc.query)
+			require.NoError(t, err)
+
+			gotFilters, gotErr := ParseFilters(values, "name", "os")
+
+			if tc.wantErr != "" {
+				require.EqualError(t, gotErr, tc.wantErr)
+			} else {
+				require.NoError(t, gotErr)
+				assert.Equal(t, tc.wantFilters, gotFilters)
+			}
+		})
+	}
+}
+
+func TestPaginationBounds(t *testing.T) {
+	testCases := []struct {
+		name string
+
+		pagination Pagination
+		total      int
+
+		wantStart int
+		wantEnd   int
+	}{
+		{
+			name:       "no limit",
+			pagination: Pagination{},
+			total:      5,
+			wantStart:  0,
+			wantEnd:    5,
+		},
+		{
+			name:       "first page",
+			pagination: Pagination{Limit: 2},
+			total:      5,
+			wantStart:  0,
+			wantEnd:    2,
+		},
+		{
+			name:       "last page",
+			pagination: Pagination{Limit: 2, Offset: 4},
+			total:      5,
+			wantStart:  4,
+			wantEnd:    5,
+		},
+		{
+			name:       "offset out of range",
+			pagination: Pagination{Limit: 2, Offset: 10},
+			total:      5,
+			wantStart:  5,
+			wantEnd:    5,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gotStart, gotEnd := tc.pagination.Bounds(tc.total)
+
+			assert.Equal(t, tc.wantStart, gotStart)
+			assert.Equal(t, tc.wantEnd, gotEnd)
+		})
+	}
+}
+
+func TestParseFields(t *testing.T) {
+	values := url.Values{"fields[clients]": {"id, name"}, "fields[other]": {"unknown"}}
+
+	gotFields, gotErr := ParseFields(values, "clients", "id", "name", "os")
+	require.NoError(t, gotErr)
+	assert.Equal(t, []string{"id", "name"}, gotFields)
+
+	gotFields, gotErr = ParseFields(values, "missing", "id")
+	require.NoError(t, gotErr)
+	assert.Nil(t, gotFields)
+
+	_, gotErr = ParseFields(values, "other", "id")
+	require.EqualError(t, gotErr, `unsupported field "unknown", expected one of: id`)
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// TrustedProxies are networks of reverse proxies in front of the API. Only they are trusted to set
+// X-Forwarded-For and X-Real-IP headers.
+type TrustedProxies []*net.IPNet
+
+// ParseTrustedProxies parses a list of IP addresses and CIDR networks.
+func ParseTrustedProxies(raw []string) (TrustedProxies, error) {
+	res := make(TrustedProxies, 0, len(raw))
+	for _, cur := range raw {
+		if !strings.Contains(cur, "/") {
+			ip := net.ParseIP(cur)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP address %q", cur)
+			}
+			bits := 8 * net.IPv4len
+			if ip.To4() == nil {
+				bits = 8 * net.IPv6len
+			}
+			cur = fmt.Sprintf("%s/%d", cur, bits)
+		}
+		_, ipNet, err := net.ParseCIDR(cur)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, ipNet)
+	}
+	return res, nil
+}
+
+// Contains returns true if a given IP belongs to one of the trusted proxies.
+func (p TrustedProxies) Contains(ip net.IP) bool {
+	for _, cur := range p {
+		if cur.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// RemoteIP returns the source IP of a given request. Forwarding headers are used only if the request comes from
+// a trusted proxy. In this case the last address in X-Forwarded-For that is not a trusted proxy is returned,
+// because all addresses before it can be set by the client itself.
+func (p TrustedProxies) RemoteIP(req *http.Request) string {
+	peer := req.RemoteAddr
+	if host, _, err := net.SplitHostPort(peer); err == nil {
+		peer = host
+	}
+	if !p.Contains(net.ParseIP(peer)) {
+		return peer
+	}
+
+	if xff := req.Header.Get("X-Forwarded-For"); xff != "" {
+		hops := strings.Split(xff, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			ip := net.ParseIP(hop)
+			if ip == nil {
+				break
+			}
+			if !p.Contains(ip) {
+				return hop
+			}
+		}
+	}
+
+	if xrip := strings.TrimSpace(req.Header.Get("X-Real-IP")); net.ParseIP(xrip) != nil {
+		return xrip
+	}
+
+	return peer
+}
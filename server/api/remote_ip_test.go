@@ -0,0 +1,90 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTrustedProxies(t *testing.T) {
+	proxies, err := ParseTrustedProxies([]string{"127.0.0.1", "10.0.0.0/8", "::1"})
+	require.NoError(t, err)
+	require.Len(t, proxies, 3)
+	assert.Equal(t, "127.0.0.1/32", proxies[0].String())
+	assert.Equal(t, "10.0.0.0/8", proxies[1].String())
+	assert.Equal(t, "::1/128", proxies[2].String())
+
+	_, err = ParseTrustedProxies([]string{"localhost"})
+	assert.EqualError(t, err, `invalid IP address "localhost"`)
+
+	_, err = ParseTrustedProxies([]string{"10.0.0.0/33"})
+	assert.EqualError(t, err, "invalid CIDR address: 10.0.0.0/33")
+}
+
+func TestRemoteIP(t *testing.T) {
+	proxies, err := ParseTrustedProxies([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		proxies    TrustedProxies
+		wantIP     string
+	}{
+		{
+			name:       "no proxies",
+			remoteAddr: "192.0.2.1:1234",
+			wantIP:     "192.0.2.1",
+		},
+		{
+			name:       "untrusted forwarding headers",
+			remoteAddr: "192.0.2.1:1234",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.1", "X-Real-IP": "198.51.100.2"},
+			proxies:    proxies,
+			wantIP:     "192.0.2.1",
+		},
+		{
+			name:       "trusted proxy",
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.1"},
+			proxies:    proxies,
+			wantIP:     "198.51.100.1",
+		},
+		{
+			name:       "trusted proxy chain, spoofed first hop",
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string]string{"X-Forwarded-For": "203.0.113.1, 198.51.100.1, 10.0.0.2"},
+			proxies:    proxies,
+			wantIP:     "198.51.100.1",
+		},
+		{
+			name:       "trusted proxy, real ip",
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string]string{"X-Real-IP": "198.51.100.2"},
+			proxies:    proxies,
+			wantIP:     "198.51.100.2",
+		},
+		{
+			name:       "trusted proxy, no headers",
+			remoteAddr: "10.0.0.1:1234",
+			proxies:    proxies,
+			wantIP:     "10.0.0.1",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tc.remoteAddr
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+
+			assert.Equal(t, tc.wantIP, tc.proxies.RemoteIP(req))
+		})
+	}
+}
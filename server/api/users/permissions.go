@@ -0,0 +1,98 @@
+package users
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Administrators is a built-in group. Its members are granted all permissions.
+const Administrators = "Administrators"
+
+// Permission represents a right to perform a certain kind of state-changing API action.
+type Permission string
+
+const (
+	PermissionTunnels      Permission = "tunnels"
+	PermissionCommands     Permission = "commands"
+	PermissionClientsAuth  Permission = "clients-auth"
+	PermissionClientGroups Permission = "client-groups"
+	PermissionLibrary      Permission = "library"
+)
+
+var AllPermissions = []Permission{
+	PermissionTunnels,
+	PermissionCommands,
+	PermissionClientsAuth,
+	PermissionClientGroups,
+	PermissionLibrary,
+}
+
+// GroupPermissions maps user groups to permissions granted to their members. Group names are case-insensitive.
+type GroupPermissions map[string][]Permission
+
+// ParseGroupPermissions returns group permissions from a given raw config value.
+func ParseGroupPermissions(raw map[string][]string) (GroupPermissions, error) {
+	res := make(GroupPermissions, len(raw))
+	for group, rawPermissions := range raw {
+		group = strings.TrimSpace(group)
+		if group == "" {
+			return nil, fmt.Errorf("group name can not be empty")
+		}
+		permissions := make([]Permission, 0, len(rawPermissions))
+		for _, p := range rawPermissions {
+			permission, err := parsePermission(p)
+			if err != nil {
+				return nil, fmt.Errorf("group %q: %v", group, err)
+			}
+			permissions = append(permissions, permission)
+		}
+		key := strings.ToLower(group)
+		if existing, ok := res[key]; ok {
+			permissions = append(existing, permissions...)
+		}
+		res[key] = permissions
+	}
+	return res, nil
+}
+
+func parsePermission(raw string) (Permission, error) {
+	p := Permission(strings.ToLower(strings.TrimSpace(raw)))
+	for _, cur := range AllPermissions {
+		if p == cur {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("unknown permission %q, expected one of: %v", raw, AllPermissions)
+}
+
+// Get returns all permissions granted to a given user. Administrators get all permissions.
+func (gp GroupPermissions) Get(u *User) []Permission {
+	if u.IsAdmin() {
+		return AllPermissions
+	}
+
+	res := make([]Permission, 0)
+	for _, p := range AllPermissions {
+		if gp.Has(u, p) {
+			res = append(res, p)
+		}
+	}
+	return res
+}
+
+// Has returns true if a given user is an administrator or belongs to at least one group
+// that is granted a given permission.
+func (gp GroupPermissions) Has(u *User, permission Permission) bool {
+	if u.IsAdmin() {
+		return true
+	}
+
+	for _, group := range u.Groups {
+		for _, cur := range gp[strings.ToLower(group)] {
+			if cur == permission {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package users
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGroupPermissions(t *testing.T) {
+	testCases := []struct {
+		descr string // Test Case Description
+
+		raw map[string][]string
+
+		wantRes         GroupPermissions
+		wantErrContains string
+	}{
+		{
+			descr:   "empty",
+			raw:     nil,
+			wantRes: GroupPermissions{},
+		},
+		{
+			descr: "valid",
+			raw: map[string][]string{
+				"Operators": {"tunnels", " Commands "},
+				"helpdesk":  {},
+			},
+			wantRes: GroupPermissions{
+				"operators": {PermissionTunnels, PermissionCommands},
+				"helpdesk":  {},
+			},
+		},
+		{
+			descr: "unknown permission",
+			raw: map[string][]string{
+				"operators": {"tunnels", "unknown"},
+			},
+			wantErrContains: `group "operators": unknown permission "unknown"`,
+		},
+		{
+			descr: "empty group",
+			raw: map[string][]string{
+				" ": {"tunnels"},
+			},
+			wantErrContains: "group name can not be empty",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.descr, func(t *testing.T) {
+			// when
+			gotRes, gotErr := ParseGroupPermissions(tc.raw)
+
+			// then
+			if tc.wantErrContains != "" {
+				require.Error(t, gotErr)
+				assert.Contains(t, gotErr.Error(), tc.wantErrContains)
+				return
+			}
+			require.NoError(t, gotErr)
+			assert.Equal(t, tc.wantRes, gotRes)
+		})
+	}
+}
+
+func TestGroupPermissions(t *testing.T) {
+	gp := GroupPermissions{
+		"operators":    {PermissionTunnels, PermissionCommands},
+		"groupeditors": {PermissionClientGroups},
+		"helpdesk":     {},
+	}
+	admin := &User{Username: "admin", Groups: []string{"administrators"}}
+	operator := &User{Username: "operator", Groups: []string{"Operators", "GroupEditors"}}
+	helpdesk := &User{Username: "helpdesk", Groups: []string{"Helpdesk"}}
+	noGroups := &User{Username: "no-groups"}
+
+	assert.Equal(t, AllPermissions, gp.Get(admin))
+	assert.Equal(t, []Permission{PermissionTunnels, PermissionCommands, PermissionClientGroups}, gp.Get(operator))
+	assert.Equal(t, []Permission{}, gp.Get(helpdesk))
+	assert.Equal(t, []Permission{}, gp.Get(noGroups))
+
+	assert.True(t, gp.Has(admin, PermissionClientsAuth))
+	assert.True(t, gp.Has(operator, PermissionCommands))
+	assert.False(t, gp.Has(operator, PermissionClientsAuth))
+	assert.False(t, gp.Has(helpdesk, PermissionTunnels))
+	assert.False(t, gp.Has(noGroups, PermissionTunnels))
+}
@@ -1,8 +1,20 @@
 package users
 
+import "strings"
+
 // User represents API user.
 type User struct {
 	Username string
 	Password string
 	Groups   []string
 }
+
+// IsAdmin returns true if a user belongs to the built-in administrators group.
+func (u *User) IsAdmin() bool {
+	for _, group := range u.Groups {
+		if strings.EqualFold(group, Administrators) {
+			return true
+		}
+	}
+	return false
+}
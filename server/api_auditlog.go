@@ -0,0 +1,88 @@
+package chserver
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/cloudradar-monitoring/rport/server/api"
+	"github.com/cloudradar-monitoring/rport/server/auditlog"
+)
+
+const (
+	auditLogDefaultLimit = 50
+	auditLogMaxLimit     = 500
+
+	queryParamSince = "filter[since]"
+	queryParamUntil = "filter[until]"
+)
+
+func (al *APIListener) handleGetAuditLog(w http.ResponseWriter, req *http.Request) {
+	opts, err := parseAuditLogListOptions(req.URL.Query())
+	if err != nil {
+		al.jsonErrorResponseWithErrCode(w, http.StatusBadRequest, ErrCodeInvalidRequest, err.Error())
+		return
+	}
+
+	entries, err := al.auditLog.List(req.Context(), opts)
+	if err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", "Failed to get audit log entries.", err)
+		return
+	}
+
+	count, err := al.auditLog.Count(req.Context(), opts)
+	if err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", "Failed to count audit log entries.", err)
+		return
+	}
+
+	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayloadWithMeta(entries, api.Meta{Count: count}))
+}
+
+func parseAuditLogListOptions(values url.Values) (*auditlog.ListOptions, error) {
+	opts := &auditlog.ListOptions{}
+
+	// since and until are not regular fields, so exclude them before parsing other filters
+	filterValues := url.Values{}
+	for k, v := range values {
+		if k != queryParamSince && k != queryParamUntil {
+			filterValues[k] = v
+		}
+	}
+
+	var err error
+	opts.Filters, err = api.ParseFilters(filterValues, auditlog.FilterFields...)
+	if err != nil {
+		return nil, err
+	}
+
+	opts.Since, err = parseTimeQueryParam(values, queryParamSince)
+	if err != nil {
+		return nil, err
+	}
+
+	opts.Until, err = parseTimeQueryParam(values, queryParamUntil)
+	if err != nil {
+		return nil, err
+	}
+
+	opts.Pagination, err = api.ParsePagination(values, auditLogDefaultLimit, auditLogMaxLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	return opts, nil
+}
+
+func parseTimeQueryParam(values url.Values, param string) (*time.Time, error) {
+	str := values.Get(param)
+	if str == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, str)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %q query param: expected RFC3339 time, got %q", param, str)
+	}
+	return &t, nil
+}
@@ -0,0 +1,124 @@
+package chserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudradar-monitoring/rport/server/api"
+	"github.com/cloudradar-monitoring/rport/server/api/users"
+	"github.com/cloudradar-monitoring/rport/server/auditlog"
+)
+
+func TestHandleGetAuditLog(t *testing.T) {
+	ctx := context.Background()
+	provider, err := auditlog.NewSqliteProvider(":memory:")
+	require.NoError(t, err)
+	defer provider.Close()
+
+	t1 := time.Date(2020, 10, 10, 10, 10, 1, 0, time.UTC)
+	entries := []*auditlog.Entry{
+		{Timestamp: t1, Username: "admin", RemoteIP: "127.0.0.1", Application: auditlog.ApplicationAuth, Action: auditlog.ActionLogin, Params: json.RawMessage("null")},
+		{Timestamp: t1.Add(time.Minute), Username: "admin", RemoteIP: "127.0.0.1", Application: auditlog.ApplicationClientGroups, Action: auditlog.ActionCreate, AffectedID: "group-1", Params: json.RawMessage(`{"client_id":["client-1"]}`)},
+		{Timestamp: t1.Add(2 * time.Minute), Username: "user1", RemoteIP: "127.0.0.2", Application: auditlog.ApplicationAuth, Action: auditlog.ActionLoginFailed, Params: json.RawMessage("null")},
+	}
+	for _, e := range entries {
+		require.NoError(t, provider.Save(ctx, e))
+	}
+
+	al := APIListener{
+		insecureForTests: true,
+		Server: &Server{
+			config:   &Config{},
+			auditLog: auditlog.New(testLog, provider, nil),
+		},
+	}
+	al.initRouter()
+
+	testCases := []struct {
+		name string
+
+		query string
+
+		wantStatusCode int
+		wantJSON       string
+	}{
+		{
+			name:           "filter and paginate",
+			query:          "filter[username]=admin&filter[since]=2020-10-10T10:10:00Z&page[limit]=1",
+			wantStatusCode: http.StatusOK,
+			wantJSON: `{
+				"data": [{
+					"timestamp": "2020-10-10T10:11:01Z",
+					"username": "admin",
+					"remote_ip": "127.0.0.1",
+					"application": "client_groups",
+					"action": "create",
+					"affected_id": "group-1",
+					"client_id": "",
+					"params": {"client_id": ["client-1"]}
+				}],
+				"meta": {"count": 2}
+			}`,
+		},
+		{
+			name:           "no matches",
+			query:          "filter[action]=delete",
+			wantStatusCode: http.StatusOK,
+			wantJSON:       `{"data": [], "meta": {"count": 0}}`,
+		},
+		{
+			name:           "unsupported filter",
+			query:          "filter[params]=1",
+			wantStatusCode: http.StatusBadRequest,
+			wantJSON:       `{"errors": [{"code": "ERR_CODE_INVALID_REQUEST", "title": "unsupported filter field \"params\", expected one of: username, remote_ip, application, action, affected_id, client_id", "detail": ""}]}`,
+		},
+		{
+			name:           "invalid since",
+			query:          "filter[since]=yesterday",
+			wantStatusCode: http.StatusBadRequest,
+			wantJSON:       `{"errors": [{"code": "ERR_CODE_INVALID_REQUEST", "title": "invalid \"filter[since]\" query param: expected RFC3339 time, got \"yesterday\"", "detail": ""}]}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/auditlog?"+tc.query, nil)
+
+			al.router.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.wantStatusCode, w.Code)
+			assert.JSONEq(t, tc.wantJSON, w.Body.String())
+		})
+	}
+}
+
+func TestAdminMiddleware(t *testing.T) {
+	admin := &users.User{Username: "admin", Groups: []string{users.Administrators}}
+	operator := &users.User{Username: "operator", Groups: []string{"Operators"}}
+	al := APIListener{
+		Server:  &Server{config: &Config{}},
+		userSrv: users.NewUserCache([]*users.User{admin, operator}),
+		Logger:  testLog,
+	}
+	handler := al.adminMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	handler(w, req.WithContext(api.WithUser(req.Context(), admin.Username)))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	handler(w, req.WithContext(api.WithUser(req.Context(), operator.Username)))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.JSONEq(t, `{"errors": [{"code": "", "title": "Access denied. Only members of \"Administrators\" group are allowed.", "detail": ""}]}`, w.Body.String())
+}
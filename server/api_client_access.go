@@ -0,0 +1,199 @@
+package chserver
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/cloudradar-monitoring/rport/server/cgroups"
+	"github.com/cloudradar-monitoring/rport/server/clients"
+	"github.com/cloudradar-monitoring/rport/share/models"
+)
+
+// clientAccess defines clients a user is allowed to see and act on.
+type clientAccess struct {
+	// unrestricted is true when a user has access to all clients
+	unrestricted bool
+	groups       []*cgroups.ClientGroup
+}
+
+// Allowed returns true if a given client is accessible.
+func (a *clientAccess) Allowed(c *clients.Client) bool {
+	return a.unrestricted || c.BelongsToOneOf(a.groups)
+}
+
+// Filter returns only accessible clients from a given list.
+func (a *clientAccess) Filter(all []*clients.Client) []*clients.Client {
+	if a.unrestricted {
+		return all
+	}
+	res := make([]*clients.Client, 0, len(all))
+	for _, cur := range all {
+		if a.Allowed(cur) {
+			res = append(res, cur)
+		}
+	}
+	return res
+}
+
+// AllowedGroup returns true if all clients of a given client group are accessible, i.e. the group is one of the
+// user's groups.
+func (a *clientAccess) AllowedGroup(groupID string) bool {
+	if a.unrestricted {
+		return true
+	}
+	for _, cur := range a.groups {
+		if cur.ID == groupID {
+			return true
+		}
+	}
+	return false
+}
+
+// getClientAccess returns clients a current user has access to. If 'restrict_clients_by_group' is enabled,
+// users that are not administrators can access only clients of client groups which IDs match their user groups.
+func (al *APIListener) getClientAccess(ctx context.Context) (*clientAccess, error) {
+	if !al.config.API.RestrictClientsByGroup {
+		return &clientAccess{unrestricted: true}, nil
+	}
+
+	curUser, err := al.getCurUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if curUser == nil {
+		return &clientAccess{}, nil
+	}
+	if curUser.IsAdmin() {
+		return &clientAccess{unrestricted: true}, nil
+	}
+
+	allGroups, err := al.clientGroupProvider.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client groups: %v", err)
+	}
+
+	res := &clientAccess{}
+	for _, group := range allGroups {
+		for _, userGroup := range curUser.Groups {
+			if strings.EqualFold(group.ID, userGroup) {
+				res.groups = append(res.groups, group)
+				break
+			}
+		}
+	}
+	return res, nil
+}
+
+// allowClientAccess responds with 403 and returns false if a current user has no access to a given client.
+func (al *APIListener) allowClientAccess(w http.ResponseWriter, req *http.Request, client *clients.Client) bool {
+	access, err := al.getClientAccess(req.Context())
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+		return false
+	}
+
+	if !access.Allowed(client) {
+		al.jsonErrorResponseWithTitle(w, http.StatusForbidden, fmt.Sprintf("Access denied to client with id=%q.", client.ID))
+		return false
+	}
+
+	return true
+}
+
+// allowClientIDAccess is like allowClientAccess but for a client that can be already deleted, e.g. to read its job
+// history. Jobs of deleted clients are accessible only by users with access to all clients.
+func (al *APIListener) allowClientIDAccess(w http.ResponseWriter, req *http.Request, clientID string) bool {
+	client, err := al.clientService.GetByID(clientID)
+	if err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", fmt.Sprintf("Failed to find a client with id=%q.", clientID), err)
+		return false
+	}
+	if client != nil {
+		return al.allowClientAccess(w, req, client)
+	}
+
+	access, err := al.getClientAccess(req.Context())
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+		return false
+	}
+	if !access.unrestricted {
+		al.jsonErrorResponseWithTitle(w, http.StatusForbidden, fmt.Sprintf("Access denied to client with id=%q.", clientID))
+		return false
+	}
+	return true
+}
+
+// allowedClientIDs returns IDs of accessible clients or nil if all clients are accessible.
+func (al *APIListener) allowedClientIDs(access *clientAccess) ([]string, error) {
+	if access.unrestricted {
+		return nil, nil
+	}
+	all, err := al.clientService.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get clients: %v", err)
+	}
+	res := make([]string, 0, len(all))
+	for _, cur := range access.Filter(all) {
+		res = append(res, cur.ID)
+	}
+	return res, nil
+}
+
+// allowedClientIDSet is like allowedClientIDs but returns a set of IDs.
+func (al *APIListener) allowedClientIDSet(access *clientAccess) (map[string]bool, error) {
+	ids, err := al.allowedClientIDs(access)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		res[id] = true
+	}
+	return res, nil
+}
+
+// targetsAllowed returns true if all given clients and client groups are accessible. allowedClients is a set of IDs
+// of accessible clients as returned by allowedClientIDSet.
+func targetsAllowed(access *clientAccess, allowedClients map[string]bool, clientIDs, groupIDs []string) bool {
+	if access.unrestricted {
+		return true
+	}
+	for _, groupID := range groupIDs {
+		if !access.AllowedGroup(groupID) {
+			return false
+		}
+	}
+	for _, clientID := range clientIDs {
+		if !allowedClients[clientID] {
+			return false
+		}
+	}
+	return true
+}
+
+// filterMultiJob leaves only jobs and client IDs of a multi-client job that belong to given clients.
+func filterMultiJob(job *models.MultiJob, clientIDs []string) {
+	allowed := make(map[string]bool, len(clientIDs))
+	for _, id := range clientIDs {
+		allowed[id] = true
+	}
+
+	jobs := make([]*models.Job, 0, len(job.Jobs))
+	for _, cur := range job.Jobs {
+		if allowed[cur.ClientID] {
+			jobs = append(jobs, cur)
+		}
+	}
+	job.Jobs = jobs
+
+	ids := make([]string, 0, len(job.ClientIDs))
+	for _, id := range job.ClientIDs {
+		if allowed[id] {
+			ids = append(ids, id)
+		}
+	}
+	job.ClientIDs = ids
+}
@@ -0,0 +1,137 @@
+package chserver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudradar-monitoring/rport/server/api"
+	"github.com/cloudradar-monitoring/rport/server/api/users"
+	"github.com/cloudradar-monitoring/rport/server/cgroups"
+	"github.com/cloudradar-monitoring/rport/server/clients"
+	"github.com/cloudradar-monitoring/rport/share/models"
+)
+
+type ClientGroupProviderMock struct {
+	cgroups.ClientGroupProvider
+	ReturnGroups []*cgroups.ClientGroup
+}
+
+func (p *ClientGroupProviderMock) GetAll(ctx context.Context) ([]*cgroups.ClientGroup, error) {
+	return p.ReturnGroups, nil
+}
+
+func TestGetClientAccess(t *testing.T) {
+	c1 := clients.New(t).ID("client-1").Build()
+	c2 := clients.New(t).ID("client-2").Build()
+	c3 := clients.New(t).ID("client-3").Build()
+	allClients := []*clients.Client{c1, c2, c3}
+
+	g1 := &cgroups.ClientGroup{ID: "customer-1", Params: &cgroups.ClientParams{ClientID: &cgroups.ParamValues{"client-1"}}}
+	g2 := &cgroups.ClientGroup{ID: "customer-2", Params: &cgroups.ClientParams{ClientID: &cgroups.ParamValues{"client-2"}}}
+
+	admin := &users.User{Username: "admin", Groups: []string{users.Administrators}}
+	user1 := &users.User{Username: "user1", Groups: []string{"Customer-1"}}
+	user12 := &users.User{Username: "user12", Groups: []string{"customer-1", "customer-2"}}
+	noGroups := &users.User{Username: "no-groups"}
+
+	testCases := []struct {
+		name string
+
+		restrict bool
+		username string
+
+		wantClients []*clients.Client
+	}{
+		{
+			name:        "restriction disabled",
+			restrict:    false,
+			username:    user1.Username,
+			wantClients: allClients,
+		},
+		{
+			name:        "admin",
+			restrict:    true,
+			username:    admin.Username,
+			wantClients: allClients,
+		},
+		{
+			name:        "user with one client group",
+			restrict:    true,
+			username:    user1.Username,
+			wantClients: []*clients.Client{c1},
+		},
+		{
+			name:        "user with two client groups",
+			restrict:    true,
+			username:    user12.Username,
+			wantClients: []*clients.Client{c1, c2},
+		},
+		{
+			name:        "user without groups",
+			restrict:    true,
+			username:    noGroups.Username,
+			wantClients: []*clients.Client{},
+		},
+		{
+			name:        "unknown user",
+			restrict:    true,
+			username:    "unknown",
+			wantClients: []*clients.Client{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			al := APIListener{
+				Server: &Server{
+					config: &Config{
+						API: APIConfig{RestrictClientsByGroup: tc.restrict},
+					},
+					clientGroupProvider: &ClientGroupProviderMock{ReturnGroups: []*cgroups.ClientGroup{g1, g2}},
+				},
+				userSrv: users.NewUserCache([]*users.User{admin, user1, user12, noGroups}),
+				Logger:  testLog,
+			}
+			ctx := api.WithUser(context.Background(), tc.username)
+
+			// when
+			gotAccess, gotErr := al.getClientAccess(ctx)
+
+			// then
+			require.NoError(t, gotErr)
+			assert.Equal(t, tc.wantClients, gotAccess.Filter(allClients))
+			for _, c := range allClients {
+				assert.Equal(t, containsClient(tc.wantClients, c), gotAccess.Allowed(c))
+			}
+		})
+	}
+}
+
+func containsClient(list []*clients.Client, c *clients.Client) bool {
+	for _, cur := range list {
+		if cur == c {
+			return true
+		}
+	}
+	return false
+}
+
+func TestFilterMultiJob(t *testing.T) {
+	job := &models.MultiJob{
+		ClientIDs: []string{"client-1", "client-2", "client-3"},
+		Jobs: []*models.Job{
+			{JobSummary: models.JobSummary{JID: "1"}, ClientID: "client-1"},
+			{JobSummary: models.JobSummary{JID: "2"}, ClientID: "client-2"},
+		},
+	}
+
+	filterMultiJob(job, []string{"client-2", "client-3"})
+
+	assert.Equal(t, []string{"client-2", "client-3"}, job.ClientIDs)
+	require.Len(t, job.Jobs, 1)
+	assert.Equal(t, "2", job.Jobs[0].JID)
+}
@@ -0,0 +1,86 @@
+package chserver
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/cloudradar-monitoring/rport/server/api"
+	"github.com/cloudradar-monitoring/rport/server/api/jobs"
+)
+
+const (
+	jobsMaxLimit = 1000
+
+	queryParamCommand       = "filter[command]"
+	queryParamStartedSince  = "filter[started_since]"
+	queryParamStartedUntil  = "filter[started_until]"
+	queryParamFinishedSince = "filter[finished_since]"
+	queryParamFinishedUntil = "filter[finished_until]"
+)
+
+// parseJobListOptions returns options to list jobs requested by given query params. filterFields and sortFields are
+// fields the jobs can be filtered and sorted by. withFinished defines whether jobs can be filtered by finished time.
+func parseJobListOptions(values url.Values, filterFields, sortFields []string, withFinished bool) (*jobs.ListOptions, error) {
+	opts := &jobs.ListOptions{}
+
+	specialParams := map[string]bool{
+		queryParamCommand:      true,
+		queryParamStartedSince: true,
+		queryParamStartedUntil: true,
+	}
+	if withFinished {
+		specialParams[queryParamFinishedSince] = true
+		specialParams[queryParamFinishedUntil] = true
+	}
+	// command and time ranges are not regular fields, so exclude them before parsing other filters
+	filterValues := url.Values{}
+	for k, v := range values {
+		if !specialParams[k] {
+			filterValues[k] = v
+		}
+	}
+
+	var err error
+	opts.Filters, err = api.ParseFilters(filterValues, filterFields...)
+	if err != nil {
+		return nil, err
+	}
+
+	opts.Command = values.Get(queryParamCommand)
+
+	opts.StartedSince, err = parseTimeQueryParam(values, queryParamStartedSince)
+	if err != nil {
+		return nil, err
+	}
+	opts.StartedUntil, err = parseTimeQueryParam(values, queryParamStartedUntil)
+	if err != nil {
+		return nil, err
+	}
+	if withFinished {
+		opts.FinishedSince, err = parseTimeQueryParam(values, queryParamFinishedSince)
+		if err != nil {
+			return nil, err
+		}
+		opts.FinishedUntil, err = parseTimeQueryParam(values, queryParamFinishedUntil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if sortStr := values.Get(queryParamSort); sortStr != "" {
+		opts.Sort = strings.TrimPrefix(sortStr, "-")
+		opts.SortDesc = strings.HasPrefix(sortStr, "-")
+		if err := jobs.ValidateSortField(opts.Sort, sortFields); err != nil {
+			return nil, fmt.Errorf("invalid %q query param: %v", queryParamSort, err)
+		}
+	}
+
+	// by default all jobs are returned
+	opts.Pagination, err = api.ParsePagination(values, 0, jobsMaxLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	return opts, nil
+}
@@ -0,0 +1,257 @@
+package chserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/cloudradar-monitoring/rport/server/api"
+	"github.com/cloudradar-monitoring/rport/server/auditlog"
+	"github.com/cloudradar-monitoring/rport/server/library"
+	"github.com/cloudradar-monitoring/rport/share/random"
+)
+
+const (
+	routeParamScriptID = "script_id"
+
+	queryParamVersion   = "version"
+	queryParamFilterTag = "filter[tag]"
+)
+
+// scriptRequest is a request to create or update a library script.
+type scriptRequest struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Interpreter string          `json:"interpreter"`
+	Script      string          `json:"script"`
+	Params      []library.Param `json:"params"`
+	Tags        []string        `json:"tags"`
+}
+
+var generateNewScriptID = func() string {
+	return random.UUID4()
+}
+
+func (al *APIListener) handleGetLibraryScripts(w http.ResponseWriter, req *http.Request) {
+	scripts, err := al.libraryProvider.GetAll(req.Context())
+	if err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", "Failed to get library scripts.", err)
+		return
+	}
+
+	tag := req.URL.Query().Get(queryParamFilterTag)
+	res := make([]*library.Script, 0, len(scripts))
+	for _, cur := range scripts {
+		if tag == "" || cur.HasTag(tag) {
+			res = append(res, cur)
+		}
+	}
+
+	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(res))
+}
+
+func (al *APIListener) handleGetLibraryScript(w http.ResponseWriter, req *http.Request) {
+	id := mux.Vars(req)[routeParamScriptID]
+
+	var version int
+	if v := req.URL.Query().Get(queryParamVersion); v != "" {
+		var err error
+		version, err = strconv.Atoi(v)
+		if err != nil || version <= 0 {
+			al.jsonErrorResponseWithErrCode(w, http.StatusBadRequest, ErrCodeInvalidRequest, fmt.Sprintf("Invalid %q query param: expected a positive integer, actual: %s.", queryParamVersion, v))
+			return
+		}
+	}
+
+	script, err := al.libraryProvider.Get(req.Context(), id, version)
+	if err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", fmt.Sprintf("Failed to find library script[id=%q].", id), err)
+		return
+	}
+	if script == nil {
+		al.jsonErrorResponseWithTitle(w, http.StatusNotFound, fmt.Sprintf("Library script[id=%q] not found.", id))
+		return
+	}
+
+	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(script))
+}
+
+func (al *APIListener) handlePostLibraryScripts(w http.ResponseWriter, req *http.Request) {
+	script, ok := al.parseScriptRequest(w, req)
+	if !ok {
+		return
+	}
+	script.ID = generateNewScriptID()
+
+	if err := al.libraryProvider.Save(req.Context(), script); err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", "Failed to persist a new library script.", err)
+		return
+	}
+
+	al.auditLog.Entry(auditlog.ApplicationLibraryScript, auditlog.ActionCreate).
+		WithHTTPRequest(req).
+		WithID(script.ID).
+		Save()
+
+	al.writeJSONResponse(w, http.StatusCreated, api.NewSuccessPayload(script))
+	al.Debugf("Library script [id=%q] created.", script.ID)
+}
+
+func (al *APIListener) handlePutLibraryScript(w http.ResponseWriter, req *http.Request) {
+	id := mux.Vars(req)[routeParamScriptID]
+
+	existing, err := al.libraryProvider.Get(req.Context(), id, 0)
+	if err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", fmt.Sprintf("Failed to find library script[id=%q].", id), err)
+		return
+	}
+	if existing == nil {
+		al.jsonErrorResponseWithTitle(w, http.StatusNotFound, fmt.Sprintf("Library script[id=%q] not found.", id))
+		return
+	}
+
+	script, ok := al.parseScriptRequest(w, req)
+	if !ok {
+		return
+	}
+	script.ID = id
+
+	if err := al.libraryProvider.Save(req.Context(), script); err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", "Failed to persist library script.", err)
+		return
+	}
+
+	al.auditLog.Entry(auditlog.ApplicationLibraryScript, auditlog.ActionUpdate).
+		WithHTTPRequest(req).
+		WithID(script.ID).
+		Save()
+
+	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(script))
+	al.Debugf("Library script [id=%q] updated to version %d.", script.ID, script.Version)
+}
+
+func (al *APIListener) handleDeleteLibraryScript(w http.ResponseWriter, req *http.Request) {
+	id := mux.Vars(req)[routeParamScriptID]
+
+	existing, err := al.libraryProvider.Get(req.Context(), id, 0)
+	if err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", fmt.Sprintf("Failed to find library script[id=%q].", id), err)
+		return
+	}
+	if existing == nil {
+		al.jsonErrorResponseWithTitle(w, http.StatusNotFound, fmt.Sprintf("Library script[id=%q] not found.", id))
+		return
+	}
+
+	if err := al.libraryProvider.Delete(req.Context(), id); err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", fmt.Sprintf("Failed to delete library script[id=%q].", id), err)
+		return
+	}
+
+	al.auditLog.Entry(auditlog.ApplicationLibraryScript, auditlog.ActionDelete).
+		WithHTTPRequest(req).
+		WithID(id).
+		Save()
+
+	w.WriteHeader(http.StatusNoContent)
+	al.Debugf("Library script [id=%q] deleted.", id)
+}
+
+// parseScriptRequest returns a new version of a library script from a given request. If the request is invalid,
+// writes an error response and returns false.
+func (al *APIListener) parseScriptRequest(w http.ResponseWriter, req *http.Request) (*library.Script, bool) {
+	var reqBody scriptRequest
+	dec := json.NewDecoder(req.Body)
+	dec.DisallowUnknownFields()
+	err := dec.Decode(&reqBody)
+	if err == io.EOF { // is handled separately to return an informative error message
+		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, "Missing body with json data.")
+		return nil, false
+	} else if err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusBadRequest, "", "Invalid JSON data.", err)
+		return nil, false
+	}
+
+	script := &library.Script{
+		Name:        reqBody.Name,
+		Description: reqBody.Description,
+		Interpreter: reqBody.Interpreter,
+		Script:      reqBody.Script,
+		Params:      reqBody.Params,
+		Tags:        reqBody.Tags,
+		CreatedBy:   api.GetUser(req.Context(), al.Logger),
+		CreatedAt:   time.Now(),
+	}
+	if err := script.Validate(); err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusBadRequest, "", "Invalid library script.", err)
+		return nil, false
+	}
+	return script, true
+}
+
+// validateLibraryScript checks that a library script is requested without an inline command or script, and params are
+// given only for a library script.
+func validateLibraryScript(scriptID string, scriptVersion int, params map[string]string, command, shell, script, interpreter string) error {
+	if scriptID == "" {
+		if scriptVersion != 0 {
+			return errors.New("script_version can be specified only with script_id")
+		}
+		if len(params) > 0 {
+			return errors.New("params can be specified only for a library script")
+		}
+		return nil
+	}
+	if command != "" || shell != "" || script != "" || interpreter != "" {
+		return errors.New("script_id cannot be specified together with command, shell, script or interpreter")
+	}
+	if scriptVersion < 0 {
+		return fmt.Errorf("invalid script_version: %d", scriptVersion)
+	}
+	return nil
+}
+
+// libraryScriptEnv returns env vars to run a given library script with given param values and additional env vars.
+func libraryScriptEnv(script *library.Script, params, env map[string]string) (map[string]string, error) {
+	res, err := script.Env(params)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range env {
+		if _, ok := res[name]; ok {
+			return nil, fmt.Errorf("env variable %q conflicts with the script param with the same name", name)
+		}
+		res[name] = value
+	}
+	return res, nil
+}
+
+// resolveLibraryScript replaces a library script with a given ID referenced by a command request with its content:
+// it sets the script, its interpreter and version and merges given param values into env. Does nothing if scriptID is empty.
+func (al *APIListener) resolveLibraryScript(ctx context.Context, scriptID string, params map[string]string, scriptVersion *int, env *map[string]string, script, interpreter *string) *apiError {
+	if scriptID == "" {
+		return nil
+	}
+	libScript, err := al.libraryProvider.Get(ctx, scriptID, *scriptVersion)
+	if err != nil {
+		return &apiError{code: http.StatusInternalServerError, title: fmt.Sprintf("Failed to find a library script with id=%q.", scriptID), err: err}
+	}
+	if libScript == nil {
+		return &apiError{code: http.StatusBadRequest, title: fmt.Sprintf("Unknown library script with id=%q.", scriptID)}
+	}
+	scriptEnv, err := libraryScriptEnv(libScript, params, *env)
+	if err != nil {
+		return &apiError{code: http.StatusBadRequest, title: "Invalid script params.", err: err}
+	}
+	*env = scriptEnv
+	*script = libScript.Script
+	*interpreter = libScript.Interpreter
+	*scriptVersion = libScript.Version
+	return nil
+}
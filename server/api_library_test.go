@@ -0,0 +1,235 @@
+package chserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudradar-monitoring/rport/server/api"
+	"github.com/cloudradar-monitoring/rport/server/clients"
+	"github.com/cloudradar-monitoring/rport/server/library"
+	"github.com/cloudradar-monitoring/rport/share/comm"
+	"github.com/cloudradar-monitoring/rport/share/test"
+)
+
+func TestHandleLibraryScripts(t *testing.T) {
+	provider, err := library.NewSqliteProvider(":memory:")
+	require.NoError(t, err)
+	defer provider.Close()
+
+	generateNewScriptID = func() string {
+		return "script-1"
+	}
+
+	al := APIListener{
+		insecureForTests: true,
+		Server: &Server{
+			config: &Config{
+				Server: ServerConfig{
+					MaxRequestBytes: 1024 * 1024,
+				},
+			},
+			libraryProvider: provider,
+		},
+		Logger: testLog,
+	}
+	al.initRouter()
+
+	do := func(method, url, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		req = req.WithContext(api.WithUser(context.Background(), "admin"))
+		w := httptest.NewRecorder()
+		al.router.ServeHTTP(w, req)
+		return w
+	}
+	getScript := func(w *httptest.ResponseRecorder) *library.Script {
+		resp := struct {
+			Data *library.Script `json:"data"`
+		}{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp.Data
+	}
+
+	// create
+	w := do(http.MethodPost, "/api/v1/library/scripts", `{"name": "Clean up", "script": "rm -rf $DIR/*", "params": [{"name": "DIR", "default": "/tmp"}], "tags": ["maintenance"]}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	created := getScript(w)
+	assert.Equal(t, "script-1", created.ID)
+	assert.Equal(t, 1, created.Version)
+	assert.Equal(t, "admin", created.CreatedBy)
+
+	// invalid script
+	w = do(http.MethodPost, "/api/v1/library/scripts", `{"name": "Clean up", "script": ""}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, `{"errors":[{"code":"","title":"Invalid library script.","detail":"script cannot be empty"}]}`, w.Body.String())
+
+	// update creates a new version
+	w = do(http.MethodPut, "/api/v1/library/scripts/script-1", `{"name": "Clean up", "script": "rm -rf $DIR/*.tmp", "tags": ["maintenance"]}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, 2, getScript(w).Version)
+
+	w = do(http.MethodPut, "/api/v1/library/scripts/unknown", `{"name": "Clean up", "script": "uptime"}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// get
+	w = do(http.MethodGet, "/api/v1/library/scripts/script-1", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "rm -rf $DIR/*.tmp", getScript(w).Script)
+
+	w = do(http.MethodGet, "/api/v1/library/scripts/script-1?version=1", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "rm -rf $DIR/*", getScript(w).Script)
+
+	w = do(http.MethodGet, "/api/v1/library/scripts/script-1?version=0", "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = do(http.MethodGet, "/api/v1/library/scripts/script-1?version=3", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// list
+	w = do(http.MethodGet, "/api/v1/library/scripts?filter[tag]=Maintenance", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"id":"script-1","version":2`)
+
+	w = do(http.MethodGet, "/api/v1/library/scripts?filter[tag]=unknown", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"data":[]}`, w.Body.String())
+
+	// delete
+	w = do(http.MethodDelete, "/api/v1/library/scripts/script-1", "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = do(http.MethodDelete, "/api/v1/library/scripts/script-1", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestHandlePostCommandWithLibraryScript(t *testing.T) {
+	ctx := context.Background()
+	provider, err := library.NewSqliteProvider(":memory:")
+	require.NoError(t, err)
+	defer provider.Close()
+
+	defaultDir := "/tmp"
+	script := &library.Script{
+		ID:          "script-1",
+		Name:        "Clean up",
+		Interpreter: "/bin/bash",
+		Script:      "rm -rf $DIR/*",
+		Params:      []library.Param{{Name: "DIR", Default: &defaultDir}, {Name: "DAYS"}},
+		CreatedAt:   time.Now(),
+	}
+	require.NoError(t, provider.Save(ctx, script))
+
+	connMock := test.NewConnMock()
+	connMock.ReturnOk = true
+	sshRespBytes, err := json.Marshal(comm.RunCmdResponse{Pid: 123})
+	require.NoError(t, err)
+	connMock.ReturnResponsePayload = sshRespBytes
+	c1 := clients.New(t).Connection(connMock).Build()
+
+	testCases := []struct {
+		name        string
+		requestBody string
+
+		wantStatusCode int
+		wantErrTitle   string
+		wantErrDetail  string
+		wantEnv        map[string]string
+	}{
+		{
+			name:           "defaults and env",
+			requestBody:    `{"script_id": "script-1", "params": {"DAYS": "7"}, "env": {"LANG": "C"}}`,
+			wantStatusCode: http.StatusOK,
+			wantEnv:        map[string]string{"DIR": "/tmp", "DAYS": "7", "LANG": "C"},
+		},
+		{
+			name:           "missing required param",
+			requestBody:    `{"script_id": "script-1", "script_version": 1}`,
+			wantStatusCode: http.StatusBadRequest,
+			wantErrTitle:   "Invalid script params.",
+			wantErrDetail:  `missing required param "DAYS"`,
+		},
+		{
+			name:           "env conflicts with param",
+			requestBody:    `{"script_id": "script-1", "params": {"DAYS": "7"}, "env": {"DIR": "/"}}`,
+			wantStatusCode: http.StatusBadRequest,
+			wantErrTitle:   "Invalid script params.",
+			wantErrDetail:  `env variable "DIR" conflicts with the script param with the same name`,
+		},
+		{
+			name:           "unknown script",
+			requestBody:    `{"script_id": "unknown"}`,
+			wantStatusCode: http.StatusBadRequest,
+			wantErrTitle:   `Unknown library script with id="unknown".`,
+		},
+		{
+			name:           "script id and command",
+			requestBody:    `{"script_id": "script-1", "command": "uptime"}`,
+			wantStatusCode: http.StatusBadRequest,
+			wantErrTitle:   "Invalid script.",
+			wantErrDetail:  "script_id cannot be specified together with command, shell, script or interpreter",
+		},
+		{
+			name:           "params without script id",
+			requestBody:    `{"command": "uptime", "params": {"DAYS": "7"}}`,
+			wantStatusCode: http.StatusBadRequest,
+			wantErrTitle:   "Invalid script.",
+			wantErrDetail:  "params can be specified only for a library script",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			al := APIListener{
+				insecureForTests: true,
+				Server: &Server{
+					clientService:   NewClientService(nil, clients.NewClientRepository([]*clients.Client{c1}, &hour)),
+					libraryProvider: provider,
+					config: &Config{
+						Server: ServerConfig{
+							RunRemoteCmdTimeoutSec: 60,
+							MaxRequestBytes:        1024 * 1024,
+						},
+					},
+				},
+				Logger: testLog,
+			}
+			al.initRouter()
+			jp := NewJobProviderMock()
+			al.jobProvider = jp
+
+			req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/clients/%s/commands", c1.ID), strings.NewReader(tc.requestBody))
+
+			// when
+			w := httptest.NewRecorder()
+			al.router.ServeHTTP(w, req)
+
+			// then
+			assert.Equal(t, tc.wantStatusCode, w.Code, w.Body.String())
+			if tc.wantErrTitle != "" {
+				wantResp := api.NewErrorPayloadWithCode("", tc.wantErrTitle, tc.wantErrDetail)
+				wantRespBytes, err := json.Marshal(wantResp)
+				require.NoError(t, err)
+				assert.Equal(t, string(wantRespBytes), w.Body.String())
+				return
+			}
+			gotJob := jp.InputCreateJob
+			require.NotNil(t, gotJob)
+			assert.Equal(t, "script-1", gotJob.ScriptID)
+			assert.Equal(t, 1, gotJob.ScriptVersion)
+			assert.Equal(t, script.Script, gotJob.Script)
+			assert.Equal(t, script.Interpreter, gotJob.Interpreter)
+			assert.Equal(t, scriptHash(script.Script), gotJob.ScriptHash)
+			assert.Equal(t, tc.wantEnv, gotJob.Env)
+		})
+	}
+}
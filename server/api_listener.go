@@ -183,6 +183,41 @@ func (al *APIListener) lookupUser(r *http.Request) (authorized bool, username st
 	return
 }
 
+// authenticateTunnelProxyRequest returns true if a request to a tunnel in http proxy mode contains valid API credentials
+// of a user with the tunnels permission and an access to the client of the tunnel.
+func (al *APIListener) authenticateTunnelProxyRequest(r *http.Request, clientID string) (bool, error) {
+	if al.userSrv == nil {
+		// API auth is not enabled
+		return false, nil
+	}
+	authorized, username, err := al.lookupUser(r)
+	if err != nil || !authorized {
+		return false, err
+	}
+
+	ctx := api.WithUser(r.Context(), username)
+	curUser, err := al.getCurUser(ctx)
+	if err != nil {
+		return false, err
+	}
+	if curUser == nil || !al.config.GroupPermissions().Has(curUser, users.PermissionTunnels) {
+		return false, nil
+	}
+
+	client, err := al.clientService.GetByID(clientID)
+	if err != nil {
+		return false, fmt.Errorf("failed to find a client with id=%q: %v", clientID, err)
+	}
+	if client == nil {
+		return false, nil
+	}
+	access, err := al.getClientAccess(ctx)
+	if err != nil {
+		return false, err
+	}
+	return access.Allowed(client), nil
+}
+
 func (al *APIListener) handleBearerToken(bearerToken string) (bool, string, error) {
 	authorized, username, apiSession, err := al.validateBearerToken(bearerToken)
 	if err != nil {
@@ -233,7 +268,8 @@ func parseHTTPAuthStr(basicAuth string) (*users.User, error) {
 		return nil, fmt.Errorf("invalid auth format: expected <user>:<password>, actual %s", basicAuth)
 	}
 
-	return &users.User{Username: user, Password: pass}, nil
+	// a single user has full access
+	return &users.User{Username: user, Password: pass, Groups: []string{users.Administrators}}, nil
 }
 
 const WebSocketAccessTokenQueryParam = "access_token"
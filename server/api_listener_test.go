@@ -2,11 +2,16 @@ package chserver
 
 import (
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/cloudradar-monitoring/rport/server/api/users"
+	"github.com/cloudradar-monitoring/rport/server/cgroups"
+	"github.com/cloudradar-monitoring/rport/server/clients"
 )
 
 func TestValidateCredentials(t *testing.T) {
@@ -132,3 +137,97 @@ func TestValidateCredentials(t *testing.T) {
 		assert.Equalf(t, gotRes, tc.wantRes, msg)
 	}
 }
+
+func TestAuthenticateTunnelProxyRequest(t *testing.T) {
+	c1 := clients.New(t).ID("client-1").Build()
+	c2 := clients.New(t).ID("client-2").Build()
+	g1 := &cgroups.ClientGroup{ID: "customer-1", Params: &cgroups.ClientParams{ClientID: &cgroups.ParamValues{"client-1"}}}
+
+	admin := &users.User{Username: "admin", Password: "admin", Groups: []string{users.Administrators}}
+	operator := &users.User{Username: "operator", Password: "operator", Groups: []string{"customer-1"}}
+	helpdesk := &users.User{Username: "helpdesk", Password: "helpdesk", Groups: []string{"helpdesk"}}
+
+	testCases := []struct {
+		name string
+
+		username string
+		password string
+		clientID string
+
+		wantRes bool
+	}{
+		{
+			name:     "admin",
+			username: admin.Username,
+			password: admin.Password,
+			clientID: c2.ID,
+			wantRes:  true,
+		},
+		{
+			name:     "allowed client",
+			username: operator.Username,
+			password: operator.Password,
+			clientID: c1.ID,
+			wantRes:  true,
+		},
+		{
+			name:     "denied client",
+			username: operator.Username,
+			password: operator.Password,
+			clientID: c2.ID,
+			wantRes:  false,
+		},
+		{
+			name:     "unknown client",
+			username: admin.Username,
+			password: admin.Password,
+			clientID: "unknown",
+			wantRes:  false,
+		},
+		{
+			name:     "missing tunnels permission",
+			username: helpdesk.Username,
+			password: helpdesk.Password,
+			clientID: c1.ID,
+			wantRes:  false,
+		},
+		{
+			name:     "invalid credentials",
+			username: operator.Username,
+			password: "wrong",
+			clientID: c1.ID,
+			wantRes:  false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			al := &APIListener{
+				Server: &Server{
+					config: &Config{
+						API: APIConfig{
+							RestrictClientsByGroup: true,
+							groupPermissions: users.GroupPermissions{
+								"customer-1": {users.PermissionTunnels},
+							},
+						},
+					},
+					clientService:       NewClientService(nil, clients.NewClientRepository([]*clients.Client{c1, c2}, nil)),
+					clientGroupProvider: &ClientGroupProviderMock{ReturnGroups: []*cgroups.ClientGroup{g1}},
+				},
+				userSrv: users.NewUserCache([]*users.User{admin, operator, helpdesk}),
+				Logger:  testLog,
+			}
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.SetBasicAuth(tc.username, tc.password)
+
+			// when
+			gotRes, gotErr := al.authenticateTunnelProxyRequest(req, tc.clientID)
+
+			// then
+			require.NoError(t, gotErr)
+			assert.Equal(t, tc.wantRes, gotRes)
+		})
+	}
+}
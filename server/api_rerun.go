@@ -0,0 +1,194 @@
+package chserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+
+	"github.com/gorilla/mux"
+
+	"github.com/cloudradar-monitoring/rport/share/models"
+)
+
+// rerunRequest is an optional request body to re-run a multi-client job.
+type rerunRequest struct {
+	// FailedOnly defines whether to re-run the job only on the clients the previous run failed on.
+	FailedOnly bool `json:"failed_only"`
+}
+
+// rerunCmdRequest is an optional request body to re-run a job.
+type rerunCmdRequest struct {
+	QueueIfOffline bool `json:"queue_if_offline"`
+	QueueExpirySec int  `json:"queue_expiry_sec"`
+}
+
+// handlePostCommandRerun creates a new job that executes the same command on the same client as a given job.
+func (al *APIListener) handlePostCommandRerun(w http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	cid := vars[routeParamClientID]
+	if cid == "" {
+		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, fmt.Sprintf("Missing %q route param.", routeParamClientID))
+		return
+	}
+	jid := vars[routeParamJobID]
+	if jid == "" {
+		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, fmt.Sprintf("Missing %q route param.", routeParamJobID))
+		return
+	}
+
+	reqBody := rerunCmdRequest{}
+	dec := json.NewDecoder(req.Body)
+	dec.DisallowUnknownFields()
+	// the body is optional
+	if err := dec.Decode(&reqBody); err != nil && err != io.EOF {
+		al.jsonErrorResponseWithError(w, http.StatusBadRequest, "", "Invalid JSON data.", err)
+		return
+	}
+
+	if !al.allowClientIDAccess(w, req, cid) {
+		return
+	}
+
+	prevJob, err := al.jobProvider.GetByJID(cid, jid)
+	if err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", fmt.Sprintf("Failed to find a job[id=%q].", jid), err)
+		return
+	}
+	if prevJob == nil {
+		al.jsonErrorResponseWithTitle(w, http.StatusNotFound, fmt.Sprintf("Job[id=%q] not found.", jid))
+		return
+	}
+
+	cmdReq := newRerunCmdRequest(prevJob)
+	cmdReq.QueueIfOffline = reqBody.QueueIfOffline
+	cmdReq.QueueExpirySec = reqBody.QueueExpirySec
+
+	al.startJob(w, req, cid, cmdReq)
+}
+
+// handlePostMultiClientCommandRerun creates a new multi-client job that executes the same command on the same targets
+// as a given multi-client job or only on the clients it failed on.
+func (al *APIListener) handlePostMultiClientCommandRerun(w http.ResponseWriter, req *http.Request) {
+	vars := mux.Vars(req)
+	jid := vars[routeParamJobID]
+	if jid == "" {
+		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, fmt.Sprintf("Missing %q route param.", routeParamJobID))
+		return
+	}
+
+	reqBody := rerunRequest{}
+	dec := json.NewDecoder(req.Body)
+	dec.DisallowUnknownFields()
+	// the body is optional
+	if err := dec.Decode(&reqBody); err != nil && err != io.EOF {
+		al.jsonErrorResponseWithError(w, http.StatusBadRequest, "", "Invalid JSON data.", err)
+		return
+	}
+
+	prevJob, err := al.jobProvider.GetMultiJob(jid)
+	if err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", fmt.Sprintf("Failed to find a multi-client job[id=%q].", jid), err)
+		return
+	}
+	if prevJob == nil {
+		al.jsonErrorResponseWithTitle(w, http.StatusNotFound, fmt.Sprintf("Multi-client Job[id=%q] not found.", jid))
+		return
+	}
+
+	access, err := al.getClientAccess(req.Context())
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+	allowedIDs, err := al.allowedClientIDs(access)
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+	if allowedIDs != nil {
+		// re-run only on the clients the user has access to, and don't reveal a job that has none of them
+		filterMultiJob(prevJob, allowedIDs)
+		if len(prevJob.Jobs) == 0 && len(prevJob.ClientIDs) == 0 {
+			al.jsonErrorResponseWithTitle(w, http.StatusNotFound, fmt.Sprintf("Multi-client Job[id=%q] not found.", jid))
+			return
+		}
+	}
+
+	cmdReq := newRerunMultiClientCmdRequest(prevJob)
+	minClients := 2
+	if reqBody.FailedOnly {
+		cmdReq.ClientIDs = failedClientIDs(prevJob)
+		cmdReq.GroupIDs = nil
+		if len(cmdReq.ClientIDs) == 0 {
+			al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, fmt.Sprintf("Multi-client Job[id=%q] has no failed clients.", jid))
+			return
+		}
+		minClients = 1
+	}
+
+	al.startMultiClientJob(w, req, cmdReq, minClients)
+}
+
+// newRerunCmdRequest returns a request to execute the same command as a given job. A library script is not resolved
+// again, so exactly the same script version and params are used.
+func newRerunCmdRequest(prevJob *models.Job) *cmdRequest {
+	return &cmdRequest{
+		Command:          prevJob.Command,
+		Shell:            prevJob.Shell,
+		Interpreter:      prevJob.Interpreter,
+		Script:           prevJob.Script,
+		ScriptID:         prevJob.ScriptID,
+		ScriptVersion:    prevJob.ScriptVersion,
+		Cwd:              prevJob.Cwd,
+		Env:              prevJob.Env,
+		RunAsUser:        prevJob.RunAsUser,
+		TimeoutSec:       prevJob.TimeoutSec,
+		TimeoutPolicy:    prevJob.TimeoutPolicy,
+		TimeoutGraceSec:  prevJob.TimeoutGraceSec,
+		SuccessExitCodes: prevJob.SuccessExitCodes,
+	}
+}
+
+// newRerunMultiClientCmdRequest returns a request to execute the same command as a given multi-client job on the same
+// targets. A library script is not resolved again, so exactly the same script version and params are used.
+func newRerunMultiClientCmdRequest(prevJob *models.MultiJob) *multiClientCmdRequest {
+	abortOnErr := prevJob.AbortOnErr
+	return &multiClientCmdRequest{
+		ClientIDs:           prevJob.ClientIDs,
+		GroupIDs:            prevJob.GroupIDs,
+		Command:             prevJob.Command,
+		Shell:               prevJob.Shell,
+		Interpreter:         prevJob.Interpreter,
+		Script:              prevJob.Script,
+		ScriptID:            prevJob.ScriptID,
+		ScriptVersion:       prevJob.ScriptVersion,
+		Cwd:                 prevJob.Cwd,
+		Env:                 prevJob.Env,
+		RunAsUser:           prevJob.RunAsUser,
+		TimeoutSec:          prevJob.TimeoutSec,
+		TimeoutPolicy:       prevJob.TimeoutPolicy,
+		TimeoutGraceSec:     prevJob.TimeoutGraceSec,
+		SuccessExitCodes:    prevJob.SuccessExitCodes,
+		ExecuteConcurrently: prevJob.Concurrent,
+		AbortOnError:        &abortOnErr,
+		BatchSize:           prevJob.BatchSize,
+		BatchPercent:        prevJob.BatchPercent,
+		BatchByGroup:        prevJob.BatchByGroup,
+		BatchPauseSec:       prevJob.BatchPauseSec,
+		MaxFailurePercent:   prevJob.MaxFailurePercent,
+	}
+}
+
+// failedClientIDs returns sorted IDs of clients the child jobs of a given multi-client job finished unsuccessfully on.
+func failedClientIDs(multiJob *models.MultiJob) []string {
+	var res []string
+	for _, job := range multiJob.Jobs {
+		if job.FinishedAt != nil && job.Status != models.JobStatusSuccessful {
+			res = append(res, job.ClientID)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
@@ -0,0 +1,335 @@
+package chserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudradar-monitoring/rport/server/api"
+	"github.com/cloudradar-monitoring/rport/server/api/jobs"
+	"github.com/cloudradar-monitoring/rport/server/api/users"
+	"github.com/cloudradar-monitoring/rport/server/cgroups"
+	"github.com/cloudradar-monitoring/rport/server/clients"
+	"github.com/cloudradar-monitoring/rport/server/test/jb"
+	"github.com/cloudradar-monitoring/rport/share/comm"
+	"github.com/cloudradar-monitoring/rport/share/models"
+	"github.com/cloudradar-monitoring/rport/share/test"
+)
+
+func newRerunTestConn(t *testing.T) *test.ConnMock {
+	connMock := test.NewConnMock()
+	connMock.ReturnOk = true
+	sshRespBytes, err := json.Marshal(comm.RunCmdResponse{Pid: 1, StartedAt: time.Date(2020, 10, 10, 10, 10, 1, 0, time.UTC)})
+	require.NoError(t, err)
+	connMock.ReturnResponsePayload = sshRespBytes
+	return connMock
+}
+
+func TestHandlePostCommandRerun(t *testing.T) {
+	c1 := clients.New(t).ID("client-1").Connection(newRerunTestConn(t)).Build()
+	c2 := clients.New(t).ID("client-2").DisconnectedDuration(5 * time.Minute).Build()
+
+	jp, err := jobs.NewSqliteProvider(":memory:", testLog)
+	require.NoError(t, err)
+	defer jp.Close()
+
+	prevJob := jb.New(t).ClientID(c1.ID).Status(models.JobStatusFailed).FinishedAt(time.Now()).Build()
+	prevJob.Shell = "powershell"
+	prevJob.Cwd = "/tmp"
+	prevJob.TimeoutPolicy = models.JobTimeoutPolicyKill
+	prevJob.TimeoutGraceSec = 5
+	require.NoError(t, jp.CreateJob(prevJob))
+	offlineClientJob := jb.New(t).ClientID(c2.ID).Status(models.JobStatusSuccessful).FinishedAt(time.Now()).Build()
+	require.NoError(t, jp.CreateJob(offlineClientJob))
+
+	al := APIListener{
+		insecureForTests: true,
+		Server: &Server{
+			clientService: NewClientService(nil, clients.NewClientRepository([]*clients.Client{c1, c2}, &hour)),
+			config: &Config{
+				Server: ServerConfig{MaxRequestBytes: 1024 * 1024},
+			},
+			jobProvider: jp,
+		},
+		Logger: testLog,
+	}
+	al.initRouter()
+
+	testCases := []struct {
+		name           string
+		clientID       string
+		jid            string
+		requestBody    string
+		wantStatusCode int
+		wantJobStatus  string
+	}{
+		{
+			name:           "valid",
+			clientID:       c1.ID,
+			jid:            prevJob.JID,
+			wantStatusCode: http.StatusOK,
+			wantJobStatus:  models.JobStatusRunning,
+		},
+		{
+			name:           "unknown job",
+			clientID:       c1.ID,
+			jid:            "unknown",
+			wantStatusCode: http.StatusNotFound,
+		},
+		{
+			name:           "disconnected client",
+			clientID:       c2.ID,
+			jid:            offlineClientJob.JID,
+			wantStatusCode: http.StatusNotFound,
+		},
+		{
+			name:           "disconnected client, queue if offline",
+			clientID:       c2.ID,
+			jid:            offlineClientJob.JID,
+			requestBody:    `{"queue_if_offline": true}`,
+			wantStatusCode: http.StatusOK,
+			wantJobStatus:  models.JobStatusQueued,
+		},
+		{
+			name:           "invalid body",
+			clientID:       c1.ID,
+			jid:            prevJob.JID,
+			requestBody:    `{"unknown": true}`,
+			wantStatusCode: http.StatusBadRequest,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// when
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/clients/"+tc.clientID+"/commands/"+tc.jid+"/rerun", strings.NewReader(tc.requestBody))
+			req = req.WithContext(api.WithUser(context.Background(), "other-user"))
+			al.router.ServeHTTP(w, req)
+
+			// then
+			require.Equal(t, tc.wantStatusCode, w.Code)
+			if tc.wantStatusCode != http.StatusOK {
+				return
+			}
+			gotResp := struct {
+				Data newJobResponse `json:"data"`
+			}{}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &gotResp))
+			gotJob, err := jp.GetByJID(tc.clientID, gotResp.Data.JID)
+			require.NoError(t, err)
+			require.NotNil(t, gotJob)
+			wantJob, err := jp.GetByJID(tc.clientID, tc.jid)
+			require.NoError(t, err)
+			assert.NotEqual(t, wantJob.JID, gotJob.JID)
+			assert.Equal(t, tc.wantJobStatus, gotJob.Status)
+			assert.Equal(t, "other-user", gotJob.CreatedBy)
+			assert.Equal(t, wantJob.Command, gotJob.Command)
+			assert.Equal(t, wantJob.Shell, gotJob.Shell)
+			assert.Equal(t, wantJob.Cwd, gotJob.Cwd)
+			assert.Equal(t, wantJob.TimeoutSec, gotJob.TimeoutSec)
+			assert.Equal(t, wantJob.TimeoutPolicy, gotJob.TimeoutPolicy)
+			assert.Equal(t, wantJob.TimeoutGraceSec, gotJob.TimeoutGraceSec)
+		})
+	}
+}
+
+func TestHandlePostMultiClientCommandRerun(t *testing.T) {
+	c1 := clients.New(t).ID("client-1").Connection(newRerunTestConn(t)).Build()
+	c2 := clients.New(t).ID("client-2").Connection(newRerunTestConn(t)).Build()
+	c3 := clients.New(t).ID("client-3").Connection(newRerunTestConn(t)).Build()
+
+	jp, err := jobs.NewSqliteProvider(":memory:", testLog)
+	require.NoError(t, err)
+	defer jp.Close()
+
+	prevJob := jb.NewMulti(t).ClientIDs(c1.ID, c2.ID, c3.ID).Build()
+	prevJob.Shell = "powershell"
+	require.NoError(t, jp.SaveMultiJob(prevJob))
+	now := time.Now()
+	require.NoError(t, jp.CreateJob(jb.New(t).ClientID(c1.ID).MultiJobID(prevJob.JID).Status(models.JobStatusSuccessful).FinishedAt(now).Build()))
+	require.NoError(t, jp.CreateJob(jb.New(t).ClientID(c2.ID).MultiJobID(prevJob.JID).Status(models.JobStatusFailed).FinishedAt(now).Build()))
+	require.NoError(t, jp.CreateJob(jb.New(t).ClientID(c3.ID).MultiJobID(prevJob.JID).Status(models.JobStatusTimeout).FinishedAt(now).Build()))
+
+	successfulJob := jb.NewMulti(t).ClientIDs(c1.ID, c2.ID).Build()
+	require.NoError(t, jp.SaveMultiJob(successfulJob))
+	require.NoError(t, jp.CreateJob(jb.New(t).ClientID(c1.ID).MultiJobID(successfulJob.JID).Status(models.JobStatusSuccessful).FinishedAt(now).Build()))
+
+	testCases := []struct {
+		name           string
+		jid            string
+		requestBody    string
+		wantStatusCode int
+		wantClientIDs  []string
+	}{
+		{
+			name:           "all clients",
+			jid:            prevJob.JID,
+			wantStatusCode: http.StatusOK,
+			wantClientIDs:  []string{c1.ID, c2.ID, c3.ID},
+		},
+		{
+			name:           "failed only",
+			jid:            prevJob.JID,
+			requestBody:    `{"failed_only": true}`,
+			wantStatusCode: http.StatusOK,
+			wantClientIDs:  []string{c2.ID, c3.ID},
+		},
+		{
+			name:           "no failed clients",
+			jid:            successfulJob.JID,
+			requestBody:    `{"failed_only": true}`,
+			wantStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:           "unknown job",
+			jid:            "unknown",
+			wantStatusCode: http.StatusNotFound,
+		},
+		{
+			name:           "invalid body",
+			jid:            prevJob.JID,
+			requestBody:    `{"unknown": true}`,
+			wantStatusCode: http.StatusBadRequest,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			done := make(chan bool)
+			al := APIListener{
+				insecureForTests: true,
+				Server: &Server{
+					clientService: NewClientService(nil, clients.NewClientRepository([]*clients.Client{c1, c2, c3}, &hour)),
+					config: &Config{
+						Server: ServerConfig{MaxRequestBytes: 1024 * 1024},
+					},
+					jobProvider: jp,
+					jobsDoneChannel: jobResultChanMap{
+						m: make(map[string]chan *models.Job),
+					},
+				},
+				Logger:   testLog,
+				testDone: done,
+			}
+			al.initRouter()
+
+			// when
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/commands/"+tc.jid+"/rerun", strings.NewReader(tc.requestBody))
+			req = req.WithContext(api.WithUser(context.Background(), "other-user"))
+			al.router.ServeHTTP(w, req)
+
+			// then
+			require.Equal(t, tc.wantStatusCode, w.Code)
+			if tc.wantStatusCode != http.StatusOK {
+				return
+			}
+			<-done
+			gotResp := struct {
+				Data newJobResponse `json:"data"`
+			}{}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &gotResp))
+			gotJob, err := jp.GetMultiJob(gotResp.Data.JID)
+			require.NoError(t, err)
+			require.NotNil(t, gotJob)
+			assert.Equal(t, tc.wantClientIDs, gotJob.ClientIDs)
+			assert.Len(t, gotJob.Jobs, len(tc.wantClientIDs))
+			assert.Equal(t, "other-user", gotJob.CreatedBy)
+			assert.Equal(t, prevJob.Command, gotJob.Command)
+			assert.Equal(t, prevJob.Shell, gotJob.Shell)
+			assert.Equal(t, prevJob.TimeoutSec, gotJob.TimeoutSec)
+		})
+	}
+}
+
+func TestHandlePostMultiClientCommandRerunRestricted(t *testing.T) {
+	c1 := clients.New(t).ID("client-1").Connection(newRerunTestConn(t)).Build()
+	c2 := clients.New(t).ID("client-2").Connection(newRerunTestConn(t)).Build()
+	c3 := clients.New(t).ID("client-3").Connection(newRerunTestConn(t)).Build()
+	g1 := &cgroups.ClientGroup{ID: "group-1", Params: &cgroups.ClientParams{ClientID: &cgroups.ParamValues{"client-1"}}}
+
+	jp, err := jobs.NewSqliteProvider(":memory:", testLog)
+	require.NoError(t, err)
+	defer jp.Close()
+
+	now := time.Now()
+	otherJob := jb.NewMulti(t).ClientIDs(c2.ID, c3.ID).Build()
+	require.NoError(t, jp.SaveMultiJob(otherJob))
+	require.NoError(t, jp.CreateJob(jb.New(t).ClientID(c2.ID).MultiJobID(otherJob.JID).Status(models.JobStatusFailed).FinishedAt(now).Build()))
+	mixedJob := jb.NewMulti(t).ClientIDs(c1.ID, c2.ID).Build()
+	require.NoError(t, jp.SaveMultiJob(mixedJob))
+	require.NoError(t, jp.CreateJob(jb.New(t).ClientID(c1.ID).MultiJobID(mixedJob.JID).Status(models.JobStatusFailed).FinishedAt(now).Build()))
+	require.NoError(t, jp.CreateJob(jb.New(t).ClientID(c2.ID).MultiJobID(mixedJob.JID).Status(models.JobStatusFailed).FinishedAt(now).Build()))
+
+	testCases := []struct {
+		name           string
+		jid            string
+		wantStatusCode int
+		wantClientIDs  []string
+	}{
+		{
+			name:           "no accessible clients",
+			jid:            otherJob.JID,
+			wantStatusCode: http.StatusNotFound,
+		},
+		{
+			name:           "some accessible clients",
+			jid:            mixedJob.JID,
+			wantStatusCode: http.StatusOK,
+			wantClientIDs:  []string{c1.ID},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			done := make(chan bool)
+			al := APIListener{
+				insecureForTests: true,
+				Server: &Server{
+					clientService: NewClientService(nil, clients.NewClientRepository([]*clients.Client{c1, c2, c3}, &hour)),
+					config: &Config{
+						Server: ServerConfig{MaxRequestBytes: 1024 * 1024},
+						API:    APIConfig{RestrictClientsByGroup: true},
+					},
+					clientGroupProvider: &ClientGroupProviderMock{ReturnGroups: []*cgroups.ClientGroup{g1}},
+					jobProvider:         jp,
+					jobsDoneChannel: jobResultChanMap{
+						m: make(map[string]chan *models.Job),
+					},
+				},
+				userSrv:  users.NewUserCache([]*users.User{{Username: "user1", Groups: []string{g1.ID}}}),
+				Logger:   testLog,
+				testDone: done,
+			}
+			al.initRouter()
+
+			// when
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/commands/"+tc.jid+"/rerun", strings.NewReader(`{"failed_only": true}`))
+			req = req.WithContext(api.WithUser(context.Background(), "user1"))
+			al.router.ServeHTTP(w, req)
+
+			// then
+			require.Equal(t, tc.wantStatusCode, w.Code)
+			if tc.wantStatusCode != http.StatusOK {
+				return
+			}
+			<-done
+			gotResp := struct {
+				Data newJobResponse `json:"data"`
+			}{}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &gotResp))
+			gotJob, err := jp.GetMultiJob(gotResp.Data.JID)
+			require.NoError(t, err)
+			require.NotNil(t, gotJob)
+			assert.Equal(t, tc.wantClientIDs, gotJob.ClientIDs)
+		})
+	}
+}
@@ -0,0 +1,405 @@
+package chserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/cloudradar-monitoring/rport/server/api"
+	"github.com/cloudradar-monitoring/rport/server/api/users"
+	"github.com/cloudradar-monitoring/rport/server/auditlog"
+	"github.com/cloudradar-monitoring/rport/server/schedules"
+	"github.com/cloudradar-monitoring/rport/share/random"
+)
+
+const (
+	routeParamScheduleID = "schedule_id"
+
+	// schedulesCheckInterval is how often schedules are checked whether they should run. Cron expressions have
+	// a precision of one minute, so it should be less than a minute.
+	schedulesCheckInterval = 10 * time.Second
+)
+
+// scheduleRequest is a request to create or update a schedule.
+type scheduleRequest struct {
+	Name     string `json:"name"`
+	Schedule string `json:"schedule"`
+	Enabled  *bool  `json:"enabled"` // pointer is used because it's default value is true
+	schedules.CommandSpec
+}
+
+var generateNewScheduleID = func() string {
+	return random.UUID4()
+}
+
+func (al *APIListener) handleGetSchedules(w http.ResponseWriter, req *http.Request) {
+	res, err := al.scheduleProvider.GetAll(req.Context())
+	if err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", "Failed to get schedules.", err)
+		return
+	}
+
+	res, err = al.filterSchedules(req.Context(), res)
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	now := time.Now()
+	for _, cur := range res {
+		cur.NextRunAt = cur.Next(now)
+	}
+
+	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(res))
+}
+
+func (al *APIListener) handleGetSchedule(w http.ResponseWriter, req *http.Request) {
+	schedule, ok := al.getScheduleOrWriteError(w, req)
+	if !ok {
+		return
+	}
+
+	schedule.NextRunAt = schedule.Next(time.Now())
+	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(schedule))
+}
+
+func (al *APIListener) handlePostSchedules(w http.ResponseWriter, req *http.Request) {
+	schedule, ok := al.parseScheduleRequest(w, req)
+	if !ok {
+		return
+	}
+	schedule.ID = generateNewScheduleID()
+
+	if err := al.scheduleProvider.Save(req.Context(), schedule); err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", "Failed to persist a new schedule.", err)
+		return
+	}
+
+	al.auditLog.Entry(auditlog.ApplicationSchedule, auditlog.ActionCreate).
+		WithHTTPRequest(req).
+		WithID(schedule.ID).
+		WithParams(scheduleAuditParams(schedule)).
+		Save()
+
+	schedule.NextRunAt = schedule.Next(time.Now())
+	al.writeJSONResponse(w, http.StatusCreated, api.NewSuccessPayload(schedule))
+	al.Debugf("Schedule [id=%q] created.", schedule.ID)
+}
+
+func (al *APIListener) handlePutSchedule(w http.ResponseWriter, req *http.Request) {
+	existing, ok := al.getScheduleOrWriteError(w, req)
+	if !ok {
+		return
+	}
+
+	schedule, ok := al.parseScheduleRequest(w, req)
+	if !ok {
+		return
+	}
+	schedule.ID = existing.ID
+
+	if err := al.scheduleProvider.Save(req.Context(), schedule); err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", "Failed to persist schedule.", err)
+		return
+	}
+
+	al.auditLog.Entry(auditlog.ApplicationSchedule, auditlog.ActionUpdate).
+		WithHTTPRequest(req).
+		WithID(schedule.ID).
+		WithParams(scheduleAuditParams(schedule)).
+		Save()
+
+	schedule.NextRunAt = schedule.Next(time.Now())
+	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(schedule))
+	al.Debugf("Schedule [id=%q] updated.", schedule.ID)
+}
+
+func (al *APIListener) handleDeleteSchedule(w http.ResponseWriter, req *http.Request) {
+	existing, ok := al.getScheduleOrWriteError(w, req)
+	if !ok {
+		return
+	}
+
+	if err := al.scheduleProvider.Delete(req.Context(), existing.ID); err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", fmt.Sprintf("Failed to delete schedule[id=%q].", existing.ID), err)
+		return
+	}
+
+	al.auditLog.Entry(auditlog.ApplicationSchedule, auditlog.ActionDelete).
+		WithHTTPRequest(req).
+		WithID(existing.ID).
+		Save()
+
+	w.WriteHeader(http.StatusNoContent)
+	al.Debugf("Schedule [id=%q] deleted.", existing.ID)
+}
+
+func (al *APIListener) handlePostScheduleEnable(w http.ResponseWriter, req *http.Request) {
+	al.setScheduleEnabled(w, req, true)
+}
+
+func (al *APIListener) handlePostScheduleDisable(w http.ResponseWriter, req *http.Request) {
+	al.setScheduleEnabled(w, req, false)
+}
+
+func (al *APIListener) setScheduleEnabled(w http.ResponseWriter, req *http.Request, enabled bool) {
+	schedule, ok := al.getScheduleOrWriteError(w, req)
+	if !ok {
+		return
+	}
+
+	schedule.Enabled = enabled
+	if enabled {
+		// from now on the schedule runs on behalf of the user who enabled it
+		schedule.CreatedBy = api.GetUser(req.Context(), al.Logger)
+	}
+	if err := al.scheduleProvider.Save(req.Context(), schedule); err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", "Failed to persist schedule.", err)
+		return
+	}
+
+	al.auditLog.Entry(auditlog.ApplicationSchedule, auditlog.ActionUpdate).
+		WithHTTPRequest(req).
+		WithID(schedule.ID).
+		WithParams(map[string]bool{"enabled": enabled}).
+		Save()
+
+	schedule.NextRunAt = schedule.Next(time.Now())
+	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(schedule))
+	al.Debugf("Schedule [id=%q] enabled: %t.", schedule.ID, enabled)
+}
+
+func (al *APIListener) handleGetScheduleRuns(w http.ResponseWriter, req *http.Request) {
+	schedule, ok := al.getScheduleOrWriteError(w, req)
+	if !ok {
+		return
+	}
+
+	res, err := al.jobProvider.GetMultiJobSummariesByScheduleID(schedule.ID)
+	if err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", fmt.Sprintf("Failed to get runs of schedule[id=%q].", schedule.ID), err)
+		return
+	}
+
+	al.writeJSONResponse(w, http.StatusOK, api.NewSuccessPayload(res))
+}
+
+// scheduleAuditParams returns params of a schedule to record in the audit log. Secrets of its command are omitted
+// the same way as for commands executed via the API.
+func scheduleAuditParams(schedule *schedules.Schedule) interface{} {
+	cmdReq := multiClientCmdRequest(schedule.CommandSpec)
+	return struct {
+		Name     string `json:"name"`
+		Schedule string `json:"schedule"`
+		Enabled  bool   `json:"enabled"`
+		*cmdAuditParams
+	}{
+		Name:           schedule.Name,
+		Schedule:       schedule.Schedule,
+		Enabled:        schedule.Enabled,
+		cmdAuditParams: cmdReq.auditParams(),
+	}
+}
+
+// getScheduleOrWriteError returns a schedule by an id from the request path. If not found or failed to get it,
+// writes an error response and returns false.
+func (al *APIListener) getScheduleOrWriteError(w http.ResponseWriter, req *http.Request) (*schedules.Schedule, bool) {
+	id := mux.Vars(req)[routeParamScheduleID]
+
+	schedule, err := al.scheduleProvider.Get(req.Context(), id)
+	if err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", fmt.Sprintf("Failed to find schedule[id=%q].", id), err)
+		return nil, false
+	}
+	if schedule == nil {
+		al.jsonErrorResponseWithTitle(w, http.StatusNotFound, fmt.Sprintf("Schedule[id=%q] not found.", id))
+		return nil, false
+	}
+
+	allowed, err := al.filterSchedules(req.Context(), []*schedules.Schedule{schedule})
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+		return nil, false
+	}
+	if len(allowed) == 0 {
+		al.jsonErrorResponseWithTitle(w, http.StatusForbidden, fmt.Sprintf("Access denied to schedule[id=%q].", id))
+		return nil, false
+	}
+	return schedule, true
+}
+
+// filterSchedules returns only schedules which clients and client groups are all accessible by a current user.
+func (al *APIListener) filterSchedules(ctx context.Context, all []*schedules.Schedule) ([]*schedules.Schedule, error) {
+	access, err := al.getClientAccess(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if access.unrestricted {
+		return all, nil
+	}
+	allowedClients, err := al.allowedClientIDSet(access)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*schedules.Schedule, 0, len(all))
+	for _, cur := range all {
+		if targetsAllowed(access, allowedClients, cur.ClientIDs, cur.GroupIDs) {
+			res = append(res, cur)
+		}
+	}
+	return res, nil
+}
+
+// parseScheduleRequest returns a schedule from a given request. If the request is invalid, writes an error response
+// and returns false.
+func (al *APIListener) parseScheduleRequest(w http.ResponseWriter, req *http.Request) (*schedules.Schedule, bool) {
+	var reqBody scheduleRequest
+	dec := json.NewDecoder(req.Body)
+	dec.DisallowUnknownFields()
+	err := dec.Decode(&reqBody)
+	if err == io.EOF { // is handled separately to return an informative error message
+		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, "Missing body with json data.")
+		return nil, false
+	} else if err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusBadRequest, "", "Invalid JSON data.", err)
+		return nil, false
+	}
+
+	// by default a new schedule is enabled
+	enabled := true
+	if reqBody.Enabled != nil {
+		enabled = *reqBody.Enabled
+	}
+	schedule := &schedules.Schedule{
+		Name:        reqBody.Name,
+		Schedule:    reqBody.Schedule,
+		Enabled:     enabled,
+		CommandSpec: reqBody.CommandSpec,
+		CreatedBy:   api.GetUser(req.Context(), al.Logger),
+		CreatedAt:   time.Now(),
+	}
+	if err := schedule.Validate(); err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusBadRequest, "", "Invalid schedule.", err)
+		return nil, false
+	}
+
+	cmdReq := multiClientCmdRequest(schedule.CommandSpec)
+	if title, err := validateMultiClientCmdRequest(&cmdReq); title != "" {
+		al.jsonErrorResponseWithError(w, http.StatusBadRequest, "", title, err)
+		return nil, false
+	}
+
+	for _, groupID := range schedule.GroupIDs {
+		group, err := al.clientGroupProvider.Get(req.Context(), groupID)
+		if err != nil {
+			al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", fmt.Sprintf("Failed to get a client group with id=%q.", groupID), err)
+			return nil, false
+		}
+		if group == nil {
+			al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, fmt.Sprintf("Unknown group with id=%q.", groupID))
+			return nil, false
+		}
+	}
+
+	allowed, err := al.filterSchedules(req.Context(), []*schedules.Schedule{schedule})
+	if err != nil {
+		al.jsonErrorResponse(w, http.StatusInternalServerError, err)
+		return nil, false
+	}
+	if len(allowed) == 0 {
+		al.jsonErrorResponseWithTitle(w, http.StatusForbidden, "Access denied to some of the clients or client groups of the schedule.")
+		return nil, false
+	}
+
+	// the schedule keeps referencing the library script, so it's resolved on a copy only to validate params
+	spec := schedule.CommandSpec
+	if err := al.resolveLibraryScript(req.Context(), spec.ScriptID, spec.Params, &spec.ScriptVersion, &spec.Env, &spec.Script, &spec.Interpreter); err != nil {
+		al.jsonErrorResponseWithError(w, err.code, "", err.title, err.err)
+		return nil, false
+	}
+
+	return schedule, true
+}
+
+type schedulesTask struct {
+	al        *APIListener
+	lastCheck time.Time
+}
+
+// newSchedulesTask returns a task to start multi-client jobs of enabled schedules when their time comes.
+func newSchedulesTask(al *APIListener) *schedulesTask {
+	return &schedulesTask{
+		al:        al,
+		lastCheck: time.Now(),
+	}
+}
+
+func (t *schedulesTask) Run(ctx context.Context) error {
+	now := time.Now()
+	all, err := t.al.scheduleProvider.GetAll(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get schedules: %v", err)
+	}
+
+	for _, schedule := range all {
+		next := schedule.Next(t.lastCheck)
+		if next == nil || next.After(now) {
+			continue
+		}
+		if err := t.al.runSchedule(ctx, schedule); err != nil {
+			t.al.Errorf("Failed to run schedule[id=%q]: %v", schedule.ID, err)
+		}
+	}
+	t.lastCheck = now
+	return nil
+}
+
+// runSchedule starts a multi-client job of a given schedule on behalf of the user who created the schedule.
+// Clients that are not active or not accessible by the user are skipped.
+func (al *APIListener) runSchedule(ctx context.Context, schedule *schedules.Schedule) error {
+	ctx = api.WithUser(ctx, schedule.CreatedBy)
+	if err := al.checkScheduleOwner(ctx, schedule); err != nil {
+		return err
+	}
+	reqBody := multiClientCmdRequest(schedule.CommandSpec)
+
+	if err := al.resolveLibraryScript(ctx, reqBody.ScriptID, reqBody.Params, &reqBody.ScriptVersion, &reqBody.Env, &reqBody.Script, &reqBody.Interpreter); err != nil {
+		return err
+	}
+
+	scheduleID := schedule.ID
+	if _, err := al.createMultiClientJob(ctx, &reqBody, 1, &scheduleID); err != nil {
+		return err
+	}
+	return nil
+}
+
+// checkScheduleOwner returns an error if the user a given schedule runs on behalf of was deleted, is not allowed to
+// execute commands anymore or has no access to some of the clients or client groups of the schedule.
+func (al *APIListener) checkScheduleOwner(ctx context.Context, schedule *schedules.Schedule) error {
+	user, err := al.getCurUser(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get user %q: %v", schedule.CreatedBy, err)
+	}
+	if user == nil {
+		return fmt.Errorf("user %q not found", schedule.CreatedBy)
+	}
+	if !al.config.GroupPermissions().Has(user, users.PermissionCommands) {
+		return fmt.Errorf("user %q has no %q permission", schedule.CreatedBy, users.PermissionCommands)
+	}
+
+	allowed, err := al.filterSchedules(ctx, []*schedules.Schedule{schedule})
+	if err != nil {
+		return err
+	}
+	if len(allowed) == 0 {
+		return fmt.Errorf("user %q has no access to some of the clients or client groups", schedule.CreatedBy)
+	}
+	return nil
+}
@@ -0,0 +1,447 @@
+package chserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudradar-monitoring/rport/server/api"
+	"github.com/cloudradar-monitoring/rport/server/api/jobs"
+	"github.com/cloudradar-monitoring/rport/server/api/users"
+	"github.com/cloudradar-monitoring/rport/server/cgroups"
+	"github.com/cloudradar-monitoring/rport/server/clients"
+	"github.com/cloudradar-monitoring/rport/server/schedules"
+	"github.com/cloudradar-monitoring/rport/server/test/jb"
+	"github.com/cloudradar-monitoring/rport/share/comm"
+	"github.com/cloudradar-monitoring/rport/share/models"
+	"github.com/cloudradar-monitoring/rport/share/test"
+)
+
+func TestHandleSchedules(t *testing.T) {
+	ctx := context.Background()
+	scheduleProvider, err := schedules.NewSqliteProvider(":memory:")
+	require.NoError(t, err)
+	defer scheduleProvider.Close()
+	groupProvider, err := cgroups.NewSqliteProvider(":memory:")
+	require.NoError(t, err)
+	defer groupProvider.Close()
+	require.NoError(t, groupProvider.Create(ctx, &cgroups.ClientGroup{ID: "group-1", Params: &cgroups.ClientParams{ClientID: &cgroups.ParamValues{"client-1"}}}))
+	jp, err := jobs.NewSqliteProvider("file::memory:?cache=shared", testLog)
+	require.NoError(t, err)
+	defer jp.Close()
+
+	generateNewScheduleID = func() string {
+		return "schedule-1"
+	}
+
+	al := APIListener{
+		insecureForTests: true,
+		Server: &Server{
+			config: &Config{
+				Server: ServerConfig{
+					MaxRequestBytes: 1024 * 1024,
+				},
+			},
+			scheduleProvider:    scheduleProvider,
+			clientGroupProvider: groupProvider,
+			jobProvider:         jp,
+		},
+		Logger: testLog,
+	}
+	al.initRouter()
+
+	do := func(method, url, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		req = req.WithContext(api.WithUser(context.Background(), "admin"))
+		w := httptest.NewRecorder()
+		al.router.ServeHTTP(w, req)
+		return w
+	}
+	getSchedule := func(w *httptest.ResponseRecorder) *schedules.Schedule {
+		resp := struct {
+			Data *schedules.Schedule `json:"data"`
+		}{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp.Data
+	}
+
+	// create
+	w := do(http.MethodPost, "/api/v1/schedules", `{"name": "Uptime", "schedule": "*/5 * * * *", "group_ids": ["group-1"], "command": "uptime"}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	created := getSchedule(w)
+	assert.Equal(t, "schedule-1", created.ID)
+	assert.True(t, created.Enabled)
+	assert.Equal(t, "admin", created.CreatedBy)
+	assert.NotNil(t, created.NextRunAt)
+
+	// invalid schedule
+	w = do(http.MethodPost, "/api/v1/schedules", `{"name": "Uptime", "schedule": "* * *", "group_ids": ["group-1"], "command": "uptime"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, `{"errors":[{"code":"","title":"Invalid schedule.","detail":"invalid cron expression \"* * *\": expected 5 fields, actual: 3"}]}`, w.Body.String())
+
+	w = do(http.MethodPost, "/api/v1/schedules", `{"name": "Uptime", "schedule": "* * * * *", "group_ids": ["group-1"]}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, `{"errors":[{"code":"","title":"Command cannot be empty.","detail":""}]}`, w.Body.String())
+
+	w = do(http.MethodPost, "/api/v1/schedules", `{"name": "Uptime", "schedule": "* * * * *", "group_ids": ["unknown"], "command": "uptime"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, `{"errors":[{"code":"","title":"Unknown group with id=\"unknown\".","detail":""}]}`, w.Body.String())
+
+	// update
+	w = do(http.MethodPut, "/api/v1/schedules/schedule-1", `{"name": "Uptime", "schedule": "0 * * * *", "client_ids": ["client-1"], "command": "uptime", "enabled": false}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	updated := getSchedule(w)
+	assert.Equal(t, "0 * * * *", updated.Schedule)
+	assert.False(t, updated.Enabled)
+	assert.Nil(t, updated.NextRunAt)
+
+	w = do(http.MethodPut, "/api/v1/schedules/unknown", `{"name": "Uptime", "schedule": "0 * * * *", "client_ids": ["client-1"], "command": "uptime"}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// enable
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/schedules/schedule-1/enable", nil)
+	req = req.WithContext(api.WithUser(context.Background(), "user1"))
+	w = httptest.NewRecorder()
+	al.router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	enabled := getSchedule(w)
+	assert.True(t, enabled.Enabled)
+	assert.Equal(t, "user1", enabled.CreatedBy)
+
+	// get
+	w = do(http.MethodGet, "/api/v1/schedules/schedule-1", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	got := getSchedule(w)
+	assert.True(t, got.Enabled)
+	assert.Equal(t, []string{"client-1"}, got.ClientIDs)
+
+	// disable
+	w = do(http.MethodPost, "/api/v1/schedules/schedule-1/disable", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.False(t, getSchedule(w).Enabled)
+
+	// list
+	w = do(http.MethodGet, "/api/v1/schedules", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"id":"schedule-1","name":"Uptime","schedule":"0 * * * *","enabled":false`)
+
+	// runs
+	require.NoError(t, jp.SaveMultiJob(jb.NewMulti(t).ScheduleID("schedule-1").Build()))
+	w = do(http.MethodGet, "/api/v1/schedules/schedule-1/runs", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"schedule_id":"schedule-1"`)
+
+	// delete
+	w = do(http.MethodDelete, "/api/v1/schedules/schedule-1", "")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = do(http.MethodDelete, "/api/v1/schedules/schedule-1", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestHandleSchedulesRestrictedUser(t *testing.T) {
+	ctx := context.Background()
+	scheduleProvider, err := schedules.NewSqliteProvider(":memory:")
+	require.NoError(t, err)
+	defer scheduleProvider.Close()
+	groupProvider, err := cgroups.NewSqliteProvider(":memory:")
+	require.NoError(t, err)
+	defer groupProvider.Close()
+	require.NoError(t, groupProvider.Create(ctx, &cgroups.ClientGroup{ID: "group-1", Params: &cgroups.ClientParams{ClientID: &cgroups.ParamValues{"client-1"}}}))
+	require.NoError(t, groupProvider.Create(ctx, &cgroups.ClientGroup{ID: "group-2", Params: &cgroups.ClientParams{ClientID: &cgroups.ParamValues{"client-2"}}}))
+	jp, err := jobs.NewSqliteProvider("file::memory:?cache=shared", testLog)
+	require.NoError(t, err)
+	defer jp.Close()
+
+	c1 := clients.New(t).ID("client-1").Build()
+	c2 := clients.New(t).ID("client-2").Build()
+	newSchedule := func(id string, clientIDs, groupIDs []string) *schedules.Schedule {
+		return &schedules.Schedule{
+			ID:       id,
+			Name:     id,
+			Schedule: "* * * * *",
+			CommandSpec: schedules.CommandSpec{
+				ClientIDs: clientIDs,
+				GroupIDs:  groupIDs,
+				Command:   "uptime",
+			},
+			CreatedBy: "admin",
+		}
+	}
+	require.NoError(t, scheduleProvider.Save(ctx, newSchedule("own-group", nil, []string{"group-1"})))
+	require.NoError(t, scheduleProvider.Save(ctx, newSchedule("own-client", []string{c1.ID}, nil)))
+	require.NoError(t, scheduleProvider.Save(ctx, newSchedule("other-client", []string{c1.ID, c2.ID}, nil)))
+	require.NoError(t, scheduleProvider.Save(ctx, newSchedule("other-group", nil, []string{"group-2"})))
+
+	generateNewScheduleID = func() string {
+		return "schedule-1"
+	}
+
+	al := APIListener{
+		insecureForTests: true,
+		Server: &Server{
+			config: &Config{
+				Server: ServerConfig{
+					MaxRequestBytes: 1024 * 1024,
+				},
+				API: APIConfig{
+					RestrictClientsByGroup: true,
+				},
+			},
+			clientService:       NewClientService(nil, clients.NewClientRepository([]*clients.Client{c1, c2}, &hour)),
+			scheduleProvider:    scheduleProvider,
+			clientGroupProvider: groupProvider,
+			jobProvider:         jp,
+		},
+		userSrv: users.NewUserCache([]*users.User{{Username: "user1", Groups: []string{"group-1"}}}),
+		Logger:  testLog,
+	}
+	al.initRouter()
+
+	do := func(method, url, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		req = req.WithContext(api.WithUser(context.Background(), "user1"))
+		w := httptest.NewRecorder()
+		al.router.ServeHTTP(w, req)
+		return w
+	}
+
+	// list
+	w := do(http.MethodGet, "/api/v1/schedules", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	resp := struct {
+		Data []*schedules.Schedule `json:"data"`
+	}{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	var gotIDs []string
+	for _, cur := range resp.Data {
+		gotIDs = append(gotIDs, cur.ID)
+	}
+	assert.ElementsMatch(t, []string{"own-group", "own-client"}, gotIDs)
+
+	// accessible schedules
+	w = do(http.MethodGet, "/api/v1/schedules/own-group", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = do(http.MethodGet, "/api/v1/schedules/own-client/runs", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// not accessible schedules
+	for _, id := range []string{"other-client", "other-group"} {
+		for _, tc := range []struct {
+			method string
+			url    string
+			body   string
+		}{
+			{method: http.MethodGet, url: "/api/v1/schedules/" + id},
+			{method: http.MethodPut, url: "/api/v1/schedules/" + id, body: `{"name": "Uptime", "schedule": "* * * * *", "client_ids": ["client-1"], "command": "uptime"}`},
+			{method: http.MethodDelete, url: "/api/v1/schedules/" + id},
+			{method: http.MethodPost, url: "/api/v1/schedules/" + id + "/enable"},
+			{method: http.MethodPost, url: "/api/v1/schedules/" + id + "/disable"},
+			{method: http.MethodGet, url: "/api/v1/schedules/" + id + "/runs"},
+		} {
+			w = do(tc.method, tc.url, tc.body)
+			assert.Equal(t, http.StatusForbidden, w.Code, tc.method+" "+tc.url)
+			assert.Equal(t, `{"errors":[{"code":"","title":"Access denied to schedule[id=\"`+id+`\"].","detail":""}]}`, w.Body.String())
+		}
+	}
+
+	// not accessible targets
+	wantErr := `{"errors":[{"code":"","title":"Access denied to some of the clients or client groups of the schedule.","detail":""}]}`
+	w = do(http.MethodPost, "/api/v1/schedules", `{"name": "Uptime", "schedule": "* * * * *", "group_ids": ["group-2"], "command": "uptime"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, wantErr, w.Body.String())
+	w = do(http.MethodPut, "/api/v1/schedules/own-client", `{"name": "Uptime", "schedule": "* * * * *", "client_ids": ["client-2"], "command": "uptime"}`)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, wantErr, w.Body.String())
+
+	w = do(http.MethodPost, "/api/v1/schedules", `{"name": "Uptime", "schedule": "* * * * *", "group_ids": ["group-1"], "command": "uptime"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+}
+
+func TestScheduleAuditParams(t *testing.T) {
+	schedule := &schedules.Schedule{
+		ID:       "schedule-1",
+		Name:     "Uptime",
+		Schedule: "* * * * *",
+		Enabled:  true,
+		CommandSpec: schedules.CommandSpec{
+			GroupIDs: []string{"group-1"},
+			Command:  "uptime",
+			Env:      map[string]string{"TOKEN": "secret"},
+		},
+	}
+
+	gotBytes, err := json.Marshal(scheduleAuditParams(schedule))
+	require.NoError(t, err)
+	assert.Equal(t, `{"name":"Uptime","schedule":"* * * * *","enabled":true,"group_ids":["group-1"],"command":"uptime"}`, string(gotBytes))
+}
+
+func TestSchedulesTaskRun(t *testing.T) {
+	ctx := context.Background()
+	scheduleProvider, err := schedules.NewSqliteProvider(":memory:")
+	require.NoError(t, err)
+	defer scheduleProvider.Close()
+	jp, err := jobs.NewSqliteProvider("file::memory:?cache=shared", testLog)
+	require.NoError(t, err)
+	defer jp.Close()
+
+	connMock := test.NewConnMock()
+	connMock.ReturnOk = true
+	sshRespBytes, err := json.Marshal(comm.RunCmdResponse{Pid: 123})
+	require.NoError(t, err)
+	connMock.ReturnResponsePayload = sshRespBytes
+	c1 := clients.New(t).ID("client-1").Connection(connMock).Build()
+	c2 := clients.New(t).ID("client-2").DisconnectedDuration(5 * time.Minute).Build()
+
+	enabled := &schedules.Schedule{
+		ID:       "schedule-enabled",
+		Name:     "Every minute",
+		Schedule: "* * * * *",
+		Enabled:  true,
+		CommandSpec: schedules.CommandSpec{
+			ClientIDs: []string{c1.ID, c2.ID},
+			Command:   "uptime",
+		},
+		CreatedBy: "admin",
+	}
+	disabled := &schedules.Schedule{
+		ID:       "schedule-disabled",
+		Name:     "Every minute",
+		Schedule: "* * * * *",
+		CommandSpec: schedules.CommandSpec{
+			ClientIDs: []string{c1.ID},
+			Command:   "uptime",
+		},
+		CreatedBy: "admin",
+	}
+	require.NoError(t, scheduleProvider.Save(ctx, enabled))
+	require.NoError(t, scheduleProvider.Save(ctx, disabled))
+
+	done := make(chan bool)
+	al := &APIListener{
+		insecureForTests: true,
+		Server: &Server{
+			clientService: NewClientService(nil, clients.NewClientRepository([]*clients.Client{c1, c2}, &hour)),
+			config: &Config{
+				Server: ServerConfig{
+					RunRemoteCmdTimeoutSec: 60,
+				},
+			},
+			scheduleProvider: scheduleProvider,
+			jobProvider:      jp,
+			jobsDoneChannel: jobResultChanMap{
+				m: make(map[string]chan *models.Job),
+			},
+		},
+		userSrv:  users.NewUserCache([]*users.User{{Username: "admin", Groups: []string{users.Administrators}}}),
+		Logger:   testLog,
+		testDone: done,
+	}
+	task := newSchedulesTask(al)
+
+	// when
+	task.lastCheck = time.Now().Add(-time.Minute)
+	require.NoError(t, task.Run(ctx))
+	<-done
+
+	// then
+	gotRuns, err := jp.GetMultiJobSummariesByScheduleID(enabled.ID)
+	require.NoError(t, err)
+	require.Len(t, gotRuns, 1)
+	gotJob, err := jp.GetMultiJob(gotRuns[0].JID)
+	require.NoError(t, err)
+	assert.Equal(t, "admin", gotJob.CreatedBy)
+	assert.Equal(t, 60, gotJob.TimeoutSec)
+	require.Len(t, gotJob.Jobs, 1)
+	assert.Equal(t, c1.ID, gotJob.Jobs[0].ClientID)
+	assert.Equal(t, models.JobStatusRunning, gotJob.Jobs[0].Status)
+
+	gotRuns, err = jp.GetMultiJobSummariesByScheduleID(disabled.ID)
+	require.NoError(t, err)
+	assert.Len(t, gotRuns, 0)
+
+	// the next run is not due yet
+	require.NoError(t, task.Run(ctx))
+	gotRuns, err = jp.GetMultiJobSummariesByScheduleID(enabled.ID)
+	require.NoError(t, err)
+	assert.Len(t, gotRuns, 1)
+}
+
+func TestRunScheduleChecksOwner(t *testing.T) {
+	c1 := clients.New(t).ID("client-1").Build()
+	c2 := clients.New(t).ID("client-2").Build()
+	g1 := &cgroups.ClientGroup{ID: "group-1", Params: &cgroups.ClientParams{ClientID: &cgroups.ParamValues{"client-1"}}}
+
+	testCases := []struct {
+		name      string
+		createdBy string
+		clientIDs []string
+		wantErr   string
+	}{
+		{
+			name:      "deleted user",
+			createdBy: "deleted",
+			clientIDs: []string{c1.ID},
+			wantErr:   `user "deleted" not found`,
+		},
+		{
+			name:      "no permission",
+			createdBy: "helpdesk",
+			clientIDs: []string{c1.ID},
+			wantErr:   `user "helpdesk" has no "commands" permission`,
+		},
+		{
+			name:      "no client access",
+			createdBy: "operator",
+			clientIDs: []string{c1.ID, c2.ID},
+			wantErr:   `user "operator" has no access to some of the clients or client groups`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			jp := NewJobProviderMock()
+			al := &APIListener{
+				Server: &Server{
+					clientService: NewClientService(nil, clients.NewClientRepository([]*clients.Client{c1, c2}, &hour)),
+					config: &Config{
+						API: APIConfig{
+							RestrictClientsByGroup: true,
+							groupPermissions: users.GroupPermissions{
+								"group-1": {users.PermissionCommands},
+							},
+						},
+					},
+					clientGroupProvider: &ClientGroupProviderMock{ReturnGroups: []*cgroups.ClientGroup{g1}},
+					jobProvider:         jp,
+				},
+				userSrv: users.NewUserCache([]*users.User{
+					{Username: "operator", Groups: []string{"group-1"}},
+					{Username: "helpdesk"},
+				}),
+				Logger: testLog,
+			}
+			schedule := &schedules.Schedule{
+				ID:       "schedule-1",
+				Schedule: "* * * * *",
+				Enabled:  true,
+				CommandSpec: schedules.CommandSpec{
+					ClientIDs: tc.clientIDs,
+					Command:   "uptime",
+				},
+				CreatedBy: tc.createdBy,
+			}
+
+			err := al.runSchedule(context.Background(), schedule)
+
+			assert.EqualError(t, err, tc.wantErr)
+		})
+	}
+}
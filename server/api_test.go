@@ -9,6 +9,7 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"reflect"
 	"runtime"
@@ -23,6 +24,7 @@ import (
 
 	"github.com/cloudradar-monitoring/rport/server/api"
 	"github.com/cloudradar-monitoring/rport/server/api/jobs"
+	"github.com/cloudradar-monitoring/rport/server/api/users"
 	"github.com/cloudradar-monitoring/rport/server/cgroups"
 	"github.com/cloudradar-monitoring/rport/server/clients"
 	"github.com/cloudradar-monitoring/rport/server/clientsauth"
@@ -43,10 +45,14 @@ type JobProviderMock struct {
 	ReturnJobSummaries []*models.JobSummary
 	ReturnErr          error
 
-	InputCID       string
-	InputJID       string
-	InputSaveJob   *models.Job
-	InputCreateJob *models.Job
+	ReturnCancelQueued bool
+
+	InputCID            string
+	InputJID            string
+	InputSaveJob        *models.Job
+	InputCreateJob      *models.Job
+	InputListOptions    *jobs.ListOptions
+	InputCancelQueuedID string
 }
 
 func NewJobProviderMock() *JobProviderMock {
@@ -59,11 +65,16 @@ func (p *JobProviderMock) GetByJID(cid, jid string) (*models.Job, error) {
 	return p.ReturnJob, p.ReturnErr
 }
 
-func (p *JobProviderMock) GetSummariesByClientID(cid string) ([]*models.JobSummary, error) {
+func (p *JobProviderMock) ListSummaries(cid string, opts *jobs.ListOptions) ([]*models.JobSummary, error) {
 	p.InputCID = cid
+	p.InputListOptions = opts
 	return p.ReturnJobSummaries, p.ReturnErr
 }
 
+func (p *JobProviderMock) CountSummaries(cid string, opts *jobs.ListOptions) (int, error) {
+	return len(p.ReturnJobSummaries), p.ReturnErr
+}
+
 func (p *JobProviderMock) SaveJob(job *models.Job) error {
 	p.InputSaveJob = job
 	return p.ReturnErr
@@ -74,6 +85,11 @@ func (p *JobProviderMock) CreateJob(job *models.Job) error {
 	return p.ReturnErr
 }
 
+func (p *JobProviderMock) CancelQueuedJob(jid string, finishedAt time.Time) (bool, error) {
+	p.InputCancelQueuedID = jid
+	return p.ReturnCancelQueued, p.ReturnErr
+}
+
 func (p *JobProviderMock) Close() error {
 	return nil
 }
@@ -665,12 +681,21 @@ func TestHandlePostCommand(t *testing.T) {
 		runningJob      *models.Job
 		clients         []*clients.Client
 
-		wantStatusCode int
-		wantTimeout    int
-		wantErrCode    string
-		wantErrTitle   string
-		wantErrDetail  string
-		wantShell      string
+		wantStatusCode      int
+		wantTimeout         int
+		wantTimeoutPolicy   string
+		wantTimeoutGraceSec int
+		wantErrCode         string
+		wantErrTitle        string
+		wantErrDetail       string
+		wantShell           string
+		wantCwd             string
+		wantEnv             map[string]string
+		wantRunAsUser       string
+		wantScript          string
+		wantScriptHash      string
+		wantQueued          bool
+		wantQueueExpirySec  int
 	}{
 		{
 			name:           "valid cmd",
@@ -680,6 +705,16 @@ func TestHandlePostCommand(t *testing.T) {
 			wantStatusCode: http.StatusOK,
 			wantTimeout:    gotCmdTimeoutSec,
 		},
+		{
+			name:           "queued cmd",
+			requestBody:    validReqBody,
+			connReturnResp: []byte(`{"Queued":true}`),
+			cid:            c1.ID,
+			clients:        []*clients.Client{c1},
+			wantStatusCode: http.StatusOK,
+			wantTimeout:    gotCmdTimeoutSec,
+			wantQueued:     true,
+		},
 		{
 			name:           "valid cmd with shell",
 			requestBody:    `{"command": "` + gotCmd + `","shell": "powershell"}`,
@@ -698,6 +733,74 @@ func TestHandlePostCommand(t *testing.T) {
 			wantErrTitle:   "Invalid shell.",
 			wantErrDetail:  "expected shell to be one of: [cmd powershell], actual: unsupported",
 		},
+		{
+			name:           "valid cmd with cwd, env and user",
+			requestBody:    `{"command": "` + gotCmd + `","cwd": "/tmp", "env": {"VAR1": "value1"}, "run_as_user": "nobody"}`,
+			cid:            c1.ID,
+			clients:        []*clients.Client{c1},
+			wantStatusCode: http.StatusOK,
+			wantTimeout:    defaultTimeout,
+			wantCwd:        "/tmp",
+			wantEnv:        map[string]string{"VAR1": "value1"},
+			wantRunAsUser:  "nobody",
+		},
+		{
+			name:           "valid script",
+			requestBody:    `{"script": "echo 1\necho 2", "interpreter": "bash"}`,
+			cid:            c1.ID,
+			clients:        []*clients.Client{c1},
+			wantStatusCode: http.StatusOK,
+			wantTimeout:    defaultTimeout,
+			wantScript:     "echo 1\necho 2",
+			wantScriptHash: "eaac7780aa9672c5e8c0c506bad2452b263c9e231c945b85f86ac23dc0b89450",
+		},
+		{
+			name:           "both cmd and script",
+			requestBody:    `{"command": "` + gotCmd + `","script": "echo 1"}`,
+			cid:            c1.ID,
+			clients:        []*clients.Client{c1},
+			wantStatusCode: http.StatusBadRequest,
+			wantErrTitle:   "Invalid script.",
+			wantErrDetail:  "command and script cannot be specified together",
+		},
+		{
+			name:           "invalid env",
+			requestBody:    `{"command": "` + gotCmd + `","env": {"VAR1=": "value1"}}`,
+			cid:            c1.ID,
+			clients:        []*clients.Client{c1},
+			wantStatusCode: http.StatusBadRequest,
+			wantErrTitle:   "Invalid env.",
+			wantErrDetail:  `invalid environment variable name: "VAR1="`,
+		},
+		{
+			name:                "kill on timeout, default grace period",
+			requestBody:         `{"command": "` + gotCmd + `","timeout_policy": "kill"}`,
+			cid:                 c1.ID,
+			clients:             []*clients.Client{c1},
+			wantStatusCode:      http.StatusOK,
+			wantTimeout:         defaultTimeout,
+			wantTimeoutPolicy:   models.JobTimeoutPolicyKill,
+			wantTimeoutGraceSec: defaultTimeoutGraceSec,
+		},
+		{
+			name:                "kill on timeout with grace period",
+			requestBody:         `{"command": "` + gotCmd + `","timeout_policy": "kill", "timeout_grace_sec": 3}`,
+			cid:                 c1.ID,
+			clients:             []*clients.Client{c1},
+			wantStatusCode:      http.StatusOK,
+			wantTimeout:         defaultTimeout,
+			wantTimeoutPolicy:   models.JobTimeoutPolicyKill,
+			wantTimeoutGraceSec: 3,
+		},
+		{
+			name:           "invalid timeout policy",
+			requestBody:    `{"command": "` + gotCmd + `","timeout_policy": "unsupported"}`,
+			cid:            c1.ID,
+			clients:        []*clients.Client{c1},
+			wantStatusCode: http.StatusBadRequest,
+			wantErrTitle:   "Invalid timeout policy.",
+			wantErrDetail:  "expected timeout policy to be one of: [stop_observing kill], actual: unsupported",
+		},
 		{
 			name:           "valid cmd with no timeout",
 			requestBody:    `{"command": "/bin/date;foo;whoami"}`,
@@ -772,6 +875,34 @@ func TestHandlePostCommand(t *testing.T) {
 			wantStatusCode: http.StatusNotFound,
 			wantErrTitle:   fmt.Sprintf("Active client with id=%q not found.", c2.ID),
 		},
+		{
+			name:               "disconnected client, queue if offline",
+			requestBody:        `{"command": "` + gotCmd + `","queue_if_offline": true, "queue_expiry_sec": 3600}`,
+			cid:                c2.ID,
+			clients:            []*clients.Client{c1, c2},
+			wantStatusCode:     http.StatusOK,
+			wantTimeout:        defaultTimeout,
+			wantQueued:         true,
+			wantQueueExpirySec: 3600,
+		},
+		{
+			name:               "disconnected client, queue if offline with default expiry",
+			requestBody:        `{"command": "` + gotCmd + `","queue_if_offline": true}`,
+			cid:                c2.ID,
+			clients:            []*clients.Client{c1, c2},
+			wantStatusCode:     http.StatusOK,
+			wantTimeout:        defaultTimeout,
+			wantQueued:         true,
+			wantQueueExpirySec: defaultQueueExpirySec,
+		},
+		{
+			name:           "queue expiry without queue if offline",
+			requestBody:    `{"command": "` + gotCmd + `","queue_expiry_sec": 3600}`,
+			cid:            c2.ID,
+			clients:        []*clients.Client{c1, c2},
+			wantStatusCode: http.StatusBadRequest,
+			wantErrTitle:   "Queue expiry can be specified only together with queue_if_offline.",
+		},
 		{
 			name:            "error on save job",
 			requestBody:     validReqBody,
@@ -837,6 +968,7 @@ func TestHandlePostCommand(t *testing.T) {
 
 			connMock.ReturnErr = tc.connReturnErr
 			connMock.ReturnOk = !tc.connReturnNotOk
+			connMock.ReturnResponsePayload = sshRespBytes
 			if len(tc.connReturnResp) > 0 {
 				connMock.ReturnResponsePayload = tc.connReturnResp // override stubbed success payload
 			}
@@ -857,16 +989,38 @@ func TestHandlePostCommand(t *testing.T) {
 				gotRunningJob := jp.InputCreateJob
 				assert.NotNil(t, gotRunningJob)
 				assert.Equal(t, testJID, gotRunningJob.JID)
-				assert.Equal(t, models.JobStatusRunning, gotRunningJob.Status)
 				assert.Nil(t, gotRunningJob.FinishedAt)
 				assert.Equal(t, tc.cid, gotRunningJob.ClientID)
-				assert.Equal(t, gotCmd, gotRunningJob.Command)
+				if tc.wantScript != "" {
+					assert.Empty(t, gotRunningJob.Command)
+				} else {
+					assert.Equal(t, gotCmd, gotRunningJob.Command)
+				}
+				assert.Equal(t, tc.wantScript, gotRunningJob.Script)
+				assert.Equal(t, tc.wantScriptHash, gotRunningJob.ScriptHash)
 				assert.Equal(t, tc.wantShell, gotRunningJob.Shell)
-				assert.Equal(t, &sshSuccessResp.Pid, gotRunningJob.PID)
-				assert.Equal(t, sshSuccessResp.StartedAt, gotRunningJob.StartedAt)
+				assert.Equal(t, tc.wantCwd, gotRunningJob.Cwd)
+				assert.Equal(t, tc.wantEnv, gotRunningJob.Env)
+				assert.Equal(t, tc.wantRunAsUser, gotRunningJob.RunAsUser)
+				if tc.wantQueued {
+					assert.Equal(t, models.JobStatusQueued, gotRunningJob.Status)
+					assert.Nil(t, gotRunningJob.PID)
+				} else {
+					assert.Equal(t, models.JobStatusRunning, gotRunningJob.Status)
+					assert.Equal(t, &sshSuccessResp.Pid, gotRunningJob.PID)
+					assert.Equal(t, sshSuccessResp.StartedAt, gotRunningJob.StartedAt)
+				}
 				assert.Equal(t, testUser, gotRunningJob.CreatedBy)
 				assert.Equal(t, tc.wantTimeout, gotRunningJob.TimeoutSec)
+				assert.Equal(t, tc.wantTimeoutPolicy, gotRunningJob.TimeoutPolicy)
+				assert.Equal(t, tc.wantTimeoutGraceSec, gotRunningJob.TimeoutGraceSec)
 				assert.Nil(t, gotRunningJob.Result)
+				if tc.wantQueueExpirySec > 0 {
+					require.NotNil(t, gotRunningJob.ExpiresAt)
+					assert.Equal(t, time.Duration(tc.wantQueueExpirySec)*time.Second, gotRunningJob.ExpiresAt.Sub(gotRunningJob.StartedAt))
+				} else {
+					assert.Nil(t, gotRunningJob.ExpiresAt)
+				}
 			} else {
 				// failure case
 				wantResp := api.NewErrorPayloadWithCode(tc.wantErrCode, tc.wantErrTitle, tc.wantErrDetail)
@@ -878,6 +1032,153 @@ func TestHandlePostCommand(t *testing.T) {
 	}
 }
 
+func TestHandleDeleteCommand(t *testing.T) {
+	connMock := test.NewConnMock()
+	c1 := clients.New(t).ID("client-1").Connection(connMock).Build()
+	c2 := clients.New(t).ID("client-2").DisconnectedDuration(5 * time.Minute).Build()
+	runningJob := jb.New(t).ClientID(c1.ID).JID("jid-1").Status(models.JobStatusRunning).Build()
+
+	testCases := []struct {
+		name string
+
+		cid             string
+		job             *models.Job
+		connReturnNotOk bool
+		cancelQueued    bool
+
+		wantStatusCode      int
+		wantErrCode         string
+		wantErrTitle        string
+		wantErrDetail       string
+		wantSavedJob        bool
+		wantCancelledQueued bool
+	}{
+		{
+			name:           "running job",
+			cid:            c1.ID,
+			job:            runningJob,
+			wantStatusCode: http.StatusNoContent,
+		},
+		{
+			name:           "job with unknown status",
+			cid:            c1.ID,
+			job:            jb.New(t).ClientID(c1.ID).JID("jid-1").Status(models.JobStatusUnknown).Build(),
+			wantStatusCode: http.StatusNoContent,
+			wantSavedJob:   true,
+		},
+		{
+			name:           "finished job",
+			cid:            c1.ID,
+			job:            jb.New(t).ClientID(c1.ID).JID("jid-1").Status(models.JobStatusSuccessful).Build(),
+			wantStatusCode: http.StatusConflict,
+			wantErrCode:    ErrCodeJobNotRunning,
+			wantErrTitle:   `Job[id="jid-1"] is not running, status: successful.`,
+		},
+		{
+			name:           "job of another client",
+			cid:            c1.ID,
+			job:            jb.New(t).ClientID("client-3").JID("jid-1").Status(models.JobStatusRunning).Build(),
+			wantStatusCode: http.StatusNotFound,
+			wantErrTitle:   `Job[id="jid-1"] not found.`,
+		},
+		{
+			name:           "job not found",
+			cid:            c1.ID,
+			wantStatusCode: http.StatusNotFound,
+			wantErrTitle:   `Job[id="jid-1"] not found.`,
+		},
+		{
+			name:           "client not found",
+			cid:            "unknown-client",
+			wantStatusCode: http.StatusNotFound,
+			wantErrTitle:   `Client with id="unknown-client" not found.`,
+		},
+		{
+			name:           "client not active",
+			cid:            c2.ID,
+			job:            jb.New(t).ClientID(c2.ID).JID("jid-1").Status(models.JobStatusRunning).Build(),
+			wantStatusCode: http.StatusBadRequest,
+			wantErrTitle:   `Client with id="client-2" is not active.`,
+		},
+		{
+			name:                "job queued on the server for inactive client",
+			cid:                 c2.ID,
+			job:                 jb.New(t).ClientID(c2.ID).JID("jid-1").Status(models.JobStatusQueued).ExpiresAt(time.Now().Add(time.Hour)).Build(),
+			cancelQueued:        true,
+			wantStatusCode:      http.StatusNoContent,
+			wantCancelledQueued: true,
+		},
+		{
+			name:            "client error",
+			cid:             c1.ID,
+			job:             runningJob,
+			connReturnNotOk: true,
+			wantStatusCode:  http.StatusConflict,
+			wantErrCode:     ErrCodeJobNotRunning,
+			wantErrTitle:    `Failed to cancel a job[id="jid-1"].`,
+			wantErrDetail:   "client error: no running command found",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			al := APIListener{
+				insecureForTests: true,
+				Server: &Server{
+					clientService: NewClientService(nil, clients.NewClientRepository([]*clients.Client{c1, c2}, &hour)),
+					config: &Config{
+						Server: ServerConfig{MaxRequestBytes: 1024 * 1024},
+					},
+				},
+				Logger: testLog,
+			}
+			al.initRouter()
+
+			jp := NewJobProviderMock()
+			jp.ReturnJob = tc.job
+			jp.ReturnCancelQueued = tc.cancelQueued
+			al.jobProvider = jp
+
+			connMock.ReturnOk = !tc.connReturnNotOk
+			connMock.ReturnResponsePayload = []byte(`{"Pid":123}`)
+			if tc.connReturnNotOk {
+				connMock.ReturnResponsePayload = []byte("no running command found")
+			}
+
+			req := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/api/v1/clients/%s/commands/jid-1", tc.cid), nil)
+
+			// when
+			w := httptest.NewRecorder()
+			al.router.ServeHTTP(w, req)
+
+			// then
+			assert.Equal(t, tc.wantStatusCode, w.Code)
+			if tc.wantCancelledQueued {
+				assert.Empty(t, w.Body.String())
+				assert.Equal(t, "jid-1", jp.InputCancelQueuedID)
+			} else if tc.wantErrTitle == "" {
+				assert.Empty(t, w.Body.String())
+				name, _, payload := connMock.InputSendRequest()
+				assert.Equal(t, comm.RequestTypeCancelCmd, name)
+				assert.JSONEq(t, `{"JID":"jid-1"}`, string(payload))
+			} else {
+				wantResp := api.NewErrorPayloadWithCode(tc.wantErrCode, tc.wantErrTitle, tc.wantErrDetail)
+				wantRespBytes, err := json.Marshal(wantResp)
+				require.NoError(t, err)
+				assert.JSONEq(t, string(wantRespBytes), w.Body.String())
+			}
+			if tc.wantSavedJob {
+				require.NotNil(t, jp.InputSaveJob)
+				assert.Equal(t, models.JobStatusCancelled, jp.InputSaveJob.Status)
+				assert.NotNil(t, jp.InputSaveJob.FinishedAt)
+			} else {
+				assert.Nil(t, jp.InputSaveJob)
+			}
+		})
+	}
+}
+
 func TestHandleGetCommand(t *testing.T) {
 	wantJob := jb.New(t).ClientID("cid-1234").JID("jid-1234").Build()
 	wantJobResp := api.NewSuccessPayload(wantJob)
@@ -888,6 +1189,7 @@ func TestHandleGetCommand(t *testing.T) {
 	testCases := []struct {
 		name string
 
+		restrict    bool
 		jpReturnErr error
 		jpReturnJob *models.Job
 
@@ -907,6 +1209,19 @@ func TestHandleGetCommand(t *testing.T) {
 			wantStatusCode: http.StatusNotFound,
 			wantErrTitle:   fmt.Sprintf("Job[id=%q] not found.", wantJob.JID),
 		},
+		{
+			name:           "job of another client",
+			jpReturnJob:    jb.New(t).ClientID("cid-5678").JID(wantJob.JID).Build(),
+			wantStatusCode: http.StatusNotFound,
+			wantErrTitle:   fmt.Sprintf("Job[id=%q] not found.", wantJob.JID),
+		},
+		{
+			name:           "no access to client",
+			restrict:       true,
+			jpReturnJob:    wantJob,
+			wantStatusCode: http.StatusForbidden,
+			wantErrTitle:   fmt.Sprintf("Access denied to client with id=%q.", wantJob.ClientID),
+		},
 		{
 			name:           "error on get job",
 			jpReturnErr:    errors.New("get job fake error"),
@@ -925,7 +1240,9 @@ func TestHandleGetCommand(t *testing.T) {
 				Server: &Server{
 					config: &Config{
 						Server: ServerConfig{MaxRequestBytes: 1024 * 1024},
+						API:    APIConfig{RestrictClientsByGroup: tc.restrict},
 					},
+					clientService: NewClientService(nil, clients.NewClientRepository([]*clients.Client{clients.New(t).ID(wantJob.ClientID).Build()}, &hour)),
 				},
 			}
 			al.initRouter()
@@ -967,20 +1284,24 @@ func TestHandleGetCommands(t *testing.T) {
 	job2 := jb.Status(models.JobStatusUnknown).FinishedAt(ft.Add(-time.Hour)).Build().JobSummary
 	job3 := jb.Status(models.JobStatusFailed).FinishedAt(ft.Add(time.Minute)).Build().JobSummary
 	job4 := jb.Status(models.JobStatusRunning).Build().JobSummary
-	jpSuccessReturnJobSummaries := []*models.JobSummary{&job1, &job2, &job3, &job4}
-	wantSuccessResp := api.NewSuccessPayload([]*models.JobSummary{&job4, &job3, &job1, &job2}) // sorted in desc
+	jpSuccessReturnJobSummaries := []*models.JobSummary{&job4, &job3, &job1, &job2}
+	wantSuccessResp := api.NewSuccessPayloadWithMeta(jpSuccessReturnJobSummaries, api.Meta{Count: 4})
 	b, err := json.Marshal(wantSuccessResp)
 	require.NoError(t, err)
 	wantSuccessRespJobsJSON := string(b)
+	since := time.Date(2020, 10, 1, 0, 0, 0, 0, time.UTC)
 
 	testCases := []struct {
 		name string
 
+		query                string
+		restrict             bool
 		jpReturnErr          error
 		jpReturnJobSummaries []*models.JobSummary
 
 		wantStatusCode  int
 		wantSuccessResp string
+		wantListOptions *jobs.ListOptions
 		wantErrCode     string
 		wantErrTitle    string
 		wantErrDetail   string
@@ -990,12 +1311,51 @@ func TestHandleGetCommands(t *testing.T) {
 			jpReturnJobSummaries: jpSuccessReturnJobSummaries,
 			wantSuccessResp:      wantSuccessRespJobsJSON,
 			wantStatusCode:       http.StatusOK,
+			wantListOptions: &jobs.ListOptions{
+				Filters:    map[string][]string{},
+				Pagination: &api.Pagination{},
+			},
 		},
 		{
 			name:                 "not found",
 			jpReturnJobSummaries: []*models.JobSummary{},
-			wantSuccessResp:      `{"data":[]}`,
+			wantSuccessResp:      `{"data":[],"meta":{"count":0}}`,
+			wantStatusCode:       http.StatusOK,
+		},
+		{
+			name:                 "filters, sort and page",
+			query:                "?filter[status]=failed,unknown&filter[created_by]=admin&filter[command]=/bin/date,now&filter[finished_since]=2020-10-01T00:00:00Z&sort=-started_at&page[limit]=10&page[offset]=20",
+			jpReturnJobSummaries: jpSuccessReturnJobSummaries,
+			wantSuccessResp:      wantSuccessRespJobsJSON,
 			wantStatusCode:       http.StatusOK,
+			wantListOptions: &jobs.ListOptions{
+				Filters:       map[string][]string{"status": {"failed", "unknown"}, "created_by": {"admin"}},
+				Command:       "/bin/date,now",
+				FinishedSince: &since,
+				Sort:          "started_at",
+				SortDesc:      true,
+				Pagination:    &api.Pagination{Limit: 10, Offset: 20},
+			},
+		},
+		{
+			name:           "invalid sort",
+			query:          "?sort=pid",
+			wantStatusCode: http.StatusBadRequest,
+			wantErrCode:    ErrCodeInvalidRequest,
+			wantErrTitle:   `invalid "sort" query param: unsupported sort field "pid", expected one of: finished_at, started_at, status, created_by`,
+		},
+		{
+			name:           "unsupported filter",
+			query:          "?filter[schedule_id]=1",
+			wantStatusCode: http.StatusBadRequest,
+			wantErrCode:    ErrCodeInvalidRequest,
+			wantErrTitle:   `unsupported filter field "schedule_id", expected one of: status, created_by`,
+		},
+		{
+			name:           "no access to client",
+			restrict:       true,
+			wantStatusCode: http.StatusForbidden,
+			wantErrTitle:   fmt.Sprintf("Access denied to client with id=%q.", testCID),
 		},
 		{
 			name:           "error on get job summaries",
@@ -1015,7 +1375,9 @@ func TestHandleGetCommands(t *testing.T) {
 				Server: &Server{
 					config: &Config{
 						Server: ServerConfig{MaxRequestBytes: 1024 * 1024},
+						API:    APIConfig{RestrictClientsByGroup: tc.restrict},
 					},
+					clientService: NewClientService(nil, clients.NewClientRepository([]*clients.Client{clients.New(t).ID(testCID).Build()}, &hour)),
 				},
 			}
 			al.initRouter()
@@ -1025,7 +1387,7 @@ func TestHandleGetCommands(t *testing.T) {
 			jp.ReturnJobSummaries = tc.jpReturnJobSummaries
 			al.jobProvider = jp
 
-			req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/clients/%s/commands", testCID), nil)
+			req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/clients/%s/commands%s", testCID, tc.query), nil)
 
 			// when
 			w := httptest.NewRecorder()
@@ -1037,6 +1399,9 @@ func TestHandleGetCommands(t *testing.T) {
 				// success case
 				assert.Equal(t, tc.wantSuccessResp, w.Body.String())
 				assert.Equal(t, testCID, jp.InputCID)
+				if tc.wantListOptions != nil {
+					assert.Equal(t, tc.wantListOptions, jp.InputListOptions)
+				}
 			} else {
 				// failure case
 				wantResp := api.NewErrorPayloadWithCode(tc.wantErrCode, tc.wantErrTitle, tc.wantErrDetail)
@@ -1048,6 +1413,143 @@ func TestHandleGetCommands(t *testing.T) {
 	}
 }
 
+func TestHandleGetCommandOutput(t *testing.T) {
+	job := jb.New(t).ClientID("cid-1234").JID("jid-1234").Status(models.JobStatusRunning).Result(&models.JobResult{StdOut: "out1", StdErr: "err1"}).Build()
+	al := APIListener{
+		insecureForTests: true,
+		Logger:           testLog,
+		Server: &Server{
+			config: &Config{
+				Server: ServerConfig{MaxRequestBytes: 1024 * 1024},
+			},
+			clientService: NewClientService(nil, clients.NewClientRepository([]*clients.Client{clients.New(t).ID(job.ClientID).Build()}, &hour)),
+		},
+	}
+	al.initRouter()
+	jp := NewJobProviderMock()
+	jp.ReturnJob = job
+	al.jobProvider = jp
+	url := fmt.Sprintf("/api/v1/clients/%s/commands/%s/output", job.ClientID, job.JID)
+
+	t.Run("without follow", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		al.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"data":{"jid":"jid-1234","status":"running","stdout":"out1","stderr":"err1"}}`, w.Body.String())
+	})
+
+	t.Run("invalid follow", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		al.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url+"?follow=abc", nil))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		wantResp := api.NewErrorPayloadWithCode(ErrCodeInvalidRequest, "Invalid follow param abc.", "")
+		b, err := json.Marshal(wantResp)
+		require.NoError(t, err)
+		assert.JSONEq(t, string(b), w.Body.String())
+	})
+
+	t.Run("with follow", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			al.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url+"?follow=1", nil))
+		}()
+		require.Eventually(t, func() bool {
+			return al.jobOutputs.subscribersCount(job.JID) == 1
+		}, time.Second, time.Millisecond)
+
+		chunk := &models.JobOutput{JID: job.JID, ClientID: job.ClientID, StdOut: "out2"}
+		delivered, err := al.jobOutputs.Publish(chunk, func() error { return nil })
+		require.NoError(t, err)
+		assert.True(t, delivered)
+		finishedJob := *job
+		finishedJob.Status = models.JobStatusSuccessful
+		jp.ReturnJob = &finishedJob
+		al.jobOutputs.Finish(job.JID)
+		<-done
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/x-ndjson; charset=UTF-8", w.Header().Get("Content-Type"))
+		lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+		require.Len(t, lines, 3)
+		assert.JSONEq(t, `{"jid":"jid-1234","status":"running","stdout":"out1","stderr":"err1"}`, lines[0])
+		assert.JSONEq(t, `{"jid":"jid-1234","status":"running","stdout":"out2","stderr":""}`, lines[1])
+		assert.JSONEq(t, `{"jid":"jid-1234","status":"successful","stdout":"","stderr":""}`, lines[2])
+		assert.Empty(t, al.jobOutputs.topics)
+	})
+
+	t.Run("with follow client disconnected", func(t *testing.T) {
+		jp.ReturnJob = job
+		w := httptest.NewRecorder()
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			al.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url+"?follow=1", nil))
+		}()
+		require.Eventually(t, func() bool {
+			return al.jobOutputs.subscribersCount(job.JID) == 1
+		}, time.Second, time.Millisecond)
+
+		al.jobOutputs.FinishByClientID(job.ClientID)
+		<-done
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+		require.Len(t, lines, 2)
+		assert.JSONEq(t, `{"jid":"jid-1234","status":"running","stdout":"","stderr":""}`, lines[1])
+		assert.Empty(t, al.jobOutputs.topics)
+	})
+
+	t.Run("with follow finished job", func(t *testing.T) {
+		finishedJob := *job
+		finishedJob.Status = models.JobStatusFailed
+		jp.ReturnJob = &finishedJob
+
+		w := httptest.NewRecorder()
+		al.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url+"?follow=1", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"jid":"jid-1234","status":"failed","stdout":"out1","stderr":"err1"}`, w.Body.String())
+		assert.Empty(t, al.jobOutputs.topics)
+	})
+
+	t.Run("with follow not found", func(t *testing.T) {
+		jp.ReturnJob = nil
+
+		w := httptest.NewRecorder()
+		al.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url+"?follow=1", nil))
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Empty(t, al.jobOutputs.topics)
+	})
+
+	t.Run("job of another client", func(t *testing.T) {
+		jp.ReturnJob = jb.New(t).ClientID("cid-5678").JID(job.JID).Build()
+
+		w := httptest.NewRecorder()
+		al.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("no access to client", func(t *testing.T) {
+		jp.ReturnJob = job
+		al.config.API.RestrictClientsByGroup = true
+		defer func() {
+			al.config.API.RestrictClientsByGroup = false
+		}()
+
+		w := httptest.NewRecorder()
+		al.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url+"?follow=1", nil))
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Empty(t, al.jobOutputs.topics)
+	})
+}
+
 func TestHandleGetClients(t *testing.T) {
 	c1 := clients.New(t).ID("client-1").ClientAuthID(cl1.ID).Build()
 	c2 := clients.New(t).ID("client-2").ClientAuthID(cl1.ID).DisconnectedDuration(5 * time.Minute).Build()
@@ -1097,7 +1599,22 @@ func TestHandleGetClients(t *testing.T) {
                "lport_random":false,
                "scheme":null,
                "acl":null,
-               "id":"1"
+               "idle_timeout_sec":0,
+               "auto_close_at":null,
+               "protocol":"",
+               "http_proxy":false,
+               "host_header":"",
+               "proxy_auth":false,
+               "id":"1",
+               "closes_in_sec":null,
+               "stats":{
+                  "bytes_in":0,
+                  "bytes_out":0,
+                  "connections":0,
+                  "active_connections":0,
+                  "last_connection_at":null,
+                  "last_peer_ip":""
+               }
             },
             {
                "lhost":"0.0.0.0",
@@ -1107,7 +1624,22 @@ func TestHandleGetClients(t *testing.T) {
                "lport_random":false,
                "scheme":null,
                "acl":null,
-               "id":"2"
+               "idle_timeout_sec":0,
+               "auto_close_at":null,
+               "protocol":"",
+               "http_proxy":false,
+               "host_header":"",
+               "proxy_auth":false,
+               "id":"2",
+               "closes_in_sec":null,
+               "stats":{
+                  "bytes_in":0,
+                  "bytes_out":0,
+                  "connections":0,
+                  "active_connections":0,
+                  "last_connection_at":null,
+                  "last_peer_ip":""
+               }
             }
          ],
          "connection_state":"connected",
@@ -1143,7 +1675,22 @@ func TestHandleGetClients(t *testing.T) {
                "lport_random":false,
                "scheme":null,
                "acl":null,
-               "id":"1"
+               "idle_timeout_sec":0,
+               "auto_close_at":null,
+               "protocol":"",
+               "http_proxy":false,
+               "host_header":"",
+               "proxy_auth":false,
+               "id":"1",
+               "closes_in_sec":null,
+               "stats":{
+                  "bytes_in":0,
+                  "bytes_out":0,
+                  "connections":0,
+                  "active_connections":0,
+                  "last_connection_at":null,
+                  "last_peer_ip":""
+               }
             },
             {
                "lhost":"0.0.0.0",
@@ -1153,75 +1700,421 @@ func TestHandleGetClients(t *testing.T) {
                "lport_random":false,
                "scheme":null,
                "acl":null,
-               "id":"2"
+               "idle_timeout_sec":0,
+               "auto_close_at":null,
+               "protocol":"",
+               "http_proxy":false,
+               "host_header":"",
+               "proxy_auth":false,
+               "id":"2",
+               "closes_in_sec":null,
+               "stats":{
+                  "bytes_in":0,
+                  "bytes_out":0,
+                  "connections":0,
+                  "active_connections":0,
+                  "last_connection_at":null,
+                  "last_peer_ip":""
+               }
             }
          ],
          "connection_state":"disconnected",
          "disconnected_at":"2020-08-19T13:04:23+03:00",
          "client_auth_id":"user1"
       }
-   ]
+   ],
+   "meta": {
+      "count": 2
+   }
 }`
 
 	assert.Equal(t, 200, w.Code)
 	assert.JSONEq(t, expectedJSON, w.Body.String())
 }
 
-func TestHandlePostMultiClientCommand(t *testing.T) {
-	testUser := "test-user"
-
-	connMock1 := test.NewConnMock()
-	// by default set to return success
-	connMock1.ReturnOk = true
-	sshSuccessResp1 := comm.RunCmdResponse{Pid: 1, StartedAt: time.Date(2020, 10, 10, 10, 10, 1, 0, time.UTC)}
-	sshRespBytes1, err := json.Marshal(sshSuccessResp1)
-	require.NoError(t, err)
-	connMock1.ReturnResponsePayload = sshRespBytes1
-
-	connMock2 := test.NewConnMock()
-	// by default set to return success
-	connMock2.ReturnOk = true
-	sshSuccessResp2 := comm.RunCmdResponse{Pid: 2, StartedAt: time.Date(2020, 10, 10, 10, 10, 2, 0, time.UTC)}
-	sshRespBytes2, err := json.Marshal(sshSuccessResp2)
-	require.NoError(t, err)
-	connMock2.ReturnResponsePayload = sshRespBytes2
-
-	c1 := clients.New(t).ID("client-1").Connection(connMock1).Build()
-	c2 := clients.New(t).ID("client-2").Connection(connMock2).Build()
-	c3 := clients.New(t).ID("client-3").DisconnectedDuration(5 * time.Minute).Build()
-
-	defaultTimeout := 60
-	gotCmd := "/bin/date;foo;whoami"
-	gotCmdTimeoutSec := 30
-	validReqBody := `{"command": "` + gotCmd +
-		`","timeout_sec": ` + strconv.Itoa(gotCmdTimeoutSec) +
-		`,"client_ids": ["` + c1.ID + `", "` + c2.ID + `"]` +
-		`,"abort_on_error": false` +
-		`,"execute_concurrently": false` +
-		`}`
+func TestHandleGetClientsWithQueryParams(t *testing.T) {
+	c1 := clients.New(t).ID("client-1").ClientAuthID(cl1.ID).Build()
+	c2 := clients.New(t).ID("client-2").ClientAuthID(cl1.ID).DisconnectedDuration(5 * time.Minute).Build()
+	c3 := clients.New(t).ID("client-3").ClientAuthID(cl2.ID).Build()
+	c3.Hostname = "win-srv-01"
+	al := APIListener{
+		insecureForTests: true,
+		Server: &Server{
+			clientService: NewClientService(nil, clients.NewClientRepository([]*clients.Client{c1, c2, c3}, &hour)),
+			config: &Config{
+				Server: ServerConfig{MaxRequestBytes: 1024 * 1024},
+			},
+		},
+	}
+	al.initRouter()
 
 	testCases := []struct {
 		name string
 
-		requestBody string
-		abortOnErr  bool
-
-		connReturnErr error
+		query string
 
 		wantStatusCode int
-		wantErrCode    string
-		wantErrTitle   string
-		wantErrDetail  string
-		wantJobErr     string
+		wantJSON       string
 	}{
 		{
-			name:           "valid cmd",
-			requestBody:    validReqBody,
+			name:           "filter and fields",
+			query:          "filter[connection_state]=connected&fields[clients]=id,hostname",
 			wantStatusCode: http.StatusOK,
+			wantJSON: `{
+				"data": [
+					{"id": "client-1", "hostname": "alpine-3-10-tk-01"},
+					{"id": "client-3", "hostname": "win-srv-01"}
+				],
+				"meta": {"count": 2}
+			}`,
 		},
 		{
-			name: "only one client",
-			requestBody: `
+			name:           "filter with wildcard",
+			query:          "filter[hostname]=WIN*&fields[clients]=id",
+			wantStatusCode: http.StatusOK,
+			wantJSON:       `{"data": [{"id": "client-3"}], "meta": {"count": 1}}`,
+		},
+		{
+			name:           "pagination with sort",
+			query:          "sort=-id&page[limit]=2&page[offset]=1&fields[clients]=id",
+			wantStatusCode: http.StatusOK,
+			wantJSON:       `{"data": [{"id": "client-2"}, {"id": "client-1"}], "meta": {"count": 3}}`,
+		},
+		{
+			name:           "offset out of range",
+			query:          "page[offset]=10&fields[clients]=id",
+			wantStatusCode: http.StatusOK,
+			wantJSON:       `{"data": [], "meta": {"count": 3}}`,
+		},
+		{
+			name:           "unsupported filter",
+			query:          "filter[tunnels]=1",
+			wantStatusCode: http.StatusBadRequest,
+			wantJSON:       `{"errors": [{"code": "ERR_CODE_INVALID_REQUEST", "title": "unsupported filter field \"tunnels\", expected one of: client_id, name, os, os_arch, os_family, os_kernel, hostname, ipv4, ipv6, tag, version, address, client_auth_id, connection_state", "detail": ""}]}`,
+		},
+		{
+			name:           "unsupported field",
+			query:          "fields[clients]=id,secret",
+			wantStatusCode: http.StatusBadRequest,
+			wantJSON:       `{"errors": [{"code": "ERR_CODE_INVALID_REQUEST", "title": "unsupported field \"secret\", expected one of: id, name, os, os_arch, os_family, os_kernel, hostname, ipv4, ipv6, tags, version, address, tunnels, disconnected_at, connection_state, client_auth_id", "detail": ""}]}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/clients?"+tc.query, nil)
+
+			al.router.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.wantStatusCode, w.Code)
+			assert.JSONEq(t, tc.wantJSON, w.Body.String())
+		})
+	}
+}
+
+func TestHandleGetClient(t *testing.T) {
+	ft := time.Date(2020, 10, 10, 10, 10, 10, 0, time.UTC)
+	c1 := clients.New(t).ID("client-1").ClientAuthID(cl1.ID).Build()
+	c2 := clients.New(t).ID("client-2").ClientAuthID(cl1.ID).DisconnectedDuration(5 * time.Minute).Build()
+	obsolete := clients.New(t).ID("client-3").ClientAuthID(cl1.ID).DisconnectedDuration(2 * hour).Build()
+	jb := jb.New(t).ClientID(c1.ID)
+	job1 := jb.Status(models.JobStatusSuccessful).FinishedAt(ft).Build().JobSummary
+	job2 := jb.Status(models.JobStatusRunning).Build().JobSummary
+
+	testCases := []struct {
+		name string
+
+		cid                  string
+		jpReturnErr          error
+		jpReturnJobSummaries []*models.JobSummary
+
+		wantStatusCode int
+		wantResp       interface{}
+	}{
+		{
+			name:                 "connected client with jobs",
+			cid:                  c1.ID,
+			jpReturnJobSummaries: []*models.JobSummary{&job2, &job1},
+			wantStatusCode:       http.StatusOK,
+			wantResp: api.NewSuccessPayload(ClientDetailsPayload{
+				ClientPayload: convertToClientPayload(c1),
+				Jobs:          []*models.JobSummary{&job2, &job1},
+			}),
+		},
+		{
+			name:                 "disconnected client without jobs",
+			cid:                  c2.ID,
+			jpReturnJobSummaries: []*models.JobSummary{},
+			wantStatusCode:       http.StatusOK,
+			wantResp: api.NewSuccessPayload(ClientDetailsPayload{
+				ClientPayload: convertToClientPayload(c2),
+				Jobs:          []*models.JobSummary{},
+			}),
+		},
+		{
+			name:           "obsolete client",
+			cid:            obsolete.ID,
+			wantStatusCode: http.StatusNotFound,
+			wantResp:       api.NewErrorPayloadWithCode("", `Client with id="client-3" not found.`, ""),
+		},
+		{
+			name:           "unknown client",
+			cid:            "unknown",
+			wantStatusCode: http.StatusNotFound,
+			wantResp:       api.NewErrorPayloadWithCode("", `Client with id="unknown" not found.`, ""),
+		},
+		{
+			name:           "error on get job summaries",
+			cid:            c1.ID,
+			jpReturnErr:    errors.New("get job summaries fake error"),
+			wantStatusCode: http.StatusInternalServerError,
+			wantResp:       api.NewErrorPayloadWithCode("", `Failed to get client jobs: client_id="client-1".`, "get job summaries fake error"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			al := APIListener{
+				insecureForTests: true,
+				Logger:           testLog,
+				Server: &Server{
+					clientService: NewClientService(nil, clients.NewClientRepository([]*clients.Client{c1, c2, obsolete}, &hour)),
+					config: &Config{
+						Server: ServerConfig{MaxRequestBytes: 1024 * 1024},
+					},
+				},
+			}
+			al.initRouter()
+
+			jp := NewJobProviderMock()
+			jp.ReturnErr = tc.jpReturnErr
+			jp.ReturnJobSummaries = tc.jpReturnJobSummaries
+			al.jobProvider = jp
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/clients/"+tc.cid, nil)
+
+			// when
+			w := httptest.NewRecorder()
+			al.router.ServeHTTP(w, req)
+
+			// then
+			assert.Equal(t, tc.wantStatusCode, w.Code)
+			wantRespBytes, err := json.Marshal(tc.wantResp)
+			require.NoError(t, err)
+			assert.JSONEq(t, string(wantRespBytes), w.Body.String())
+		})
+	}
+}
+
+func TestHandleGetClientTunnel(t *testing.T) {
+	c1 := clients.New(t).ID("client-1").ClientAuthID(cl1.ID).Build()
+
+	testCases := []struct {
+		name string
+
+		cid string
+		tid string
+
+		wantStatusCode int
+		wantResp       interface{}
+	}{
+		{
+			name:           "existing tunnel",
+			cid:            c1.ID,
+			tid:            "2",
+			wantStatusCode: http.StatusOK,
+			wantResp:       api.NewSuccessPayload(c1.Tunnels[1]),
+		},
+		{
+			name:           "unknown tunnel",
+			cid:            c1.ID,
+			tid:            "3",
+			wantStatusCode: http.StatusNotFound,
+			wantResp:       api.NewErrorPayloadWithCode("", "tunnel not found", ""),
+		},
+		{
+			name:           "unknown client",
+			cid:            "unknown",
+			tid:            "1",
+			wantStatusCode: http.StatusNotFound,
+			wantResp:       api.NewErrorPayloadWithCode("", "client with id unknown not found", ""),
+		},
+	}
+
+	al := APIListener{
+		insecureForTests: true,
+		Logger:           testLog,
+		Server: &Server{
+			clientService: NewClientService(nil, clients.NewClientRepository([]*clients.Client{c1}, &hour)),
+			config: &Config{
+				Server: ServerConfig{MaxRequestBytes: 1024 * 1024},
+			},
+		},
+	}
+	al.initRouter()
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/clients/"+tc.cid+"/tunnels/"+tc.tid, nil)
+
+			// when
+			w := httptest.NewRecorder()
+			al.router.ServeHTTP(w, req)
+
+			// then
+			assert.Equal(t, tc.wantStatusCode, w.Code)
+			wantRespBytes, err := json.Marshal(tc.wantResp)
+			require.NoError(t, err)
+			assert.JSONEq(t, string(wantRespBytes), w.Body.String())
+		})
+	}
+}
+
+func TestHandleDeleteOfflineClient(t *testing.T) {
+	ctx := context.Background()
+	c1 := clients.New(t).ID("client-1").ClientAuthID(cl1.ID).Build()
+	c2 := clients.New(t).ID("client-2").ClientAuthID(cl1.ID).DisconnectedDuration(5 * time.Minute).Build()
+
+	testCases := []struct {
+		name string
+
+		cid string
+
+		wantStatusCode int
+		wantErrCode    string
+		wantErrTitle   string
+		wantClients    []*clients.Client
+	}{
+		{
+			name:           "disconnected client",
+			cid:            c2.ID,
+			wantStatusCode: http.StatusNoContent,
+			wantClients:    []*clients.Client{c1},
+		},
+		{
+			name:           "active client",
+			cid:            c1.ID,
+			wantStatusCode: http.StatusConflict,
+			wantErrCode:    ErrCodeClientActive,
+			wantErrTitle:   `Client with id="client-1" is active and can't be deleted. Only disconnected clients can be deleted.`,
+			wantClients:    []*clients.Client{c1, c2},
+		},
+		{
+			name:           "unknown client",
+			cid:            "unknown",
+			wantStatusCode: http.StatusNotFound,
+			wantErrTitle:   `Client with id="unknown" not found.`,
+			wantClients:    []*clients.Client{c1, c2},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			clientProvider, err := clients.NewSqliteProvider(":memory:", hour)
+			require.NoError(t, err)
+			defer clientProvider.Close()
+			require.NoError(t, clientProvider.Save(ctx, c1))
+			require.NoError(t, clientProvider.Save(ctx, c2))
+
+			al := APIListener{
+				insecureForTests: true,
+				Logger:           testLog,
+				Server: &Server{
+					clientService:  NewClientService(nil, clients.NewClientRepository([]*clients.Client{c1, c2}, &hour)),
+					clientProvider: clientProvider,
+					config: &Config{
+						Server: ServerConfig{MaxRequestBytes: 1024 * 1024},
+					},
+				},
+			}
+			al.initRouter()
+
+			req := httptest.NewRequest(http.MethodDelete, "/api/v1/clients/"+tc.cid, nil)
+
+			// when
+			w := httptest.NewRecorder()
+			al.router.ServeHTTP(w, req)
+
+			// then
+			assert.Equal(t, tc.wantStatusCode, w.Code)
+			if tc.wantErrTitle != "" {
+				wantResp := api.NewErrorPayloadWithCode(tc.wantErrCode, tc.wantErrTitle, "")
+				wantRespBytes, err := json.Marshal(wantResp)
+				require.NoError(t, err)
+				assert.Equal(t, string(wantRespBytes), w.Body.String())
+			}
+
+			gotRepoClients, err := al.clientService.GetAll()
+			require.NoError(t, err)
+			assert.ElementsMatch(t, tc.wantClients, gotRepoClients)
+
+			gotDBClients, err := clientProvider.GetAll(ctx)
+			require.NoError(t, err)
+			assert.Len(t, gotDBClients, len(tc.wantClients))
+		})
+	}
+}
+
+func TestHandlePostMultiClientCommand(t *testing.T) {
+	testUser := "test-user"
+
+	connMock1 := test.NewConnMock()
+	// by default set to return success
+	connMock1.ReturnOk = true
+	sshSuccessResp1 := comm.RunCmdResponse{Pid: 1, StartedAt: time.Date(2020, 10, 10, 10, 10, 1, 0, time.UTC)}
+	sshRespBytes1, err := json.Marshal(sshSuccessResp1)
+	require.NoError(t, err)
+	connMock1.ReturnResponsePayload = sshRespBytes1
+
+	connMock2 := test.NewConnMock()
+	// by default set to return success
+	connMock2.ReturnOk = true
+	sshSuccessResp2 := comm.RunCmdResponse{Pid: 2, StartedAt: time.Date(2020, 10, 10, 10, 10, 2, 0, time.UTC)}
+	sshRespBytes2, err := json.Marshal(sshSuccessResp2)
+	require.NoError(t, err)
+	connMock2.ReturnResponsePayload = sshRespBytes2
+
+	c1 := clients.New(t).ID("client-1").Connection(connMock1).Build()
+	c2 := clients.New(t).ID("client-2").Connection(connMock2).Build()
+	c3 := clients.New(t).ID("client-3").DisconnectedDuration(5 * time.Minute).Build()
+
+	defaultTimeout := 60
+	gotCmd := "/bin/date;foo;whoami"
+	gotCmdTimeoutSec := 30
+	validReqBody := `{"command": "` + gotCmd +
+		`","timeout_sec": ` + strconv.Itoa(gotCmdTimeoutSec) +
+		`,"client_ids": ["` + c1.ID + `", "` + c2.ID + `"]` +
+		`,"abort_on_error": false` +
+		`,"execute_concurrently": false` +
+		`,"timeout_policy": "kill"` +
+		`,"success_exit_codes": [0, 1]` +
+		`}`
+
+	testCases := []struct {
+		name string
+
+		requestBody string
+		abortOnErr  bool
+
+		connReturnErr error
+
+		wantStatusCode int
+		wantErrCode    string
+		wantErrTitle   string
+		wantErrDetail  string
+		wantJobErr     string
+	}{
+		{
+			name:           "valid cmd",
+			requestBody:    validReqBody,
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name: "only one client",
+			requestBody: `
 		{
 			"command": "/bin/date;foo;whoami",
 			"timeout_sec": 30,
@@ -1345,6 +2238,9 @@ func TestHandlePostMultiClientCommand(t *testing.T) {
 				} else {
 					require.Len(t, gotMultiJob.Jobs, 2)
 				}
+				assert.Equal(t, gotMultiJob.TimeoutPolicy, gotMultiJob.Jobs[0].TimeoutPolicy)
+				assert.Equal(t, gotMultiJob.TimeoutGraceSec, gotMultiJob.Jobs[0].TimeoutGraceSec)
+				assert.Equal(t, gotMultiJob.SuccessExitCodes, gotMultiJob.Jobs[0].SuccessExitCodes)
 				if tc.connReturnErr != nil {
 					assert.Equal(t, models.JobStatusFailed, gotMultiJob.Jobs[0].Status)
 					assert.Equal(t, tc.wantJobErr, gotMultiJob.Jobs[0].Error)
@@ -1365,6 +2261,144 @@ func TestHandlePostMultiClientCommand(t *testing.T) {
 	}
 }
 
+func TestHandleDeleteMultiClientCommand(t *testing.T) {
+	connMock1 := test.NewConnMock()
+	connMock1.ReturnOk = true
+	connMock1.ReturnResponsePayload = []byte(`{"Pid":123}`)
+	connMock2 := test.NewConnMock()
+	c1 := clients.New(t).ID("client-1").Connection(connMock1).Build()
+	c2 := clients.New(t).ID("client-2").Connection(connMock2).Build()
+	c3 := clients.New(t).ID("client-3").DisconnectedDuration(5 * time.Minute).Build()
+
+	multiJob := jb.NewMulti(t).JID("multi-jid").ClientIDs(c1.ID, c2.ID, c3.ID).Build()
+	job1 := jb.New(t).JID("jid-1").MultiJobID(multiJob.JID).ClientID(c1.ID).Status(models.JobStatusRunning).Build()
+	job2 := jb.New(t).JID("jid-2").MultiJobID(multiJob.JID).ClientID(c2.ID).Status(models.JobStatusSuccessful).Build()
+	job3 := jb.New(t).JID("jid-3").MultiJobID(multiJob.JID).ClientID(c3.ID).Status(models.JobStatusRunning).Build()
+	job4 := jb.New(t).JID("jid-4").MultiJobID(multiJob.JID).ClientID(c3.ID).Status(models.JobStatusQueued).ExpiresAt(time.Now().Add(time.Hour)).Build()
+
+	al := APIListener{
+		insecureForTests: true,
+		Server: &Server{
+			clientService: NewClientService(nil, clients.NewClientRepository([]*clients.Client{c1, c2, c3}, &hour)),
+			config: &Config{
+				Server: ServerConfig{MaxRequestBytes: 1024 * 1024},
+			},
+		},
+		Logger: testLog,
+	}
+	al.initRouter()
+
+	jp, err := jobs.NewSqliteProvider(":memory:", testLog)
+	require.NoError(t, err)
+	defer jp.Close()
+	al.jobProvider = jp
+	require.NoError(t, jp.SaveMultiJob(multiJob))
+	require.NoError(t, jp.SaveJob(job1))
+	require.NoError(t, jp.SaveJob(job2))
+	require.NoError(t, jp.SaveJob(job3))
+	require.NoError(t, jp.SaveJob(job4))
+	al.multiJobsCancel.Start(multiJob.JID)
+
+	// when
+	w := httptest.NewRecorder()
+	al.router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/v1/commands/multi-jid", nil))
+
+	// then
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.True(t, al.multiJobsCancel.IsCancelled(multiJob.JID))
+	// only the running job of the active client is cancelled
+	name, _, payload := connMock1.InputSendRequest()
+	assert.Equal(t, comm.RequestTypeCancelCmd, name)
+	assert.JSONEq(t, `{"JID":"jid-1"}`, string(payload))
+	name, _, _ = connMock2.InputSendRequest()
+	assert.Empty(t, name)
+	// the job queued on the server for the inactive client is cancelled without contacting it
+	gotJob4, err := jp.GetByJID(c3.ID, job4.JID)
+	require.NoError(t, err)
+	assert.Equal(t, models.JobStatusCancelled, gotJob4.Status)
+
+	// verify finished multi-client job can't be cancelled
+	al.multiJobsCancel.Finish(multiJob.JID)
+	require.NoError(t, jp.SaveJob(jb.New(t).JID("jid-1").MultiJobID(multiJob.JID).ClientID(c1.ID).Status(models.JobStatusCancelled).Build()))
+	require.NoError(t, jp.SaveJob(jb.New(t).JID("jid-3").MultiJobID(multiJob.JID).ClientID(c3.ID).Status(models.JobStatusFailed).Build()))
+
+	w = httptest.NewRecorder()
+	al.router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/v1/commands/multi-jid", nil))
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	wantResp := api.NewErrorPayloadWithCode(ErrCodeJobNotRunning, `Multi-client Job[id="multi-jid"] is not running.`, "")
+	wantRespBytes, err := json.Marshal(wantResp)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(wantRespBytes), w.Body.String())
+
+	// verify not found
+	w = httptest.NewRecorder()
+	al.router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/v1/commands/unknown-jid", nil))
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestHandleDeleteMultiClientCommandRestricted(t *testing.T) {
+	connMock := test.NewConnMock()
+	connMock.ReturnOk = true
+	connMock.ReturnResponsePayload = []byte(`{"Pid":123}`)
+	c1 := clients.New(t).ID("client-1").Connection(connMock).Build()
+	c2 := clients.New(t).ID("client-2").Connection(test.NewConnMock()).Build()
+	g1 := &cgroups.ClientGroup{ID: "group-1", Params: &cgroups.ClientParams{ClientID: &cgroups.ParamValues{"client-1"}}}
+
+	al := APIListener{
+		insecureForTests: true,
+		Server: &Server{
+			clientService: NewClientService(nil, clients.NewClientRepository([]*clients.Client{c1, c2}, &hour)),
+			config: &Config{
+				Server: ServerConfig{MaxRequestBytes: 1024 * 1024},
+				API:    APIConfig{RestrictClientsByGroup: true},
+			},
+			clientGroupProvider: &ClientGroupProviderMock{ReturnGroups: []*cgroups.ClientGroup{g1}},
+		},
+		userSrv: users.NewUserCache([]*users.User{{Username: "user1", Groups: []string{"group-1"}}}),
+		Logger:  testLog,
+	}
+	al.initRouter()
+
+	jp, err := jobs.NewSqliteProvider(":memory:", testLog)
+	require.NoError(t, err)
+	defer jp.Close()
+	al.jobProvider = jp
+
+	// a job on an accessible client only
+	ownJob := jb.NewMulti(t).JID("own-jid").ClientIDs(c1.ID).Build()
+	require.NoError(t, jp.SaveMultiJob(ownJob))
+	require.NoError(t, jp.SaveJob(jb.New(t).JID("jid-1").MultiJobID(ownJob.JID).ClientID(c1.ID).Status(models.JobStatusRunning).Build()))
+	// a job on accessible and not accessible clients
+	mixedJob := jb.NewMulti(t).JID("mixed-jid").ClientIDs(c1.ID, c2.ID).Build()
+	require.NoError(t, jp.SaveMultiJob(mixedJob))
+	require.NoError(t, jp.SaveJob(jb.New(t).JID("jid-2").MultiJobID(mixedJob.JID).ClientID(c1.ID).Status(models.JobStatusRunning).Build()))
+	al.multiJobsCancel.Start(mixedJob.JID)
+	defer al.multiJobsCancel.Finish(mixedJob.JID)
+
+	do := func(jid string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodDelete, "/api/v1/commands/"+jid, nil)
+		req = req.WithContext(api.WithUser(req.Context(), "user1"))
+		w := httptest.NewRecorder()
+		al.router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(mixedJob.JID)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, `{"errors":[{"code":"","title":"Access denied to multi-client job[id=\"mixed-jid\"].","detail":""}]}`, w.Body.String())
+	assert.False(t, al.multiJobsCancel.IsCancelled(mixedJob.JID))
+	name, _, _ := connMock.InputSendRequest()
+	assert.Empty(t, name)
+
+	w = do(ownJob.JID)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	name, _, payload := connMock.InputSendRequest()
+	assert.Equal(t, comm.RequestTypeCancelCmd, name)
+	assert.JSONEq(t, `{"JID":"jid-1"}`, string(payload))
+}
+
 func TestValidateInputClientGroup(t *testing.T) {
 	testCases := []struct {
 		name    string
@@ -1437,3 +2471,239 @@ func TestValidateInputClientGroup(t *testing.T) {
 		})
 	}
 }
+
+func TestPermissionsMiddleware(t *testing.T) {
+	admin := &users.User{Username: "admin", Groups: []string{users.Administrators}}
+	operator := &users.User{Username: "operator", Groups: []string{"Operators"}}
+	helpdesk := &users.User{Username: "helpdesk", Groups: []string{"Helpdesk"}}
+
+	testCases := []struct {
+		name string
+
+		username   string
+		permission users.Permission
+
+		wantStatusCode int
+		wantErrTitle   string
+	}{
+		{
+			name:           "admin",
+			username:       admin.Username,
+			permission:     users.PermissionClientsAuth,
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "granted permission",
+			username:       operator.Username,
+			permission:     users.PermissionCommands,
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "missing permission",
+			username:       operator.Username,
+			permission:     users.PermissionClientsAuth,
+			wantStatusCode: http.StatusForbidden,
+			wantErrTitle:   `Access denied. Permission "clients-auth" is required.`,
+		},
+		{
+			name:           "no permissions",
+			username:       helpdesk.Username,
+			permission:     users.PermissionTunnels,
+			wantStatusCode: http.StatusForbidden,
+			wantErrTitle:   `Access denied. Permission "tunnels" is required.`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			al := APIListener{
+				Server: &Server{
+					config: &Config{
+						API: APIConfig{
+							groupPermissions: users.GroupPermissions{
+								"operators": {users.PermissionTunnels, users.PermissionCommands},
+							},
+						},
+					},
+				},
+				userSrv: users.NewUserCache([]*users.User{admin, operator, helpdesk}),
+				Logger:  testLog,
+			}
+			handler := al.permissionsMiddleware(tc.permission, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+			req := httptest.NewRequest(http.MethodPost, "/some-url", nil)
+			req = req.WithContext(api.WithUser(req.Context(), tc.username))
+
+			// when
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			// then
+			assert.Equal(t, tc.wantStatusCode, w.Code)
+			if tc.wantErrTitle != "" {
+				wantRespBytes, err := json.Marshal(api.NewErrorPayloadWithCode("", tc.wantErrTitle, ""))
+				require.NoError(t, err)
+				assert.Equal(t, string(wantRespBytes), w.Body.String())
+			}
+		})
+	}
+}
+
+func TestCmdAuditParams(t *testing.T) {
+	reqBody := multiClientCmdRequest{
+		ClientIDs:   []string{"client-1"},
+		GroupIDs:    []string{"group-1"},
+		Interpreter: "powershell",
+		Script:      "echo $env:TOKEN",
+		ScriptID:    "script-1",
+		Params:      map[string]string{"PASSWORD": "secret"},
+		Env:         map[string]string{"TOKEN": "secret"},
+	}
+
+	gotBytes, err := json.Marshal(reqBody.auditParams())
+	require.NoError(t, err)
+	assert.Equal(t, `{"client_ids":["client-1"],"group_ids":["group-1"],"interpreter":"powershell","script_id":"script-1"}`, string(gotBytes))
+}
+
+func TestClientGroupsMiddleware(t *testing.T) {
+	admin := &users.User{Username: "admin", Groups: []string{users.Administrators}}
+	operator := &users.User{Username: "operator", Groups: []string{"Operators"}}
+
+	testCases := []struct {
+		name string
+
+		username string
+		restrict bool
+
+		wantStatusCode int
+		wantErrTitle   string
+	}{
+		{
+			name:           "granted permission",
+			username:       operator.Username,
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "restricted, admin",
+			username:       admin.Username,
+			restrict:       true,
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "restricted, granted permission",
+			username:       operator.Username,
+			restrict:       true,
+			wantStatusCode: http.StatusForbidden,
+			wantErrTitle:   `Access denied. Only members of "Administrators" group are allowed.`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// given
+			al := APIListener{
+				Server: &Server{
+					config: &Config{
+						API: APIConfig{
+							RestrictClientsByGroup: tc.restrict,
+							groupPermissions: users.GroupPermissions{
+								"operators": {users.PermissionClientGroups},
+							},
+						},
+					},
+				},
+				userSrv: users.NewUserCache([]*users.User{admin, operator}),
+				Logger:  testLog,
+			}
+			handler := al.clientGroupsMiddleware(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+			req := httptest.NewRequest(http.MethodPost, "/client-groups", nil)
+			req = req.WithContext(api.WithUser(req.Context(), tc.username))
+
+			// when
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			// then
+			assert.Equal(t, tc.wantStatusCode, w.Code)
+			if tc.wantErrTitle != "" {
+				wantRespBytes, err := json.Marshal(api.NewErrorPayloadWithCode("", tc.wantErrTitle, ""))
+				require.NoError(t, err)
+				assert.Equal(t, string(wantRespBytes), w.Body.String())
+			}
+		})
+	}
+}
+
+func TestParseHTTPProxyParams(t *testing.T) {
+	testCases := []struct {
+		descr      string
+		params     url.Values
+		scheme     string
+		protocol   string
+		wantRemote chshare.Remote
+		wantErr    string
+	}{
+		{
+			descr:      "no proxy",
+			params:     url.Values{},
+			wantRemote: chshare.Remote{},
+		},
+		{
+			descr:      "proxy with all options",
+			params:     url.Values{"http_proxy": {"1"}, "proxy_auth": {"true"}, "host_header": {"app.internal"}},
+			scheme:     "https",
+			wantRemote: chshare.Remote{HTTPProxy: true, ProxyAuth: true, HostHeader: "app.internal"},
+		},
+		{
+			descr:   "invalid http_proxy",
+			params:  url.Values{"http_proxy": {"yes"}},
+			wantErr: `invalid "http_proxy" query param: expected a boolean value, actual "yes"`,
+		},
+		{
+			descr:   "invalid proxy_auth",
+			params:  url.Values{"http_proxy": {"1"}, "proxy_auth": {"yes"}},
+			wantErr: `invalid "proxy_auth" query param: expected a boolean value, actual "yes"`,
+		},
+		{
+			descr:   "proxy options without proxy",
+			params:  url.Values{"host_header": {"app.internal"}},
+			wantErr: "'proxy_auth' and 'host_header' can be set only if 'http_proxy' is enabled",
+		},
+		{
+			descr:    "udp",
+			params:   url.Values{"http_proxy": {"1"}},
+			protocol: chshare.ProtocolUDP,
+			wantErr:  "http proxy is not supported for udp tunnels",
+		},
+		{
+			descr:   "unsupported scheme",
+			params:  url.Values{"http_proxy": {"1"}},
+			scheme:  "ssh",
+			wantErr: `invalid scheme "ssh" for http proxy: expected 'http' or 'https'`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.descr, func(t *testing.T) {
+			remote := &chshare.Remote{Protocol: tc.protocol}
+			if tc.scheme != "" {
+				remote.Scheme = &tc.scheme
+			}
+
+			err := parseHTTPProxyParams(tc.params, remote)
+
+			if tc.wantErr != "" {
+				assert.EqualError(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantRemote.HTTPProxy, remote.HTTPProxy)
+			assert.Equal(t, tc.wantRemote.ProxyAuth, remote.ProxyAuth)
+			assert.Equal(t, tc.wantRemote.HostHeader, remote.HostHeader)
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package auditlog
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/cloudradar-monitoring/rport/server/api"
+	chshare "github.com/cloudradar-monitoring/rport/share"
+)
+
+const (
+	ApplicationAuth               = "auth"
+	ApplicationClient             = "client"
+	ApplicationClientTunnel       = "client.tunnel"
+	ApplicationClientCommand      = "client.command"
+	ApplicationMultiClientCommand = "multi_client.command"
+	ApplicationClientsAuth        = "clients_auth"
+	ApplicationClientGroups       = "client_groups"
+	ApplicationLibraryScript      = "library.script"
+	ApplicationSchedule           = "schedule"
+
+	ActionCreate      = "create"
+	ActionUpdate      = "update"
+	ActionDelete      = "delete"
+	ActionCancel      = "cancel"
+	ActionLogin       = "login"
+	ActionLoginFailed = "login_failed"
+)
+
+// now is used to stub time.Now in tests
+var now = time.Now
+
+type Provider interface {
+	Save(ctx context.Context, e *Entry) error
+	List(ctx context.Context, opts *ListOptions) ([]*Entry, error)
+	Count(ctx context.Context, opts *ListOptions) (int, error)
+	Close() error
+}
+
+// AuditLog records state-changing API actions. The log is append-only, recorded entries are never changed.
+type AuditLog struct {
+	log            *chshare.Logger
+	provider       Provider
+	trustedProxies api.TrustedProxies
+}
+
+func New(log *chshare.Logger, provider Provider, trustedProxies api.TrustedProxies) *AuditLog {
+	return &AuditLog{
+		log:            log,
+		provider:       provider,
+		trustedProxies: trustedProxies,
+	}
+}
+
+// Entry returns a new entry to record a given action. A nil AuditLog returns an entry that is never saved.
+func (a *AuditLog) Entry(application, action string) *Entry {
+	return &Entry{
+		Application: application,
+		Action:      action,
+		auditLog:    a,
+	}
+}
+
+// List returns entries matching given options sorted by timestamp in desc order.
+func (a *AuditLog) List(ctx context.Context, opts *ListOptions) ([]*Entry, error) {
+	return a.provider.List(ctx, opts)
+}
+
+// Count returns a number of entries matching given options regardless of pagination.
+func (a *AuditLog) Count(ctx context.Context, opts *ListOptions) (int, error) {
+	return a.provider.Count(ctx, opts)
+}
+
+func (a *AuditLog) Close() error {
+	return a.provider.Close()
+}
+
+// Entry represents a single recorded API action.
+type Entry struct {
+	Timestamp   time.Time       `json:"timestamp"`
+	Username    string          `json:"username"`
+	RemoteIP    string          `json:"remote_ip"`
+	Application string          `json:"application"`
+	Action      string          `json:"action"`
+	AffectedID  string          `json:"affected_id"`
+	ClientID    string          `json:"client_id"`
+	Params      json.RawMessage `json:"params"`
+
+	auditLog *AuditLog
+	params   interface{}
+}
+
+// WithHTTPRequest sets the user who performs a given authenticated request and the source IP of the request.
+func (e *Entry) WithHTTPRequest(req *http.Request) *Entry {
+	if e.auditLog == nil {
+		return e
+	}
+	e.Username = api.GetUser(req.Context(), e.auditLog.log)
+	return e.WithRemoteIP(req)
+}
+
+// WithRemoteIP sets the source IP of a given request.
+func (e *Entry) WithRemoteIP(req *http.Request) *Entry {
+	if e.auditLog == nil {
+		return e
+	}
+	e.RemoteIP = e.auditLog.trustedProxies.RemoteIP(req)
+	return e
+}
+
+// WithUsername sets the user who performed the action.
+func (e *Entry) WithUsername(username string) *Entry {
+	e.Username = username
+	return e
+}
+
+// WithID sets an ID of the affected object, e.g. tunnel ID, job ID, etc.
+func (e *Entry) WithID(id string) *Entry {
+	e.AffectedID = id
+	return e
+}
+
+func (e *Entry) WithClientID(clientID string) *Entry {
+	e.ClientID = clientID
+	return e
+}
+
+// WithParams sets parameters of the action. They are stored as JSON.
+func (e *Entry) WithParams(params interface{}) *Entry {
+	e.params = params
+	return e
+}
+
+// Save records the entry. Errors are only logged to not fail already performed actions.
+func (e *Entry) Save() {
+	if e.auditLog == nil {
+		return
+	}
+
+	e.Timestamp = now().UTC()
+	e.Params = json.RawMessage("null")
+	if e.params != nil {
+		b, err := json.Marshal(e.params)
+		if err != nil {
+			e.auditLog.log.Errorf("Failed to encode audit log params of %s %s: %v", e.Application, e.Action, err)
+		} else {
+			e.Params = b
+		}
+	}
+
+	if err := e.auditLog.provider.Save(context.Background(), e); err != nil {
+		e.auditLog.log.Errorf("Failed to save audit log entry %s %s by %q: %v", e.Application, e.Action, e.Username, err)
+	}
+}
@@ -0,0 +1,136 @@
+package auditlog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/cloudradar-monitoring/rport/db/migration/auditlog"
+	"github.com/cloudradar-monitoring/rport/db/sqlite"
+	"github.com/cloudradar-monitoring/rport/server/api"
+)
+
+// FilterFields are fields entries can be filtered by.
+var FilterFields = []string{"username", "remote_ip", "application", "action", "affected_id", "client_id"}
+
+// ListOptions defines what entries to return.
+type ListOptions struct {
+	// Filters contains accepted values by field, see FilterFields.
+	Filters    map[string][]string
+	Since      *time.Time
+	Until      *time.Time
+	Pagination *api.Pagination
+}
+
+// entryRow is used to read entries from DB, params are stored as TEXT that can't be scanned into json.RawMessage.
+type entryRow struct {
+	Timestamp   time.Time `db:"timestamp"`
+	Username    string    `db:"username"`
+	RemoteIP    string    `db:"remote_ip"`
+	Application string    `db:"application"`
+	Action      string    `db:"action"`
+	AffectedID  string    `db:"affected_id"`
+	ClientID    string    `db:"client_id"`
+	Params      string    `db:"params"`
+}
+
+func (r *entryRow) toEntry() *Entry {
+	return &Entry{
+		Timestamp:   r.Timestamp,
+		Username:    r.Username,
+		RemoteIP:    r.RemoteIP,
+		Application: r.Application,
+		Action:      r.Action,
+		AffectedID:  r.AffectedID,
+		ClientID:    r.ClientID,
+		Params:      json.RawMessage(r.Params),
+	}
+}
+
+type SqliteProvider struct {
+	db *sqlx.DB
+}
+
+func NewSqliteProvider(dbPath string) (*SqliteProvider, error) {
+	db, err := sqlite.New(dbPath, auditlog.AssetNames(), auditlog.Asset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create auditlog DB instance: %v", err)
+	}
+	return &SqliteProvider{db: db}, nil
+}
+
+func (p *SqliteProvider) Save(ctx context.Context, e *Entry) error {
+	_, err := p.db.ExecContext(
+		ctx,
+		`INSERT INTO auditlog (timestamp, username, remote_ip, application, action, affected_id, client_id, params)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		e.Timestamp, e.Username, e.RemoteIP, e.Application, e.Action, e.AffectedID, e.ClientID, string(e.Params),
+	)
+	return err
+}
+
+func (p *SqliteProvider) List(ctx context.Context, opts *ListOptions) ([]*Entry, error) {
+	where, params := buildWhere(opts)
+	query := "SELECT timestamp, username, remote_ip, application, action, affected_id, client_id, params FROM auditlog" + where + " ORDER BY timestamp DESC, id DESC"
+	if opts.Pagination != nil {
+		query += " LIMIT ? OFFSET ?"
+		params = append(params, opts.Pagination.Limit, opts.Pagination.Offset)
+	}
+
+	var rows []*entryRow
+	err := p.db.SelectContext(ctx, &rows, query, params...)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*Entry, 0, len(rows))
+	for _, r := range rows {
+		res = append(res, r.toEntry())
+	}
+	return res, nil
+}
+
+func (p *SqliteProvider) Count(ctx context.Context, opts *ListOptions) (int, error) {
+	where, params := buildWhere(opts)
+	var res int
+	err := p.db.GetContext(ctx, &res, "SELECT COUNT(*) FROM auditlog"+where, params...)
+	return res, err
+}
+
+func (p *SqliteProvider) Close() error {
+	return p.db.Close()
+}
+
+func buildWhere(opts *ListOptions) (string, []interface{}) {
+	var conditions []string
+	var params []interface{}
+	// iterate over supported fields to have a deterministic query and to use only known column names
+	for _, field := range FilterFields {
+		values := opts.Filters[field]
+		if len(values) == 0 {
+			continue
+		}
+		conditions = append(conditions, fmt.Sprintf("%s IN (?%s)", field, strings.Repeat(", ?", len(values)-1)))
+		for _, v := range values {
+			params = append(params, v)
+		}
+	}
+	// timestamps are stored in UTC and compared as strings, so bounds should be in UTC as well
+	if opts.Since != nil {
+		conditions = append(conditions, "timestamp >= ?")
+		params = append(params, opts.Since.UTC())
+	}
+	if opts.Until != nil {
+		conditions = append(conditions, "timestamp <= ?")
+		params = append(params, opts.Until.UTC())
+	}
+
+	if len(conditions) == 0 {
+		return "", params
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), params
+}
@@ -0,0 +1,106 @@
+package auditlog
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudradar-monitoring/rport/server/api"
+	chshare "github.com/cloudradar-monitoring/rport/share"
+)
+
+var testLog = chshare.NewLogger("auditlog-test", chshare.LogOutput{File: os.Stdout}, chshare.LogLevelDebug)
+
+func TestAuditLogSqlite(t *testing.T) {
+	ctx := context.Background()
+	p, err := NewSqliteProvider(":memory:")
+	require.NoError(t, err)
+	defer p.Close()
+	a := New(testLog, p, nil)
+
+	t1 := time.Date(2020, 10, 10, 10, 10, 1, 0, time.UTC)
+	t2 := t1.Add(time.Minute)
+	t3 := t2.Add(time.Minute)
+	t2Local := t2.In(time.FixedZone("UTC+2", 2*60*60))
+
+	now = func() time.Time { return t1 }
+	a.Entry(ApplicationAuth, ActionLogin).WithUsername("admin").Save()
+	now = func() time.Time { return t2 }
+	a.Entry(ApplicationClientTunnel, ActionCreate).WithUsername("admin").WithID("1").WithClientID("client-1").WithParams(map[string]string{"remote": "22"}).Save()
+	now = func() time.Time { return t3 }
+	a.Entry(ApplicationClientCommand, ActionCreate).WithUsername("user1").WithID("job-1").WithClientID("client-1").Save()
+	defer func() { now = time.Now }()
+
+	e1 := &Entry{Timestamp: t1, Username: "admin", Application: ApplicationAuth, Action: ActionLogin, Params: json.RawMessage("null")}
+	e2 := &Entry{Timestamp: t2, Username: "admin", Application: ApplicationClientTunnel, Action: ActionCreate, AffectedID: "1", ClientID: "client-1", Params: json.RawMessage(`{"remote":"22"}`)}
+	e3 := &Entry{Timestamp: t3, Username: "user1", Application: ApplicationClientCommand, Action: ActionCreate, AffectedID: "job-1", ClientID: "client-1", Params: json.RawMessage("null")}
+
+	testCases := []struct {
+		name string
+
+		opts *ListOptions
+
+		wantEntries []*Entry
+		wantCount   int
+	}{
+		{
+			name:        "all",
+			opts:        &ListOptions{},
+			wantEntries: []*Entry{e3, e2, e1},
+			wantCount:   3,
+		},
+		{
+			name:        "filter by username",
+			opts:        &ListOptions{Filters: map[string][]string{"username": {"admin"}}},
+			wantEntries: []*Entry{e2, e1},
+			wantCount:   2,
+		},
+		{
+			name:        "filter by several values",
+			opts:        &ListOptions{Filters: map[string][]string{"application": {ApplicationAuth, ApplicationClientCommand}}},
+			wantEntries: []*Entry{e3, e1},
+			wantCount:   2,
+		},
+		{
+			name:        "filter by client and time range",
+			opts:        &ListOptions{Filters: map[string][]string{"client_id": {"client-1"}}, Since: &t2, Until: &t2},
+			wantEntries: []*Entry{e2},
+			wantCount:   1,
+		},
+		{
+			name:        "time range in other time zone",
+			opts:        &ListOptions{Since: &t2Local},
+			wantEntries: []*Entry{e3, e2},
+			wantCount:   2,
+		},
+		{
+			name:        "no matches",
+			opts:        &ListOptions{Filters: map[string][]string{"action": {ActionDelete}}},
+			wantEntries: []*Entry{},
+			wantCount:   0,
+		},
+		{
+			name:        "pagination",
+			opts:        &ListOptions{Pagination: &api.Pagination{Limit: 1, Offset: 1}},
+			wantEntries: []*Entry{e2},
+			wantCount:   3,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gotEntries, err := a.List(ctx, tc.opts)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantEntries, gotEntries)
+
+			gotCount, err := a.Count(ctx, tc.opts)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantCount, gotCount)
+		})
+	}
+}
@@ -207,7 +207,7 @@ func (cl *ClientListener) handleWebsocket(w http.ResponseWriter, req *http.Reque
 
 	clientBanner := client.Banner()
 	clog.Debugf("Open %s", clientBanner)
-	go cl.handleSSHRequests(clog, reqs)
+	go cl.handleSSHRequests(clog, client.ID, reqs)
 	go cl.handleSSHChannels(clog, chans)
 	_ = sshConn.Wait()
 	clog.Debugf("Close %s", clientBanner)
@@ -216,6 +216,8 @@ func (cl *ClientListener) handleWebsocket(w http.ResponseWriter, req *http.Reque
 	if err != nil {
 		cl.Errorf("could not terminate client: %s", err)
 	}
+	// results of running jobs won't be received anymore
+	cl.Server.jobOutputs.FinishByClientID(client.ID)
 }
 
 // checkVersions print if client and server versions dont match.
@@ -283,7 +285,7 @@ loop2:
 	for _, curNew := range new {
 		if !curNew.IsLocalSpecified() {
 			for i, curOld := range old {
-				if !oldMarked[i] && curOld.LocalPortRandom && curNew.Remote() == curOld.Remote() {
+				if !oldMarked[i] && curOld.LocalPortRandom && curNew.SameTarget(curOld) {
 					oldMarked[i] = true
 					continue loop2
 				}
@@ -324,18 +326,20 @@ func (cl *ClientListener) replyConnectionError(r *ssh.Request, err error) {
 	_ = r.Reply(false, []byte(err.Error()))
 }
 
-func (cl *ClientListener) handleSSHRequests(clientLog *chshare.Logger, reqs <-chan *ssh.Request) {
+// handleSSHRequests handles requests of a client with a given ID.
+func (cl *ClientListener) handleSSHRequests(clientLog *chshare.Logger, clientID string, reqs <-chan *ssh.Request) {
 	for r := range reqs {
 		switch r.Type {
 		case comm.RequestTypePing:
 			_ = r.Reply(true, nil)
 		case comm.RequestTypeCmdResult:
-			job, err := cl.saveCmdResult(r.Payload)
+			job, err := cl.saveCmdResult(clientID, r.Payload)
 			if err != nil {
 				clientLog.Errorf("Failed to save cmd result: %s", err)
 				continue
 			}
 			clientLog.Debugf("%s, Command result saved successfully.", job.LogPrefix())
+			cl.Server.jobOutputs.Finish(job.JID)
 
 			if job.MultiJobID != nil {
 				done := cl.jobsDoneChannel.Get(*job.MultiJobID)
@@ -346,19 +350,45 @@ func (cl *ClientListener) handleSSHRequests(clientLog *chshare.Logger, reqs <-ch
 					}(done, job)
 				}
 			}
+		case comm.RequestTypeCmdStarted:
+			job, err := cl.saveCmdStarted(clientID, r.Payload)
+			if err != nil {
+				clientLog.Errorf("Failed to save started cmd: %s", err)
+				continue
+			}
+			clientLog.Debugf("%s, Queued command started.", job.LogPrefix())
+		case comm.RequestTypeCmdOutput:
+			err := cl.saveCmdOutput(clientID, r.Payload)
+			if err != nil {
+				clientLog.Errorf("Failed to save cmd output: %s", err)
+			}
 		default:
 			clientLog.Debugf("Unknown request: %s", r.Type)
 		}
 	}
 }
 
-func (cl *ClientListener) saveCmdResult(respBytes []byte) (*models.Job, error) {
+func (cl *ClientListener) saveCmdResult(clientID string, respBytes []byte) (*models.Job, error) {
 	resp := models.Job{}
 	err := json.Unmarshal(respBytes, &resp)
 	if err != nil {
 		return nil, fmt.Errorf("failed to decode cmd result request: %s", err)
 	}
 
+	// the job can be not persisted yet if the command finished before the response to start it was handled
+	existing, err := cl.getOwnJob(clientID, resp.JID)
+	if err != nil {
+		return nil, err
+	}
+	resp.ClientID = clientID
+	if existing != nil {
+		resp.MultiJobID = existing.MultiJobID
+	}
+	respBytes, err = json.Marshal(resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode cmd result: %s", err)
+	}
+
 	var wsJID string
 	if resp.MultiJobID != nil {
 		wsJID = *resp.MultiJobID
@@ -384,6 +414,108 @@ func (cl *ClientListener) saveCmdResult(respBytes []byte) (*models.Job, error) {
 	return &resp, nil
 }
 
+func (cl *ClientListener) saveCmdStarted(clientID string, jobBytes []byte) (*models.Job, error) {
+	job := &models.Job{}
+	err := json.Unmarshal(jobBytes, job)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode started cmd request: %s", err)
+	}
+
+	existing, err := cl.getOwnJob(clientID, job.JID)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, fmt.Errorf("job[id=%q] not found", job.JID)
+	}
+	job.ClientID = existing.ClientID
+	job.MultiJobID = existing.MultiJobID
+	jobBytes, err = json.Marshal(job)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode started cmd: %s", err)
+	}
+
+	wsJID := job.JID
+	if job.MultiJobID != nil {
+		wsJID = *job.MultiJobID
+	}
+	if ws := cl.Server.uiJobWebSockets.Get(wsJID); ws != nil {
+		err := ws.WriteIntermediateMessage(websocket.TextMessage, jobBytes)
+		if err != nil {
+			cl.Errorf("%s, failed to write started job to UI Web Socket: %v", job.LogPrefix(), err)
+			// proceed further
+		}
+	}
+
+	err = cl.jobProvider.SaveJob(job)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save started job: %s", err)
+	}
+
+	return job, nil
+}
+
+func (cl *ClientListener) saveCmdOutput(clientID string, outputBytes []byte) error {
+	output := &models.JobOutput{}
+	err := json.Unmarshal(outputBytes, output)
+	if err != nil {
+		return fmt.Errorf("failed to decode cmd output request: %s", err)
+	}
+
+	job, err := cl.getOwnJob(clientID, output.JID)
+	if err != nil {
+		return err
+	}
+	if job == nil {
+		// the job is not persisted yet, so the chunk can't be verified and stored
+		cl.Debugf("Job[id=%q] not found, output chunk is dropped.", output.JID)
+		return nil
+	}
+	output.ClientID = job.ClientID
+	output.MultiJobID = job.MultiJobID
+	outputBytes, err = json.Marshal(output)
+	if err != nil {
+		return fmt.Errorf("failed to encode cmd output: %s", err)
+	}
+	logPrefix := job.LogPrefix()
+
+	wsJID := output.JID
+	if output.MultiJobID != nil {
+		wsJID = *output.MultiJobID
+	}
+	if ws := cl.Server.uiJobWebSockets.Get(wsJID); ws != nil {
+		err := ws.WriteIntermediateMessage(websocket.TextMessage, outputBytes)
+		if err != nil {
+			cl.Errorf("%s, failed to write output to UI Web Socket: %v", logPrefix, err)
+			// proceed further
+		}
+	}
+
+	delivered, err := cl.Server.jobOutputs.Publish(output, func() error {
+		return cl.jobProvider.AppendOutput(output)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to append job output: %s", err)
+	}
+	if !delivered {
+		cl.Errorf("%s, output chunk was dropped for a slow subscriber", logPrefix)
+	}
+	return nil
+}
+
+// getOwnJob returns a job with a given ID or nil if not found. It returns an error if the job belongs to another
+// client than a given one, because clients are allowed to update only their own jobs.
+func (cl *ClientListener) getOwnJob(clientID, jid string) (*models.Job, error) {
+	job, err := cl.jobProvider.GetByJID(clientID, jid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job[id=%q]: %s", jid, err)
+	}
+	if job != nil && job.ClientID != clientID {
+		return nil, fmt.Errorf("job[id=%q] belongs to another client", jid)
+	}
+	return job, nil
+}
+
 func (cl *ClientListener) handleSSHChannels(clientLog *chshare.Logger, chans <-chan ssh.NewChannel) {
 	for ch := range chans {
 		remote := string(ch.ExtraData())
@@ -1,13 +1,18 @@
 package chserver
 
 import (
+	"encoding/json"
 	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/cloudradar-monitoring/rport/server/api/jobs"
+	"github.com/cloudradar-monitoring/rport/server/test/jb"
 	chshare "github.com/cloudradar-monitoring/rport/share"
+	"github.com/cloudradar-monitoring/rport/share/models"
+	"github.com/cloudradar-monitoring/rport/share/ws"
 )
 
 func TestGetTunnelsToReestablish(t *testing.T) {
@@ -284,6 +289,19 @@ func TestGetTunnelsToReestablish(t *testing.T) {
 			},
 			wantResStr: nil,
 		},
+		{
+			descr: "new udp tunnel specified in form '<remote-host>:<remote-port>' does not match old tcp tunnel",
+			oldStr: []string{
+				"foobar.com:53",     //contains randomPorts[0]
+				"foobar.com:53/udp", //contains randomPorts[1]
+			},
+			newStr: []string{
+				"foobar.com:53/udp",
+			},
+			wantResStr: []string{
+				"::foobar.com:53",
+			},
+		},
 	}
 	for _, tc := range testCases {
 		msg := fmt.Sprintf("test case: %q", tc.descr)
@@ -319,3 +337,50 @@ func TestGetTunnelsToReestablish(t *testing.T) {
 		assert.ElementsMatch(t, tc.wantResStr, gotResStr, msg)
 	}
 }
+
+func TestSaveCmdOutputOfOwnJobsOnly(t *testing.T) {
+	jp, err := jobs.NewSqliteProvider(":memory:", testLog)
+	require.NoError(t, err)
+	defer jp.Close()
+	job := jb.New(t).JID("job-1").ClientID("client-1").MultiJobID("multi-job-1").Status(models.JobStatusRunning).Result(&models.JobResult{}).Build()
+	require.NoError(t, jp.CreateJob(job))
+
+	cl := &ClientListener{
+		Server: &Server{
+			jobProvider:     jp,
+			uiJobWebSockets: ws.NewWebSocketCache(),
+		},
+		Logger: testLog,
+	}
+	outputs, err := cl.jobOutputs.Subscribe("client-1", "job-1", func() error { return nil })
+	require.NoError(t, err)
+	defer cl.jobOutputs.Unsubscribe("job-1", outputs)
+
+	// other client
+	outputBytes, err := json.Marshal(models.JobOutput{JID: "job-1", ClientID: "client-1", StdOut: "injected"})
+	require.NoError(t, err)
+	err = cl.saveCmdOutput("client-2", outputBytes)
+	assert.EqualError(t, err, `job[id="job-1"] belongs to another client`)
+
+	// own job, ids of the stored job are used
+	outputBytes, err = json.Marshal(models.JobOutput{JID: "job-1", ClientID: "client-2", StdOut: "out"})
+	require.NoError(t, err)
+	require.NoError(t, cl.saveCmdOutput("client-1", outputBytes))
+
+	got := <-outputs
+	assert.Equal(t, "out", got.StdOut)
+	assert.Equal(t, "client-1", got.ClientID)
+	require.NotNil(t, got.MultiJobID)
+	assert.Equal(t, "multi-job-1", *got.MultiJobID)
+
+	gotJob, err := jp.GetByJID("client-1", "job-1")
+	require.NoError(t, err)
+	require.NotNil(t, gotJob.Result)
+	assert.Equal(t, "out", gotJob.Result.StdOut)
+
+	// started job of other client
+	startedBytes, err := json.Marshal(job)
+	require.NoError(t, err)
+	_, err = cl.saveCmdStarted("client-2", startedBytes)
+	assert.EqualError(t, err, `job[id="job-1"] belongs to another client`)
+}
@@ -2,6 +2,7 @@ package chserver
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sort"
 	"strconv"
@@ -19,6 +20,10 @@ import (
 type ClientService struct {
 	repo            *clients.ClientRepository
 	portDistributor *ports.PortDistributor
+	// onClientStarted is called asynchronously when a client connects, if set
+	onClientStarted func(client *clients.Client)
+	// tunnelProxyConfig is used to serve tunnels in http proxy mode, nil if they are disabled
+	tunnelProxyConfig *clients.TunnelProxyConfig
 
 	mu sync.Mutex
 }
@@ -150,6 +155,10 @@ func (s *ClientService) StartClient(
 	if err != nil {
 		return nil, err
 	}
+
+	if s.onClientStarted != nil {
+		go s.onClientStarted(client)
+	}
 	return client, nil
 }
 
@@ -187,7 +196,7 @@ func (s *ClientService) startClientTunnels(client *clients.Client, remotes []*ch
 			}
 		}
 
-		t, err := client.StartTunnel(remote, acl)
+		t, err := client.StartTunnel(remote, acl, s.tunnelProxyConfig)
 		if err != nil {
 			return nil, err
 		}
@@ -230,6 +239,32 @@ func (s *ClientService) ForceDelete(client *clients.Client) error {
 	return s.repo.Delete(client)
 }
 
+var (
+	errClientNotFound = errors.New("client not found")
+	errClientActive   = errors.New("client is active")
+)
+
+// DeleteOffline deletes a disconnected client from a given storage and from repo. Returns an error if the client is not
+// found or is active. The client can't reconnect in between, so it's never deleted while it's active.
+func (s *ClientService) DeleteOffline(ctx context.Context, clientID string, provider clients.ClientProvider) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	existing, err := s.repo.GetByID(clientID)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return errClientNotFound
+	}
+	if existing.DisconnectedAt == nil {
+		return errClientActive
+	}
+	if err := provider.Delete(ctx, clientID); err != nil {
+		return fmt.Errorf("failed to delete client from storage: %v", err)
+	}
+	return s.repo.Delete(existing)
+}
+
 // isClientAuthIDInUse returns true when the client with different id exists for the client auth
 func (s *ClientService) isClientAuthIDInUse(clientAuthID, clientID string) bool {
 	for _, s := range s.repo.GetAllByClientAuthID(clientAuthID) {
@@ -0,0 +1,38 @@
+package clients
+
+import (
+	"context"
+	"time"
+
+	chshare "github.com/cloudradar-monitoring/rport/share"
+)
+
+// TunnelsAutoCloseInterval is how often tunnels are checked whether they should be closed automatically.
+const TunnelsAutoCloseInterval = 5 * time.Second
+
+type TunnelsAutoCloseTask struct {
+	log *chshare.Logger
+	cr  *ClientRepository
+}
+
+// NewTunnelsAutoCloseTask returns a task to close tunnels of active clients that are idle too long or reached their
+// auto close time.
+func NewTunnelsAutoCloseTask(log *chshare.Logger, cr *ClientRepository) *TunnelsAutoCloseTask {
+	return &TunnelsAutoCloseTask{
+		log: log,
+		cr:  cr,
+	}
+}
+
+func (t *TunnelsAutoCloseTask) Run(ctx context.Context) error {
+	for _, client := range t.cr.GetAllActive() {
+		client.Lock()
+		closed := client.CloseExpiredTunnels(now())
+		client.Unlock()
+
+		for _, tunnel := range closed {
+			t.log.Infof("Tunnel %s of client %s is closed automatically.", tunnel.ID, client.ID)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	chshare "github.com/cloudradar-monitoring/rport/share"
+)
+
+type closerMock struct {
+	closed bool
+}
+
+func (c *closerMock) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestTunnelClosesAt(t *testing.T) {
+	now = nowMockF
+	autoCloseAt := now().Add(time.Hour)
+	tunnel := NewTunnel(testLog, nil, "1", &chshare.Remote{
+		LocalHost:      "127.0.0.1",
+		LocalPort:      "0",
+		RemoteHost:     "0.0.0.0",
+		RemotePort:     "22",
+		IdleTimeoutSec: 60,
+		AutoCloseAt:    &autoCloseAt,
+	}, nil, nil)
+
+	// not started
+	assert.Equal(t, &autoCloseAt, tunnel.ClosesAt())
+
+	require.NoError(t, tunnel.Start(context.Background()))
+	defer tunnel.Terminate(true)
+
+	// idle
+	wantIdleAt := now().Add(time.Minute)
+	assert.Equal(t, &wantIdleAt, tunnel.ClosesAt())
+	b, err := json.Marshal(tunnel)
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `"idle_timeout_sec":60`)
+	assert.Contains(t, string(b), `"closes_in_sec":60`)
+
+	// with active connection
+	_, ok := tunnel.addConnection(&closerMock{}, "127.0.0.1")
+	require.True(t, ok)
+	assert.Equal(t, &autoCloseAt, tunnel.ClosesAt())
+
+	// without limits
+	tunnel.AutoCloseAt = nil
+	tunnel.IdleTimeoutSec = 0
+	assert.Nil(t, tunnel.ClosesAt())
+	b, err = json.Marshal(tunnel)
+	require.NoError(t, err)
+	assert.Contains(t, string(b), `"closes_in_sec":null`)
+}
+
+func TestTunnelsAutoCloseTask(t *testing.T) {
+	now = nowMockF
+	c1 := New(t).Build()
+	c1.Tunnels = nil
+	c1.Context = context.Background()
+	c1.Logger = testLog
+	c2 := New(t).DisconnectedDuration(time.Minute).Build()
+
+	autoCloseAt := now().Add(-time.Second)
+	expired, err := c1.StartTunnel(&chshare.Remote{LocalHost: "127.0.0.1", LocalPort: "0", RemoteHost: "0.0.0.0", RemotePort: "22", AutoCloseAt: &autoCloseAt}, nil, nil)
+	require.NoError(t, err)
+	conn := &closerMock{}
+	_, ok := expired.addConnection(conn, "127.0.0.1")
+	require.True(t, ok)
+	active, err := c1.StartTunnel(&chshare.Remote{LocalHost: "127.0.0.1", LocalPort: "0", RemoteHost: "0.0.0.0", RemotePort: "80", IdleTimeoutSec: 60}, nil, nil)
+	require.NoError(t, err)
+	defer active.Terminate(true)
+
+	task := NewTunnelsAutoCloseTask(testLog, NewClientRepository([]*Client{c1, c2}, &hour))
+
+	// when
+	require.NoError(t, task.Run(context.Background()))
+
+	// then
+	assert.Equal(t, []*Tunnel{active}, c1.Tunnels)
+	assert.True(t, conn.closed)
+	_, ok = expired.addConnection(&closerMock{}, "127.0.0.1")
+	assert.False(t, ok)
+	assert.Len(t, c2.Tunnels, 2)
+}
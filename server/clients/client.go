@@ -75,14 +75,15 @@ func (c *Client) FindTunnelByRemote(r *chshare.Remote) *Tunnel {
 	return nil
 }
 
-func (c *Client) StartTunnel(r *chshare.Remote, acl *TunnelACL) (*Tunnel, error) {
+func (c *Client) StartTunnel(r *chshare.Remote, acl *TunnelACL, proxy *TunnelProxyConfig) (*Tunnel, error) {
 	t := c.FindTunnelByRemote(r)
 	if t != nil {
 		return t, nil
 	}
 
 	tunnelID := strconv.FormatInt(c.generateNewTunnelID(), 10)
-	t = NewTunnel(c.Logger, c.Connection, tunnelID, r, acl)
+	t = NewTunnel(c.Logger, c.Connection, tunnelID, r, acl, proxy)
+	t.clientID = c.ID
 	err := t.Start(c.Context)
 	if err != nil {
 		return nil, err
@@ -91,12 +92,29 @@ func (c *Client) StartTunnel(r *chshare.Remote, acl *TunnelACL) (*Tunnel, error)
 	return t, nil
 }
 
-func (c *Client) TerminateTunnel(t *Tunnel) {
+// TerminateTunnel stops a given tunnel. If force is true its active connections are closed, otherwise it waits until
+// they are finished.
+func (c *Client) TerminateTunnel(t *Tunnel, force bool) {
 	c.Logger.Infof("Terminating tunnel %s...", t.ID)
-	t.Terminate()
+	t.Terminate(force)
 	c.removeTunnel(t)
 }
 
+// CloseExpiredTunnels terminates tunnels that should be closed automatically by a given time together with their
+// active connections. Returns the closed tunnels.
+func (c *Client) CloseExpiredTunnels(at time.Time) []*Tunnel {
+	var res []*Tunnel
+	for _, t := range c.Tunnels {
+		if closesAt := t.ClosesAt(); closesAt != nil && !closesAt.After(at) {
+			res = append(res, t)
+		}
+	}
+	for _, t := range res {
+		c.TerminateTunnel(t, true)
+	}
+	return res
+}
+
 func (c *Client) FindTunnel(id string) *Tunnel {
 	for _, curr := range c.Tunnels {
 		if curr.ID == id {
@@ -0,0 +1,74 @@
+package clients
+
+import (
+	"github.com/cloudradar-monitoring/rport/server/cgroups"
+)
+
+// FilterFields are fields clients can be filtered by. Names match client group params plus connection state.
+var FilterFields = []string{
+	"client_id",
+	"name",
+	"os",
+	"os_arch",
+	"os_family",
+	"os_kernel",
+	"hostname",
+	"ipv4",
+	"ipv6",
+	"tag",
+	"version",
+	"address",
+	"client_auth_id",
+	"connection_state",
+}
+
+var filterFieldValues = map[string]func(c *Client) []string{
+	"client_id":        func(c *Client) []string { return []string{c.ID} },
+	"name":             func(c *Client) []string { return []string{c.Name} },
+	"os":               func(c *Client) []string { return []string{c.OS} },
+	"os_arch":          func(c *Client) []string { return []string{c.OSArch} },
+	"os_family":        func(c *Client) []string { return []string{c.OSFamily} },
+	"os_kernel":        func(c *Client) []string { return []string{c.OSKernel} },
+	"hostname":         func(c *Client) []string { return []string{c.Hostname} },
+	"ipv4":             func(c *Client) []string { return c.IPv4 },
+	"ipv6":             func(c *Client) []string { return c.IPv6 },
+	"tag":              func(c *Client) []string { return c.Tags },
+	"version":          func(c *Client) []string { return []string{c.Version} },
+	"address":          func(c *Client) []string { return []string{c.Address} },
+	"client_auth_id":   func(c *Client) []string { return []string{c.ClientAuthID} },
+	"connection_state": func(c *Client) []string { return []string{string(c.ConnectionState())} },
+}
+
+// MatchesFilters returns true if a client matches all given filters. Filter values are matched the same way as
+// client group params: ignoring case and with wildcards support. A client matches a filter if at least one of
+// its values matches one of the filter values. Unknown fields are ignored, see FilterFields.
+func (c *Client) MatchesFilters(filters map[string][]string) bool {
+	for field, values := range filters {
+		getValues, ok := filterFieldValues[field]
+		if !ok || len(values) == 0 {
+			continue
+		}
+		params := make(cgroups.ParamValues, 0, len(values))
+		for _, v := range values {
+			params = append(params, cgroups.Param(v))
+		}
+		if !params.MatchesOneOf(getValues(c)...) {
+			return false
+		}
+	}
+	return true
+}
+
+// Filter returns clients that match all given filters.
+func Filter(all []*Client, filters map[string][]string) []*Client {
+	if len(filters) == 0 {
+		return all
+	}
+	res := make([]*Client, 0, len(all))
+	for _, c := range all {
+		if c.MatchesFilters(filters) {
+			res = append(res, c)
+		}
+	}
+	return res
+}
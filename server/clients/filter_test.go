@@ -0,0 +1,80 @@
+package clients
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilter(t *testing.T) {
+	c1 := New(t).ID("client-1").ClientAuthID("auth-1").Build()
+	c1.Name = "Web Server"
+	c1.Tags = []string{"Linux", "Datacenter 1"}
+	c2 := New(t).ID("client-2").ClientAuthID("auth-2").DisconnectedDuration(time.Minute).Build()
+	c2.Name = "DB Server"
+	c2.Tags = []string{"Linux", "Datacenter 2"}
+	c3 := New(t).ID("client-3").ClientAuthID("auth-1").Build()
+	c3.Name = "Win Desktop"
+	c3.OSKernel = "windows"
+	c3.Tags = nil
+	all := []*Client{c1, c2, c3}
+
+	testCases := []struct {
+		name string
+
+		filters map[string][]string
+
+		wantClients []*Client
+	}{
+		{
+			name:        "no filters",
+			filters:     nil,
+			wantClients: all,
+		},
+		{
+			name:        "exact match ignoring case",
+			filters:     map[string][]string{"os_kernel": {"LINUX"}},
+			wantClients: []*Client{c1, c2},
+		},
+		{
+			name:        "wildcard",
+			filters:     map[string][]string{"name": {"*server"}},
+			wantClients: []*Client{c1, c2},
+		},
+		{
+			name:        "one of values",
+			filters:     map[string][]string{"client_id": {"client-1", "client-3"}},
+			wantClients: []*Client{c1, c3},
+		},
+		{
+			name:        "multi-value field",
+			filters:     map[string][]string{"tag": {"datacenter 2"}},
+			wantClients: []*Client{c2},
+		},
+		{
+			name:        "connection state",
+			filters:     map[string][]string{"connection_state": {"connected"}},
+			wantClients: []*Client{c1, c3},
+		},
+		{
+			name: "all filters should match",
+			filters: map[string][]string{
+				"client_auth_id": {"auth-1"},
+				"name":           {"web*"},
+			},
+			wantClients: []*Client{c1},
+		},
+		{
+			name:        "no matches",
+			filters:     map[string][]string{"version": {"1.*"}},
+			wantClients: []*Client{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.wantClients, Filter(all, tc.filters))
+		})
+	}
+}
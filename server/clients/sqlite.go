@@ -19,6 +19,7 @@ type ClientProvider interface {
 	GetAll(ctx context.Context) ([]*Client, error)
 	Save(ctx context.Context, client *Client) error
 	DeleteObsolete(ctx context.Context) error
+	Delete(ctx context.Context, id string) error
 	Close() error
 }
 
@@ -70,6 +71,11 @@ func (p *SqliteProvider) Save(ctx context.Context, client *Client) error {
 	return err
 }
 
+func (p *SqliteProvider) Delete(ctx context.Context, id string) error {
+	_, err := p.db.ExecContext(ctx, "DELETE FROM clients WHERE id = ?", id)
+	return err
+}
+
 func (p *SqliteProvider) DeleteObsolete(ctx context.Context) error {
 	_, err := p.db.ExecContext(
 		ctx,
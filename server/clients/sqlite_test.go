@@ -61,4 +61,13 @@ func TestClientsSqliteProvider(t *testing.T) {
 	gotAll, err = p.GetAll(ctx)
 	require.NoError(t, err)
 	assert.ElementsMatch(t, []*Client{c1, c2, c3, c4}, gotAll)
+
+	// verify delete
+	require.NoError(t, p.Delete(ctx, c2.ID))
+	gotDeleted, err := p.get(ctx, c2.ID)
+	require.NoError(t, err)
+	require.Nil(t, gotDeleted)
+	gotAll, err = p.GetAll(ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []*Client{c1, c3, c4}, gotAll)
 }
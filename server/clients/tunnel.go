@@ -2,10 +2,12 @@ package clients
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net"
 	"sync"
+	"time"
 
 	"github.com/jpillora/sizestr"
 	"golang.org/x/crypto/ssh"
@@ -21,42 +23,106 @@ type Tunnel struct {
 
 	ID string `json:"id"`
 
+	clientID                  string // id of the client the tunnel belongs to
 	sshConn                   ssh.Conn
 	connectionIDAutoIncrement int
 	stopFn                    func()
 	wg                        sync.WaitGroup
 	acl                       *TunnelACL // parsed Remote.ACL field
+	proxy                     *TunnelProxyConfig
+	proxyListener             *proxyListener // is set if Remote.HTTPProxy is enabled
+
+	mu         sync.Mutex
+	conns      map[int]io.Closer // active connections by id
+	lastActive time.Time         // when the tunnel was started or the last connection was closed
+	closed     bool              // is set when active connections are closed on terminate, no new ones are accepted
+	stats      TunnelStats
+}
+
+// TunnelStats are cumulative usage statistics of a tunnel since it was started.
+type TunnelStats struct {
+	// BytesIn is a number of bytes received from tunnel users and forwarded to the client.
+	BytesIn int64 `json:"bytes_in"`
+	// BytesOut is a number of bytes received from the client and forwarded to tunnel users.
+	BytesOut          int64      `json:"bytes_out"`
+	Connections       int        `json:"connections"`
+	ActiveConnections int        `json:"active_connections"`
+	LastConnectionAt  *time.Time `json:"last_connection_at"`
+	LastPeerIP        string     `json:"last_peer_ip"`
 }
 
-func NewTunnel(logger *chshare.Logger, ssh ssh.Conn, id string, remote *chshare.Remote, acl *TunnelACL) *Tunnel {
-	return &Tunnel{
+func NewTunnel(logger *chshare.Logger, ssh ssh.Conn, id string, remote *chshare.Remote, acl *TunnelACL, proxy *TunnelProxyConfig) *Tunnel {
+	t := &Tunnel{
 		Logger:  logger.Fork("tunnel#%s:%s", id, remote),
 		Remote:  *remote,
 		ID:      id,
 		sshConn: ssh,
 		acl:     acl,
+		proxy:   proxy,
+		conns:   make(map[int]io.Closer),
+	}
+	if t.Protocol == "" {
+		t.Protocol = chshare.ProtocolTCP
 	}
+	return t
 }
 
 func (t *Tunnel) Start(ctx context.Context) error {
-	// TODO(m-terel): consider to use ListenTCP
-	l, err := net.Listen("tcp4", t.LocalHost+":"+t.LocalPort)
-	if err != nil {
-		return fmt.Errorf("%s: %s", t.Logger.Prefix(), err)
+	if t.HTTPProxy && (t.proxy == nil || t.proxy.TLSConfig == nil) {
+		return fmt.Errorf("%s: http proxy is not configured", t.Logger.Prefix())
+	}
+
+	addr := net.JoinHostPort(t.LocalHost, t.LocalPort)
+	var listen func(ctx context.Context)
+	if t.IsUDP() {
+		pc, err := net.ListenPacket(t.listenNetwork("udp"), addr)
+		if err != nil {
+			return fmt.Errorf("%s: %s", t.Logger.Prefix(), err)
+		}
+		listen = func(ctx context.Context) { t.listenUDP(ctx, pc) }
+	} else {
+		// TODO(m-terel): consider to use ListenTCP
+		l, err := net.Listen(t.listenNetwork("tcp"), addr)
+		if err != nil {
+			return fmt.Errorf("%s: %s", t.Logger.Prefix(), err)
+		}
+		listen = func(ctx context.Context) { t.listen(ctx, l) }
+		if t.HTTPProxy {
+			t.proxyListener = newProxyListener(l.Addr())
+		}
 	}
 
 	ctx, t.stopFn = context.WithCancel(ctx)
+	if t.proxyListener != nil {
+		t.startHTTPProxy(ctx, t.proxyListener)
+	}
+	t.mu.Lock()
+	t.lastActive = now()
+	t.mu.Unlock()
 	t.wg.Add(1)
-	go t.listen(ctx, l)
+	go listen(ctx)
 	return nil
 }
 
-func (t *Tunnel) Terminate() {
+// listenNetwork returns a given network restricted to IPv4 unless the tunnel listens on an IPv6 address. Listening on
+// "::" accepts both IPv4 and IPv6 connections.
+func (t *Tunnel) listenNetwork(network string) string {
+	if ip := net.ParseIP(t.LocalHost); ip != nil && ip.To4() == nil {
+		return network
+	}
+	return network + "4"
+}
+
+// Terminate stops the tunnel. If force is true active connections are closed, otherwise it waits until they are finished.
+func (t *Tunnel) Terminate(force bool) {
 	if t.stopFn == nil {
 		return
 	}
 
 	t.stopFn()
+	if force {
+		t.closeConnections()
+	}
 	t.wg.Wait()
 	t.Infof("stopped")
 	t.stopFn = nil
@@ -102,7 +168,7 @@ func (t *Tunnel) listen(ctx context.Context, l net.Listener) {
 				continue
 			}
 
-			if !t.acl.CheckAccess(tcpAddr) {
+			if !t.acl.CheckAccess(tcpAddr.IP) {
 				t.Debugf("Access rejected. Remote addr: %s", tcpAddr)
 				conn.Close()
 				continue
@@ -117,16 +183,29 @@ func (t *Tunnel) listen(ctx context.Context, l net.Listener) {
 	}
 }
 
-func (t *Tunnel) accept(src io.ReadWriteCloser) {
-	defer src.Close()
-	t.connectionIDAutoIncrement++
-	cid := t.connectionIDAutoIncrement
+func (t *Tunnel) accept(conn net.Conn) {
+	defer conn.Close()
+	cid, ok := t.addConnection(conn, peerIP(conn.RemoteAddr()))
+	if !ok {
+		return
+	}
+	defer t.removeConnection(cid)
+	src := &countingConn{ReadWriteCloser: conn, tunnel: t}
 	l := t.Fork("conn#%d", cid)
 	l.Debugf("Open")
 	if t.sshConn == nil {
 		l.Debugf("No remote connection")
 		return
 	}
+	if t.proxyListener != nil {
+		// the http proxy opens a stream to the remote for each request
+		pc := newProxyConn(conn, t)
+		if t.proxyListener.handOver(pc) {
+			<-pc.closed
+		}
+		l.Debugf("Close")
+		return
+	}
 	//ssh request for tcp connection for this proxy's remote
 	dst, reqs, err := t.sshConn.OpenChannel("rport", []byte(t.Remote.Remote()))
 	if err != nil {
@@ -138,3 +217,131 @@ func (t *Tunnel) accept(src io.ReadWriteCloser) {
 	s, r := chshare.Pipe(src, dst)
 	l.Debugf("Close (sent %s received %s)", sizestr.ToString(s), sizestr.ToString(r))
 }
+
+// addConnection registers a new active connection from a given peer IP. Returns its id and false if the tunnel is
+// being terminated.
+func (t *Tunnel) addConnection(conn io.Closer, peerIP string) (int, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return 0, false
+	}
+	t.connectionIDAutoIncrement++
+	t.conns[t.connectionIDAutoIncrement] = conn
+
+	connectedAt := now()
+	t.stats.Connections++
+	t.stats.ActiveConnections = len(t.conns)
+	t.stats.LastConnectionAt = &connectedAt
+	t.stats.LastPeerIP = peerIP
+	return t.connectionIDAutoIncrement, true
+}
+
+func (t *Tunnel) removeConnection(id int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.conns, id)
+	t.stats.ActiveConnections = len(t.conns)
+	t.lastActive = now()
+}
+
+// Stats returns the current usage statistics of the tunnel.
+func (t *Tunnel) Stats() TunnelStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.stats
+}
+
+func (t *Tunnel) addTraffic(in, out int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stats.BytesIn += in
+	t.stats.BytesOut += out
+}
+
+// countingConn counts the traffic of a tunnel connection while it's transferred.
+type countingConn struct {
+	io.ReadWriteCloser
+	tunnel *Tunnel
+}
+
+func (c *countingConn) Read(p []byte) (int, error) {
+	n, err := c.ReadWriteCloser.Read(p)
+	c.tunnel.addTraffic(int64(n), 0)
+	return n, err
+}
+
+func (c *countingConn) Write(p []byte) (int, error) {
+	n, err := c.ReadWriteCloser.Write(p)
+	c.tunnel.addTraffic(0, int64(n))
+	return n, err
+}
+
+func peerIP(addr net.Addr) string {
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		return a.IP.String()
+	case *net.UDPAddr:
+		return a.IP.String()
+	}
+	return addr.String()
+}
+
+func (t *Tunnel) closeConnections() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.closed = true
+	for id, conn := range t.conns {
+		if err := conn.Close(); err != nil {
+			t.Errorf("Failed to close connection #%d: %v", id, err)
+		}
+	}
+}
+
+// ClosesAt returns a time when the tunnel should be closed automatically or nil if it's not limited. A tunnel with
+// active connections is not idle, so only its auto close time is taken into account.
+func (t *Tunnel) ClosesAt() *time.Time {
+	var res *time.Time
+	if t.AutoCloseAt != nil {
+		autoCloseAt := *t.AutoCloseAt
+		res = &autoCloseAt
+	}
+	if t.IdleTimeoutSec <= 0 {
+		return res
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	// a tunnel that is not started yet or has active connections is not idle
+	if t.lastActive.IsZero() || len(t.conns) > 0 {
+		return res
+	}
+	idleAt := t.lastActive.Add(time.Duration(t.IdleTimeoutSec) * time.Second)
+	if res == nil || idleAt.Before(*res) {
+		res = &idleAt
+	}
+	return res
+}
+
+// MarshalJSON adds the remaining lifetime and the usage statistics of the tunnel.
+func (t *Tunnel) MarshalJSON() ([]byte, error) {
+	res := struct {
+		chshare.Remote
+		ID string `json:"id"`
+		// ClosesInSec is a number of seconds left until the tunnel is closed automatically, nil if it's not limited.
+		ClosesInSec *int        `json:"closes_in_sec"`
+		Stats       TunnelStats `json:"stats"`
+	}{
+		Remote: t.Remote,
+		ID:     t.ID,
+		Stats:  t.Stats(),
+	}
+	if closesAt := t.ClosesAt(); closesAt != nil {
+		closesIn := int(closesAt.Sub(now()).Seconds())
+		if closesIn < 0 {
+			closesIn = 0
+		}
+		res.ClosesInSec = &closesIn
+	}
+	return json.Marshal(res)
+}
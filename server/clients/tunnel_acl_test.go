@@ -0,0 +1,34 @@
+package clients
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTunnelACL(t *testing.T) {
+	acl, err := ParseTunnelACL("213.90.90.123,189.20.90.0/24,2001:db8::1,fd00:1::/64")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		ip   string
+		want bool
+	}{
+		{ip: "213.90.90.123", want: true},
+		{ip: "213.90.90.124", want: false},
+		{ip: "189.20.90.15", want: true},
+		{ip: "::ffff:189.20.90.15", want: true},
+		{ip: "2001:db8::1", want: true},
+		{ip: "2001:db8::2", want: false},
+		{ip: "fd00:1::abcd", want: true},
+		{ip: "fd00:2::abcd", want: false},
+	}
+	for _, tc := range testCases {
+		assert.Equalf(t, tc.want, acl.CheckAccess(net.ParseIP(tc.ip)), "ip %s", tc.ip)
+	}
+
+	_, err = ParseTunnelACL("fd00::zz")
+	assert.EqualError(t, err, "invalid IP addr: fd00::zz")
+}
@@ -0,0 +1,206 @@
+package clients
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+
+	chshare "github.com/cloudradar-monitoring/rport/share"
+)
+
+// TunnelProxyConfig defines how tunnels in http proxy mode are served.
+type TunnelProxyConfig struct {
+	// TLSConfig contains the certificate of rportd to serve the tunnels with https.
+	TLSConfig *tls.Config
+	// Authenticate returns true if a given request contains valid rportd API credentials of a user who is allowed to
+	// use tunnels of a given client. It's used for tunnels with ProxyAuth enabled.
+	Authenticate func(r *http.Request, clientID string) (bool, error)
+}
+
+var errProxyListenerClosed = errors.New("http proxy listener is closed")
+
+// proxyListener is a listener of an http proxy server that accepts connections handed over by the tunnel, so the
+// tunnel keeps applying its ACL and tracking its connections.
+type proxyListener struct {
+	addr  net.Addr
+	conns chan net.Conn
+	done  chan struct{}
+	once  sync.Once
+}
+
+func newProxyListener(addr net.Addr) *proxyListener {
+	return &proxyListener{
+		addr:  addr,
+		conns: make(chan net.Conn),
+		done:  make(chan struct{}),
+	}
+}
+
+// handOver passes a given connection to the http proxy server. Returns false if the listener is closed.
+func (l *proxyListener) handOver(conn net.Conn) bool {
+	select {
+	case l.conns <- conn:
+		return true
+	case <-l.done:
+		return false
+	}
+}
+
+func (l *proxyListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.done:
+		return nil, errProxyListenerClosed
+	}
+}
+
+func (l *proxyListener) Close() error {
+	l.once.Do(func() {
+		close(l.done)
+	})
+	return nil
+}
+
+func (l *proxyListener) Addr() net.Addr {
+	return l.addr
+}
+
+// proxyConn is a tunnel connection served by the http proxy. It counts the traffic and notifies when it's closed.
+type proxyConn struct {
+	net.Conn
+	tunnel *Tunnel
+	closed chan struct{}
+	once   sync.Once
+}
+
+func newProxyConn(conn net.Conn, tunnel *Tunnel) *proxyConn {
+	return &proxyConn{
+		Conn:   conn,
+		tunnel: tunnel,
+		closed: make(chan struct{}),
+	}
+}
+
+func (c *proxyConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.tunnel.addTraffic(int64(n), 0)
+	return n, err
+}
+
+func (c *proxyConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	c.tunnel.addTraffic(0, int64(n))
+	return n, err
+}
+
+func (c *proxyConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(func() {
+		close(c.closed)
+	})
+	return err
+}
+
+// startHTTPProxy starts an https server that forwards requests of a given listener to the remote of the tunnel until
+// the context is done.
+func (t *Tunnel) startHTTPProxy(ctx context.Context, l *proxyListener) {
+	srv := &http.Server{
+		Handler:   t.newHTTPProxyHandler(),
+		TLSConfig: t.proxy.TLSConfig.Clone(),
+	}
+	go func() {
+		// certificates are taken from TLSConfig
+		if err := srv.ServeTLS(l, "", ""); err != nil && err != errProxyListenerClosed {
+			t.Errorf("HTTP proxy stopped: %v", err)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		// active requests are finished gracefully, their connections are closed on force terminate
+		if err := srv.Shutdown(context.Background()); err != nil {
+			t.Errorf("Failed to stop HTTP proxy: %v", err)
+		}
+	}()
+}
+
+func (t *Tunnel) newHTTPProxyHandler() http.Handler {
+	target := &url.URL{
+		Scheme: "http",
+		Host:   t.Remote.Remote(),
+	}
+	if t.Scheme != nil && *t.Scheme == "https" {
+		target.Scheme = "https"
+	}
+
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	director := proxy.Director
+	proxy.Director = func(r *http.Request) {
+		director(r)
+		if t.HostHeader != "" {
+			r.Host = t.HostHeader
+		}
+		if t.ProxyAuth {
+			// rportd credentials are not passed to the remote
+			r.Header.Del("Authorization")
+		}
+	}
+	proxy.Transport = &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return t.dialRemote()
+		},
+		// remotes behind clients mostly use self-signed certificates
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, //nolint:gosec
+	}
+	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		t.Debugf("HTTP proxy request to %s failed: %v", r.URL, err)
+		w.WriteHeader(http.StatusBadGateway)
+	}
+
+	if !t.ProxyAuth {
+		return proxy
+	}
+	return t.withProxyAuth(proxy)
+}
+
+// withProxyAuth allows requests with valid rportd API credentials only.
+func (t *Tunnel) withProxyAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if t.proxy.Authenticate == nil {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		authorized, err := t.proxy.Authenticate(r, t.clientID)
+		if err != nil {
+			t.Errorf("Failed to authenticate HTTP proxy request: %v", err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		if !authorized {
+			w.Header().Set("WWW-Authenticate", `Basic realm="rport tunnel"`)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// dialRemote opens a new connection to the remote of the tunnel through the client.
+func (t *Tunnel) dialRemote() (net.Conn, error) {
+	if t.sshConn == nil {
+		return nil, errors.New("no remote connection")
+	}
+	channel, reqs, err := t.sshConn.OpenChannel("rport", []byte(t.Remote.Remote()))
+	if err != nil {
+		return nil, err
+	}
+	go ssh.DiscardRequests(reqs)
+	return chshare.NewRWCConn(channel), nil
+}
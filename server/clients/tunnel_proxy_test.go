@@ -0,0 +1,133 @@
+package clients
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+
+	chshare "github.com/cloudradar-monitoring/rport/share"
+)
+
+// dialConn is an ssh connection that connects streams to the requested remotes directly.
+type dialConn struct {
+	ssh.Conn
+}
+
+func (c *dialConn) OpenChannel(name string, data []byte) (ssh.Channel, <-chan *ssh.Request, error) {
+	conn, err := net.Dial("tcp", string(data))
+	if err != nil {
+		return nil, nil, err
+	}
+	reqs := make(chan *ssh.Request)
+	close(reqs)
+	return &pipeChannel{conn: conn}, reqs, nil
+}
+
+func TestHTTPProxyTunnel(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	remote := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if websocket.IsWebSocketUpgrade(r) {
+			conn, err := upgrader.Upgrade(w, r, nil)
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			msgType, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			_ = conn.WriteMessage(msgType, append([]byte("echo: "), msg...))
+			return
+		}
+		_, _ = w.Write([]byte(r.Host + " " + r.URL.Path + " " + r.Header.Get("Authorization")))
+	}))
+	defer remote.Close()
+	remoteURL, err := url.Parse(remote.URL)
+	require.NoError(t, err)
+
+	scheme := "https"
+	tunnel := NewTunnel(testLog, &dialConn{}, "1", &chshare.Remote{
+		LocalHost:  "127.0.0.1",
+		LocalPort:  "0",
+		RemoteHost: remoteURL.Hostname(),
+		RemotePort: remoteURL.Port(),
+		Scheme:     &scheme,
+		HTTPProxy:  true,
+		HostHeader: "app.internal",
+		ProxyAuth:  true,
+	}, nil, &TunnelProxyConfig{
+		// the test server certificate is valid for 127.0.0.1
+		TLSConfig: remote.TLS,
+		Authenticate: func(r *http.Request, clientID string) (bool, error) {
+			user, password, ok := r.BasicAuth()
+			return ok && user == "admin" && password == "foobaz" && clientID == "client-1", nil
+		},
+	})
+	tunnel.clientID = "client-1"
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	tunnel.stopFn = cancel
+	tunnel.proxyListener = newProxyListener(l.Addr())
+	tunnel.startHTTPProxy(ctx, tunnel.proxyListener)
+	tunnel.wg.Add(1)
+	go tunnel.listen(ctx, l)
+	defer tunnel.Terminate(true)
+
+	proxyURL := "https://" + l.Addr().String()
+	client := remote.Client()
+
+	// unauthorized
+	resp, err := client.Get(proxyURL + "/index.html")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(t, `Basic realm="rport tunnel"`, resp.Header.Get("WWW-Authenticate"))
+
+	// authorized, the host header is rewritten and the credentials are not forwarded
+	req, err := http.NewRequest(http.MethodGet, proxyURL+"/index.html", nil)
+	require.NoError(t, err)
+	req.SetBasicAuth("admin", "foobaz")
+	resp, err = client.Do(req)
+	require.NoError(t, err)
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "app.internal /index.html ", string(body))
+
+	// websocket
+	dialer := websocket.Dialer{TLSClientConfig: client.Transport.(*http.Transport).TLSClientConfig}
+	header := http.Header{}
+	header.Set("Authorization", req.Header.Get("Authorization"))
+	wsConn, _, err := dialer.Dial("wss://"+l.Addr().String()+"/ws", header)
+	require.NoError(t, err)
+	require.NoError(t, wsConn.WriteMessage(websocket.TextMessage, []byte("ping")))
+	_, msg, err := wsConn.ReadMessage()
+	require.NoError(t, err)
+	assert.Equal(t, "echo: ping", string(msg))
+	wsConn.Close()
+
+	stats := tunnel.Stats()
+	assert.True(t, stats.Connections > 0)
+	assert.True(t, stats.BytesIn > 0)
+	assert.True(t, stats.BytesOut > 0)
+}
+
+func TestHTTPProxyTunnelNotConfigured(t *testing.T) {
+	tunnel := NewTunnel(testLog, nil, "1", &chshare.Remote{LocalHost: "127.0.0.1", LocalPort: "0", RemoteHost: "0.0.0.0", RemotePort: "80", HTTPProxy: true}, nil, &TunnelProxyConfig{})
+
+	err := tunnel.Start(context.Background())
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "http proxy is not configured")
+}
@@ -0,0 +1,213 @@
+package clients
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+
+	chshare "github.com/cloudradar-monitoring/rport/share"
+)
+
+type bufferConn struct {
+	*bytes.Buffer
+}
+
+func (c bufferConn) Close() error {
+	return nil
+}
+
+func TestTunnelStats(t *testing.T) {
+	now = nowMockF
+	tunnel := NewTunnel(testLog, nil, "1", &chshare.Remote{LocalHost: "127.0.0.1", LocalPort: "0", RemoteHost: "0.0.0.0", RemotePort: "22"}, nil, nil)
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	tunnel.stopFn = cancel
+	tunnel.wg.Add(1)
+	go tunnel.listen(ctx, l)
+	defer tunnel.Terminate(true)
+
+	for i := 0; i < 2; i++ {
+		conn, err := net.Dial("tcp4", l.Addr().String())
+		require.NoError(t, err)
+		// the connection is closed by the tunnel as there is no remote connection
+		_, err = ioutil.ReadAll(conn)
+		require.NoError(t, err)
+		conn.Close()
+	}
+	// wait until the tunnel handles the closed connections
+	require.Eventually(t, func() bool { return tunnel.Stats().ActiveConnections == 0 }, time.Second, 10*time.Millisecond)
+
+	stats := tunnel.Stats()
+	assert.Equal(t, 2, stats.Connections)
+	assert.Equal(t, "127.0.0.1", stats.LastPeerIP)
+	assert.Equal(t, now(), *stats.LastConnectionAt)
+
+	// traffic
+	src := &countingConn{ReadWriteCloser: bufferConn{bytes.NewBufferString("request")}, tunnel: tunnel}
+	_, err = ioutil.ReadAll(src)
+	require.NoError(t, err)
+	_, err = src.Write([]byte("response"))
+	require.NoError(t, err)
+
+	stats = tunnel.Stats()
+	assert.EqualValues(t, 7, stats.BytesIn)
+	assert.EqualValues(t, 8, stats.BytesOut)
+}
+
+// udpEchoConn is an ssh connection that replies to udp datagrams with upper-cased ones.
+type udpEchoConn struct {
+	ssh.Conn
+}
+
+func (c *udpEchoConn) OpenChannel(name string, data []byte) (ssh.Channel, <-chan *ssh.Request, error) {
+	local, remote := net.Pipe()
+	go func() {
+		defer remote.Close()
+		buf := make([]byte, chshare.MaxDatagramSize)
+		for {
+			n, err := chshare.ReadDatagram(remote, buf)
+			if err != nil {
+				return
+			}
+			if err := chshare.WriteDatagram(remote, bytes.ToUpper(buf[:n])); err != nil {
+				return
+			}
+		}
+	}()
+	reqs := make(chan *ssh.Request)
+	close(reqs)
+	return &pipeChannel{conn: local}, reqs, nil
+}
+
+type pipeChannel struct {
+	ssh.Channel
+	conn net.Conn
+}
+
+func (c *pipeChannel) Read(p []byte) (int, error) {
+	return c.conn.Read(p)
+}
+
+func (c *pipeChannel) Write(p []byte) (int, error) {
+	return c.conn.Write(p)
+}
+
+func (c *pipeChannel) Close() error {
+	return c.conn.Close()
+}
+
+func TestUDPTunnel(t *testing.T) {
+	now = nowMockF
+	remote := &chshare.Remote{LocalHost: "127.0.0.1", LocalPort: "0", RemoteHost: "0.0.0.0", RemotePort: "53", Protocol: chshare.ProtocolUDP}
+	tunnel := NewTunnel(testLog, &udpEchoConn{}, "1", remote, nil, nil)
+	pc, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	tunnel.stopFn = cancel
+	tunnel.wg.Add(1)
+	go tunnel.listenUDP(ctx, pc)
+
+	conn, err := net.Dial("udp4", pc.LocalAddr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	buf := make([]byte, 100)
+	for _, msg := range []string{"ping", "pong"} {
+		_, err = conn.Write([]byte(msg))
+		require.NoError(t, err)
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+		n, err := conn.Read(buf)
+		require.NoError(t, err)
+		assert.Equal(t, strings.ToUpper(msg), string(buf[:n]))
+	}
+
+	// datagrams of a peer are forwarded within a single session
+	stats := tunnel.Stats()
+	assert.Equal(t, 1, stats.Connections)
+	assert.Equal(t, 1, stats.ActiveConnections)
+	assert.Equal(t, "127.0.0.1", stats.LastPeerIP)
+	assert.EqualValues(t, 8, stats.BytesIn)
+	assert.EqualValues(t, 8, stats.BytesOut)
+
+	tunnel.Terminate(false)
+	assert.Equal(t, 0, tunnel.Stats().ActiveConnections)
+}
+
+func TestUDPTunnelMaxSessions(t *testing.T) {
+	defer func(max int) {
+		maxUDPSessions = max
+	}(maxUDPSessions)
+	maxUDPSessions = 1
+	now = nowMockF
+	remote := &chshare.Remote{LocalHost: "127.0.0.1", LocalPort: "0", RemoteHost: "0.0.0.0", RemotePort: "53", Protocol: chshare.ProtocolUDP}
+	tunnel := NewTunnel(testLog, &udpEchoConn{}, "1", remote, nil, nil)
+	pc, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	tunnel.stopFn = cancel
+	tunnel.wg.Add(1)
+	go tunnel.listenUDP(ctx, pc)
+	defer tunnel.Terminate(false)
+
+	buf := make([]byte, 100)
+	conn1, err := net.Dial("udp4", pc.LocalAddr().String())
+	require.NoError(t, err)
+	defer conn1.Close()
+	_, err = conn1.Write([]byte("ping"))
+	require.NoError(t, err)
+	require.NoError(t, conn1.SetReadDeadline(time.Now().Add(time.Second)))
+	n, err := conn1.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "PING", string(buf[:n]))
+
+	// a datagram of another peer is dropped
+	conn2, err := net.Dial("udp4", pc.LocalAddr().String())
+	require.NoError(t, err)
+	defer conn2.Close()
+	_, err = conn2.Write([]byte("ping"))
+	require.NoError(t, err)
+	require.NoError(t, conn2.SetReadDeadline(time.Now().Add(100*time.Millisecond)))
+	_, err = conn2.Read(buf)
+	assert.Error(t, err)
+	assert.Equal(t, 1, tunnel.Stats().Connections)
+}
+
+func TestUDPSessionsCloseIdle(t *testing.T) {
+	now = nowMockF
+	sessions := newUDPSessions()
+	active := &udpSession{peer: &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1001}, channel: bufferConn{&bytes.Buffer{}}, lastActive: now()}
+	idle := &udpSession{peer: &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1002}, channel: bufferConn{&bytes.Buffer{}}, lastActive: now().Add(-2 * udpSessionIdleTimeout)}
+	sessions.add(active)
+	sessions.add(idle)
+
+	closed := sessions.closeIdle(now().Add(-udpSessionIdleTimeout))
+
+	assert.Equal(t, 1, closed)
+	assert.Equal(t, active, sessions.get("127.0.0.1:1001"))
+	assert.Nil(t, sessions.get("127.0.0.1:1002"))
+}
+
+func TestTunnelListenNetwork(t *testing.T) {
+	testCases := []struct {
+		host string
+		want string
+	}{
+		{host: "0.0.0.0", want: "tcp4"},
+		{host: "127.0.0.1", want: "tcp4"},
+		{host: "localhost", want: "tcp4"},
+		{host: "::", want: "tcp"},
+		{host: "fd00::1", want: "tcp"},
+	}
+	for _, tc := range testCases {
+		tunnel := NewTunnel(testLog, nil, "1", &chshare.Remote{LocalHost: tc.host, LocalPort: "3000", RemoteHost: "0.0.0.0", RemotePort: "22"}, nil, nil)
+		assert.Equalf(t, tc.want, tunnel.listenNetwork("tcp"), "host %s", tc.host)
+	}
+}
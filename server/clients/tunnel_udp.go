@@ -0,0 +1,252 @@
+package clients
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	chshare "github.com/cloudradar-monitoring/rport/share"
+)
+
+const (
+	// udpSessionIdleTimeout is how long a session of a udp peer is kept open without datagrams in any direction.
+	udpSessionIdleTimeout = time.Minute
+	// udpSessionsCheckInterval is how often idle udp sessions are checked.
+	udpSessionsCheckInterval = 10 * time.Second
+)
+
+// maxUDPSessions is a max number of udp peers a tunnel forwards datagrams of at the same time. Datagrams of new peers
+// are dropped until sessions of other peers are closed.
+var maxUDPSessions = 256
+
+// udpSession forwards datagrams of a single udp peer over a separate ssh channel.
+type udpSession struct {
+	peer    net.Addr
+	channel io.ReadWriteCloser
+
+	mu         sync.Mutex
+	lastActive time.Time
+}
+
+func (s *udpSession) touch() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastActive = now()
+}
+
+func (s *udpSession) idleSince() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastActive
+}
+
+// udpSessions are active sessions of a udp tunnel by peer address.
+type udpSessions struct {
+	mu       sync.Mutex
+	sessions map[string]*udpSession
+}
+
+func newUDPSessions() *udpSessions {
+	return &udpSessions{
+		sessions: make(map[string]*udpSession),
+	}
+}
+
+func (s *udpSessions) get(peer string) *udpSession {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sessions[peer]
+}
+
+func (s *udpSessions) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.sessions)
+}
+
+func (s *udpSessions) add(session *udpSession) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions[session.peer.String()] = session
+}
+
+func (s *udpSessions) remove(session *udpSession) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sessions[session.peer.String()] == session {
+		delete(s.sessions, session.peer.String())
+	}
+}
+
+// closeIdle closes sessions that are not active since a given time. Returns a number of closed sessions.
+func (s *udpSessions) closeIdle(before time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	closed := 0
+	for peer, session := range s.sessions {
+		if session.idleSince().Before(before) {
+			session.channel.Close()
+			delete(s.sessions, peer)
+			closed++
+		}
+	}
+	return closed
+}
+
+func (s *udpSessions) closeAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for peer, session := range s.sessions {
+		session.channel.Close()
+		delete(s.sessions, peer)
+	}
+}
+
+func (t *Tunnel) listenUDP(ctx context.Context, pc net.PacketConn) {
+	defer func() {
+		t.wg.Done()
+	}()
+
+	t.Infof("Listening")
+
+	sessions := newUDPSessions()
+
+	// background goroutine to close the connection and the sessions when Done channel is closed
+	go func() {
+		<-ctx.Done()
+		if err := pc.Close(); err != nil {
+			t.Errorf("Failed to close: %v", err)
+		}
+		sessions.closeAll()
+		t.Infof("Closed")
+	}()
+
+	// background goroutine to close sessions of peers that stopped sending datagrams
+	go func() {
+		ticker := time.NewTicker(udpSessionsCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if closed := sessions.closeIdle(now().Add(-udpSessionIdleTimeout)); closed > 0 {
+					t.Debugf("Closed %d idle udp session(s)", closed)
+				}
+			}
+		}
+	}()
+
+	buf := make([]byte, chshare.MaxDatagramSize)
+	for {
+		n, addr, err := pc.ReadFrom(buf)
+		if err != nil {
+			select {
+			case <-ctx.Done():
+				//connection closed
+				return
+			default:
+				t.Errorf("Failed to read datagram: %v", err)
+			}
+			continue
+		}
+
+		if t.acl != nil {
+			udpAddr, ok := addr.(*net.UDPAddr)
+			if !ok {
+				t.Errorf("Unsupported remote address type. Expected net.UDPAddr. %v", addr)
+				continue
+			}
+
+			if !t.acl.CheckAccess(udpAddr.IP) {
+				t.Debugf("Access rejected. Remote addr: %s", udpAddr)
+				continue
+			}
+		}
+
+		session := sessions.get(addr.String())
+		if session == nil {
+			// sessions are added only here, so the limit can't be exceeded meanwhile
+			if sessions.count() >= maxUDPSessions {
+				t.Debugf("Too many udp sessions, datagram from %s is dropped", addr)
+				continue
+			}
+			session = t.openUDPSession(pc, addr, sessions)
+			if session == nil {
+				continue
+			}
+		}
+
+		if err := chshare.WriteDatagram(session.channel, buf[:n]); err != nil {
+			t.Debugf("Failed to forward datagram from %s: %v", addr, err)
+			sessions.remove(session)
+			session.channel.Close()
+			continue
+		}
+		session.touch()
+		t.addTraffic(int64(n), 0)
+	}
+}
+
+// openUDPSession opens an ssh channel for a new udp peer and starts to forward the replies to it. Returns nil if
+// the session can't be opened.
+func (t *Tunnel) openUDPSession(pc net.PacketConn, peer net.Addr, sessions *udpSessions) *udpSession {
+	if t.sshConn == nil {
+		t.Debugf("No remote connection")
+		return nil
+	}
+	//ssh request for udp forwarding for this proxy's remote
+	channel, reqs, err := t.sshConn.OpenChannel(chshare.UDPChannelType, []byte(t.Remote.Remote()))
+	if err != nil {
+		t.Errorf("Stream error: %s", err)
+		return nil
+	}
+	go ssh.DiscardRequests(reqs)
+
+	session := &udpSession{
+		peer:       peer,
+		channel:    channel,
+		lastActive: now(),
+	}
+	cid, ok := t.addConnection(channel, peerIP(peer))
+	if !ok {
+		channel.Close()
+		return nil
+	}
+	sessions.add(session)
+
+	l := t.Fork("conn#%d", cid)
+	l.Debugf("Open udp session for %s", peer)
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		defer t.removeConnection(cid)
+		defer sessions.remove(session)
+		defer channel.Close()
+		t.forwardUDPReplies(pc, session)
+		l.Debugf("Close udp session for %s", peer)
+	}()
+	return session
+}
+
+// forwardUDPReplies sends datagrams received from the client back to the peer of a given session until its channel
+// is closed.
+func (t *Tunnel) forwardUDPReplies(pc net.PacketConn, session *udpSession) {
+	buf := make([]byte, chshare.MaxDatagramSize)
+	for {
+		n, err := chshare.ReadDatagram(session.channel, buf)
+		if err != nil {
+			return
+		}
+		if _, err := pc.WriteTo(buf[:n], session.peer); err != nil {
+			t.Debugf("Failed to send datagram to %s: %v", session.peer, err)
+			return
+		}
+		session.touch()
+		t.addTraffic(0, int64(n))
+	}
+}
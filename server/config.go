@@ -14,6 +14,8 @@ import (
 	mapset "github.com/deckarep/golang-set"
 	"github.com/jpillora/requestlog"
 
+	"github.com/cloudradar-monitoring/rport/server/api"
+	"github.com/cloudradar-monitoring/rport/server/api/users"
 	"github.com/cloudradar-monitoring/rport/server/ports"
 	chshare "github.com/cloudradar-monitoring/rport/share"
 )
@@ -29,6 +31,13 @@ type APIConfig struct {
 	CertFile       string `mapstructure:"cert_file"`
 	KeyFile        string `mapstructure:"key_file"`
 	AccessLogFile  string `mapstructure:"access_log_file"`
+
+	GroupPermissionsRaw    map[string][]string `mapstructure:"group_permissions"`
+	RestrictClientsByGroup bool                `mapstructure:"restrict_clients_by_group"`
+	TrustedProxiesRaw      []string            `mapstructure:"trusted_proxies"`
+
+	groupPermissions users.GroupPermissions
+	trustedProxies   api.TrustedProxies
 }
 
 const (
@@ -56,6 +65,10 @@ type ServerConfig struct {
 	KeepLostClients            time.Duration `mapstructure:"keep_lost_clients"`
 	SaveClients                time.Duration `mapstructure:"save_clients_interval"`
 	CleanupClients             time.Duration `mapstructure:"cleanup_clients_interval"`
+	KeepJobs                   time.Duration `mapstructure:"keep_jobs"`
+	MaxJobsPerClient           int           `mapstructure:"max_jobs_per_client"`
+	CleanupJobs                time.Duration `mapstructure:"cleanup_jobs_interval"`
+	VacuumJobs                 time.Duration `mapstructure:"vacuum_jobs_interval"`
 	MaxRequestBytes            int64         `mapstructure:"max_request_bytes"`
 	CheckPortTimeout           time.Duration `mapstructure:"check_port_timeout"`
 	RunRemoteCmdTimeoutSec     int           `mapstructure:"run_remote_cmd_timeout_sec"`
@@ -63,10 +76,13 @@ type ServerConfig struct {
 	AuthMultiuseCreds          bool          `mapstructure:"auth_multiuse_creds"`
 	EquateClientauthidClientid bool          `mapstructure:"equate_clientauthid_clientid"`
 	AllowRoot                  bool          `mapstructure:"allow_root"`
+	TunnelProxyCertFile        string        `mapstructure:"tunnel_proxy_cert_file"`
+	TunnelProxyKeyFile         string        `mapstructure:"tunnel_proxy_key_file"`
 
-	excludedPorts mapset.Set
-	authID        string
-	authPassword  string
+	excludedPorts        mapset.Set
+	authID               string
+	authPassword         string
+	tunnelProxyTLSConfig *tls.Config
 }
 
 type DatabaseConfig struct {
@@ -100,6 +116,15 @@ func (c *Config) ExcludedPorts() mapset.Set {
 	return c.Server.excludedPorts
 }
 
+func (c *Config) GroupPermissions() users.GroupPermissions {
+	return c.API.groupPermissions
+}
+
+// TrustedProxies returns reverse proxies which forwarding headers are used to get the source IP of API requests.
+func (c *Config) TrustedProxies() api.TrustedProxies {
+	return c.API.trustedProxies
+}
+
 func (c *Config) ParseAndValidate() error {
 	if c.Server.URL == "" {
 		c.Server.URL = "http://" + c.Server.ListenAddress
@@ -127,10 +152,18 @@ func (c *Config) ParseAndValidate() error {
 		return fmt.Errorf("expected 'Keep Lost Clients' can be in range [%v, %v], actual: %v", MinKeepLostClients, MaxKeepLostClients, c.Server.KeepLostClients)
 	}
 
+	if err := c.validateJobsRetention(); err != nil {
+		return err
+	}
+
 	if err := c.parseAndValidateClientAuth(); err != nil {
 		return err
 	}
 
+	if err := c.parseAndValidateTunnelProxy(); err != nil {
+		return err
+	}
+
 	if err := c.parseAndValidateAPI(); err != nil {
 		return fmt.Errorf("API: %v", err)
 	}
@@ -142,6 +175,22 @@ func (c *Config) ParseAndValidate() error {
 	return nil
 }
 
+func (c *Config) validateJobsRetention() error {
+	if c.Server.KeepJobs < 0 {
+		return fmt.Errorf("'keep_jobs' cannot be negative, actual: %v", c.Server.KeepJobs)
+	}
+	if c.Server.MaxJobsPerClient < 0 {
+		return fmt.Errorf("'max_jobs_per_client' cannot be negative, actual: %d", c.Server.MaxJobsPerClient)
+	}
+	if c.Server.VacuumJobs < 0 {
+		return fmt.Errorf("'vacuum_jobs_interval' cannot be negative, actual: %v", c.Server.VacuumJobs)
+	}
+	if c.Server.CleanupJobs <= 0 && (c.Server.KeepJobs > 0 || c.Server.MaxJobsPerClient > 0 || c.Server.VacuumJobs > 0) {
+		return fmt.Errorf("'cleanup_jobs_interval' should be positive when jobs retention or vacuum is enabled, actual: %v", c.Server.CleanupJobs)
+	}
+	return nil
+}
+
 func (c *Config) parseAndValidateClientAuth() error {
 	if c.Server.Auth == "" && c.Server.AuthFile == "" && c.Server.AuthTable == "" {
 		return errors.New("client authentication must be enabled: set either 'auth', 'auth_file' or 'auth_table'")
@@ -171,6 +220,27 @@ func (c *Config) parseAndValidateClientAuth() error {
 	return nil
 }
 
+// parseAndValidateTunnelProxy loads the certificate to serve tunnels in http proxy mode over https.
+func (c *Config) parseAndValidateTunnelProxy() error {
+	if c.Server.TunnelProxyCertFile == "" && c.Server.TunnelProxyKeyFile == "" {
+		return nil
+	}
+	if c.Server.TunnelProxyCertFile != "" && c.Server.TunnelProxyKeyFile == "" {
+		return errors.New("when 'tunnel_proxy_cert_file' is set, 'tunnel_proxy_key_file' must be set as well")
+	}
+	if c.Server.TunnelProxyCertFile == "" && c.Server.TunnelProxyKeyFile != "" {
+		return errors.New("when 'tunnel_proxy_key_file' is set, 'tunnel_proxy_cert_file' must be set as well")
+	}
+	cert, err := tls.LoadX509KeyPair(c.Server.TunnelProxyCertFile, c.Server.TunnelProxyKeyFile)
+	if err != nil {
+		return fmt.Errorf("invalid 'tunnel_proxy_cert_file', 'tunnel_proxy_key_file': %v", err)
+	}
+	c.Server.tunnelProxyTLSConfig = &tls.Config{
+		Certificates: []tls.Certificate{cert},
+	}
+	return nil
+}
+
 func (c *Config) parseAndValidateAPI() error {
 	if c.API.Address != "" {
 		// API enabled
@@ -182,6 +252,14 @@ func (c *Config) parseAndValidateAPI() error {
 		if err != nil {
 			return err
 		}
+		c.API.groupPermissions, err = users.ParseGroupPermissions(c.API.GroupPermissionsRaw)
+		if err != nil {
+			return fmt.Errorf("invalid 'group_permissions': %v", err)
+		}
+		c.API.trustedProxies, err = api.ParseTrustedProxies(c.API.TrustedProxiesRaw)
+		if err != nil {
+			return fmt.Errorf("invalid 'trusted_proxies': %v", err)
+		}
 		if c.API.JWTSecret == "" {
 			c.API.JWTSecret, err = generateJWTSecret()
 			if err != nil {
@@ -183,6 +183,47 @@ func TestParseAndValidateClientAuth(t *testing.T) {
 	}
 }
 
+func TestParseAndValidateTunnelProxy(t *testing.T) {
+	testCases := []struct {
+		Name          string
+		Server        ServerConfig
+		ExpectedError error
+	}{
+		{
+			Name:   "disabled",
+			Server: ServerConfig{},
+		}, {
+			Name: "no key file",
+			Server: ServerConfig{
+				TunnelProxyCertFile: "/var/lib/rport/server.crt",
+			},
+			ExpectedError: errors.New("when 'tunnel_proxy_cert_file' is set, 'tunnel_proxy_key_file' must be set as well"),
+		}, {
+			Name: "no cert file",
+			Server: ServerConfig{
+				TunnelProxyKeyFile: "/var/lib/rport/server.key",
+			},
+			ExpectedError: errors.New("when 'tunnel_proxy_key_file' is set, 'tunnel_proxy_cert_file' must be set as well"),
+		}, {
+			Name: "missing files",
+			Server: ServerConfig{
+				TunnelProxyCertFile: "/not/existing/server.crt",
+				TunnelProxyKeyFile:  "/not/existing/server.key",
+			},
+			ExpectedError: errors.New("invalid 'tunnel_proxy_cert_file', 'tunnel_proxy_key_file': open /not/existing/server.crt: no such file or directory"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			config := Config{Server: tc.Server}
+			err := config.parseAndValidateTunnelProxy()
+			assert.Equal(t, tc.ExpectedError, err)
+			assert.Nil(t, config.Server.tunnelProxyTLSConfig)
+		})
+	}
+}
+
 func TestParseAndValidateAPI(t *testing.T) {
 	testCases := []struct {
 		Name                 string
@@ -325,6 +366,30 @@ func TestParseAndValidateAPI(t *testing.T) {
 			},
 			ExpectedError: errors.New("API: when 'key_file' is set, 'cert_file' must be set as well"),
 		},
+		{
+			Name: "api enabled, invalid group permissions",
+			Config: Config{
+				API: APIConfig{
+					Address: "0.0.0.0:3000",
+					Auth:    "abc:def",
+					GroupPermissionsRaw: map[string][]string{
+						"operators": {"tunnels", "reboot"},
+					},
+				},
+			},
+			ExpectedError: errors.New(`API: invalid 'group_permissions': group "operators": unknown permission "reboot", expected one of: [tunnels commands clients-auth client-groups library]`),
+		},
+		{
+			Name: "api enabled, invalid trusted proxies",
+			Config: Config{
+				API: APIConfig{
+					Address:           "0.0.0.0:3000",
+					Auth:              "abc:def",
+					TrustedProxiesRaw: []string{"127.0.0.1", "proxy.local"},
+				},
+			},
+			ExpectedError: errors.New(`API: invalid 'trusted_proxies': invalid IP address "proxy.local"`),
+		},
 	}
 
 	for _, tc := range testCases {
@@ -0,0 +1,155 @@
+package chserver
+
+import (
+	"sync"
+
+	"github.com/cloudradar-monitoring/rport/share/models"
+)
+
+// jobOutputBufferSize is a number of output chunks that can be queued for a slow subscriber before new chunks are dropped.
+const jobOutputBufferSize = 100
+
+// jobOutputTopic holds subscribers of a single job. Its lock serializes storing output chunks of the job with loading
+// the job by new subscribers.
+type jobOutputTopic struct {
+	clientID string
+	// refs is a number of subscribers and publishers using the topic, guarded by the broker lock
+	refs int
+
+	mu   sync.Mutex
+	subs map[chan *models.JobOutput]struct{}
+}
+
+// jobOutputBroker is thread safe. It delivers chunks of output of running jobs to subscribers.
+// A zero value is ready to use.
+type jobOutputBroker struct {
+	// mu guards only the topics map, chunks of different jobs are stored and delivered concurrently
+	mu     sync.Mutex
+	topics map[string]*jobOutputTopic
+}
+
+// acquire returns a topic of a job with a given ID creating it if needed. The topic has to be released when it's not used anymore.
+func (b *jobOutputBroker) acquire(clientID, jid string) *jobOutputTopic {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.topics == nil {
+		b.topics = make(map[string]*jobOutputTopic)
+	}
+	t := b.topics[jid]
+	if t == nil {
+		t = &jobOutputTopic{
+			clientID: clientID,
+			subs:     make(map[chan *models.JobOutput]struct{}),
+		}
+		b.topics[jid] = t
+	}
+	t.refs++
+	return t
+}
+
+func (b *jobOutputBroker) release(jid string, t *jobOutputTopic) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	t.refs--
+	if t.refs == 0 {
+		delete(b.topics, jid)
+	}
+}
+
+// Subscribe returns a channel to receive output chunks of a job with a given ID. The channel is closed when the job or
+// the connection of its client is finished. load is called under the same lock of the job as a publisher's store func,
+// so output stored before subscription is read exactly once. Every subscription has to be ended with Unsubscribe.
+func (b *jobOutputBroker) Subscribe(clientID, jid string, load func() error) (chan *models.JobOutput, error) {
+	t := b.acquire(clientID, jid)
+	t.mu.Lock()
+	if err := load(); err != nil {
+		t.mu.Unlock()
+		b.release(jid, t)
+		return nil, err
+	}
+
+	ch := make(chan *models.JobOutput, jobOutputBufferSize)
+	t.subs[ch] = struct{}{}
+	t.mu.Unlock()
+	return ch, nil
+}
+
+// Unsubscribe stops sending output chunks to a given channel.
+func (b *jobOutputBroker) Unsubscribe(jid string, ch chan *models.JobOutput) {
+	b.mu.Lock()
+	t := b.topics[jid]
+	b.mu.Unlock()
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	if _, ok := t.subs[ch]; ok {
+		delete(t.subs, ch)
+		close(ch)
+	}
+	t.mu.Unlock()
+	b.release(jid, t)
+}
+
+// Publish stores a given output chunk by calling store and sends it to all subscribers of the job.
+// Returns false if the chunk was dropped for at least one slow subscriber.
+func (b *jobOutputBroker) Publish(output *models.JobOutput, store func() error) (bool, error) {
+	t := b.acquire(output.ClientID, output.JID)
+	defer b.release(output.JID, t)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err := store(); err != nil {
+		return true, err
+	}
+
+	delivered := true
+	for ch := range t.subs {
+		select {
+		case ch <- output:
+		default:
+			delivered = false
+		}
+	}
+	return delivered, nil
+}
+
+// Finish closes channels of all subscribers of a job with a given ID.
+func (b *jobOutputBroker) Finish(jid string) {
+	b.mu.Lock()
+	t := b.topics[jid]
+	b.mu.Unlock()
+	if t != nil {
+		t.finish()
+	}
+}
+
+// FinishByClientID closes channels of all subscribers of jobs of a client with a given ID. It's used when the client
+// disconnects, so results of its running jobs won't be received anymore.
+func (b *jobOutputBroker) FinishByClientID(clientID string) {
+	b.mu.Lock()
+	var topics []*jobOutputTopic
+	for _, t := range b.topics {
+		if t.clientID == clientID {
+			topics = append(topics, t)
+		}
+	}
+	b.mu.Unlock()
+
+	for _, t := range topics {
+		t.finish()
+	}
+}
+
+func (t *jobOutputTopic) finish() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for ch := range t.subs {
+		delete(t.subs, ch)
+		close(ch)
+	}
+}
@@ -0,0 +1,69 @@
+package chserver
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudradar-monitoring/rport/share/models"
+)
+
+// subscribersCount returns a number of subscribers of a job with a given ID.
+func (b *jobOutputBroker) subscribersCount(jid string) int {
+	b.mu.Lock()
+	t := b.topics[jid]
+	b.mu.Unlock()
+	if t == nil {
+		return 0
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.subs)
+}
+
+func TestJobOutputBroker(t *testing.T) {
+	b := &jobOutputBroker{}
+
+	ch1, err := b.Subscribe("client-1", "job-1", func() error { return nil })
+	require.NoError(t, err)
+	ch2, err := b.Subscribe("client-1", "job-2", func() error { return nil })
+	require.NoError(t, err)
+	ch3, err := b.Subscribe("client-2", "job-3", func() error { return nil })
+	require.NoError(t, err)
+
+	// chunks of different jobs are stored concurrently
+	storing := make(chan struct{})
+	release := make(chan struct{})
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, _ = b.Publish(&models.JobOutput{JID: "job-1", ClientID: "client-1", StdOut: "slow"}, func() error {
+			close(storing)
+			<-release
+			return nil
+		})
+	}()
+	<-storing
+	delivered, err := b.Publish(&models.JobOutput{JID: "job-3", ClientID: "client-2", StdOut: "out"}, func() error { return nil })
+	require.NoError(t, err)
+	assert.True(t, delivered)
+	assert.Equal(t, "out", (<-ch3).StdOut)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, "slow", (<-ch1).StdOut)
+
+	b.FinishByClientID("client-1")
+	_, ok := <-ch1
+	assert.False(t, ok)
+	_, ok = <-ch2
+	assert.False(t, ok)
+	assert.Equal(t, 1, b.subscribersCount("job-3"))
+
+	b.Unsubscribe("job-1", ch1)
+	b.Unsubscribe("job-2", ch2)
+	b.Unsubscribe("job-3", ch3)
+	assert.Empty(t, b.topics)
+}
@@ -0,0 +1,90 @@
+package chserver
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/cloudradar-monitoring/rport/server/auditlog"
+	chshare "github.com/cloudradar-monitoring/rport/share"
+)
+
+type jobsCleanupTask struct {
+	log              *chshare.Logger
+	jobProvider      JobProvider
+	keepJobs         time.Duration
+	maxJobsPerClient int
+	vacuumInterval   time.Duration
+	lastVacuum       time.Time
+}
+
+// newJobsCleanupTask returns a task to delete jobs that are older than keepJobs or exceed maxJobsPerClient latest jobs
+// of a client. Zero values disable the corresponding retention. The database is vacuumed with a given interval.
+func newJobsCleanupTask(log *chshare.Logger, jobProvider JobProvider, keepJobs time.Duration, maxJobsPerClient int, vacuumInterval time.Duration) *jobsCleanupTask {
+	return &jobsCleanupTask{
+		log:              log,
+		jobProvider:      jobProvider,
+		keepJobs:         keepJobs,
+		maxJobsPerClient: maxJobsPerClient,
+		vacuumInterval:   vacuumInterval,
+		lastVacuum:       time.Now(),
+	}
+}
+
+func (t *jobsCleanupTask) Run(ctx context.Context) error {
+	if t.keepJobs > 0 {
+		deleted, err := t.jobProvider.DeleteFinishedBefore(time.Now().Add(-t.keepJobs))
+		if err != nil {
+			return fmt.Errorf("failed to delete obsolete jobs: %v", err)
+		}
+		if deleted > 0 {
+			t.log.Debugf("Deleted %d job(s) finished more than %v ago.", deleted, t.keepJobs)
+		}
+	}
+
+	if t.maxJobsPerClient > 0 {
+		deleted, err := t.jobProvider.DeleteExceedingPerClient(t.maxJobsPerClient)
+		if err != nil {
+			return fmt.Errorf("failed to delete jobs exceeding max number per client: %v", err)
+		}
+		if deleted > 0 {
+			t.log.Debugf("Deleted %d job(s) exceeding %d jobs per client.", deleted, t.maxJobsPerClient)
+		}
+	}
+
+	if t.vacuumInterval > 0 && time.Since(t.lastVacuum) >= t.vacuumInterval {
+		if err := t.jobProvider.Vacuum(); err != nil {
+			return fmt.Errorf("failed to vacuum jobs database: %v", err)
+		}
+		t.lastVacuum = time.Now()
+		t.log.Debugf("Jobs database vacuumed.")
+	}
+	return nil
+}
+
+// handleDeleteCommands deletes the history of finished commands of a given client. Running and queued commands are kept.
+func (al *APIListener) handleDeleteCommands(w http.ResponseWriter, req *http.Request) {
+	cid := mux.Vars(req)[routeParamClientID]
+	if cid == "" {
+		al.jsonErrorResponseWithTitle(w, http.StatusBadRequest, fmt.Sprintf("Missing %q route param.", routeParamClientID))
+		return
+	}
+
+	deleted, err := al.jobProvider.DeleteFinishedByClientID(cid)
+	if err != nil {
+		al.jsonErrorResponseWithError(w, http.StatusInternalServerError, "", fmt.Sprintf("Failed to delete client jobs: client_id=%q.", cid), err)
+		return
+	}
+
+	al.auditLog.Entry(auditlog.ApplicationClientCommand, auditlog.ActionDelete).
+		WithHTTPRequest(req).
+		WithClientID(cid).
+		Save()
+
+	al.Debugf("Deleted %d finished job(s) of client with id=%q.", deleted, cid)
+
+	w.WriteHeader(http.StatusNoContent)
+}
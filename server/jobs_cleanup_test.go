@@ -0,0 +1,77 @@
+package chserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudradar-monitoring/rport/server/api/jobs"
+	"github.com/cloudradar-monitoring/rport/server/test/jb"
+	"github.com/cloudradar-monitoring/rport/share/models"
+)
+
+func TestJobsCleanupTask(t *testing.T) {
+	jp, err := jobs.NewSqliteProvider(":memory:", testLog)
+	require.NoError(t, err)
+	defer jp.Close()
+
+	now := time.Now()
+	old := jb.New(t).ClientID("client-1").Status(models.JobStatusSuccessful).StartedAt(now.Add(-48 * time.Hour)).FinishedAt(now.Add(-48 * time.Hour)).Build()
+	job1 := jb.New(t).ClientID("client-1").Status(models.JobStatusSuccessful).StartedAt(now.Add(-2 * time.Hour)).FinishedAt(now.Add(-2 * time.Hour)).Build()
+	job2 := jb.New(t).ClientID("client-1").Status(models.JobStatusSuccessful).StartedAt(now.Add(-time.Hour)).FinishedAt(now.Add(-time.Hour)).Build()
+	for _, job := range []*models.Job{old, job1, job2} {
+		require.NoError(t, jp.CreateJob(job))
+	}
+
+	task := newJobsCleanupTask(testLog, jp, 24*time.Hour, 1, time.Hour)
+	task.lastVacuum = now.Add(-2 * time.Hour)
+
+	// when
+	require.NoError(t, task.Run(context.Background()))
+
+	// then
+	got, err := jp.ListSummaries("client-1", &jobs.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, job2.JID, got[0].JID)
+	assert.True(t, task.lastVacuum.After(now))
+}
+
+func TestHandleDeleteCommands(t *testing.T) {
+	jp, err := jobs.NewSqliteProvider(":memory:", testLog)
+	require.NoError(t, err)
+	defer jp.Close()
+
+	finished := jb.New(t).ClientID("client-1").Status(models.JobStatusSuccessful).FinishedAt(time.Now()).Build()
+	running := jb.New(t).ClientID("client-1").Status(models.JobStatusRunning).Build()
+	for _, job := range []*models.Job{finished, running} {
+		require.NoError(t, jp.CreateJob(job))
+	}
+
+	al := APIListener{
+		insecureForTests: true,
+		Server: &Server{
+			config:      &Config{},
+			jobProvider: jp,
+		},
+		Logger: testLog,
+	}
+	al.initRouter()
+
+	// when
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/clients/client-1/commands", nil)
+	al.router.ServeHTTP(w, req)
+
+	// then
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	got, err := jp.ListSummaries("client-1", &jobs.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, running.JID, got[0].JID)
+}
@@ -0,0 +1,90 @@
+package library
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Script is a stored script that can be executed on clients. Every update of a script creates a new version.
+type Script struct {
+	ID          string    `json:"id"`
+	Version     int       `json:"version"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Interpreter string    `json:"interpreter"`
+	Script      string    `json:"script"`
+	Params      []Param   `json:"params"`
+	Tags        []string  `json:"tags"`
+	CreatedBy   string    `json:"created_by"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Param is a named script parameter. It's passed to the script as an environment variable with the same name.
+// A param without a default value is required.
+type Param struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Default     *string `json:"default"`
+}
+
+var validParamNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Validate checks whether a given script can be stored.
+func (s *Script) Validate() error {
+	if strings.TrimSpace(s.Name) == "" {
+		return errors.New("script name cannot be empty")
+	}
+	if strings.TrimSpace(s.Script) == "" {
+		return errors.New("script cannot be empty")
+	}
+	names := make(map[string]bool, len(s.Params))
+	for _, p := range s.Params {
+		if !validParamNameRegexp.MatchString(p.Name) {
+			return fmt.Errorf("invalid param name %q: it should start with a letter or underscore and contain only letters, digits and underscores", p.Name)
+		}
+		if names[p.Name] {
+			return fmt.Errorf("duplicate param %q", p.Name)
+		}
+		names[p.Name] = true
+	}
+	for _, tag := range s.Tags {
+		if strings.TrimSpace(tag) == "" {
+			return errors.New("tag cannot be empty")
+		}
+	}
+	return nil
+}
+
+// HasTag returns true if the script has a given tag. Tags are case-insensitive.
+func (s *Script) HasTag(tag string) bool {
+	for _, cur := range s.Tags {
+		if strings.EqualFold(cur, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// Env returns environment variables to run the script with given param values. Defaults are used for missing values.
+func (s *Script) Env(values map[string]string) (map[string]string, error) {
+	env := make(map[string]string, len(s.Params))
+	for _, p := range s.Params {
+		value, ok := values[p.Name]
+		if !ok {
+			if p.Default == nil {
+				return nil, fmt.Errorf("missing required param %q", p.Name)
+			}
+			value = *p.Default
+		}
+		env[p.Name] = value
+	}
+	for name := range values {
+		if _, ok := env[name]; !ok {
+			return nil, fmt.Errorf("unknown param %q", name)
+		}
+	}
+	return env, nil
+}
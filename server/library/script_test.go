@@ -0,0 +1,113 @@
+package library
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScriptEnv(t *testing.T) {
+	defaultDir := "/tmp"
+	script := &Script{
+		Params: []Param{
+			{Name: "DIR", Default: &defaultDir},
+			{Name: "DAYS"},
+		},
+	}
+
+	testCases := []struct {
+		name    string
+		values  map[string]string
+		wantEnv map[string]string
+		wantErr string
+	}{
+		{
+			name:    "defaults applied",
+			values:  map[string]string{"DAYS": "7"},
+			wantEnv: map[string]string{"DIR": "/tmp", "DAYS": "7"},
+		},
+		{
+			name:    "all values given",
+			values:  map[string]string{"DIR": "/var/tmp", "DAYS": "7"},
+			wantEnv: map[string]string{"DIR": "/var/tmp", "DAYS": "7"},
+		},
+		{
+			name:    "missing required param",
+			values:  nil,
+			wantErr: `missing required param "DAYS"`,
+		},
+		{
+			name:    "unknown param",
+			values:  map[string]string{"DAYS": "7", "USER": "root"},
+			wantErr: `unknown param "USER"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// when
+			gotEnv, gotErr := script.Env(tc.values)
+
+			// then
+			if tc.wantErr != "" {
+				require.EqualError(t, gotErr, tc.wantErr)
+				return
+			}
+			require.NoError(t, gotErr)
+			assert.Equal(t, tc.wantEnv, gotEnv)
+		})
+	}
+}
+
+func TestScriptValidate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		script  Script
+		wantErr string
+	}{
+		{
+			name:   "valid",
+			script: Script{Name: "test", Script: "uptime", Params: []Param{{Name: "_DIR1"}}, Tags: []string{"linux"}},
+		},
+		{
+			name:    "empty name",
+			script:  Script{Name: " ", Script: "uptime"},
+			wantErr: "script name cannot be empty",
+		},
+		{
+			name:    "empty script",
+			script:  Script{Name: "test"},
+			wantErr: "script cannot be empty",
+		},
+		{
+			name:    "invalid param name",
+			script:  Script{Name: "test", Script: "uptime", Params: []Param{{Name: "1DIR"}}},
+			wantErr: `invalid param name "1DIR": it should start with a letter or underscore and contain only letters, digits and underscores`,
+		},
+		{
+			name:    "duplicate param",
+			script:  Script{Name: "test", Script: "uptime", Params: []Param{{Name: "DIR"}, {Name: "DIR"}}},
+			wantErr: `duplicate param "DIR"`,
+		},
+		{
+			name:    "empty tag",
+			script:  Script{Name: "test", Script: "uptime", Tags: []string{""}},
+			wantErr: "tag cannot be empty",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// when
+			gotErr := tc.script.Validate()
+
+			// then
+			if tc.wantErr != "" {
+				assert.EqualError(t, gotErr, tc.wantErr)
+			} else {
+				assert.NoError(t, gotErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package library
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/cloudradar-monitoring/rport/db/migration/library"
+	"github.com/cloudradar-monitoring/rport/db/sqlite"
+)
+
+type Provider interface {
+	// GetAll returns the latest versions of all scripts.
+	GetAll(ctx context.Context) ([]*Script, error)
+	// Get returns a given version of a script. Zero version means the latest one. Returns nil if not found.
+	Get(ctx context.Context, id string, version int) (*Script, error)
+	// Save stores a given script as a new version and sets its version number.
+	Save(ctx context.Context, script *Script) error
+	// Delete removes all versions of a script.
+	Delete(ctx context.Context, id string) error
+	Close() error
+}
+
+type SqliteProvider struct {
+	db *sqlx.DB
+}
+
+func NewSqliteProvider(dbPath string) (*SqliteProvider, error) {
+	db, err := sqlite.New(dbPath, library.AssetNames(), library.Asset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create library DB instance: %v", err)
+	}
+	return &SqliteProvider{db: db}, nil
+}
+
+func (p *SqliteProvider) GetAll(ctx context.Context) ([]*Script, error) {
+	var res []*scriptSqlite
+	err := p.db.SelectContext(
+		ctx,
+		&res,
+		`SELECT s.* FROM scripts s
+		JOIN (SELECT id, MAX(version) AS version FROM scripts GROUP BY id) latest ON s.id = latest.id AND s.version = latest.version
+		ORDER BY DATETIME(s.created_at) DESC, s.id`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return convertScripts(res), nil
+}
+
+func (p *SqliteProvider) Get(ctx context.Context, id string, version int) (*Script, error) {
+	res := &scriptSqlite{}
+	var err error
+	if version > 0 {
+		err = p.db.GetContext(ctx, res, "SELECT * FROM scripts WHERE id = ? AND version = ?", id, version)
+	} else {
+		err = p.db.GetContext(ctx, res, "SELECT * FROM scripts WHERE id = ? ORDER BY version DESC LIMIT 1", id)
+	}
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return res.convert(), nil
+}
+
+func (p *SqliteProvider) Save(ctx context.Context, script *Script) error {
+	tx, err := p.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var lastVersion int
+	err = tx.GetContext(ctx, &lastVersion, "SELECT IFNULL(MAX(version), 0) FROM scripts WHERE id = ?", script.ID)
+	if err != nil {
+		return err
+	}
+	script.Version = lastVersion + 1
+
+	_, err = tx.NamedExecContext(
+		ctx,
+		"INSERT INTO scripts (id, version, created_at, created_by, details) VALUES (:id, :version, :created_at, :created_by, :details)",
+		convertToSqlite(script),
+	)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (p *SqliteProvider) Delete(ctx context.Context, id string) error {
+	_, err := p.db.ExecContext(ctx, "DELETE FROM scripts WHERE id = ?", id)
+	return err
+}
+
+func (p *SqliteProvider) Close() error {
+	return p.db.Close()
+}
+
+type scriptSqlite struct {
+	ID        string         `db:"id"`
+	Version   int            `db:"version"`
+	CreatedAt time.Time      `db:"created_at"`
+	CreatedBy string         `db:"created_by"`
+	Details   *scriptDetails `db:"details"`
+}
+
+type scriptDetails struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Interpreter string   `json:"interpreter"`
+	Script      string   `json:"script"`
+	Params      []Param  `json:"params"`
+	Tags        []string `json:"tags"`
+}
+
+func (d *scriptDetails) Scan(value interface{}) error {
+	if d == nil {
+		return errors.New("'details' cannot be nil")
+	}
+	valueStr, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("expected to have string, got %T", value)
+	}
+	err := json.Unmarshal([]byte(valueStr), d)
+	if err != nil {
+		return fmt.Errorf("failed to decode 'details' field: %v", err)
+	}
+	return nil
+}
+
+func (d *scriptDetails) Value() (driver.Value, error) {
+	if d == nil {
+		return nil, errors.New("'details' cannot be nil")
+	}
+	b, err := json.Marshal(d)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode 'details' field: %v", err)
+	}
+	return string(b), nil
+}
+
+func (s *scriptSqlite) convert() *Script {
+	return &Script{
+		ID:          s.ID,
+		Version:     s.Version,
+		Name:        s.Details.Name,
+		Description: s.Details.Description,
+		Interpreter: s.Details.Interpreter,
+		Script:      s.Details.Script,
+		Params:      s.Details.Params,
+		Tags:        s.Details.Tags,
+		CreatedBy:   s.CreatedBy,
+		CreatedAt:   s.CreatedAt,
+	}
+}
+
+func convertScripts(list []*scriptSqlite) []*Script {
+	res := make([]*Script, 0, len(list))
+	for _, cur := range list {
+		res = append(res, cur.convert())
+	}
+	return res
+}
+
+func convertToSqlite(script *Script) *scriptSqlite {
+	return &scriptSqlite{
+		ID:        script.ID,
+		Version:   script.Version,
+		CreatedAt: script.CreatedAt,
+		CreatedBy: script.CreatedBy,
+		Details: &scriptDetails{
+			Name:        script.Name,
+			Description: script.Description,
+			Interpreter: script.Interpreter,
+			Script:      script.Script,
+			Params:      script.Params,
+			Tags:        script.Tags,
+		},
+	}
+}
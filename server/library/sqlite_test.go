@@ -0,0 +1,76 @@
+package library
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSqliteProvider(t *testing.T) {
+	ctx := context.Background()
+	p, err := NewSqliteProvider(":memory:")
+	require.NoError(t, err)
+	defer p.Close()
+
+	defaultDir := "/tmp"
+	t1 := time.Date(2021, 1, 1, 10, 0, 0, 0, time.UTC)
+	script1 := &Script{
+		ID:          "script-1",
+		Name:        "Clean up",
+		Interpreter: "/bin/bash",
+		Script:      "rm -rf $DIR/*",
+		Params:      []Param{{Name: "DIR", Default: &defaultDir}},
+		Tags:        []string{"maintenance"},
+		CreatedBy:   "admin",
+		CreatedAt:   t1,
+	}
+	script2 := &Script{
+		ID:        "script-2",
+		Name:      "Uptime",
+		Script:    "uptime",
+		CreatedBy: "admin",
+		CreatedAt: t1.Add(time.Minute),
+	}
+	require.NoError(t, p.Save(ctx, script1))
+	require.NoError(t, p.Save(ctx, script2))
+	assert.Equal(t, 1, script1.Version)
+	assert.Equal(t, 1, script2.Version)
+
+	// update creates a new version
+	script1v2 := *script1
+	script1v2.Script = "rm -rf $DIR/*.tmp"
+	script1v2.CreatedAt = t1.Add(2 * time.Minute)
+	require.NoError(t, p.Save(ctx, &script1v2))
+	assert.Equal(t, 2, script1v2.Version)
+
+	gotLatest, err := p.Get(ctx, script1.ID, 0)
+	require.NoError(t, err)
+	assert.Equal(t, &script1v2, gotLatest)
+
+	gotV1, err := p.Get(ctx, script1.ID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, script1, gotV1)
+
+	gotUnknownVersion, err := p.Get(ctx, script1.ID, 3)
+	require.NoError(t, err)
+	assert.Nil(t, gotUnknownVersion)
+
+	gotAll, err := p.GetAll(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []*Script{&script1v2, script2}, gotAll)
+
+	// delete removes all versions
+	require.NoError(t, p.Delete(ctx, script1.ID))
+
+	gotDeleted, err := p.Get(ctx, script1.ID, 1)
+	require.NoError(t, err)
+	assert.Nil(t, gotDeleted)
+
+	gotAll, err = p.GetAll(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []*Script{script2}, gotAll)
+}
@@ -0,0 +1,91 @@
+package chserver
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/cloudradar-monitoring/rport/server/clients"
+	chshare "github.com/cloudradar-monitoring/rport/share"
+	"github.com/cloudradar-monitoring/rport/share/comm"
+	"github.com/cloudradar-monitoring/rport/share/models"
+)
+
+// queuedJobsExpireInterval is how often jobs queued for disconnected clients are checked whether they are expired.
+const queuedJobsExpireInterval = time.Minute
+
+// runQueuedJobs sends jobs that wait on the server for a given client that has just connected.
+func (al *APIListener) runQueuedJobs(client *clients.Client) {
+	queued, err := al.jobProvider.GetQueuedByClientID(client.ID)
+	if err != nil {
+		al.Errorf("client_id=%q, Failed to get queued jobs: %v", client.ID, err)
+		return
+	}
+
+	for _, job := range queued {
+		if job.ExpiresAt.Before(time.Now()) {
+			// is marked as expired by the expire task
+			continue
+		}
+
+		// claim the job, so it's not sent twice if the client reconnects while its queued jobs are being sent
+		claimed, err := al.jobProvider.ClaimQueuedJob(job.JID)
+		if err != nil {
+			al.Errorf("%s, Failed to claim queued job: %v", job.LogPrefix(), err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		expiresAt := job.ExpiresAt
+		job.ExpiresAt = nil
+		sshResp := &comm.RunCmdResponse{}
+		err = comm.SendRequestAndGetResponse(client.Connection, comm.RequestTypeRunCmd, job, sshResp)
+		if _, ok := err.(*comm.ClientError); ok {
+			al.Errorf("%s, Error on execute queued remote command: %v", job.LogPrefix(), err)
+			job.Status = models.JobStatusFailed
+			now := time.Now()
+			job.FinishedAt = &now
+			job.Error = err.Error()
+		} else if err != nil {
+			// the client is disconnected, it gets the job when it connects again
+			al.Errorf("%s, Failed to send queued remote command: %v", job.LogPrefix(), err)
+			job.Status = models.JobStatusQueued
+			job.ExpiresAt = expiresAt
+		} else {
+			al.Debugf("%s, Queued job was sent to execute remote command: %q.", job.LogPrefix(), job.Command)
+			applyRunCmdResponse(job, sshResp)
+		}
+
+		if dbErr := al.jobProvider.UpdateQueuedJob(job); dbErr != nil {
+			// just log it, cmd is running, when it's finished it can be saved on result return
+			al.Errorf("%s, Failed to persist job: %v", job.LogPrefix(), dbErr)
+		}
+	}
+}
+
+type expireQueuedJobsTask struct {
+	log         *chshare.Logger
+	jobProvider JobProvider
+}
+
+// newExpireQueuedJobsTask returns a task to mark jobs queued for disconnected clients as expired when their time is over.
+func newExpireQueuedJobsTask(log *chshare.Logger, jobProvider JobProvider) *expireQueuedJobsTask {
+	return &expireQueuedJobsTask{
+		log:         log,
+		jobProvider: jobProvider,
+	}
+}
+
+func (t *expireQueuedJobsTask) Run(ctx context.Context) error {
+	expired, err := t.jobProvider.ExpireQueuedJobs(time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to expire queued jobs: %v", err)
+	}
+
+	if expired > 0 {
+		t.log.Debugf("%d queued job(s) expired.", expired)
+	}
+	return nil
+}
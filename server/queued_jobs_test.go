@@ -0,0 +1,158 @@
+package chserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudradar-monitoring/rport/server/api/jobs"
+	"github.com/cloudradar-monitoring/rport/server/clients"
+	"github.com/cloudradar-monitoring/rport/server/test/jb"
+	"github.com/cloudradar-monitoring/rport/share/comm"
+	"github.com/cloudradar-monitoring/rport/share/models"
+	"github.com/cloudradar-monitoring/rport/share/test"
+)
+
+func TestRunQueuedJobs(t *testing.T) {
+	jp, err := jobs.NewSqliteProvider(":memory:", testLog)
+	require.NoError(t, err)
+	defer jp.Close()
+
+	connMock := test.NewConnMock()
+	connMock.ReturnOk = true
+	sshResp := comm.RunCmdResponse{Pid: 123, StartedAt: time.Date(2020, 10, 10, 10, 10, 10, 0, time.UTC)}
+	sshRespBytes, err := json.Marshal(sshResp)
+	require.NoError(t, err)
+	connMock.ReturnResponsePayload = sshRespBytes
+	c1 := clients.New(t).ID("client-1").Connection(connMock).Build()
+
+	now := time.Now()
+	queuedJob := jb.New(t).ClientID(c1.ID).Status(models.JobStatusQueued).Result(nil).ExpiresAt(now.Add(time.Hour)).Build()
+	queuedJob.PID = nil
+	expiredJob := jb.New(t).ClientID(c1.ID).Status(models.JobStatusQueued).Result(nil).ExpiresAt(now.Add(-time.Minute)).Build()
+	otherClientJob := jb.New(t).Status(models.JobStatusQueued).Result(nil).ExpiresAt(now.Add(time.Hour)).Build()
+	for _, job := range []*models.Job{queuedJob, expiredJob, otherClientJob} {
+		require.NoError(t, jp.CreateJob(job))
+	}
+
+	al := &APIListener{
+		Server: &Server{
+			jobProvider: jp,
+		},
+		Logger: testLog,
+	}
+
+	// when
+	al.runQueuedJobs(c1)
+
+	// then
+	gotJob, err := jp.GetByJID(c1.ID, queuedJob.JID)
+	require.NoError(t, err)
+	assert.Equal(t, models.JobStatusRunning, gotJob.Status)
+	assert.Equal(t, &sshResp.Pid, gotJob.PID)
+	assert.Equal(t, sshResp.StartedAt, gotJob.StartedAt)
+	assert.Nil(t, gotJob.ExpiresAt)
+
+	// expire
+	require.NoError(t, newExpireQueuedJobsTask(testLog, jp).Run(context.Background()))
+
+	gotJob, err = jp.GetByJID(c1.ID, expiredJob.JID)
+	require.NoError(t, err)
+	assert.Equal(t, models.JobStatusExpired, gotJob.Status)
+	assert.NotNil(t, gotJob.FinishedAt)
+
+	gotJob, err = jp.GetByJID(otherClientJob.ClientID, otherClientJob.JID)
+	require.NoError(t, err)
+	assert.Equal(t, models.JobStatusQueued, gotJob.Status)
+}
+
+func TestRunQueuedJobsSendFailed(t *testing.T) {
+	testCases := []struct {
+		name       string
+		returnErr  error
+		wantStatus string
+	}{
+		{
+			name:       "client disconnected",
+			returnErr:  errors.New("EOF"),
+			wantStatus: models.JobStatusQueued,
+		},
+		{
+			name:       "client error",
+			wantStatus: models.JobStatusFailed,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			jp, err := jobs.NewSqliteProvider(":memory:", testLog)
+			require.NoError(t, err)
+			defer jp.Close()
+
+			connMock := test.NewConnMock()
+			connMock.ReturnErr = tc.returnErr
+			c1 := clients.New(t).ID("client-1").Connection(connMock).Build()
+
+			queuedJob := jb.New(t).ClientID(c1.ID).Status(models.JobStatusQueued).Result(nil).ExpiresAt(time.Now().Add(time.Hour)).Build()
+			require.NoError(t, jp.CreateJob(queuedJob))
+
+			al := &APIListener{
+				Server: &Server{
+					jobProvider: jp,
+				},
+				Logger: testLog,
+			}
+
+			// when
+			al.runQueuedJobs(c1)
+
+			// then
+			gotJob, err := jp.GetByJID(c1.ID, queuedJob.JID)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantStatus, gotJob.Status)
+			if tc.wantStatus == models.JobStatusQueued {
+				assert.NotNil(t, gotJob.ExpiresAt)
+				assert.Nil(t, gotJob.FinishedAt)
+			} else {
+				assert.NotNil(t, gotJob.FinishedAt)
+			}
+		})
+	}
+}
+
+func TestRunQueuedJobsClaimed(t *testing.T) {
+	jp, err := jobs.NewSqliteProvider(":memory:", testLog)
+	require.NoError(t, err)
+	defer jp.Close()
+
+	connMock := test.NewConnMock()
+	connMock.ReturnOk = true
+	c1 := clients.New(t).ID("client-1").Connection(connMock).Build()
+
+	queuedJob := jb.New(t).ClientID(c1.ID).Status(models.JobStatusQueued).Result(nil).ExpiresAt(time.Now().Add(time.Hour)).Build()
+	require.NoError(t, jp.CreateJob(queuedJob))
+	// is being sent by another connection of the client
+	claimed, err := jp.ClaimQueuedJob(queuedJob.JID)
+	require.NoError(t, err)
+	require.True(t, claimed)
+
+	al := &APIListener{
+		Server: &Server{
+			jobProvider: jp,
+		},
+		Logger: testLog,
+	}
+
+	// when
+	al.runQueuedJobs(c1)
+
+	// then
+	assert.Empty(t, connMock.InputSendRequests())
+	gotJob, err := jp.GetByJID(c1.ID, queuedJob.JID)
+	require.NoError(t, err)
+	assert.Equal(t, models.JobStatusSending, gotJob.Status)
+}
@@ -0,0 +1,292 @@
+package chserver
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cloudradar-monitoring/rport/server/cgroups"
+	"github.com/cloudradar-monitoring/rport/server/clients"
+	"github.com/cloudradar-monitoring/rport/share/models"
+)
+
+// multiJobResultCheckInterval is how often a multi-client job waiting for results of its child jobs checks whether it's
+// cancelled or the clients are disconnected.
+var multiJobResultCheckInterval = time.Second
+
+// validateRolloutStrategy checks the fields of a multi-client command request that define how it is rolled out.
+func validateRolloutStrategy(reqBody *multiClientCmdRequest) error {
+	if reqBody.BatchSize < 0 {
+		return fmt.Errorf("batch size cannot be negative: %d", reqBody.BatchSize)
+	}
+	if reqBody.BatchPercent < 0 || reqBody.BatchPercent > 100 {
+		return fmt.Errorf("batch percent should be between 1 and 100, actual: %d", reqBody.BatchPercent)
+	}
+	if reqBody.BatchSize > 0 && reqBody.BatchPercent > 0 {
+		return errors.New("batch size and batch percent cannot be specified together")
+	}
+	if reqBody.BatchPauseSec < 0 {
+		return fmt.Errorf("batch pause cannot be negative: %d", reqBody.BatchPauseSec)
+	}
+	if reqBody.MaxFailurePercent != nil && (*reqBody.MaxFailurePercent < 0 || *reqBody.MaxFailurePercent > 100) {
+		return fmt.Errorf("max failure percent should be between 0 and 100, actual: %d", *reqBody.MaxFailurePercent)
+	}
+	return nil
+}
+
+// newBatches splits the clients to run a given multi-client job on into batches. Clients given by IDs go first in the
+// given order, followed by group clients sorted by ID. If the job is rolled out by group, each group in the order of
+// the job groups starts a new batch. A client that is given several times is run only once.
+func newBatches(job *models.MultiJob, clientIDs []string, groups []*cgroups.ClientGroup, groupClients []*clients.Client) [][]string {
+	added := make(map[string]bool)
+	unique := func(ids []string) []string {
+		var res []string
+		for _, id := range ids {
+			if !added[id] {
+				added[id] = true
+				res = append(res, id)
+			}
+		}
+		return res
+	}
+	sorted := func(list []*clients.Client) []string {
+		var res []string
+		for _, cur := range list {
+			res = append(res, cur.ID)
+		}
+		sort.Strings(res)
+		return res
+	}
+
+	parts := [][]string{unique(clientIDs)}
+	if job.BatchByGroup {
+		for _, group := range groups {
+			var members []*clients.Client
+			for _, cur := range groupClients {
+				if cur.BelongsTo(group) {
+					members = append(members, cur)
+				}
+			}
+			parts = append(parts, unique(sorted(members)))
+		}
+	} else {
+		parts[0] = append(parts[0], unique(sorted(groupClients))...)
+	}
+
+	var total int
+	for _, part := range parts {
+		total += len(part)
+	}
+	size := job.BatchSize
+	if job.BatchPercent > 0 {
+		size = (total*job.BatchPercent + 99) / 100
+	}
+
+	var batches [][]string
+	for _, part := range parts {
+		for len(part) > 0 {
+			n := len(part)
+			if size > 0 && size < n {
+				n = size
+			}
+			batches = append(batches, part[:n])
+			part = part[n:]
+		}
+	}
+	return batches
+}
+
+// runBatches runs the child jobs of a given multi-client job batch by batch. runJob sends a child job to a given client
+// and returns false if it failed. onBatch, if not nil, is called when a batch starts and the job has several batches.
+// Returns false if the job is cancelled or aborted.
+func (al *APIListener) runBatches(job *models.MultiJob, batches [][]string, runJob func(cid string) bool, onBatch func(*models.MultiJobBatch)) bool {
+	// create a channel to get the job results, needed to execute sequentially or to wait for a batch to finish
+	var doneCh chan *models.Job
+	if !job.Concurrent || len(batches) > 1 {
+		var total int
+		for _, batch := range batches {
+			total += len(batch)
+		}
+		// buffered to not block the client listener when a result is not awaited
+		doneCh = make(chan *models.Job, total)
+		al.jobsDoneChannel.Set(job.JID, doneCh)
+		defer al.jobsDoneChannel.Del(job.JID)
+	}
+
+	// max failure percent takes precedence over abort on error, failures are then checked after each batch
+	abortOnErr := job.AbortOnErr && job.MaxFailurePercent == nil
+	var processed, failed int
+	for i, batch := range batches {
+		if i > 0 && !al.pauseBetweenBatches(job) {
+			al.Debugf("Multi-client Job[id=%q] is cancelled, skip remaining clients.", job.JID)
+			return false
+		}
+		if len(batches) > 1 {
+			job.CurrentBatch = i + 1
+			if err := al.jobProvider.SaveMultiJob(job); err != nil {
+				al.Errorf("Multi-client Job[id=%q]: failed to save the rollout progress: %v", job.JID, err)
+			}
+			al.Debugf("Multi-client Job[id=%q]: starting batch %d of %d on clients %s.", job.JID, i+1, len(batches), batch)
+			if onBatch != nil {
+				onBatch(&models.MultiJobBatch{
+					MultiJobID: job.JID,
+					Batch:      i + 1,
+					Batches:    len(batches),
+					ClientIDs:  batch,
+					Processed:  processed,
+					Failed:     failed,
+				})
+			}
+		}
+
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		var sent []string
+		for _, cid := range batch {
+			if al.multiJobsCancel.IsCancelled(job.JID) {
+				al.Debugf("Multi-client Job[id=%q] is cancelled, skip remaining clients.", job.JID)
+				return false
+			}
+			if job.Concurrent {
+				wg.Add(1)
+				go func(cid string) {
+					defer wg.Done()
+					success := runJob(cid)
+					mu.Lock()
+					defer mu.Unlock()
+					if success {
+						sent = append(sent, cid)
+					} else {
+						failed++
+					}
+				}(cid)
+				continue
+			}
+
+			processed++
+			if !runJob(cid) {
+				failed++
+				if abortOnErr {
+					return false
+				}
+				continue
+			}
+
+			// in tests skip next part to avoid waiting
+			if al.insecureForTests {
+				continue
+			}
+
+			// wait until command is finished
+			resultFailed, ok := al.waitForResults(job, doneCh, []string{cid})
+			if !ok {
+				al.Debugf("Multi-client Job[id=%q] is cancelled, skip remaining clients.", job.JID)
+				return false
+			}
+			if resultFailed > 0 {
+				failed++
+				if abortOnErr {
+					return false
+				}
+			}
+		}
+
+		last := i == len(batches)-1
+		if job.Concurrent {
+			// wait until all commands of the batch are sent
+			wg.Wait()
+			processed += len(batch)
+			// the results of the last batch are not awaited, as no other batch depends on them
+			if !last {
+				resultsFailed, ok := al.waitForResults(job, doneCh, sent)
+				if !ok {
+					al.Debugf("Multi-client Job[id=%q] is cancelled, skip remaining clients.", job.JID)
+					return false
+				}
+				failed += resultsFailed
+			}
+		}
+
+		if job.MaxFailurePercent == nil || failed*100 <= processed**job.MaxFailurePercent {
+			continue
+		}
+		// there is nothing to abort after the last batch, the exceeded threshold is only recorded
+		if last {
+			job.Error = fmt.Sprintf("Rollout is finished: %d of %d jobs failed, max failure percent is %d.", failed, processed, *job.MaxFailurePercent)
+		} else {
+			job.Error = fmt.Sprintf("Rollout is aborted after batch %d of %d: %d of %d jobs failed, max failure percent is %d.", i+1, len(batches), failed, processed, *job.MaxFailurePercent)
+		}
+		al.Infof("Multi-client Job[id=%q]: %s", job.JID, job.Error)
+		if err := al.jobProvider.SaveMultiJob(job); err != nil {
+			al.Errorf("Multi-client Job[id=%q]: failed to save the rollout progress: %v", job.JID, err)
+		}
+		if !last {
+			return false
+		}
+	}
+	return true
+}
+
+// waitForResults waits until child jobs of a given multi-client job sent to given clients are finished. A job whose
+// client disconnects meanwhile is counted as failed as its result won't be received. Returns a number of jobs that are
+// not successful and false if the multi-client job is cancelled meanwhile.
+func (al *APIListener) waitForResults(job *models.MultiJob, doneCh chan *models.Job, clientIDs []string) (int, bool) {
+	pending := make(map[string]bool, len(clientIDs))
+	for _, cid := range clientIDs {
+		pending[cid] = true
+	}
+
+	ticker := time.NewTicker(multiJobResultCheckInterval)
+	defer ticker.Stop()
+	var failed int
+	for len(pending) > 0 {
+		select {
+		case jobResult := <-doneCh:
+			// a late result of a job that is already counted as failed is ignored
+			if !pending[jobResult.ClientID] {
+				continue
+			}
+			delete(pending, jobResult.ClientID)
+			if jobResult.Status != models.JobStatusSuccessful {
+				failed++
+			}
+		case <-ticker.C:
+			if al.multiJobsCancel.IsCancelled(job.JID) {
+				return failed, false
+			}
+			for cid := range pending {
+				client, err := al.clientService.GetActiveByID(cid)
+				if err != nil {
+					al.Errorf("Multi-client Job[id=%q]: failed to get a client with id=%q: %v", job.JID, cid, err)
+					continue
+				}
+				if client == nil {
+					al.Infof("Multi-client Job[id=%q]: client with id=%q disconnected before the job finished.", job.JID, cid)
+					delete(pending, cid)
+					failed++
+				}
+			}
+		}
+	}
+	return failed, true
+}
+
+// pauseBetweenBatches waits the pause between batches of a given job. Returns false if the job is cancelled meanwhile.
+func (al *APIListener) pauseBetweenBatches(job *models.MultiJob) bool {
+	deadline := time.Now().Add(time.Duration(job.BatchPauseSec) * time.Second)
+	for {
+		if al.multiJobsCancel.IsCancelled(job.JID) {
+			return false
+		}
+		left := time.Until(deadline)
+		if left <= 0 {
+			return true
+		}
+		if left > time.Second {
+			left = time.Second
+		}
+		time.Sleep(left)
+	}
+}
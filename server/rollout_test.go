@@ -0,0 +1,321 @@
+package chserver
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cloudradar-monitoring/rport/server/api/jobs"
+	"github.com/cloudradar-monitoring/rport/server/cgroups"
+	"github.com/cloudradar-monitoring/rport/server/clients"
+	"github.com/cloudradar-monitoring/rport/server/test/jb"
+	"github.com/cloudradar-monitoring/rport/share/models"
+)
+
+func TestNewBatches(t *testing.T) {
+	c1 := clients.New(t).ID("client-1").Build()
+	c2 := clients.New(t).ID("client-2").Build()
+	c3 := clients.New(t).ID("client-3").Build()
+	c4 := clients.New(t).ID("client-4").Build()
+	c5 := clients.New(t).ID("client-5").Build()
+	g1 := &cgroups.ClientGroup{ID: "group-1", Params: &cgroups.ClientParams{ClientID: &cgroups.ParamValues{"client-5", "client-3"}}}
+	g2 := &cgroups.ClientGroup{ID: "group-2", Params: &cgroups.ClientParams{ClientID: &cgroups.ParamValues{"client-2", "client-3", "client-4"}}}
+	groupClients := []*clients.Client{c5, c4, c3, c2}
+
+	testCases := []struct {
+		name string
+		job  models.MultiJob
+		want [][]string
+	}{
+		{
+			name: "single batch",
+			want: [][]string{{c1.ID, c2.ID, c3.ID, c4.ID, c5.ID}},
+		},
+		{
+			name: "batch size",
+			job:  models.MultiJob{BatchSize: 2},
+			want: [][]string{{c1.ID, c2.ID}, {c3.ID, c4.ID}, {c5.ID}},
+		},
+		{
+			name: "batch percent",
+			job:  models.MultiJob{BatchPercent: 50},
+			want: [][]string{{c1.ID, c2.ID, c3.ID}, {c4.ID, c5.ID}},
+		},
+		{
+			name: "by group",
+			job:  models.MultiJob{BatchByGroup: true},
+			want: [][]string{{c1.ID, c2.ID}, {c3.ID, c5.ID}, {c4.ID}},
+		},
+		{
+			name: "by group with batch size",
+			job:  models.MultiJob{BatchByGroup: true, BatchSize: 1},
+			want: [][]string{{c1.ID}, {c2.ID}, {c3.ID}, {c5.ID}, {c4.ID}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := newBatches(&tc.job, []string{c1.ID, c2.ID, c1.ID}, []*cgroups.ClientGroup{g1, g2}, groupClients)
+
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestValidateRolloutStrategy(t *testing.T) {
+	negative := -1
+	testCases := []struct {
+		name    string
+		req     multiClientCmdRequest
+		wantErr string
+	}{
+		{
+			name: "valid",
+			req:  multiClientCmdRequest{BatchPercent: 10, BatchPauseSec: 60, MaxFailurePercent: new(int)},
+		},
+		{
+			name:    "size and percent",
+			req:     multiClientCmdRequest{BatchSize: 2, BatchPercent: 10},
+			wantErr: "batch size and batch percent cannot be specified together",
+		},
+		{
+			name:    "invalid percent",
+			req:     multiClientCmdRequest{BatchPercent: 101},
+			wantErr: "batch percent should be between 1 and 100, actual: 101",
+		},
+		{
+			name:    "invalid max failure percent",
+			req:     multiClientCmdRequest{MaxFailurePercent: &negative},
+			wantErr: "max failure percent should be between 0 and 100, actual: -1",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gotErr := validateRolloutStrategy(&tc.req)
+
+			if tc.wantErr != "" {
+				assert.EqualError(t, gotErr, tc.wantErr)
+			} else {
+				assert.NoError(t, gotErr)
+			}
+		})
+	}
+}
+
+func TestRunBatchesAbortsOnMaxFailurePercent(t *testing.T) {
+	jp, err := jobs.NewSqliteProvider(":memory:", testLog)
+	require.NoError(t, err)
+	defer jp.Close()
+
+	al := &APIListener{
+		Server: &Server{
+			jobProvider: jp,
+			jobsDoneChannel: jobResultChanMap{
+				m: make(map[string]chan *models.Job),
+			},
+		},
+		Logger: testLog,
+	}
+
+	maxFailurePercent := 25
+	multiJob := jb.NewMulti(t).Build()
+	multiJob.Concurrent = true
+	multiJob.MaxFailurePercent = &maxFailurePercent
+	require.NoError(t, jp.SaveMultiJob(multiJob))
+	batches := [][]string{{"client-1", "client-2"}, {"client-3", "client-4"}, {"client-5"}}
+	failing := map[string]string{"client-3": models.JobStatusFailed, "client-4": models.JobStatusTimeout}
+
+	var mu sync.Mutex
+	var gotClientIDs []string
+	runJob := func(cid string) bool {
+		mu.Lock()
+		gotClientIDs = append(gotClientIDs, cid)
+		mu.Unlock()
+		status := models.JobStatusSuccessful
+		if failing[cid] != "" {
+			status = failing[cid]
+		}
+		// simulate a result sent by the client
+		al.jobsDoneChannel.Get(multiJob.JID) <- &models.Job{JobSummary: models.JobSummary{Status: status}, ClientID: cid}
+		return true
+	}
+	var gotProgress []*models.MultiJobBatch
+	onBatch := func(batch *models.MultiJobBatch) {
+		gotProgress = append(gotProgress, batch)
+	}
+
+	// when
+	ok := al.runBatches(multiJob, batches, runJob, onBatch)
+
+	// then
+	assert.False(t, ok)
+	assert.ElementsMatch(t, []string{"client-1", "client-2", "client-3", "client-4"}, gotClientIDs)
+	assert.Equal(t, []*models.MultiJobBatch{
+		{MultiJobID: multiJob.JID, Batch: 1, Batches: 3, ClientIDs: batches[0]},
+		{MultiJobID: multiJob.JID, Batch: 2, Batches: 3, ClientIDs: batches[1], Processed: 2},
+	}, gotProgress)
+
+	gotJob, err := jp.GetMultiJob(multiJob.JID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, gotJob.CurrentBatch)
+	assert.Equal(t, "Rollout is aborted after batch 2 of 3: 2 of 4 jobs failed, max failure percent is 25.", gotJob.Error)
+}
+
+func TestRunBatchesMaxFailurePercentOverridesAbortOnErr(t *testing.T) {
+	jp, err := jobs.NewSqliteProvider(":memory:", testLog)
+	require.NoError(t, err)
+	defer jp.Close()
+
+	al := &APIListener{
+		Server: &Server{
+			jobProvider: jp,
+			jobsDoneChannel: jobResultChanMap{
+				m: make(map[string]chan *models.Job),
+			},
+		},
+		Logger:           testLog,
+		insecureForTests: true,
+	}
+
+	maxFailurePercent := 50
+	multiJob := jb.NewMulti(t).Build()
+	multiJob.AbortOnErr = true
+	multiJob.MaxFailurePercent = &maxFailurePercent
+	require.NoError(t, jp.SaveMultiJob(multiJob))
+
+	var gotClientIDs []string
+	runJob := func(cid string) bool {
+		gotClientIDs = append(gotClientIDs, cid)
+		return cid != "client-1"
+	}
+
+	// when
+	ok := al.runBatches(multiJob, [][]string{{"client-1", "client-2"}, {"client-3", "client-4"}}, runJob, nil)
+
+	// then
+	assert.True(t, ok)
+	assert.Equal(t, []string{"client-1", "client-2", "client-3", "client-4"}, gotClientIDs)
+}
+
+func TestRunBatchesWaitsForLastConcurrentBatch(t *testing.T) {
+	jp, err := jobs.NewSqliteProvider(":memory:", testLog)
+	require.NoError(t, err)
+	defer jp.Close()
+
+	al := &APIListener{
+		Server: &Server{
+			jobProvider: jp,
+			jobsDoneChannel: jobResultChanMap{
+				m: make(map[string]chan *models.Job),
+			},
+		},
+		Logger: testLog,
+	}
+
+	maxFailurePercent := 0
+	multiJob := jb.NewMulti(t).Build()
+	multiJob.Concurrent = true
+	multiJob.MaxFailurePercent = &maxFailurePercent
+	require.NoError(t, jp.SaveMultiJob(multiJob))
+
+	var mu sync.Mutex
+	var gotClientIDs []string
+	runJob := func(cid string) bool {
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		gotClientIDs = append(gotClientIDs, cid)
+		return cid != "client-2"
+	}
+
+	// when
+	ok := al.runBatches(multiJob, [][]string{{"client-1", "client-2"}}, runJob, nil)
+
+	// then
+	assert.True(t, ok)
+	mu.Lock()
+	assert.ElementsMatch(t, []string{"client-1", "client-2"}, gotClientIDs, "all jobs of the last batch are sent before it returns")
+	mu.Unlock()
+
+	gotJob, err := jp.GetMultiJob(multiJob.JID)
+	require.NoError(t, err)
+	assert.Equal(t, "Rollout is finished: 1 of 2 jobs failed, max failure percent is 0.", gotJob.Error)
+}
+
+func TestRunBatchesStopsWaitingForResults(t *testing.T) {
+	defer func(interval time.Duration) {
+		multiJobResultCheckInterval = interval
+	}(multiJobResultCheckInterval)
+	multiJobResultCheckInterval = 10 * time.Millisecond
+
+	c1 := clients.New(t).ID("client-1").Build()
+	c2 := clients.New(t).ID("client-2").Build()
+	maxFailurePercent := 0
+
+	t.Run("client disconnected", func(t *testing.T) {
+		jp, err := jobs.NewSqliteProvider(":memory:", testLog)
+		require.NoError(t, err)
+		defer jp.Close()
+		repo := clients.NewClientRepository([]*clients.Client{c1, c2}, &hour)
+		al := &APIListener{
+			Server: &Server{
+				jobProvider:   jp,
+				clientService: NewClientService(nil, repo),
+				jobsDoneChannel: jobResultChanMap{
+					m: make(map[string]chan *models.Job),
+				},
+			},
+			Logger: testLog,
+		}
+		multiJob := jb.NewMulti(t).Build()
+		multiJob.MaxFailurePercent = &maxFailurePercent
+		require.NoError(t, jp.SaveMultiJob(multiJob))
+		runJob := func(cid string) bool {
+			// the client is gone without sending the result
+			require.NoError(t, repo.Delete(c1))
+			return true
+		}
+
+		// when
+		ok := al.runBatches(multiJob, [][]string{{c1.ID}, {c2.ID}}, runJob, nil)
+
+		// then
+		assert.False(t, ok)
+		gotJob, err := jp.GetMultiJob(multiJob.JID)
+		require.NoError(t, err)
+		assert.Equal(t, "Rollout is aborted after batch 1 of 2: 1 of 1 jobs failed, max failure percent is 0.", gotJob.Error)
+	})
+
+	t.Run("multi-client job cancelled", func(t *testing.T) {
+		al := &APIListener{
+			Server: &Server{
+				clientService: NewClientService(nil, clients.NewClientRepository([]*clients.Client{c1, c2}, &hour)),
+				jobsDoneChannel: jobResultChanMap{
+					m: make(map[string]chan *models.Job),
+				},
+			},
+			Logger: testLog,
+		}
+		multiJob := jb.NewMulti(t).Build()
+		al.multiJobsCancel.Start(multiJob.JID)
+		defer al.multiJobsCancel.Finish(multiJob.JID)
+		var gotClientIDs []string
+		runJob := func(cid string) bool {
+			gotClientIDs = append(gotClientIDs, cid)
+			// the command keeps running
+			al.multiJobsCancel.Cancel(multiJob.JID)
+			return true
+		}
+
+		// when
+		ok := al.runBatches(multiJob, [][]string{{c1.ID, c2.ID}}, runJob, nil)
+
+		// then
+		assert.False(t, ok)
+		assert.Equal(t, []string{c1.ID}, gotClientIDs)
+	})
+}
@@ -0,0 +1,143 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cron is a parsed cron expression in the standard 5 fields format: minute, hour, day of month, month, day of week.
+// Each field accepts '*', single values, ranges 'a-b', steps '*/n' or 'a-b/n' and comma separated lists of them.
+// Day of week is 0-7, where both 0 and 7 mean Sunday.
+type Cron struct {
+	minute, hour, dom, month, dow uint64
+	// domStar and dowStar are needed to apply the standard rule: if both day fields are restricted,
+	// a day matches if it matches at least one of them.
+	domStar, dowStar bool
+}
+
+type cronField struct {
+	name     string
+	min, max int
+}
+
+var cronFields = []cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12},
+	{name: "day of week", min: 0, max: 7},
+}
+
+// ParseCron parses a given cron expression.
+func ParseCron(expr string) (*Cron, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("invalid cron expression %q: expected %d fields, actual: %d", expr, len(cronFields), len(fields))
+	}
+
+	bits := make([]uint64, len(fields))
+	for i, field := range fields {
+		var err error
+		bits[i], err = parseCronField(field, cronFields[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron expression %q: %v", expr, err)
+		}
+	}
+
+	// 7 is an alias of Sunday
+	dow := bits[4]
+	if dow&(1<<7) != 0 {
+		dow |= 1
+	}
+
+	return &Cron{
+		minute:  bits[0],
+		hour:    bits[1],
+		dom:     bits[2],
+		month:   bits[3],
+		dow:     dow,
+		domStar: strings.HasPrefix(fields[2], "*"),
+		dowStar: strings.HasPrefix(fields[4], "*"),
+	}, nil
+}
+
+func parseCronField(field string, f cronField) (uint64, error) {
+	var res uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			var err error
+			step, err = strconv.Atoi(part[i+1:])
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step in %s field %q", f.name, part)
+			}
+			rangePart = part[:i]
+		}
+
+		from, to := f.min, f.max
+		if rangePart != "*" {
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			from, err = strconv.Atoi(bounds[0])
+			if err != nil {
+				return 0, fmt.Errorf("invalid value in %s field %q", f.name, part)
+			}
+			to = from
+			if len(bounds) == 2 {
+				to, err = strconv.Atoi(bounds[1])
+				if err != nil {
+					return 0, fmt.Errorf("invalid value in %s field %q", f.name, part)
+				}
+			} else if step > 1 {
+				// 'a/n' means from a to the max value with a given step
+				to = f.max
+			}
+		}
+		if from < f.min || to > f.max || from > to {
+			return 0, fmt.Errorf("%s field %q is out of range %d-%d", f.name, part, f.min, f.max)
+		}
+
+		for v := from; v <= to; v += step {
+			res |= 1 << uint(v)
+		}
+	}
+	return res, nil
+}
+
+// Next returns the earliest time after a given time that matches the cron expression. Seconds are truncated.
+// Returns zero time if there is no matching time within the next 5 years, e.g. for the 30th of February.
+func (c *Cron) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (c *Cron) matchesDay(t time.Time) bool {
+	domMatch := c.dom&(1<<uint(t.Day())) != 0
+	dowMatch := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domStar || c.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
@@ -0,0 +1,114 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCronNext(t *testing.T) {
+	// Friday
+	now := time.Date(2021, 1, 15, 10, 30, 45, 0, time.UTC)
+
+	testCases := []struct {
+		expr     string
+		wantNext time.Time
+	}{
+		{
+			expr:     "* * * * *",
+			wantNext: time.Date(2021, 1, 15, 10, 31, 0, 0, time.UTC),
+		},
+		{
+			expr:     "*/15 * * * *",
+			wantNext: time.Date(2021, 1, 15, 10, 45, 0, 0, time.UTC),
+		},
+		{
+			expr:     "0 3 * * *",
+			wantNext: time.Date(2021, 1, 16, 3, 0, 0, 0, time.UTC),
+		},
+		{
+			expr:     "30 10 * * *",
+			wantNext: time.Date(2021, 1, 16, 10, 30, 0, 0, time.UTC),
+		},
+		{
+			expr:     "0 9-17/4 * * 1-5",
+			wantNext: time.Date(2021, 1, 15, 13, 0, 0, 0, time.UTC),
+		},
+		{
+			expr:     "0 0 * * 7",
+			wantNext: time.Date(2021, 1, 17, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			expr:     "0 0 1,15 * *",
+			wantNext: time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			// day of month or day of week
+			expr:     "0 0 20 * 1",
+			wantNext: time.Date(2021, 1, 18, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			expr:     "0 0 29 2 *",
+			wantNext: time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			expr:     "0 0 30 2 *",
+			wantNext: time.Time{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expr, func(t *testing.T) {
+			c, err := ParseCron(tc.expr)
+			require.NoError(t, err)
+
+			// when
+			gotNext := c.Next(now)
+
+			// then
+			assert.Equal(t, tc.wantNext, gotNext)
+		})
+	}
+}
+
+func TestParseCronErrors(t *testing.T) {
+	testCases := []struct {
+		expr    string
+		wantErr string
+	}{
+		{
+			expr:    "* * * *",
+			wantErr: `invalid cron expression "* * * *": expected 5 fields, actual: 4`,
+		},
+		{
+			expr:    "60 * * * *",
+			wantErr: `invalid cron expression "60 * * * *": minute field "60" is out of range 0-59`,
+		},
+		{
+			expr:    "* * 0 * *",
+			wantErr: `invalid cron expression "* * 0 * *": day of month field "0" is out of range 1-31`,
+		},
+		{
+			expr:    "*/0 * * * *",
+			wantErr: `invalid cron expression "*/0 * * * *": invalid step in minute field "*/0"`,
+		},
+		{
+			expr:    "* mon * * *",
+			wantErr: `invalid cron expression "* mon * * *": invalid value in hour field "mon"`,
+		},
+		{
+			expr:    "* * * * 5-1",
+			wantErr: `invalid cron expression "* * * * 5-1": day of week field "5-1" is out of range 0-7`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expr, func(t *testing.T) {
+			_, gotErr := ParseCron(tc.expr)
+
+			assert.EqualError(t, gotErr, tc.wantErr)
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package schedules
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/cloudradar-monitoring/rport/server/scheduler"
+)
+
+// Schedule is a user-defined schedule to run a multi-client command periodically.
+type Schedule struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Schedule is a cron expression in the standard 5 fields format.
+	Schedule string `json:"schedule"`
+	Enabled  bool   `json:"enabled"`
+	CommandSpec
+	// CreatedBy is a user who created or last updated the schedule. Scheduled commands run on behalf of this user.
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	// NextRunAt is not persisted, it's calculated on demand.
+	NextRunAt *time.Time `json:"next_run_at"`
+}
+
+// CommandSpec is a command to run on each scheduled run. Fields are the same as in a request to execute
+// a multi-client command, so they must be kept in sync.
+type CommandSpec struct {
+	ClientIDs           []string          `json:"client_ids"`
+	GroupIDs            []string          `json:"group_ids"`
+	Command             string            `json:"command"`
+	Shell               string            `json:"shell"`
+	Interpreter         string            `json:"interpreter"`
+	Script              string            `json:"script"`
+	ScriptID            string            `json:"script_id"`
+	ScriptVersion       int               `json:"script_version"`
+	Params              map[string]string `json:"params"`
+	Cwd                 string            `json:"cwd"`
+	Env                 map[string]string `json:"env"`
+	RunAsUser           string            `json:"run_as_user"`
+	TimeoutSec          int               `json:"timeout_sec"`
+	TimeoutPolicy       string            `json:"timeout_policy"`
+	TimeoutGraceSec     int               `json:"timeout_grace_sec"`
+	SuccessExitCodes    []int             `json:"success_exit_codes"`
+	ExecuteConcurrently bool              `json:"execute_concurrently"`
+	AbortOnError        *bool             `json:"abort_on_error"`
+	BatchSize           int               `json:"batch_size"`
+	BatchPercent        int               `json:"batch_percent"`
+	BatchByGroup        bool              `json:"batch_by_group"`
+	BatchPauseSec       int               `json:"batch_pause_sec"`
+	MaxFailurePercent   *int              `json:"max_failure_percent"`
+}
+
+// Validate checks the schedule fields except the command which is validated the same way as other commands.
+func (s *Schedule) Validate() error {
+	if strings.TrimSpace(s.Name) == "" {
+		return errors.New("name cannot be empty")
+	}
+	if _, err := scheduler.ParseCron(s.Schedule); err != nil {
+		return err
+	}
+	if len(s.ClientIDs) == 0 && len(s.GroupIDs) == 0 {
+		return errors.New("at least one of client_ids or group_ids should be specified")
+	}
+	return nil
+}
+
+// Next returns the next time after a given time when the schedule should run. Returns nil if the schedule
+// is disabled or never runs.
+func (s *Schedule) Next(after time.Time) *time.Time {
+	if !s.Enabled {
+		return nil
+	}
+	cron, err := scheduler.ParseCron(s.Schedule)
+	if err != nil {
+		return nil
+	}
+	next := cron.Next(after)
+	if next.IsZero() {
+		return nil
+	}
+	return &next
+}
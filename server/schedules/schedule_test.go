@@ -0,0 +1,77 @@
+package schedules
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduleValidate(t *testing.T) {
+	testCases := []struct {
+		name     string
+		schedule Schedule
+		wantErr  string
+	}{
+		{
+			name: "valid",
+			schedule: Schedule{
+				Name:        "Uptime",
+				Schedule:    "*/5 * * * *",
+				CommandSpec: CommandSpec{GroupIDs: []string{"group-1"}},
+			},
+		},
+		{
+			name: "empty name",
+			schedule: Schedule{
+				Name:        " ",
+				Schedule:    "*/5 * * * *",
+				CommandSpec: CommandSpec{GroupIDs: []string{"group-1"}},
+			},
+			wantErr: "name cannot be empty",
+		},
+		{
+			name: "invalid cron",
+			schedule: Schedule{
+				Name:        "Uptime",
+				Schedule:    "* * *",
+				CommandSpec: CommandSpec{GroupIDs: []string{"group-1"}},
+			},
+			wantErr: `invalid cron expression "* * *": expected 5 fields, actual: 3`,
+		},
+		{
+			name: "no clients",
+			schedule: Schedule{
+				Name:     "Uptime",
+				Schedule: "*/5 * * * *",
+			},
+			wantErr: "at least one of client_ids or group_ids should be specified",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gotErr := tc.schedule.Validate()
+
+			if tc.wantErr != "" {
+				assert.EqualError(t, gotErr, tc.wantErr)
+			} else {
+				assert.NoError(t, gotErr)
+			}
+		})
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	now := time.Date(2021, 1, 15, 10, 30, 45, 0, time.UTC)
+	s := &Schedule{Schedule: "0 3 * * *", Enabled: true}
+
+	want := time.Date(2021, 1, 16, 3, 0, 0, 0, time.UTC)
+	assert.Equal(t, &want, s.Next(now))
+
+	s.Enabled = false
+	assert.Nil(t, s.Next(now))
+
+	s = &Schedule{Schedule: "0 0 30 2 *", Enabled: true}
+	assert.Nil(t, s.Next(now))
+}
@@ -0,0 +1,151 @@
+package schedules
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/cloudradar-monitoring/rport/db/migration/schedules"
+	"github.com/cloudradar-monitoring/rport/db/sqlite"
+)
+
+type Provider interface {
+	GetAll(ctx context.Context) ([]*Schedule, error)
+	// Get returns a schedule by a given id. Returns nil if not found.
+	Get(ctx context.Context, id string) (*Schedule, error)
+	// Save creates a new schedule or replaces an existing one.
+	Save(ctx context.Context, schedule *Schedule) error
+	Delete(ctx context.Context, id string) error
+	Close() error
+}
+
+type SqliteProvider struct {
+	db *sqlx.DB
+}
+
+func NewSqliteProvider(dbPath string) (*SqliteProvider, error) {
+	db, err := sqlite.New(dbPath, schedules.AssetNames(), schedules.Asset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create schedules DB instance: %v", err)
+	}
+	return &SqliteProvider{db: db}, nil
+}
+
+func (p *SqliteProvider) GetAll(ctx context.Context) ([]*Schedule, error) {
+	var res []*scheduleSqlite
+	err := p.db.SelectContext(ctx, &res, "SELECT * FROM schedules ORDER BY DATETIME(created_at) DESC, id")
+	if err != nil {
+		return nil, err
+	}
+	return convertSchedules(res), nil
+}
+
+func (p *SqliteProvider) Get(ctx context.Context, id string) (*Schedule, error) {
+	res := &scheduleSqlite{}
+	err := p.db.GetContext(ctx, res, "SELECT * FROM schedules WHERE id = ?", id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return res.convert(), nil
+}
+
+func (p *SqliteProvider) Save(ctx context.Context, schedule *Schedule) error {
+	_, err := p.db.NamedExecContext(
+		ctx,
+		"INSERT OR REPLACE INTO schedules (id, created_at, created_by, details) VALUES (:id, :created_at, :created_by, :details)",
+		convertToSqlite(schedule),
+	)
+	return err
+}
+
+func (p *SqliteProvider) Delete(ctx context.Context, id string) error {
+	_, err := p.db.ExecContext(ctx, "DELETE FROM schedules WHERE id = ?", id)
+	return err
+}
+
+func (p *SqliteProvider) Close() error {
+	return p.db.Close()
+}
+
+type scheduleSqlite struct {
+	ID        string           `db:"id"`
+	CreatedAt time.Time        `db:"created_at"`
+	CreatedBy string           `db:"created_by"`
+	Details   *scheduleDetails `db:"details"`
+}
+
+type scheduleDetails struct {
+	Name     string `json:"name"`
+	Schedule string `json:"schedule"`
+	Enabled  bool   `json:"enabled"`
+	CommandSpec
+}
+
+func (d *scheduleDetails) Scan(value interface{}) error {
+	if d == nil {
+		return errors.New("'details' cannot be nil")
+	}
+	valueStr, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("expected to have string, got %T", value)
+	}
+	err := json.Unmarshal([]byte(valueStr), d)
+	if err != nil {
+		return fmt.Errorf("failed to decode 'details' field: %v", err)
+	}
+	return nil
+}
+
+func (d *scheduleDetails) Value() (driver.Value, error) {
+	if d == nil {
+		return nil, errors.New("'details' cannot be nil")
+	}
+	b, err := json.Marshal(d)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode 'details' field: %v", err)
+	}
+	return string(b), nil
+}
+
+func (s *scheduleSqlite) convert() *Schedule {
+	return &Schedule{
+		ID:          s.ID,
+		Name:        s.Details.Name,
+		Schedule:    s.Details.Schedule,
+		Enabled:     s.Details.Enabled,
+		CommandSpec: s.Details.CommandSpec,
+		CreatedBy:   s.CreatedBy,
+		CreatedAt:   s.CreatedAt,
+	}
+}
+
+func convertSchedules(list []*scheduleSqlite) []*Schedule {
+	res := make([]*Schedule, 0, len(list))
+	for _, cur := range list {
+		res = append(res, cur.convert())
+	}
+	return res
+}
+
+func convertToSqlite(schedule *Schedule) *scheduleSqlite {
+	return &scheduleSqlite{
+		ID:        schedule.ID,
+		CreatedAt: schedule.CreatedAt,
+		CreatedBy: schedule.CreatedBy,
+		Details: &scheduleDetails{
+			Name:        schedule.Name,
+			Schedule:    schedule.Schedule,
+			Enabled:     schedule.Enabled,
+			CommandSpec: schedule.CommandSpec,
+		},
+	}
+}
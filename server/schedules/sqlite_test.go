@@ -0,0 +1,72 @@
+package schedules
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSqliteProvider(t *testing.T) {
+	ctx := context.Background()
+	p, err := NewSqliteProvider(":memory:")
+	require.NoError(t, err)
+	defer p.Close()
+
+	abortOnErr := false
+	t1 := time.Date(2021, 1, 1, 10, 0, 0, 0, time.UTC)
+	s1 := &Schedule{
+		ID:       "schedule-1",
+		Name:     "Nightly cleanup",
+		Schedule: "0 3 * * *",
+		Enabled:  true,
+		CommandSpec: CommandSpec{
+			GroupIDs:     []string{"group-1"},
+			Command:      "rm -rf /tmp/*",
+			Env:          map[string]string{"LANG": "C"},
+			TimeoutSec:   60,
+			AbortOnError: &abortOnErr,
+		},
+		CreatedBy: "admin",
+		CreatedAt: t1,
+	}
+	s2 := &Schedule{
+		ID:       "schedule-2",
+		Name:     "Uptime",
+		Schedule: "*/5 * * * *",
+		CommandSpec: CommandSpec{
+			ClientIDs: []string{"client-1", "client-2"},
+			Command:   "uptime",
+		},
+		CreatedBy: "admin",
+		CreatedAt: t1.Add(time.Minute),
+	}
+	require.NoError(t, p.Save(ctx, s1))
+	require.NoError(t, p.Save(ctx, s2))
+
+	got, err := p.Get(ctx, s1.ID)
+	require.NoError(t, err)
+	assert.Equal(t, s1, got)
+
+	gotUnknown, err := p.Get(ctx, "unknown")
+	require.NoError(t, err)
+	assert.Nil(t, gotUnknown)
+
+	// update
+	s1.Enabled = false
+	require.NoError(t, p.Save(ctx, s1))
+
+	gotAll, err := p.GetAll(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []*Schedule{s2, s1}, gotAll)
+
+	// delete
+	require.NoError(t, p.Delete(ctx, s2.ID))
+
+	gotAll, err = p.GetAll(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []*Schedule{s1}, gotAll)
+}
@@ -17,11 +17,14 @@ import (
 	_ "github.com/mattn/go-sqlite3"
 
 	"github.com/cloudradar-monitoring/rport/server/api/jobs"
+	"github.com/cloudradar-monitoring/rport/server/auditlog"
 	"github.com/cloudradar-monitoring/rport/server/cgroups"
 	"github.com/cloudradar-monitoring/rport/server/clients"
 	"github.com/cloudradar-monitoring/rport/server/clientsauth"
+	"github.com/cloudradar-monitoring/rport/server/library"
 	"github.com/cloudradar-monitoring/rport/server/ports"
 	"github.com/cloudradar-monitoring/rport/server/scheduler"
+	"github.com/cloudradar-monitoring/rport/server/schedules"
 	chshare "github.com/cloudradar-monitoring/rport/share"
 	"github.com/cloudradar-monitoring/rport/share/files"
 	"github.com/cloudradar-monitoring/rport/share/models"
@@ -39,9 +42,14 @@ type Server struct {
 	clientAuthProvider  clientsauth.Provider
 	jobProvider         JobProvider
 	clientGroupProvider cgroups.ClientGroupProvider
+	libraryProvider     library.Provider
+	scheduleProvider    schedules.Provider
+	auditLog            *auditlog.AuditLog
 	db                  *sqlx.DB
 	uiJobWebSockets     ws.WebSocketCache // used to push job result to UI
-	jobsDoneChannel     jobResultChanMap  // used for sequential command execution to know when command is finished
+	jobsDoneChannel     jobResultChanMap  // used for sequential and batched command execution to know when command is finished
+	jobOutputs          jobOutputBroker   // used to stream output of running jobs
+	multiJobsCancel     multiJobCancelMap // used to stop starting new jobs of cancelled multi-client jobs
 }
 
 // NewServer creates and returns a new rport server
@@ -90,6 +98,22 @@ func NewServer(config *Config, filesAPI files.FileAPI) (*Server, error) {
 		return nil, err
 	}
 
+	s.libraryProvider, err = library.NewSqliteProvider(path.Join(config.Server.DataDir, "library.db"))
+	if err != nil {
+		return nil, err
+	}
+
+	s.scheduleProvider, err = schedules.NewSqliteProvider(path.Join(config.Server.DataDir, "schedules.db"))
+	if err != nil {
+		return nil, err
+	}
+
+	auditLogProvider, err := auditlog.NewSqliteProvider(path.Join(config.Server.DataDir, "auditlog.db"))
+	if err != nil {
+		return nil, err
+	}
+	s.auditLog = auditlog.New(s.Logger, auditLogProvider, config.TrustedProxies())
+
 	s.clientProvider, err = clients.NewSqliteProvider(
 		path.Join(config.Server.DataDir, "clients.db"),
 		config.Server.KeepLostClients,
@@ -134,6 +158,14 @@ func NewServer(config *Config, filesAPI files.FileAPI) (*Server, error) {
 	if err != nil {
 		return nil, err
 	}
+	// send jobs queued for disconnected clients when they connect
+	s.clientService.onClientStarted = s.apiListener.runQueuedJobs
+	if config.Server.tunnelProxyTLSConfig != nil {
+		s.clientService.tunnelProxyConfig = &clients.TunnelProxyConfig{
+			TLSConfig:    config.Server.tunnelProxyTLSConfig,
+			Authenticate: s.apiListener.authenticateTunnelProxyRequest,
+		}
+	}
 
 	return s, nil
 }
@@ -195,6 +227,21 @@ func (s *Server) Run() error {
 	go scheduler.Run(ctx, s.Logger, clients.NewSaveTask(s.Logger, s.clientListener.clientService.repo, s.clientProvider), s.config.Server.SaveClients)
 	s.Infof("Task to save clients to disk will run with interval %v", s.config.Server.SaveClients)
 
+	go scheduler.Run(ctx, s.Logger, clients.NewTunnelsAutoCloseTask(s.Logger, s.clientListener.clientService.repo), clients.TunnelsAutoCloseInterval)
+	s.Infof("Task to close expired tunnels will run with interval %v", clients.TunnelsAutoCloseInterval)
+
+	go scheduler.Run(ctx, s.Logger, newSchedulesTask(s.apiListener), schedulesCheckInterval)
+	s.Infof("Task to run scheduled commands will run with interval %v", schedulesCheckInterval)
+
+	go scheduler.Run(ctx, s.Logger, newExpireQueuedJobsTask(s.Logger, s.jobProvider), queuedJobsExpireInterval)
+	s.Infof("Task to expire queued jobs will run with interval %v", queuedJobsExpireInterval)
+
+	if s.config.Server.KeepJobs > 0 || s.config.Server.MaxJobsPerClient > 0 || s.config.Server.VacuumJobs > 0 {
+		cleanupJobsTask := newJobsCleanupTask(s.Logger, s.jobProvider, s.config.Server.KeepJobs, s.config.Server.MaxJobsPerClient, s.config.Server.VacuumJobs)
+		go scheduler.Run(ctx, s.Logger, cleanupJobsTask, s.config.Server.CleanupJobs)
+		s.Infof("Task to cleanup jobs will run with interval %v", s.config.Server.CleanupJobs)
+	}
+
 	return s.Wait()
 }
 
@@ -228,6 +275,9 @@ func (s *Server) Close() error {
 	wg.Go(s.clientProvider.Close)
 	wg.Go(s.jobProvider.Close)
 	wg.Go(s.clientGroupProvider.Close)
+	wg.Go(s.libraryProvider.Close)
+	wg.Go(s.scheduleProvider.Close)
+	wg.Go(s.auditLog.Close)
 	wg.Go(s.uiJobWebSockets.CloseConnections)
 	return wg.Wait()
 }
@@ -255,3 +305,42 @@ func (m *jobResultChanMap) Get(jobID string) chan *models.Job {
 	defer m.mu.RUnlock()
 	return m.m[jobID]
 }
+
+// multiJobCancelMap is thread safe. It keeps multi-client jobs that are in progress and whether they are cancelled.
+// A zero value is ready to use.
+type multiJobCancelMap struct {
+	m  map[string]bool
+	mu sync.RWMutex
+}
+
+func (m *multiJobCancelMap) Start(jobID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.m == nil {
+		m.m = make(map[string]bool)
+	}
+	m.m[jobID] = false
+}
+
+func (m *multiJobCancelMap) Finish(jobID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.m, jobID)
+}
+
+// Cancel marks a given job as cancelled. Returns false if the job is not in progress.
+func (m *multiJobCancelMap) Cancel(jobID string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.m[jobID]; !ok {
+		return false
+	}
+	m.m[jobID] = true
+	return true
+}
+
+func (m *multiJobCancelMap) IsCancelled(jobID string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.m[jobID]
+}
@@ -26,6 +26,7 @@ type JobBuilder struct {
 	status     string
 	startedAt  time.Time
 	finishedAt *time.Time
+	expiresAt  *time.Time
 	result     *models.JobResult
 }
 
@@ -73,6 +74,11 @@ func (b JobBuilder) FinishedAt(finishedAt time.Time) JobBuilder {
 	return b
 }
 
+func (b JobBuilder) ExpiresAt(expiresAt time.Time) JobBuilder {
+	b.expiresAt = &expiresAt
+	return b
+}
+
 func (b JobBuilder) Result(result *models.JobResult) JobBuilder {
 	b.result = result
 	return b
@@ -83,6 +89,7 @@ func (b JobBuilder) Build() *models.Job {
 		b.jid = generateRandomJID()
 	}
 	pid := 1245
+	exitCode := 0
 	// hardcoded values are used because currently was no need of other data, extend with more available options if needed
 	return &models.Job{
 		JobSummary: models.JobSummary{
@@ -96,8 +103,10 @@ func (b JobBuilder) Build() *models.Job {
 		StartedAt:  b.startedAt,
 		CreatedBy:  "test-user",
 		TimeoutSec: 60,
+		ExitCode:   &exitCode,
 		Result:     b.result,
 		MultiJobID: &b.multiJobID,
+		ExpiresAt:  b.expiresAt,
 	}
 }
 
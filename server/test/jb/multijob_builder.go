@@ -16,6 +16,7 @@ type MultiJobBuilder struct {
 	concurrent bool
 	abortOnErr bool
 	withJobs   bool
+	scheduleID *string
 }
 
 // NewMulti returns a builder to generate a multi-client job that can be used in tests.
@@ -56,6 +57,11 @@ func (b MultiJobBuilder) StartedAt(startedAt time.Time) MultiJobBuilder {
 	return b
 }
 
+func (b MultiJobBuilder) ScheduleID(scheduleID string) MultiJobBuilder {
+	b.scheduleID = &scheduleID
+	return b
+}
+
 func (b MultiJobBuilder) Build() *models.MultiJob {
 	if b.jid == "" {
 		b.jid = generateRandomJID()
@@ -74,9 +80,10 @@ func (b MultiJobBuilder) Build() *models.MultiJob {
 	}
 	return &models.MultiJob{
 		MultiJobSummary: models.MultiJobSummary{
-			JID:       b.jid,
-			StartedAt: b.startedAt,
-			CreatedBy: "test-user",
+			JID:        b.jid,
+			StartedAt:  b.startedAt,
+			CreatedBy:  "test-user",
+			ScheduleID: b.scheduleID,
 		},
 		ClientIDs:  b.clientIDs,
 		Command:    "/bin/date;foo;whoami",
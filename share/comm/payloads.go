@@ -10,10 +10,13 @@ const (
 	// request types sent by server to clients
 	RequestTypeCheckPort = "check_port"
 	RequestTypeRunCmd    = "run_cmd"
+	RequestTypeCancelCmd = "cancel_cmd"
 
 	// request types sent by clients to server
-	RequestTypePing      = "ping"
-	RequestTypeCmdResult = "cmd_result"
+	RequestTypePing       = "ping"
+	RequestTypeCmdResult  = "cmd_result"
+	RequestTypeCmdOutput  = "cmd_output"
+	RequestTypeCmdStarted = "cmd_started"
 )
 
 type CheckPortRequest struct {
@@ -37,4 +40,15 @@ type CheckPortResponse struct {
 type RunCmdResponse struct {
 	Pid       int
 	StartedAt time.Time
+	// Queued is true if the command is not started yet because the max number of concurrent commands is reached.
+	// The client sends the started job in a separate request when it's started.
+	Queued bool
+}
+
+type CancelCmdRequest struct {
+	JID string
+}
+
+type CancelCmdResponse struct {
+	Pid int
 }
@@ -8,22 +8,47 @@ import (
 const (
 	JobStatusSuccessful = "successful"
 	JobStatusRunning    = "running"
+	JobStatusQueued     = "queued"
+	JobStatusSending    = "sending" // a job queued on the server is being sent to its client
 	JobStatusFailed     = "failed"
 	JobStatusUnknown    = "unknown"
+	JobStatusCancelled  = "cancelled"
+	JobStatusTimeout    = "timeout"
+	JobStatusExpired    = "expired"
+)
+
+const (
+	// JobTimeoutPolicyStopObserving stops observing a command on timeout but leaves it running. Is used by default.
+	JobTimeoutPolicyStopObserving = "stop_observing"
+	// JobTimeoutPolicyKill terminates a command on timeout and kills it if it's still running after a grace period.
+	JobTimeoutPolicyKill = "kill"
 )
 
 type Job struct {
 	JobSummary
-	ClientID   string     `json:"client_id"`
-	Command    string     `json:"command"`
-	Shell      string     `json:"shell"`
-	PID        *int       `json:"pid"`
-	StartedAt  time.Time  `json:"started_at"`
-	CreatedBy  string     `json:"created_by"`
-	TimeoutSec int        `json:"timeout_sec"`
-	MultiJobID *string    `json:"multi_job_id"`
-	Error      string     `json:"error"`
-	Result     *JobResult `json:"result"`
+	ClientID         string            `json:"client_id"`
+	Command          string            `json:"command"`
+	Shell            string            `json:"shell"`
+	Interpreter      string            `json:"interpreter"`
+	Script           string            `json:"script"`
+	ScriptHash       string            `json:"script_hash"`
+	ScriptID         string            `json:"script_id"`
+	ScriptVersion    int               `json:"script_version"`
+	Cwd              string            `json:"cwd"`
+	Env              map[string]string `json:"env"`
+	RunAsUser        string            `json:"run_as_user"`
+	PID              *int              `json:"pid"`
+	StartedAt        time.Time         `json:"started_at"`
+	CreatedBy        string            `json:"created_by"`
+	TimeoutSec       int               `json:"timeout_sec"`
+	TimeoutPolicy    string            `json:"timeout_policy"`
+	TimeoutGraceSec  int               `json:"timeout_grace_sec"`
+	SuccessExitCodes []int             `json:"success_exit_codes"`
+	MultiJobID       *string           `json:"multi_job_id"`
+	ExpiresAt        *time.Time        `json:"expires_at"` // is set while the job waits on the server for a disconnected client to connect
+	Error            string            `json:"error"`
+	ExitCode         *int              `json:"exit_code"`
+	Result           *JobResult        `json:"result"`
 }
 
 // JobSummary short info about a job.
@@ -38,22 +63,64 @@ type JobResult struct {
 	StdErr string `json:"stderr"`
 }
 
+// JobOutput is a chunk of output of a running job.
+type JobOutput struct {
+	JID        string  `json:"jid"`
+	MultiJobID *string `json:"multi_job_id"`
+	ClientID   string  `json:"client_id"`
+	StdOut     string  `json:"stdout"`
+	StdErr     string  `json:"stderr"`
+}
+
 type MultiJob struct {
 	MultiJobSummary
+	ClientIDs        []string          `json:"client_ids"`
+	GroupIDs         []string          `json:"group_ids"`
+	Command          string            `json:"command"`
+	Shell            string            `json:"shell"`
+	Interpreter      string            `json:"interpreter"`
+	Script           string            `json:"script"`
+	ScriptHash       string            `json:"script_hash"`
+	ScriptID         string            `json:"script_id"`
+	ScriptVersion    int               `json:"script_version"`
+	Cwd              string            `json:"cwd"`
+	Env              map[string]string `json:"env"`
+	RunAsUser        string            `json:"run_as_user"`
+	TimeoutSec       int               `json:"timeout_sec"`
+	TimeoutPolicy    string            `json:"timeout_policy"`
+	TimeoutGraceSec  int               `json:"timeout_grace_sec"`
+	SuccessExitCodes []int             `json:"success_exit_codes"`
+	Concurrent       bool              `json:"concurrent"`
+	AbortOnErr       bool              `json:"abort_on_err"`
+	// rollout strategy
+	BatchSize         int  `json:"batch_size"`
+	BatchPercent      int  `json:"batch_percent"`
+	BatchByGroup      bool `json:"batch_by_group"`
+	BatchPauseSec     int  `json:"batch_pause_sec"`
+	MaxFailurePercent *int `json:"max_failure_percent"`
+	// rollout progress
+	Batches      int    `json:"batches"`
+	CurrentBatch int    `json:"current_batch"`
+	Error        string `json:"error"` // is set if the rollout was aborted
+	Jobs         []*Job `json:"jobs"`
+}
+
+// MultiJobBatch reports a progress of a multi-client job that is executed in batches.
+type MultiJobBatch struct {
+	MultiJobID string   `json:"multi_job_id"`
+	Batch      int      `json:"batch"`
+	Batches    int      `json:"batches"`
 	ClientIDs  []string `json:"client_ids"`
-	GroupIDs   []string `json:"group_ids"`
-	Command    string   `json:"command"`
-	Shell      string   `json:"shell"`
-	TimeoutSec int      `json:"timeout_sec"`
-	Concurrent bool     `json:"concurrent"`
-	AbortOnErr bool     `json:"abort_on_err"`
-	Jobs       []*Job   `json:"jobs"`
+	Processed  int      `json:"processed"`
+	Failed     int      `json:"failed"`
 }
 
 type MultiJobSummary struct {
 	JID       string    `json:"jid"`
 	StartedAt time.Time `json:"started_at"`
 	CreatedBy string    `json:"created_by"`
+	// ScheduleID is set if the job was started by a schedule.
+	ScheduleID *string `json:"schedule_id"`
 }
 
 type MultiJobResult struct {
@@ -62,6 +129,19 @@ type MultiJobResult struct {
 	Result *JobResult `json:"result"`
 }
 
+// IsSuccessExitCode returns true if a given exit code of a finished job counts as success.
+func (j Job) IsSuccessExitCode(code int) bool {
+	if len(j.SuccessExitCodes) == 0 {
+		return code == 0
+	}
+	for _, cur := range j.SuccessExitCodes {
+		if cur == code {
+			return true
+		}
+	}
+	return false
+}
+
 func (j Job) LogPrefix() string {
 	var r string
 	if j.MultiJobID != nil {
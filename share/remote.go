@@ -2,9 +2,12 @@ package chshare
 
 import (
 	"errors"
+	"fmt"
+	"net"
 	"net/url"
 	"regexp"
 	"strings"
+	"time"
 )
 
 // short-hand conversions
@@ -20,9 +23,21 @@ import (
 //   192.168.0.1:3000:google.com:80 ->
 //     local  192.168.0.1:3000
 //     remote google.com:80
+//   5353:8.8.8.8:53/udp ->
+//     local  0.0.0.0:5353
+//     remote 8.8.8.8:53 forwarded over udp
+//   [::]:3000:[fd00::1]:22 ->
+//     local  [::]:3000, IPv6 hosts are enclosed in square brackets
+//     remote [fd00::1]:22
 
 const ZeroHost = "0.0.0.0"
 
+// Protocols of tunnels.
+const (
+	ProtocolTCP = "tcp"
+	ProtocolUDP = "udp"
+)
+
 // TODO(m-terel): Remote should be only used for parsing command args and URL query params. Current Remote is kind of a Tunnel model. Refactor to use separate models for representation and business logic.
 type Remote struct {
 	LocalHost       string  `json:"lhost"`
@@ -32,15 +47,40 @@ type Remote struct {
 	LocalPortRandom bool    `json:"lport_random"`
 	Scheme          *string `json:"scheme"`
 	ACL             *string `json:"acl"` // string representation of Tunnel.TunnelACL field
+	// IdleTimeoutSec is a number of seconds without connections after which the tunnel is closed. 0 means no timeout.
+	IdleTimeoutSec int `json:"idle_timeout_sec"`
+	// AutoCloseAt is a time when the tunnel is closed. nil means it's not closed automatically.
+	AutoCloseAt *time.Time `json:"auto_close_at"`
+	// Protocol is either tcp or udp. Empty means tcp.
+	Protocol string `json:"protocol"`
+	// HTTPProxy defines whether rportd serves the tunnel with https and forwards http requests to the remote. The remote
+	// is requested with https if Scheme is "https", otherwise with http.
+	HTTPProxy bool `json:"http_proxy"`
+	// HostHeader replaces the Host header of requests forwarded by the http proxy. Empty means it's not changed.
+	HostHeader string `json:"host_header"`
+	// ProxyAuth defines whether the http proxy requires rportd API credentials, basic auth or a bearer token.
+	ProxyAuth bool `json:"proxy_auth"`
 }
 
 func DecodeRemote(s string) (*Remote, error) {
-	parts := strings.Split(s, ":")
+	protocol := ProtocolTCP
+	if i := strings.LastIndex(s, "/"); i >= 0 {
+		protocol = s[i+1:]
+		s = s[:i]
+		if err := ValidateProtocol(protocol); err != nil {
+			return nil, err
+		}
+	}
+
+	parts, err := splitRemote(s)
+	if err != nil {
+		return nil, err
+	}
 	if len(parts) <= 0 || len(parts) >= 5 {
 		return nil, errors.New("Invalid remote")
 	}
 
-	r := &Remote{}
+	r := &Remote{Protocol: protocol}
 	for i := len(parts) - 1; i >= 0; i-- {
 		p := parts[i]
 		if isPort(p) {
@@ -54,13 +94,14 @@ func DecodeRemote(s string) (*Remote, error) {
 		if r.RemotePort == "" && r.LocalPort == "" {
 			return nil, errors.New("Missing ports")
 		}
-		if !isHost(p) {
-			return nil, errors.New("Invalid host")
+		host, err := parseHost(p)
+		if err != nil {
+			return nil, err
 		}
 		if r.RemoteHost == "" {
-			r.RemoteHost = p
+			r.RemoteHost = host
 		} else {
-			r.LocalHost = p
+			r.LocalHost = host
 		}
 	}
 	if r.LocalHost == "" && r.LocalPort != "" {
@@ -83,13 +124,70 @@ func isHost(s string) bool {
 	return err == nil
 }
 
+// splitRemote splits a given remote by colons except the ones of IPv6 hosts enclosed in square brackets.
+func splitRemote(s string) ([]string, error) {
+	var parts []string
+	for {
+		end := 0
+		if strings.HasPrefix(s, "[") {
+			end = strings.Index(s, "]")
+			if end < 0 {
+				return nil, errors.New("Missing ']' in IPv6 host")
+			}
+		}
+		i := strings.Index(s[end:], ":")
+		if i < 0 {
+			return append(parts, s), nil
+		}
+		parts = append(parts, s[:end+i])
+		s = s[end+i+1:]
+	}
+}
+
+// parseHost returns a given host of a remote with square brackets of an IPv6 address removed.
+func parseHost(s string) (string, error) {
+	if strings.HasPrefix(s, "[") {
+		host := strings.TrimPrefix(strings.TrimSuffix(s, "]"), "[")
+		if !strings.HasSuffix(s, "]") || net.ParseIP(host) == nil {
+			return "", errors.New("Invalid IPv6 host")
+		}
+		return host, nil
+	}
+	if !isHost(s) {
+		return "", errors.New("Invalid host")
+	}
+	return s, nil
+}
+
+// ValidateProtocol returns an error if a given tunnel protocol is not supported.
+func ValidateProtocol(protocol string) error {
+	if protocol != ProtocolTCP && protocol != ProtocolUDP {
+		return fmt.Errorf("Invalid protocol %q, expected one of: %s, %s", protocol, ProtocolTCP, ProtocolUDP)
+	}
+	return nil
+}
+
 //implement Stringer
 func (r *Remote) String() string {
-	return r.LocalHost + ":" + r.LocalPort + ":" + r.Remote()
+	s := net.JoinHostPort(r.LocalHost, r.LocalPort) + ":" + r.Remote()
+	if r.IsUDP() {
+		s += "/" + ProtocolUDP
+	}
+	return s
+}
+
+// IsUDP returns true if the remote is forwarded over udp.
+func (r *Remote) IsUDP() bool {
+	return r.Protocol == ProtocolUDP
+}
+
+// SameTarget returns true if both remotes forward to the same remote address over the same protocol.
+func (r *Remote) SameTarget(other *Remote) bool {
+	return r.Remote() == other.Remote() && r.IsUDP() == other.IsUDP()
 }
 
 func (r *Remote) Remote() string {
-	return r.RemoteHost + ":" + r.RemotePort
+	return net.JoinHostPort(r.RemoteHost, r.RemotePort)
 }
 
 func (r *Remote) Equals(other *Remote) bool {
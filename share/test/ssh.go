@@ -17,9 +17,19 @@ type ConnMock struct {
 	ReturnErr             error
 	ReturnRemoteAddr      net.Addr
 
+	// IgnoredRequests are names of requests that are neither recorded nor signaled to DoneChannel.
+	IgnoredRequests []string
+
 	inputRequestName string
 	inputWantReply   bool
 	inputPayload     []byte
+	inputRequests    []SentRequest
+}
+
+type SentRequest struct {
+	Name      string
+	WantReply bool
+	Payload   []byte
 }
 
 func NewConnMock() *ConnMock {
@@ -27,17 +37,32 @@ func NewConnMock() *ConnMock {
 }
 
 func (c *ConnMock) SendRequest(name string, wantReply bool, payload []byte) (bool, []byte, error) {
+	for _, ignored := range c.IgnoredRequests {
+		if name == ignored {
+			return c.ReturnOk, c.ReturnResponsePayload, c.ReturnErr
+		}
+	}
+
 	c.mu.Lock()
-	defer c.mu.Unlock()
 	c.inputRequestName = name
 	c.inputWantReply = wantReply
 	c.inputPayload = payload
+	c.inputRequests = append(c.inputRequests, SentRequest{Name: name, WantReply: wantReply, Payload: payload})
+	c.mu.Unlock()
+
 	if c.DoneChannel != nil {
 		c.DoneChannel <- true
 	}
 	return c.ReturnOk, c.ReturnResponsePayload, c.ReturnErr
 }
 
+// InputSendRequests returns all sent requests in the order they were sent.
+func (c *ConnMock) InputSendRequests() []SentRequest {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]SentRequest(nil), c.inputRequests...)
+}
+
 func (c *ConnMock) InputSendRequest() (name string, wantReply bool, payload []byte) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
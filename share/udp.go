@@ -0,0 +1,101 @@
+package chshare
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/jpillora/sizestr"
+)
+
+// UDPChannelType is a type of ssh channels that forward udp datagrams of a single peer. Each datagram is sent over
+// the channel prefixed with its length, see WriteDatagram and ReadDatagram.
+const UDPChannelType = "rport-udp"
+
+// MaxDatagramSize is the max size of a udp datagram payload.
+const MaxDatagramSize = 65507
+
+// WriteDatagram writes a given udp datagram to a stream prefixed with its length.
+func WriteDatagram(w io.Writer, b []byte) error {
+	if len(b) > MaxDatagramSize {
+		return fmt.Errorf("datagram is too large: %d bytes", len(b))
+	}
+	buf := make([]byte, 2+len(b))
+	binary.BigEndian.PutUint16(buf, uint16(len(b)))
+	copy(buf[2:], b)
+	_, err := w.Write(buf)
+	return err
+}
+
+// ReadDatagram reads a udp datagram written by WriteDatagram to a given buffer that should fit MaxDatagramSize.
+// Returns the size of the datagram.
+func ReadDatagram(r io.Reader, buf []byte) (int, error) {
+	var size [2]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return 0, err
+	}
+	n := int(binary.BigEndian.Uint16(size[:]))
+	if n > len(buf) {
+		return 0, fmt.Errorf("datagram is too large: %d bytes", n)
+	}
+	return io.ReadFull(r, buf[:n])
+}
+
+// HandleUDPStream forwards datagrams received from a given stream to a remote udp address and sends the replies back
+// until the stream is closed.
+func HandleUDPStream(l *Logger, connStats *ConnStats, src io.ReadWriteCloser, remote string) {
+	dst, err := net.Dial("udp", remote)
+	if err != nil {
+		l.Debugf("Remote failed (%s)", err)
+		src.Close()
+		return
+	}
+	connStats.Open()
+	l.Debugf("%s: Open", connStats)
+
+	var sent, received int64
+	var wg sync.WaitGroup
+	var o sync.Once
+	close := func() {
+		src.Close()
+		dst.Close()
+	}
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		defer o.Do(close)
+		buf := make([]byte, MaxDatagramSize)
+		for {
+			n, err := ReadDatagram(src, buf)
+			if err != nil {
+				return
+			}
+			if _, err := dst.Write(buf[:n]); err != nil {
+				l.Debugf("Failed to send datagram: %s", err)
+				return
+			}
+			sent += int64(n)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		defer o.Do(close)
+		buf := make([]byte, MaxDatagramSize)
+		for {
+			n, err := dst.Read(buf)
+			if err != nil {
+				return
+			}
+			if err := WriteDatagram(src, buf[:n]); err != nil {
+				return
+			}
+			received += int64(n)
+		}
+	}()
+	wg.Wait()
+
+	connStats.Close()
+	l.Debugf("%s: Close (sent %s received %s)", connStats, sizestr.ToString(sent), sizestr.ToString(received))
+}
@@ -0,0 +1,63 @@
+package chshare
+
+import (
+	"bytes"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteReadDatagram(t *testing.T) {
+	buf := &bytes.Buffer{}
+	require.NoError(t, WriteDatagram(buf, []byte("first")))
+	require.NoError(t, WriteDatagram(buf, []byte{}))
+	require.NoError(t, WriteDatagram(buf, []byte("second")))
+
+	got := make([]byte, MaxDatagramSize)
+	for _, want := range []string{"first", "", "second"} {
+		n, err := ReadDatagram(buf, got)
+		require.NoError(t, err)
+		assert.Equal(t, want, string(got[:n]))
+	}
+
+	err := WriteDatagram(buf, make([]byte, MaxDatagramSize+1))
+	assert.EqualError(t, err, "datagram is too large: 65508 bytes")
+}
+
+func TestHandleUDPStream(t *testing.T) {
+	// udp echo server
+	pc, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer pc.Close()
+	go func() {
+		buf := make([]byte, MaxDatagramSize)
+		for {
+			n, addr, err := pc.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			_, _ = pc.WriteTo(bytes.ToUpper(buf[:n]), addr)
+		}
+	}()
+
+	src, stream := net.Pipe()
+	done := make(chan struct{})
+	go func() {
+		HandleUDPStream(NewLogger("test", LogOutput{File: os.Stdout}, LogLevelDebug), &ConnStats{}, stream, pc.LocalAddr().String())
+		close(done)
+	}()
+
+	buf := make([]byte, MaxDatagramSize)
+	for _, msg := range []string{"ping", "pong"} {
+		require.NoError(t, WriteDatagram(src, []byte(msg)))
+		n, err := ReadDatagram(src, buf)
+		require.NoError(t, err)
+		assert.Equal(t, bytes.ToUpper([]byte(msg)), buf[:n])
+	}
+
+	require.NoError(t, src.Close())
+	<-done
+}
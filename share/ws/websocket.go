@@ -61,6 +61,13 @@ func (ws *ConcurrentWebSocket) WriteMessage(messageType int, data []byte) error
 	return ws.Conn.WriteMessage(messageType, data)
 }
 
+// WriteIntermediateMessage writes a message that is not counted in writes before close, e.g. a chunk of command output.
+func (ws *ConcurrentWebSocket) WriteIntermediateMessage(messageType int, data []byte) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	return ws.Conn.WriteMessage(messageType, data)
+}
+
 func (ws *ConcurrentWebSocket) SetWritesBeforeClose(n int) {
 	ws.mu.Lock()
 	defer ws.mu.Unlock()